@@ -2,9 +2,12 @@ package main
 
 import (
 	"context"
+	"database/sql"
 	"errors"
 	"flag"
 	"fmt"
+	"math/rand/v2"
+	"net/http"
 	"os"
 	"os/signal"
 	"strings"
@@ -18,9 +21,32 @@ import (
 
 	"github.com/danielbukowski/twitch-chatbot/internal/access_credentials/cipher"
 	"github.com/danielbukowski/twitch-chatbot/internal/access_credentials/storage"
+	channelinfo "github.com/danielbukowski/twitch-chatbot/internal/channel_info"
+	chatsettings "github.com/danielbukowski/twitch-chatbot/internal/chat_settings"
 	"github.com/danielbukowski/twitch-chatbot/internal/command"
 	"github.com/danielbukowski/twitch-chatbot/internal/config"
+	"github.com/danielbukowski/twitch-chatbot/internal/database"
+	"github.com/danielbukowski/twitch-chatbot/internal/event"
+	"github.com/danielbukowski/twitch-chatbot/internal/eventsub"
+	"github.com/danielbukowski/twitch-chatbot/internal/giveaway"
 	lg "github.com/danielbukowski/twitch-chatbot/internal/logger"
+	"github.com/danielbukowski/twitch-chatbot/internal/minigames"
+	"github.com/danielbukowski/twitch-chatbot/internal/moderation"
+	"github.com/danielbukowski/twitch-chatbot/internal/moderation/actions"
+	"github.com/danielbukowski/twitch-chatbot/internal/moderation/blocklist"
+	"github.com/danielbukowski/twitch-chatbot/internal/moderation/raidguard"
+	"github.com/danielbukowski/twitch-chatbot/internal/moderation/strikes"
+	"github.com/danielbukowski/twitch-chatbot/internal/permission"
+	"github.com/danielbukowski/twitch-chatbot/internal/points"
+	"github.com/danielbukowski/twitch-chatbot/internal/polls"
+	"github.com/danielbukowski/twitch-chatbot/internal/presence"
+	"github.com/danielbukowski/twitch-chatbot/internal/quotes"
+	"github.com/danielbukowski/twitch-chatbot/internal/raid"
+	"github.com/danielbukowski/twitch-chatbot/internal/redemption"
+	streamstatus "github.com/danielbukowski/twitch-chatbot/internal/stream_status"
+	twitchapi "github.com/danielbukowski/twitch-chatbot/internal/twitch_api"
+	viewerqueue "github.com/danielbukowski/twitch-chatbot/internal/viewer_queue"
+	"github.com/danielbukowski/twitch-chatbot/internal/watchtime"
 	"github.com/gempir/go-twitch-irc/v4"
 	"github.com/nicklaw5/helix/v2"
 	"go.uber.org/zap"
@@ -35,6 +61,8 @@ func main() {
 
 	isDevFlag := flag.Bool("dev", false, "development environment check")
 	code := flag.String("code", "", "twitch authorization code to get access credentials")
+	quotesFile := flag.String("import-quotes", "", "path to a CSV or JSON file with quotes exported by another bot")
+	broadcasterCode := flag.String("broadcaster-code", "", "twitch authorization code of the broadcaster to manage polls and predictions")
 	flag.Parse()
 
 	cfg, err := config.New(*isDevFlag)
@@ -57,11 +85,13 @@ func main() {
 		logger.Panic("failed to create AES cipher", zap.Error(err))
 	}
 
-	accessCredentialsStorage, err := storage.NewSQLiteStorage(ctx, "file:./db/database.db", cfg.DatabaseUsername, cfg.DatabasePassword, accessCredentialsCipher, logger)
+	db, err := database.Open(ctx, "file:./db/database.db", cfg.DatabaseUsername, cfg.DatabasePassword)
 	if err != nil {
 		logger.Panic("failed to establish a connection to SQLite", zap.Error(err))
 	}
 
+	accessCredentialsStorage := storage.NewSQLiteStorage(db, accessCredentialsCipher, logger)
+
 	helixClient, err := helix.NewClient(&helix.Options{
 		ClientID:     cfg.TwitchClientID,
 		ClientSecret: cfg.TwitchClientSecret,
@@ -87,6 +117,24 @@ func main() {
 		logger.Info("successfully exchanged and saved access credentials!")
 	}
 
+	broadcasterCredentialsKey := "broadcaster:" + cfg.TwitchChannelName
+
+	if *isDevFlag && len(*broadcasterCode) != 0 {
+		logger.Info("exchanging authorization code for access credentials of the broadcaster...")
+
+		resp, err := helixClient.RequestUserAccessToken(*broadcasterCode)
+		if err != nil || resp.StatusCode != 200 {
+			logger.Panic("failed to exchange the code for access credentials of the broadcaster", zap.Error(err))
+		}
+
+		err = accessCredentialsStorage.Save(ctx, resp.Data, broadcasterCredentialsKey)
+		if err != nil {
+			logger.Panic("failed to save the exchanged access credentials of the broadcaster to database", zap.Error(err))
+		}
+
+		logger.Info("successfully exchanged and saved access credentials of the broadcaster!")
+	}
+
 	accessCredentials, err := accessCredentialsStorage.Retrieve(ctx, cfg.TwitchChannelName)
 	if err != nil {
 		logger.Panic("failed to retrieve access credentials from the database", zap.Error(err))
@@ -95,10 +143,90 @@ func main() {
 	ircClient := twitch.NewClient(cfg.TwitchChatbotName, fmt.Sprintf("oauth:%s", accessCredentials.AccessToken))
 	ircClient.Join(cfg.TwitchChannelName)
 
+	if isAccessTokenValid, _, err := helixClient.ValidateToken(accessCredentials.AccessToken); !isAccessTokenValid && err == nil {
+		logger.Info("access credentials have expired")
+
+		resp, err := helixClient.RefreshUserAccessToken(accessCredentials.RefreshToken)
+		if err != nil || resp.StatusCode != 200 {
+			logger.Panic("failed to refresh access credentials", zap.Error(err))
+		}
+
+		logger.Info("refreshed the expired access credentials")
+
+		err = accessCredentialsStorage.Update(ctx, resp.Data, cfg.TwitchChannelName)
+		if err != nil {
+			logger.Panic("failed to update access credentials", zap.Error(err))
+		}
+
+		logger.Info("saved new access credentials to the database")
+
+		ircClient.SetIRCToken(fmt.Sprintf("oauth:%s", resp.Data.AccessToken))
+		accessCredentials = resp.Data
+	}
+
+	helixClient.SetUserAccessToken(accessCredentials.AccessToken)
+	helixClient.SetRefreshToken(accessCredentials.RefreshToken)
+
+	chatbotUser, err := twitchapi.FetchUser(helixClient, cfg.TwitchChatbotName)
+	if err != nil {
+		logger.Panic("failed to fetch the chatbot user from Twitch API", zap.Error(err))
+	}
+
+	// Channel points, polls, predictions, hype trains and editing the channel require the token of the broadcaster.
+	// Without it, these features are disabled or fall back to the chat, like chat polls.
+	var broadcasterHelixClient *helix.Client
+	broadcasterCredentials, err := accessCredentialsStorage.Retrieve(ctx, broadcasterCredentialsKey)
+	switch {
+	case errors.Is(err, sql.ErrNoRows):
+		logger.Info("no access credentials of the broadcaster, features of the broadcaster are disabled")
+	case err != nil:
+		logger.Panic("failed to retrieve access credentials of the broadcaster from the database", zap.Error(err))
+	default:
+		broadcasterHelixClient, err = helix.NewClient(&helix.Options{
+			ClientID:        cfg.TwitchClientID,
+			ClientSecret:    cfg.TwitchClientSecret,
+			RedirectURI:     cfg.TwitchOAuth2RedirectURI,
+			UserAccessToken: broadcasterCredentials.AccessToken,
+			RefreshToken:    broadcasterCredentials.RefreshToken,
+		})
+		if err != nil {
+			panic(err)
+		}
+
+		broadcasterHelixClient.OnUserAccessTokenRefreshed(func(accessToken, refreshToken string) {
+			refreshed := helix.AccessCredentials{AccessToken: accessToken, RefreshToken: refreshToken}
+			if err := accessCredentialsStorage.Update(ctx, refreshed, broadcasterCredentialsKey); err != nil {
+				logger.Error("failed to update access credentials of the broadcaster", zap.Error(err))
+			}
+		})
+	}
+
+	chatClient, err := command.NewMeteredChatClient(ircClient)
+	if err != nil {
+		logger.Panic("failed to create a metered chat client", zap.Error(err))
+	}
+
 	commandPrefix := "!"
 	commandController := command.NewController(commandPrefix, logger)
 
-	commandController.UseWith(command.ErrorHandler())
+	commandMetrics, err := command.Metrics()
+	if err != nil {
+		logger.Panic("failed to create command metrics", zap.Error(err))
+	}
+
+	errorReplier := command.NewErrorReplier(
+		cfg.ChatbotLanguage,
+		command.DefaultErrorPolicies,
+		twitchapi.NewWhisperer(helixClient, chatbotUser.ID),
+		30*time.Second,
+		5*time.Second,
+		logger,
+	)
+
+	commandController.UseWith(command.ErrorHandler(errorReplier))
+	commandController.UseWith(commandMetrics)
+	commandController.UseWith(command.Timeout(10 * time.Second))
+	commandController.UseWith(command.Recover())
 
 	if *isDevFlag {
 
@@ -106,6 +234,51 @@ func main() {
 		commandController.AddCommand(commandPrefix+"ping", command.Ping, []command.Filter{})
 	}
 
+	twitchCache := twitchapi.NewCachedClient(helixClient, 10*time.Minute, time.Hour)
+
+	permissions := permission.NewService(permission.NewSQLiteStorage(db), twitchCache, cfg.TwitchBotOwnerName, commandPrefix)
+
+	permissions.AddCommand(commandController, commandPrefix+"permit", permissions.Permit(), permission.Moderator)
+	permissions.AddCommand(commandController, commandPrefix+"perm", permissions.Perm(), permission.Broadcaster)
+
+	blockedPhrases := blocklist.New(blocklist.NewSQLiteStorage(db), moderation.Punishment{Action: moderation.ActionDelete}, commandPrefix)
+	if err = blockedPhrases.Load(ctx); err != nil {
+		logger.Panic("failed to load blocked phrases", zap.Error(err))
+	}
+
+	permissions.AddCommand(commandController, commandPrefix+"blockword", blockedPhrases.BlockWord(), permission.Moderator)
+
+	moderationActions := actions.NewService(
+		helixClient,
+		actions.NewSQLiteStorage(db),
+		twitchCache,
+		chatClient,
+		chatbotUser.ID,
+		chatbotUser.Login,
+		commandPrefix,
+		logger,
+	)
+
+	permissions.AddCommand(commandController, commandPrefix+"timeout", moderationActions.TimeoutCommand(), permission.Moderator)
+	permissions.AddCommand(commandController, commandPrefix+"ban", moderationActions.BanCommand(), permission.Moderator)
+	permissions.AddCommand(commandController, commandPrefix+"unban", moderationActions.UnbanCommand(), permission.Moderator)
+
+	strikeLadder, err := strikes.ParseLadder(cfg.StrikeLadder)
+	if err != nil {
+		logger.Panic("failed to parse the strike ladder", zap.Error(err))
+	}
+
+	strikeEnforcer := strikes.NewEnforcer(
+		strikes.NewSQLiteStorage(db),
+		moderationActions,
+		strikeLadder,
+		cfg.StrikeDecay,
+		commandPrefix,
+	)
+
+	permissions.AddCommand(commandController, commandPrefix+"strikes", strikeEnforcer.Strikes(), permission.Moderator)
+	permissions.AddCommand(commandController, commandPrefix+"pardon", strikeEnforcer.Pardon(), permission.Moderator)
+
 	chatMessageCounter, err = meter.Int64Counter(
 		"chat.message.counter",
 		metric.WithDescription("Number of messages on the chat."),
@@ -115,76 +288,343 @@ func main() {
 		logger.Panic("failed to create a chat message counter", zap.Error(err))
 	}
 
-	ircClient.OnPrivateMessage(func(privateMessage twitch.PrivateMessage) {
-		userMessage := privateMessage.Message
+	moderationEngine, err := moderation.NewEngine(strikeEnforcer, permissions, logger)
+	if err != nil {
+		logger.Panic("failed to create a moderation engine", zap.Error(err))
+	}
 
-		if strings.EqualFold(privateMessage.User.Name, cfg.TwitchChatbotName) {
-			return
+	moderationEngine.AddRule(blockedPhrases, permission.Moderator)
+	moderationEngine.AddRule(moderation.LinkRule{
+		AllowedDomains: []string{"twitch.tv", "youtube.com", "youtu.be"},
+		Punishment:     moderation.Punishment{Action: moderation.ActionDelete},
+	}, permission.VIP)
+	moderationEngine.AddRule(moderation.CapsRule{
+		MinLength:  15,
+		MaxRatio:   0.7,
+		Punishment: moderation.Punishment{Action: moderation.ActionWarn},
+	}, permission.Moderator)
+	moderationEngine.AddRule(moderation.SymbolRule{
+		MinLength:  10,
+		MaxRatio:   0.5,
+		Punishment: moderation.Punishment{Action: moderation.ActionDelete},
+	}, permission.Moderator)
+	moderationEngine.AddRule(moderation.EmoteSpamRule{
+		MaxEmotes:  15,
+		Punishment: moderation.Punishment{Action: moderation.ActionDelete},
+	}, permission.Moderator)
+	moderationEngine.AddRule(moderation.LengthRule{
+		MaxLength:  400,
+		Punishment: moderation.Punishment{Action: moderation.ActionDelete},
+	}, permission.Moderator)
+	moderationEngine.AddRule(moderation.RepeatedCharsRule{
+		MaxRepeats: 15,
+		Punishment: moderation.Punishment{Action: moderation.ActionTimeout, Duration: 10 * time.Second},
+	}, permission.Moderator)
+
+	chatSettings := chatsettings.NewService(helixClient, chatbotUser.ID, time.Minute, commandPrefix, logger)
+
+	permissions.AddCommand(commandController, commandPrefix+"slow", chatSettings.Slow(), permission.Moderator)
+	permissions.AddCommand(commandController, commandPrefix+"followers", chatSettings.Followers(), permission.Moderator)
+	permissions.AddCommand(commandController, commandPrefix+"emoteonly", chatSettings.EmoteOnly(), permission.Moderator)
+	permissions.AddCommand(commandController, commandPrefix+"subsonly", chatSettings.SubsOnly(), permission.Moderator)
+	permissions.AddCommand(commandController, commandPrefix+"uniquechat", chatSettings.UniqueChat(), permission.Moderator)
+
+	raidGuard, err := raidguard.NewGuard(
+		raidguard.DefaultConfig,
+		chatSettings,
+		moderationActions,
+		chatClient,
+		permissions,
+		chatbotUser.Login,
+		commandPrefix,
+		logger,
+	)
+	if err != nil {
+		logger.Panic("failed to create a raid guard", zap.Error(err))
+	}
+
+	eventBus, err := event.NewBus(logger)
+	if err != nil {
+		logger.Panic("failed to create an event bus", zap.Error(err))
+	}
+
+	thankYouTemplates, err := event.ParseTemplates(cfg.ThankYouTemplates)
+	if err != nil {
+		logger.Panic("failed to parse thank-you templates", zap.Error(err))
+	}
+
+	thanker, err := event.NewThanker(thankYouTemplates, chatClient, 3, 10*time.Second, logger)
+	if err != nil {
+		logger.Panic("failed to create a thanker", zap.Error(err))
+	}
+	thanker.Subscribe(eventBus)
+
+	userNotices := event.NewUserNotices(eventBus, 5*time.Second)
+
+	raids, err := raid.NewService(raid.DefaultConfig, helixClient, twitchCache, chatClient, moderationEngine, raidGuard, chatbotUser.ID, commandPrefix, logger)
+	if err != nil {
+		logger.Panic("failed to create a raid service", zap.Error(err))
+	}
+	eventBus.Subscribe(event.TypeRaid, raids.HandleRaid)
+
+	permissions.AddCommand(commandController, commandPrefix+"so", raids.ShoutoutCommand(), permission.Moderator)
+
+	viewerQueue := viewerqueue.NewService(viewerqueue.NewSQLiteStorage(db), permissions, logger)
+	permissions.AddCommand(commandController, commandPrefix+"join", viewerQueue.Join(), permission.Everyone)
+	permissions.AddCommand(commandController, commandPrefix+"leave", viewerQueue.Leave(), permission.Everyone)
+	permissions.AddCommand(commandController, commandPrefix+"position", viewerQueue.Position(), permission.Everyone)
+	permissions.AddCommand(commandController, commandPrefix+"queue", viewerQueue.Queue(), permission.Everyone)
+	permissions.AddCommand(commandController, commandPrefix+"next", viewerQueue.Next(), permission.Moderator)
+
+	redemptionRoutes, err := redemption.ParseRoutes(cfg.RedemptionRoutes)
+	if err != nil {
+		logger.Panic("failed to parse redemption routes", zap.Error(err))
+	}
+
+	redemptionHandlers := map[string]command.Handler{
+		"so": raids.ShoutoutCommand(),
+	}
+
+	redemptions, err := redemption.NewRouter(redemptionRoutes, redemptionHandlers, broadcasterHelixClient, chatClient, moderationActions, viewerQueue, logger)
+	if err != nil {
+		logger.Panic("failed to create a redemption router", zap.Error(err))
+	}
+	eventBus.Subscribe(event.TypeRedemption, redemptions.HandleRedemption)
+
+	streamStatus := streamstatus.NewService(helixClient, []string{cfg.TwitchChannelName}, time.Minute, logger)
+	eventBus.Subscribe(event.TypeStreamOnline, streamStatus.HandleEvent)
+	eventBus.Subscribe(event.TypeStreamOffline, streamStatus.HandleEvent)
+
+	channelInfo := channelinfo.NewService(helixClient, broadcasterHelixClient, streamStatus, permissions, logger)
+	permissions.AddCommand(commandController, commandPrefix+"uptime", channelInfo.Uptime(), permission.Everyone, command.Cooldown(5*time.Second))
+	permissions.AddCommand(commandController, commandPrefix+"title", channelInfo.Title(), permission.Everyone)
+	permissions.AddCommand(commandController, commandPrefix+"game", channelInfo.Game(), permission.Everyone)
+
+	broadcaster, err := twitchapi.FetchUser(helixClient, cfg.TwitchChannelName)
+	if err != nil {
+		logger.Panic("failed to fetch the broadcaster from Twitch API", zap.Error(err))
+	}
+
+	presenceTracker := presence.NewTracker(chatbotUser.Login, 10*time.Minute)
+
+	watchTimeStorage := watchtime.NewSQLiteStorage(db)
+	watchTimeTracker := watchtime.NewTracker(
+		helixClient,
+		watchTimeStorage,
+		streamStatus,
+		presenceTracker,
+		map[string]string{cfg.TwitchChannelName: broadcaster.ID},
+		chatbotUser.ID,
+		chatbotUser.Login,
+		time.Minute,
+		logger,
+	)
+
+	watchTimeCommands := watchtime.NewCommands(watchTimeStorage, twitchCache)
+	permissions.AddCommand(commandController, commandPrefix+"followage", watchTimeCommands.FollowAge(), permission.Everyone)
+	permissions.AddCommand(commandController, commandPrefix+"watchtime", watchTimeCommands.WatchTime(), permission.Everyone)
+	permissions.AddCommand(commandController, commandPrefix+"topwatchers", watchTimeCommands.TopWatchers(), permission.Everyone, command.Cooldown(30*time.Second))
+
+	pointsStorage := points.NewSQLiteStorage(db)
+	pointsService := points.NewService(
+		pointsStorage,
+		streamStatus,
+		presenceTracker,
+		10,
+		5*time.Minute,
+		points.Multipliers{Subscriber: 2, VIP: 1.5},
+		logger,
+	)
+
+	pointsCommands := points.NewCommands(pointsStorage, twitchCache)
+	permissions.AddCommand(commandController, commandPrefix+"points", pointsCommands.Points(), permission.Everyone)
+	permissions.AddCommand(commandController, commandPrefix+"give", pointsCommands.Give(), permission.Everyone)
+	permissions.AddCommand(commandController, commandPrefix+"addpoints", pointsCommands.AddPoints(), permission.Moderator)
+	permissions.AddCommand(commandController, commandPrefix+"toppoints", pointsCommands.TopPoints(), permission.Everyone, command.Cooldown(30*time.Second))
+
+	miniGames := minigames.NewService(pointsStorage, minigames.SystemClock{}, rand.New(rand.NewPCG(rand.Uint64(), rand.Uint64())), logger)
+	permissions.AddCommand(commandController, commandPrefix+"gamble", miniGames.Gamble(), permission.Everyone, command.Cooldown(5*time.Second))
+	permissions.AddCommand(commandController, commandPrefix+"duel", miniGames.Duel(), permission.Everyone)
+	permissions.AddCommand(commandController, commandPrefix+"accept", miniGames.Accept(), permission.Everyone)
+	permissions.AddCommand(commandController, commandPrefix+"decline", miniGames.Decline(), permission.Everyone)
+	permissions.AddCommand(commandController, commandPrefix+"heist", miniGames.Heist(), permission.Everyone, command.LiveOnly(streamStatus))
+
+	giveaways := giveaway.NewService(giveaway.NewSQLiteStorage(db), 2, 7*24*time.Hour, logger)
+	permissions.AddCommand(commandController, commandPrefix+"giveaway", giveaways.Giveaway(), permission.Moderator)
+	permissions.AddCommand(commandController, commandPrefix+"reroll", giveaways.Reroll(), permission.Moderator)
+
+	quotesStorage := quotes.NewSQLiteStorage(db)
+	if err = quotesStorage.RebuildSearchIndex(ctx); err != nil {
+		logger.Panic("failed to rebuild the search index of quotes", zap.Error(err))
+	}
+
+	if len(*quotesFile) != 0 {
+		exported, err := quotes.ReadFile(*quotesFile)
+		if err != nil {
+			logger.Panic("failed to read the exported quotes", zap.Error(err))
+		}
+
+		imported, err := quotesStorage.Import(ctx, strings.ToLower(cfg.TwitchChannelName), exported)
+		if err != nil {
+			logger.Panic("failed to import quotes", zap.Error(err))
 		}
 
-		if !strings.HasPrefix(userMessage, commandPrefix) {
+		logger.Info("imported quotes", zap.Int("imported", imported), zap.Int("skipped", len(exported)-imported))
+	}
+
+	quoteService := quotes.NewService(quotesStorage, helixClient, logger)
+	permissions.AddCommand(commandController, commandPrefix+"quote", quoteService.Quote(), permission.Everyone, command.Cooldown(5*time.Second))
+	permissions.AddCommand(commandController, commandPrefix+"addquote", quoteService.AddQuote(), permission.Moderator)
+	permissions.AddCommand(commandController, commandPrefix+"delquote", quoteService.DelQuote(), permission.Moderator)
+
+	pollService := polls.NewService(broadcasterHelixClient, chatClient, logger)
+	permissions.AddCommand(commandController, commandPrefix+"poll", pollService.Poll(), permission.Moderator)
+	permissions.AddCommand(commandController, commandPrefix+"predict", pollService.Predict(), permission.Moderator)
+	eventBus.Subscribe(event.TypePollEnd, pollService.HandleEvent)
+	eventBus.Subscribe(event.TypePredictionEnd, pollService.HandleEvent)
+
+	eventsubClient := eventsub.NewClient(eventsub.DefaultURL, helixClient, eventsub.ModeratorSubscriptions(broadcaster.ID, chatbotUser.ID), eventBus, logger)
+
+	// A WebSocket session of EventSub belongs to a single user, so subscriptions of the broadcaster need their own session.
+	var broadcasterEventsubClient *eventsub.Client
+	if broadcasterHelixClient != nil {
+		broadcasterEventsubClient = eventsub.NewClient(eventsub.DefaultURL, broadcasterHelixClient, eventsub.BroadcasterSubscriptions(broadcaster.ID), eventBus, logger)
+	}
+
+	httpMux := http.NewServeMux()
+	httpMux.HandleFunc("GET /queue/{channel}", viewerQueue.HandleQueue)
+	httpServer := &http.Server{Addr: cfg.HTTPAddress, Handler: httpMux, ReadHeaderTimeout: 5 * time.Second}
+
+	commandDispatcher, err := command.NewDispatcher(commandController, 4, 256, command.DropNewest, 5*time.Second, logger)
+	if err != nil {
+		logger.Panic("failed to create a command dispatcher", zap.Error(err))
+	}
+
+	commandDispatcher.Observe(
+		raidGuard.Observe,
+		moderationEngine.Process,
+		func(_ context.Context, privateMessage *twitch.PrivateMessage) bool {
+			presenceTracker.Observe(privateMessage)
+			giveaways.Observe(privateMessage)
+			pollService.Observe(privateMessage)
+			return false
+		},
+		func(ctx context.Context, privateMessage *twitch.PrivateMessage) bool {
+			redemptions.HandleMessage(ctx, privateMessage)
+			return false
+		},
+		func(ctx context.Context, privateMessage *twitch.PrivateMessage) bool {
+			if strings.HasPrefix(privateMessage.Message, commandPrefix) {
+				return false
+			}
+
 			chatMessageCounter.Add(ctx, 1)
+			return true
+		},
+	)
+
+	ircClient.OnPrivateMessage(func(privateMessage twitch.PrivateMessage) {
+		if strings.EqualFold(privateMessage.User.Name, cfg.TwitchChatbotName) {
 			return
 		}
 
-		commandController.CallCommand(ctx, userMessage, privateMessage, ircClient)
+		commandDispatcher.Dispatch(privateMessage.Message, privateMessage, chatClient)
+	})
+
+	ircClient.OnUserNoticeMessage(func(userNoticeMessage twitch.UserNoticeMessage) {
+		userNotices.Handle(ctx, userNoticeMessage)
 	})
 
 	ircClient.OnConnect(func() {
 		logger.Info("connected to the twitch chat!")
 	})
 
-	if isAccessTokenValid, _, err := helixClient.ValidateToken(accessCredentials.AccessToken); !isAccessTokenValid && err == nil {
-		logger.Info("access credentials have expired")
+	g, gCtx := errgroup.WithContext(ctx)
 
-		resp, err := helixClient.RefreshUserAccessToken(accessCredentials.RefreshToken)
-		if err != nil || resp.StatusCode != 200 {
-			logger.Panic("failed to refresh access credentials", zap.Error(err))
-		}
+	g.Go(func() error {
+		logger.Info("connecting to the twitch chat...")
+		return ircClient.Connect()
+	})
 
-		logger.Info("refreshed the expired access credentials")
+	// drained is closed, when queued commands have finished, so connections they use are closed only after them.
+	drained := make(chan struct{})
+	g.Go(func() error {
+		defer close(drained)
+		return commandDispatcher.Run(gCtx)
+	})
 
-		err = accessCredentialsStorage.Update(ctx, resp.Data, cfg.TwitchChannelName)
-		if err != nil {
-			logger.Panic("failed to update access credentials", zap.Error(err))
-		}
+	g.Go(func() error {
+		return raidGuard.Run(gCtx)
+	})
 
-		logger.Info("saved new access credentials to the database")
+	g.Go(func() error {
+		return chatSettings.Run(gCtx)
+	})
 
-		ircClient.SetIRCToken(fmt.Sprintf("oauth:%s", resp.Data.AccessToken))
-	}
+	g.Go(func() error {
+		return eventsubClient.Run(gCtx)
+	})
 
-	g, gCtx := errgroup.WithContext(ctx)
+	if broadcasterEventsubClient != nil {
+		g.Go(func() error {
+			return broadcasterEventsubClient.Run(gCtx)
+		})
+	}
 
 	g.Go(func() error {
-		logger.Info("connecting to the twitch chat...")
-		return ircClient.Connect()
+		return streamStatus.Run(gCtx)
 	})
 
 	g.Go(func() error {
-		<-gCtx.Done()
+		return watchTimeTracker.Run(gCtx)
+	})
 
-		lg.Flush(logger)
+	g.Go(func() error {
+		return pointsService.Run(gCtx)
+	})
 
-		ctx, cancelShutdown := context.WithTimeout(context.Background(), 5*time.Second)
-		defer cancelShutdown()
+	// refunded is closed, when stakes of pending games were given back, so the database is closed only after them.
+	refunded := make(chan struct{})
+	g.Go(func() error {
+		defer close(refunded)
+		return miniGames.Run(gCtx)
+	})
 
-		fmt.Println("closing the OpenTelemetry connections...")
-		return shutdown(ctx)
+	g.Go(func() error {
+		logger.Info("serving HTTP", zap.String("address", cfg.HTTPAddress))
+		if err := httpServer.ListenAndServe(); !errors.Is(err, http.ErrServerClosed) {
+			return err
+		}
+		return nil
 	})
 
 	g.Go(func() error {
 		<-gCtx.Done()
 
-		fmt.Println("closing the database connection...")
-		return accessCredentialsStorage.Close()
+		ctx, cancelShutdown := context.WithTimeout(context.Background(), 5*time.Second)
+		defer cancelShutdown()
+
+		fmt.Println("closing the HTTP server...")
+		return httpServer.Shutdown(ctx)
 	})
 
 	g.Go(func() error {
-		<-gCtx.Done()
+		<-drained
+		<-refunded
 
 		fmt.Println("closing the IRC server connection...")
-		return ircClient.Disconnect()
+		ircErr := ircClient.Disconnect()
+
+		fmt.Println("closing the database connection...")
+		dbErr := db.Close()
+
+		lg.Flush(logger)
+
+		ctx, cancelShutdown := context.WithTimeout(context.Background(), 5*time.Second)
+		defer cancelShutdown()
+
+		fmt.Println("closing the OpenTelemetry connections...")
+		return errors.Join(ircErr, dbErr, shutdown(ctx))
 	})
 
 	if err = g.Wait(); err != nil {
@@ -0,0 +1,29 @@
+-- +goose Up
+-- +goose StatementBegin
+CREATE TABLE permission_overrides (
+    permission_override_id INTEGER,
+    channel_name TEXT NOT NULL,
+    username TEXT NOT NULL,
+    command_name TEXT NOT NULL,
+    is_allowed INTEGER NOT NULL,
+    granted_by TEXT NOT NULL,
+    created_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    PRIMARY KEY (permission_override_id),
+    UNIQUE (channel_name, username, command_name)
+);
+
+CREATE TABLE command_permission_levels (
+    command_permission_level_id INTEGER,
+    channel_name TEXT NOT NULL,
+    command_name TEXT NOT NULL,
+    level TEXT NOT NULL,
+    PRIMARY KEY (command_permission_level_id),
+    UNIQUE (channel_name, command_name)
+);
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+DROP TABLE command_permission_levels;
+DROP TABLE permission_overrides;
+-- +goose StatementEnd
@@ -0,0 +1,20 @@
+-- +goose Up
+-- +goose StatementBegin
+CREATE TABLE strikes (
+    strike_id INTEGER,
+    channel_name TEXT NOT NULL,
+    user_id TEXT NOT NULL,
+    username TEXT NOT NULL,
+    rule TEXT NOT NULL,
+    created_at INTEGER NOT NULL,
+    PRIMARY KEY (strike_id)
+);
+
+CREATE INDEX strikes_channel_user_idx ON strikes (channel_name, user_id, created_at);
+CREATE INDEX strikes_channel_username_idx ON strikes (channel_name, username, created_at);
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+DROP TABLE strikes;
+-- +goose StatementEnd
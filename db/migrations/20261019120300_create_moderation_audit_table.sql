@@ -0,0 +1,24 @@
+-- +goose Up
+-- +goose StatementBegin
+CREATE TABLE moderation_audit (
+    moderation_audit_id INTEGER,
+    channel_name TEXT NOT NULL,
+    action TEXT NOT NULL,
+    target_user_id TEXT NOT NULL,
+    target_username TEXT NOT NULL,
+    message_id TEXT NOT NULL DEFAULT '',
+    moderator TEXT NOT NULL,
+    reason TEXT NOT NULL,
+    trigger TEXT NOT NULL,
+    duration_seconds INTEGER NOT NULL DEFAULT 0,
+    created_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    PRIMARY KEY (moderation_audit_id)
+);
+
+CREATE INDEX moderation_audit_channel_target_idx ON moderation_audit (channel_name, target_user_id);
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+DROP TABLE moderation_audit;
+-- +goose StatementEnd
@@ -0,0 +1,19 @@
+-- +goose Up
+-- +goose StatementBegin
+CREATE TABLE watch_time (
+    channel_name TEXT NOT NULL,
+    user_id TEXT NOT NULL,
+    username TEXT NOT NULL,
+    seconds INTEGER NOT NULL DEFAULT 0,
+    updated_at INTEGER NOT NULL,
+    PRIMARY KEY (channel_name, user_id)
+);
+
+CREATE INDEX watch_time_channel_username_idx ON watch_time (channel_name, username);
+CREATE INDEX watch_time_channel_seconds_idx ON watch_time (channel_name, seconds DESC);
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+DROP TABLE watch_time;
+-- +goose StatementEnd
@@ -0,0 +1,26 @@
+-- +goose Up
+-- +goose StatementBegin
+CREATE TABLE points_balances (
+    channel_name TEXT NOT NULL,
+    user_id TEXT NOT NULL,
+    username TEXT NOT NULL,
+    balance INTEGER NOT NULL DEFAULT 0 CHECK (balance >= 0),
+    updated_at INTEGER NOT NULL,
+    PRIMARY KEY (channel_name, user_id)
+);
+
+CREATE INDEX points_balances_channel_username_idx ON points_balances (channel_name, username);
+CREATE INDEX points_balances_channel_balance_idx ON points_balances (channel_name, balance DESC);
+
+CREATE TABLE points_accruals (
+    channel_name TEXT NOT NULL,
+    bucket INTEGER NOT NULL,
+    PRIMARY KEY (channel_name, bucket)
+);
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+DROP TABLE points_accruals;
+DROP TABLE points_balances;
+-- +goose StatementEnd
@@ -0,0 +1,21 @@
+-- +goose Up
+-- +goose StatementBegin
+CREATE TABLE giveaway_draws (
+    id INTEGER PRIMARY KEY AUTOINCREMENT,
+    channel_name TEXT NOT NULL,
+    keyword TEXT NOT NULL,
+    winner_id TEXT NOT NULL,
+    winner_name TEXT NOT NULL,
+    seed TEXT NOT NULL,
+    entrants TEXT NOT NULL,
+    reroll INTEGER NOT NULL DEFAULT 0,
+    drawn_at INTEGER NOT NULL
+);
+
+CREATE INDEX giveaway_draws_channel_drawn_at_idx ON giveaway_draws (channel_name, drawn_at);
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+DROP TABLE giveaway_draws;
+-- +goose StatementEnd
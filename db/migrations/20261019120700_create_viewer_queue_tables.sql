@@ -0,0 +1,27 @@
+-- +goose Up
+-- +goose StatementBegin
+CREATE TABLE viewer_queue_entries (
+    channel_name TEXT NOT NULL,
+    user_id TEXT NOT NULL,
+    username TEXT NOT NULL,
+    display_name TEXT NOT NULL,
+    note TEXT NOT NULL DEFAULT '',
+    is_subscriber INTEGER NOT NULL DEFAULT 0,
+    joined_at INTEGER NOT NULL,
+    PRIMARY KEY (channel_name, user_id)
+);
+
+CREATE INDEX viewer_queue_entries_channel_joined_at_idx ON viewer_queue_entries (channel_name, joined_at);
+
+CREATE TABLE viewer_queue_settings (
+    channel_name TEXT PRIMARY KEY,
+    is_open INTEGER NOT NULL DEFAULT 0,
+    priority TEXT NOT NULL DEFAULT 'none'
+);
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+DROP TABLE viewer_queue_settings;
+DROP TABLE viewer_queue_entries;
+-- +goose StatementEnd
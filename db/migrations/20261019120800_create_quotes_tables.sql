@@ -0,0 +1,29 @@
+-- +goose Up
+-- +goose StatementBegin
+CREATE TABLE quotes (
+    id INTEGER PRIMARY KEY AUTOINCREMENT,
+    channel_name TEXT NOT NULL,
+    number INTEGER NOT NULL,
+    text TEXT NOT NULL,
+    game TEXT NOT NULL DEFAULT '',
+    added_by TEXT NOT NULL,
+    created_at INTEGER NOT NULL,
+    UNIQUE (channel_name, number)
+);
+
+-- Numbers of quotes come from a counter, so a number of a deleted quote is never given to a new one.
+CREATE TABLE quote_counters (
+    channel_name TEXT PRIMARY KEY,
+    last_number INTEGER NOT NULL
+);
+
+-- The index is kept up to date by the chatbot built with the sqlite_fts5 tag, so builds without FTS5 can still write quotes.
+CREATE VIRTUAL TABLE quotes_fts USING fts5 (text, content = 'quotes', content_rowid = 'id');
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+DROP TABLE quotes_fts;
+DROP TABLE quote_counters;
+DROP TABLE quotes;
+-- +goose StatementEnd
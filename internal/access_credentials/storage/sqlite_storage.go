@@ -4,17 +4,14 @@ import (
 	"context"
 	"database/sql"
 	"errors"
-	"fmt"
-	"time"
 
+	"github.com/danielbukowski/twitch-chatbot/internal/database"
 	"github.com/nicklaw5/helix/v2"
 	"go.opentelemetry.io/otel"
 	"go.opentelemetry.io/otel/codes"
 	"go.uber.org/zap"
 )
 
-const databaseRequestTimeout = 3 * time.Second
-
 var tracer = otel.Tracer("github.com/danielbukowski/twitch-chatbot/internal/access_credentials/storage")
 
 type accessCredentialsCipher interface {
@@ -28,29 +25,12 @@ type SQLiteStorage struct {
 	logger                  *zap.Logger
 }
 
-func NewSQLiteStorage(ctx context.Context, dataSourceName, username, password string, accessCredentialsCipher accessCredentialsCipher, logger *zap.Logger) (*SQLiteStorage, error) {
-	db, err := sql.Open("sqlite3", fmt.Sprintf("%s?_auth&_auth_user=%s&_auth_pass=%s&_auth_crypt=SHA384", dataSourceName, username, password))
-	if err != nil {
-		return nil, err
-	}
-
-	ctx, cancel := context.WithTimeout(ctx, databaseRequestTimeout)
-	defer cancel()
-
-	err = db.PingContext(ctx)
-	if err != nil {
-		return nil, err
-	}
-
+func NewSQLiteStorage(db *sql.DB, accessCredentialsCipher accessCredentialsCipher, logger *zap.Logger) *SQLiteStorage {
 	return &SQLiteStorage{
 		db:                      db,
 		accessCredentialsCipher: accessCredentialsCipher,
 		logger:                  logger.Named("access_credentials/storage"),
-	}, nil
-}
-
-func (s *SQLiteStorage) Close() error {
-	return s.db.Close()
+	}
 }
 
 func (s *SQLiteStorage) Retrieve(ctx context.Context, channelName string) (helix.AccessCredentials, error) {
@@ -59,7 +39,7 @@ func (s *SQLiteStorage) Retrieve(ctx context.Context, channelName string) (helix
 	ctx, span := tracer.Start(ctx, "retrieve")
 	defer span.End()
 
-	ctx, cancel := context.WithTimeout(ctx, databaseRequestTimeout)
+	ctx, cancel := context.WithTimeout(ctx, database.RequestTimeout)
 	defer cancel()
 
 	span.AddEvent("executing the query")
@@ -107,7 +87,7 @@ func (s *SQLiteStorage) Save(ctx context.Context, accessCredentials helix.Access
 	}
 	span.AddEvent("successfully encrypted access credentials")
 
-	ctx, cancel := context.WithTimeout(ctx, databaseRequestTimeout)
+	ctx, cancel := context.WithTimeout(ctx, database.RequestTimeout)
 	defer cancel()
 
 	span.AddEvent("creating a prepared statement")
@@ -155,7 +135,7 @@ func (s *SQLiteStorage) Update(ctx context.Context, accessCredentials helix.Acce
 	}
 	span.AddEvent("successfully encrypted access credentials")
 
-	ctx, cancel := context.WithTimeout(ctx, databaseRequestTimeout)
+	ctx, cancel := context.WithTimeout(ctx, database.RequestTimeout)
 	defer cancel()
 
 	span.AddEvent("creating a prepared statement")
@@ -0,0 +1,267 @@
+package channelinfo
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/danielbukowski/twitch-chatbot/internal/command"
+	"github.com/danielbukowski/twitch-chatbot/internal/permission"
+	streamstatus "github.com/danielbukowski/twitch-chatbot/internal/stream_status"
+	twitchapi "github.com/danielbukowski/twitch-chatbot/internal/twitch_api"
+	"github.com/gempir/go-twitch-irc/v4"
+	"github.com/nicklaw5/helix/v2"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.uber.org/zap"
+)
+
+var tracer = otel.Tracer("github.com/danielbukowski/twitch-chatbot/internal/channel_info")
+
+const (
+	maxTitleLength = 140 // maxTitleLength is the longest title accepted by Twitch.
+	searchLimit    = 20  // searchLimit is a number of categories fetched for matching a name.
+)
+
+var errNotModerator = command.PermissionDeniedError(errors.New("tried to change channel information without being a moderator"))
+
+var errEditingDisabled = command.UsageError("changing channel information requires the broadcaster to authorize the chatbot")
+
+// statusReader returns a status of a stream.
+type statusReader interface {
+	Status(channelName string) streamstatus.Status
+}
+
+// levelResolver returns a permission level of the author of a message.
+type levelResolver interface {
+	LevelOf(ctx context.Context, privMsg *twitch.PrivateMessage, required permission.Level) (permission.Level, error)
+}
+
+// Service reads and changes a title and a category of a channel through Twitch API.
+// Changes require a user access token of the broadcaster with the channel:manage:broadcast scope.
+type Service struct {
+	helixClient   *helix.Client    // HelixClient is used to read channel information.
+	editorClient  *helix.Client    // EditorClient is used to change channel information, it's nil without a token of the broadcaster.
+	status        statusReader     // Status tells, when a stream started.
+	levelResolver levelResolver    // LevelResolver tells, if a user may change channel information.
+	now           func() time.Time // Now returns the current time.
+	logger        *zap.Logger      // Logger is used for logging.
+}
+
+// NewService creates an instance of Service. Changes of channel information are disabled, when editorClient is nil.
+func NewService(helixClient, editorClient *helix.Client, status statusReader, levelResolver levelResolver, logger *zap.Logger) *Service {
+	return &Service{
+		helixClient:   helixClient,
+		editorClient:  editorClient,
+		status:        status,
+		levelResolver: levelResolver,
+		now:           time.Now,
+		logger:        logger.Named("channel_info"),
+	}
+}
+
+// Uptime tells how long the stream has been live.
+// Usage: `!uptime`.
+func (s *Service) Uptime() command.Handler {
+	return func(ctx context.Context, _ []string, chatClient command.ChatClient) error {
+		_, span := tracer.Start(ctx, "uptimeCommand")
+		defer span.End()
+
+		cmdCtx := command.UnwrapContext(ctx)
+		privMsg := cmdCtx.PrivMsg
+
+		status := s.status.Status(privMsg.Channel)
+		if !status.Live {
+			span.SetStatus(codes.Ok, "the stream is offline")
+			chatClient.Say(privMsg.Channel, fmt.Sprintf("@%s, the stream is offline.", privMsg.User.DisplayName))
+			return nil
+		}
+
+		span.SetStatus(codes.Ok, "successfully got uptime of the stream")
+		chatClient.Say(privMsg.Channel, fmt.Sprintf("@%s, the stream has been live for %s.", privMsg.User.DisplayName, formatUptime(s.now().Sub(status.StartedAt))))
+		return nil
+	}
+}
+
+// Title shows the title of the stream, or changes it, when a moderator gives a new one.
+// Usage: `!title [new title]`.
+func (s *Service) Title() command.Handler {
+	return func(ctx context.Context, args []string, chatClient command.ChatClient) error {
+		spanCtx, span := tracer.Start(ctx, "titleCommand")
+		defer span.End()
+
+		cmdCtx := command.UnwrapContext(ctx)
+		privMsg := cmdCtx.PrivMsg
+
+		if len(args) == 0 {
+			info, err := twitchapi.FetchChannelInformation(s.helixClient, privMsg.RoomID)
+			if err != nil {
+				span.SetStatus(codes.Error, "failed to fetch channel information")
+				span.RecordError(err)
+				return command.UpstreamError(err)
+			}
+
+			span.SetStatus(codes.Ok, "successfully got the title")
+			chatClient.Say(privMsg.Channel, fmt.Sprintf("@%s, the title is: %s", privMsg.User.DisplayName, info.Title))
+			return nil
+		}
+
+		if err := s.requireEditor(spanCtx, privMsg); err != nil {
+			span.SetStatus(codes.Error, "user may not change the title")
+			return err
+		}
+
+		title := strings.Join(args, " ")
+		if len([]rune(title)) > maxTitleLength {
+			span.SetStatus(codes.Error, "title is too long")
+			return command.UsageError(fmt.Sprintf("the title can't be longer than %d characters", maxTitleLength))
+		}
+
+		if err := s.edit(spanCtx, &helix.EditChannelInformationParams{BroadcasterID: privMsg.RoomID, Title: title}); err != nil {
+			span.SetStatus(codes.Error, "failed to change the title")
+			return command.UpstreamError(err)
+		}
+
+		span.SetStatus(codes.Ok, "successfully changed the title")
+		chatClient.Say(privMsg.Channel, fmt.Sprintf("@%s, changed the title to: %s", privMsg.User.DisplayName, title))
+		return nil
+	}
+}
+
+// Game shows the category of the stream, or changes it, when a moderator gives a name of a new one.
+// The name does not have to be exact, the closest category found on Twitch is picked.
+// Usage: `!game [name]`.
+func (s *Service) Game() command.Handler {
+	return func(ctx context.Context, args []string, chatClient command.ChatClient) error {
+		spanCtx, span := tracer.Start(ctx, "gameCommand")
+		defer span.End()
+
+		cmdCtx := command.UnwrapContext(ctx)
+		privMsg := cmdCtx.PrivMsg
+
+		if len(args) == 0 {
+			info, err := twitchapi.FetchChannelInformation(s.helixClient, privMsg.RoomID)
+			if err != nil {
+				span.SetStatus(codes.Error, "failed to fetch channel information")
+				span.RecordError(err)
+				return command.UpstreamError(err)
+			}
+
+			span.SetStatus(codes.Ok, "successfully got the category")
+			chatClient.Say(privMsg.Channel, fmt.Sprintf("@%s, the category is: %s", privMsg.User.DisplayName, info.GameName))
+			return nil
+		}
+
+		if err := s.requireEditor(spanCtx, privMsg); err != nil {
+			span.SetStatus(codes.Error, "user may not change the category")
+			return err
+		}
+
+		query := strings.Join(args, " ")
+		category, found, err := s.findCategory(spanCtx, query)
+		if err != nil {
+			span.SetStatus(codes.Error, "failed to find a category")
+			return command.UpstreamError(err)
+		}
+		if !found {
+			span.SetStatus(codes.Error, "category does not exist")
+			return command.UsageError(fmt.Sprintf("category %s does not exist", query))
+		}
+
+		if err = s.edit(spanCtx, &helix.EditChannelInformationParams{BroadcasterID: privMsg.RoomID, GameID: category.ID}); err != nil {
+			span.SetStatus(codes.Error, "failed to change the category")
+			return command.UpstreamError(err)
+		}
+
+		span.SetStatus(codes.Ok, "successfully changed the category")
+		chatClient.Say(privMsg.Channel, fmt.Sprintf("@%s, changed the category to: %s", privMsg.User.DisplayName, category.Name))
+		return nil
+	}
+}
+
+// requireEditor returns an error, when the author of the message is not at least a moderator,
+// or when channel information can't be changed without a token of the broadcaster.
+func (s *Service) requireEditor(ctx context.Context, privMsg *twitch.PrivateMessage) error {
+	level, err := s.levelResolver.LevelOf(ctx, privMsg, permission.Moderator)
+	if err != nil {
+		return command.UpstreamError(err)
+	}
+	if level < permission.Moderator {
+		return errNotModerator
+	}
+	if s.editorClient == nil {
+		return errEditingDisabled
+	}
+
+	return nil
+}
+
+// findCategory searches Twitch for categories and returns the one closest to the query.
+func (s *Service) findCategory(ctx context.Context, query string) (helix.Category, bool, error) {
+	_, span := tracer.Start(ctx, "findCategory")
+	defer span.End()
+
+	span.SetAttributes(attribute.String("category.query", query))
+
+	resp, err := s.helixClient.SearchCategories(&helix.SearchCategoriesParams{Query: query, First: searchLimit})
+	if err == nil {
+		err = twitchapi.ResponseError(resp.ResponseCommon)
+	}
+	if err != nil {
+		span.SetStatus(codes.Error, "failed to search categories")
+		span.RecordError(err)
+		return helix.Category{}, false, err
+	}
+
+	categories := resp.Data.Categories
+	names := make([]string, 0, len(categories))
+	for _, category := range categories {
+		names = append(names, category.Name)
+	}
+
+	i := bestMatch(query, names)
+	if i < 0 {
+		span.SetStatus(codes.Ok, "no category matched the query")
+		return helix.Category{}, false, nil
+	}
+
+	span.SetAttributes(attribute.String("category.name", categories[i].Name))
+	span.SetStatus(codes.Ok, "successfully found a category")
+	return categories[i], true, nil
+}
+
+// edit changes channel information.
+func (s *Service) edit(ctx context.Context, params *helix.EditChannelInformationParams) error {
+	_, span := tracer.Start(ctx, "edit")
+	defer span.End()
+
+	resp, err := s.editorClient.EditChannelInformation(params)
+	if err == nil {
+		err = twitchapi.ResponseError(resp.ResponseCommon)
+	}
+	if err != nil {
+		span.SetStatus(codes.Error, "failed to edit channel information")
+		span.RecordError(err)
+		s.logger.Warn("failed to edit channel information", zap.String("broadcaster_id", params.BroadcasterID), zap.Error(err))
+		return err
+	}
+
+	span.SetStatus(codes.Ok, "successfully edited channel information")
+	return nil
+}
+
+// formatUptime returns a readable duration, like `2h 5m` or `42m`.
+func formatUptime(d time.Duration) string {
+	d = max(d, 0).Truncate(time.Minute)
+
+	hours := int(d.Hours())
+	minutes := int(d.Minutes()) % 60
+	if hours == 0 {
+		return fmt.Sprintf("%dm", minutes)
+	}
+
+	return fmt.Sprintf("%dh %dm", hours, minutes)
+}
@@ -0,0 +1,219 @@
+package channelinfo
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/danielbukowski/twitch-chatbot/internal/command"
+	"github.com/danielbukowski/twitch-chatbot/internal/helixtest"
+	"github.com/danielbukowski/twitch-chatbot/internal/permission"
+	streamstatus "github.com/danielbukowski/twitch-chatbot/internal/stream_status"
+	"github.com/gempir/go-twitch-irc/v4"
+	"github.com/nicklaw5/helix/v2"
+	"go.uber.org/zap"
+)
+
+type chatClientMock struct {
+	messages []string
+}
+
+func (c *chatClientMock) Say(_, message string) {
+	c.messages = append(c.messages, message)
+}
+
+func (c *chatClientMock) Reply(_, _, message string) {
+	c.messages = append(c.messages, message)
+}
+
+func (c *chatClientMock) Join(_ ...string) {}
+
+func (c *chatClientMock) Depart(_ string) {}
+
+type statusReaderMock streamstatus.Status
+
+func (s statusReaderMock) Status(_ string) streamstatus.Status {
+	return streamstatus.Status(s)
+}
+
+type levelResolverMock permission.Level
+
+func (l levelResolverMock) LevelOf(_ context.Context, _ *twitch.PrivateMessage, _ permission.Level) (permission.Level, error) {
+	return permission.Level(l), nil
+}
+
+// twitchStandIn is a stand-in of Twitch API, which returns categories and records edits of channel information.
+type twitchStandIn struct {
+	categories []helix.Category
+	edits      []helix.EditChannelInformationParams
+}
+
+func newTestService(t *testing.T, standIn *twitchStandIn, status streamstatus.Status, level permission.Level) *Service {
+	t.Helper()
+
+	helixClient := helixtest.NewClient(t, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+
+		switch {
+		case r.Method == http.MethodGet && r.URL.Path == "/search/categories":
+			_ = json.NewEncoder(w).Encode(map[string]any{"data": standIn.categories})
+		case r.Method == http.MethodPatch && r.URL.Path == "/channels":
+			var params helix.EditChannelInformationParams
+			_ = json.NewDecoder(r.Body).Decode(&params)
+			params.BroadcasterID = r.URL.Query().Get("broadcaster_id")
+			standIn.edits = append(standIn.edits, params)
+			w.WriteHeader(http.StatusNoContent)
+		default:
+			w.WriteHeader(http.StatusNotFound)
+		}
+	}))
+
+	return NewService(helixClient, helixClient, statusReaderMock(status), levelResolverMock(level), zap.NewNop())
+}
+
+func commandContext(commandName string) context.Context {
+	privMsg := &twitch.PrivateMessage{Channel: "channel", RoomID: "channel-id", User: twitch.User{Name: "viewer", DisplayName: "Viewer"}}
+	return command.WithContext(context.Background(), command.NewContext(commandName, privMsg, zap.NewNop()))
+}
+
+func TestService(t *testing.T) {
+	t.Run("tells the uptime of a live stream", func(t *testing.T) {
+		// given
+		now := time.Date(2026, 10, 19, 14, 5, 30, 0, time.UTC)
+		service := newTestService(t, &twitchStandIn{}, streamstatus.Status{Live: true, StartedAt: now.Add(-(2*time.Hour + 5*time.Minute + 30*time.Second))}, permission.Everyone)
+		service.now = func() time.Time { return now }
+		chatClient := &chatClientMock{}
+
+		// when
+		err := service.Uptime()(commandContext("!uptime"), nil, chatClient)
+
+		// then
+		if err != nil {
+			t.Fatalf("Expected no error, got `%v`", err)
+		}
+		if len(chatClient.messages) != 1 || chatClient.messages[0] != "@Viewer, the stream has been live for 2h 5m." {
+			t.Fatalf("Expected the uptime, got `%v`", chatClient.messages)
+		}
+	})
+
+	t.Run("changes the category to the closest match", func(t *testing.T) {
+		// given
+		standIn := &twitchStandIn{categories: []helix.Category{
+			{ID: "1", Name: "Minecraft Dungeons"},
+			{ID: "2", Name: "Minecraft"},
+			{ID: "3", Name: "Minecraft: Story Mode"},
+		}}
+		service := newTestService(t, standIn, streamstatus.Status{}, permission.Moderator)
+		chatClient := &chatClientMock{}
+
+		// when
+		err := service.Game()(commandContext("!game"), []string{"minecraft"}, chatClient)
+
+		// then
+		if err != nil {
+			t.Fatalf("Expected no error, got `%v`", err)
+		}
+		if len(standIn.edits) != 1 || standIn.edits[0].GameID != "2" || standIn.edits[0].BroadcasterID != "channel-id" {
+			t.Fatalf("Expected the category to be changed to Minecraft, got `%+v`", standIn.edits)
+		}
+		if chatClient.messages[0] != "@Viewer, changed the category to: Minecraft" {
+			t.Fatalf("Expected a confirmation, got `%v`", chatClient.messages)
+		}
+	})
+
+	t.Run("rejects a change of the title by a viewer", func(t *testing.T) {
+		// given
+		standIn := &twitchStandIn{}
+		service := newTestService(t, standIn, streamstatus.Status{}, permission.VIP)
+
+		// when
+		err := service.Title()(commandContext("!title"), []string{"new", "title"}, &chatClientMock{})
+
+		// then
+		if command.Classify(err) != command.KindPermissionDenied {
+			t.Fatalf("Expected a permission denied error, got `%v`", err)
+		}
+		if len(standIn.edits) != 0 {
+			t.Fatalf("Expected no edits, got `%+v`", standIn.edits)
+		}
+	})
+
+	t.Run("rejects a change of the title without a token of the broadcaster", func(t *testing.T) {
+		// given
+		standIn := &twitchStandIn{}
+		service := newTestService(t, standIn, streamstatus.Status{}, permission.Moderator)
+		service.editorClient = nil
+
+		// when
+		err := service.Title()(commandContext("!title"), []string{"new", "title"}, &chatClientMock{})
+
+		// then
+		if command.Classify(err) != command.KindUsage {
+			t.Fatalf("Expected a usage error, got `%v`", err)
+		}
+		if len(standIn.edits) != 0 {
+			t.Fatalf("Expected no edits, got `%+v`", standIn.edits)
+		}
+	})
+
+	t.Run("returns a usage error, when no category matches", func(t *testing.T) {
+		// given
+		standIn := &twitchStandIn{categories: []helix.Category{{ID: "1", Name: "Just Chatting"}}}
+		service := newTestService(t, standIn, streamstatus.Status{}, permission.Broadcaster)
+
+		// when
+		err := service.Game()(commandContext("!game"), []string{"elden", "ring"}, &chatClientMock{})
+
+		// then
+		if command.Classify(err) != command.KindUsage {
+			t.Fatalf("Expected a usage error, got `%v`", err)
+		}
+	})
+}
+
+func TestBestMatch(t *testing.T) {
+	testCases := []struct {
+		name     string
+		query    string
+		names    []string
+		expected int
+	}{
+		{name: "exact match ignoring case", query: "just chatting", names: []string{"Just Dance", "Just Chatting"}, expected: 1},
+		{name: "shortest name starting with the query", query: "elden", names: []string{"Elden Ring: Shadow of the Erdtree", "Elden Ring"}, expected: 1},
+		{name: "typo", query: "valorent", names: []string{"Valheim", "VALORANT"}, expected: 1},
+		{name: "nothing similar", query: "chess", names: []string{"Fortnite", "Apex Legends"}, expected: -1},
+		{name: "no names", query: "chess", names: nil, expected: -1},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			// when
+			got := bestMatch(tc.query, tc.names)
+
+			// then
+			if got != tc.expected {
+				t.Errorf("Expected %d, got %d", tc.expected, got)
+			}
+		})
+	}
+}
+
+func TestLevenshtein(t *testing.T) {
+	testCases := []struct {
+		a, b     string
+		expected int
+	}{
+		{a: "", b: "abc", expected: 3},
+		{a: "kitten", b: "sitting", expected: 3},
+		{a: "pokémon", b: "pokemon", expected: 1},
+		{a: "same", b: "same", expected: 0},
+	}
+
+	for _, tc := range testCases {
+		if got := levenshtein(tc.a, tc.b); got != tc.expected {
+			t.Errorf("Expected distance between `%s` and `%s` to be %d, got %d", tc.a, tc.b, tc.expected, got)
+		}
+	}
+}
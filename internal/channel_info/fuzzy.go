@@ -0,0 +1,74 @@
+package channelinfo
+
+import (
+	"strings"
+	"unicode/utf8"
+)
+
+// minSimilarity is the lowest similarity of a category name to a query, which is still accepted as a match.
+const minSimilarity = 0.5
+
+// bestMatch returns an index of a name most similar to the query, ignoring case. An exact match always wins,
+// then a name starting with the query, and then the one with the smallest edit distance.
+// It returns -1, when no name is similar enough.
+func bestMatch(query string, names []string) int {
+	query = strings.ToLower(strings.TrimSpace(query))
+
+	best, bestScore := -1, minSimilarity
+	for i, name := range names {
+		name = strings.ToLower(name)
+
+		var score float64
+		switch {
+		case name == query:
+			return i
+		case strings.HasPrefix(name, query):
+			// a prefix is ranked above any edit distance, and shorter names are closer to the query.
+			score = 1 + float64(utf8.RuneCountInString(query))/float64(utf8.RuneCountInString(name))
+		default:
+			score = similarity(query, name)
+		}
+
+		if score > bestScore {
+			best, bestScore = i, score
+		}
+	}
+
+	return best
+}
+
+// similarity returns 1 for equal texts and 0 for texts without anything in common, based on the Levenshtein distance.
+func similarity(a, b string) float64 {
+	longest := max(utf8.RuneCountInString(a), utf8.RuneCountInString(b))
+	if longest == 0 {
+		return 1
+	}
+
+	return 1 - float64(levenshtein(a, b))/float64(longest)
+}
+
+// levenshtein returns a minimal number of single character insertions, deletions and substitutions,
+// which turn one text into the other.
+func levenshtein(a, b string) int {
+	ra, rb := []rune(a), []rune(b)
+
+	previous := make([]int, len(rb)+1)
+	current := make([]int, len(rb)+1)
+	for j := range previous {
+		previous[j] = j
+	}
+
+	for i := 1; i <= len(ra); i++ {
+		current[0] = i
+		for j := 1; j <= len(rb); j++ {
+			cost := 1
+			if ra[i-1] == rb[j-1] {
+				cost = 0
+			}
+			current[j] = min(previous[j]+1, current[j-1]+1, previous[j-1]+cost)
+		}
+		previous, current = current, previous
+	}
+
+	return previous[len(rb)]
+}
@@ -0,0 +1,293 @@
+package chatsettings
+
+import (
+	"context"
+	"errors"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+
+	twitchapi "github.com/danielbukowski/twitch-chatbot/internal/twitch_api"
+	"github.com/nicklaw5/helix/v2"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/codes"
+	"go.uber.org/zap"
+)
+
+var tracer = otel.Tracer("github.com/danielbukowski/twitch-chatbot/internal/chat_settings")
+
+// cachedSettings holds chat settings of a channel fetched from Twitch API.
+type cachedSettings struct {
+	settings  helix.ChatSettings
+	expiresAt time.Time
+}
+
+// pendingRevert is a revert of chat settings scheduled for later.
+type pendingRevert struct {
+	timer         *time.Timer
+	broadcasterID string
+	params        helix.UpdateChatSettingsParams
+}
+
+// Service reads and changes chat settings through Twitch API. It requires a user access token of a moderator
+// with the moderator:manage:chat_settings scope.
+// Settings are cached, and every change can be reverted automatically after some time. Pending reverts
+// are applied, when the service stops, so timed changes don't outlive the chatbot.
+type Service struct {
+	helixClient *helix.Client             // HelixClient is used to call Twitch API.
+	moderatorID string                    // ModeratorID is an ID of the user that changes settings, usually the chatbot.
+	cacheTTL    time.Duration             // CacheTTL is how long settings are cached.
+	prefix      string                    // Prefix is a prefix of commands.
+	cache       map[string]cachedSettings // Cache holds settings of every channel.
+	reverts     map[string]pendingRevert  // Reverts holds scheduled reverts of every mode of every channel.
+	mu          sync.Mutex                // Mu guards the cache and reverts.
+	now         func() time.Time          // Now returns the current time.
+	logger      *zap.Logger               // Logger is used for logging.
+}
+
+// NewService creates an instance of Service.
+func NewService(helixClient *helix.Client, moderatorID string, cacheTTL time.Duration, prefix string, logger *zap.Logger) *Service {
+	return &Service{
+		helixClient: helixClient,
+		moderatorID: moderatorID,
+		cacheTTL:    cacheTTL,
+		prefix:      prefix,
+		cache:       make(map[string]cachedSettings),
+		reverts:     make(map[string]pendingRevert),
+		now:         time.Now,
+		logger:      logger.Named("chat_settings"),
+	}
+}
+
+// Get returns current chat settings of a channel.
+func (s *Service) Get(ctx context.Context, broadcasterID string) (helix.ChatSettings, error) {
+	s.mu.Lock()
+	cached, ok := s.cache[broadcasterID]
+	s.mu.Unlock()
+	if ok && s.now().Before(cached.expiresAt) {
+		return cached.settings, nil
+	}
+
+	_, span := tracer.Start(ctx, "get")
+	defer span.End()
+
+	resp, err := s.helixClient.GetChatSettings(&helix.GetChatSettingsParams{BroadcasterID: broadcasterID, ModeratorID: s.moderatorID})
+	if err == nil {
+		err = twitchapi.ResponseError(resp.ResponseCommon)
+	}
+	if err == nil && len(resp.Data.Settings) == 0 {
+		err = errors.New("twitch api returned no chat settings")
+	}
+	if err != nil {
+		errMsg := "failed to get chat settings"
+		span.SetStatus(codes.Error, errMsg)
+		span.RecordError(err)
+		return helix.ChatSettings{}, errors.Join(errors.New(errMsg), err)
+	}
+
+	settings := resp.Data.Settings[0]
+	s.store(broadcasterID, settings)
+
+	span.SetStatus(codes.Ok, "successfully got chat settings")
+	return settings, nil
+}
+
+// Update changes chat settings of a channel. Fields of params left as nil are not changed.
+// Scheduled reverts of the changed modes are cancelled.
+func (s *Service) Update(ctx context.Context, broadcasterID string, params helix.UpdateChatSettingsParams) error {
+	s.cancelReverts(broadcasterID, params)
+
+	return s.update(ctx, broadcasterID, params)
+}
+
+// UpdateFor changes chat settings of a channel and reverts the changed modes to their previous state after the duration.
+// When the modes already wait for a revert, the new revert restores the same state as the pending one.
+func (s *Service) UpdateFor(ctx context.Context, broadcasterID string, params helix.UpdateChatSettingsParams, duration time.Duration) error {
+	key := revertKey(broadcasterID, params)
+
+	s.mu.Lock()
+	pending, ok := s.reverts[key]
+	s.mu.Unlock()
+
+	revert := pending.params
+	if !ok {
+		previous, err := s.Get(ctx, broadcasterID)
+		if err != nil {
+			return err
+		}
+		revert = Revert(previous, params)
+	}
+
+	if err := s.Update(ctx, broadcasterID, params); err != nil {
+		return err
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.reverts[key] = pendingRevert{
+		broadcasterID: broadcasterID,
+		params:        revert,
+		timer: time.AfterFunc(duration, func() {
+			s.mu.Lock()
+			delete(s.reverts, key)
+			s.mu.Unlock()
+
+			if err := s.update(context.Background(), broadcasterID, revert); err != nil {
+				s.logger.Error("failed to revert chat settings", zap.String("broadcaster_id", broadcasterID), zap.Error(err))
+			}
+		}),
+	}
+
+	return nil
+}
+
+// Run waits until the context is done and then applies every pending revert right away.
+func (s *Service) Run(ctx context.Context) error {
+	<-ctx.Done()
+
+	s.revertAll(context.WithoutCancel(ctx))
+	return nil
+}
+
+// revertAll applies pending reverts, which have not started yet.
+func (s *Service) revertAll(ctx context.Context) {
+	s.mu.Lock()
+	pending := make([]pendingRevert, 0, len(s.reverts))
+	for key, revert := range s.reverts {
+		if revert.timer.Stop() {
+			pending = append(pending, revert)
+		}
+		delete(s.reverts, key)
+	}
+	s.mu.Unlock()
+
+	for _, revert := range pending {
+		if err := s.update(ctx, revert.broadcasterID, revert.params); err != nil {
+			s.logger.Error("failed to revert chat settings", zap.String("broadcaster_id", revert.broadcasterID), zap.Error(err))
+		}
+	}
+}
+
+// update changes chat settings and caches the new state returned by Twitch API.
+func (s *Service) update(ctx context.Context, broadcasterID string, params helix.UpdateChatSettingsParams) error {
+	_, span := tracer.Start(ctx, "update")
+	defer span.End()
+
+	params.BroadcasterID = broadcasterID
+	params.ModeratorID = s.moderatorID
+
+	resp, err := s.helixClient.UpdateChatSettings(&params)
+	if err == nil {
+		err = twitchapi.ResponseError(resp.ResponseCommon)
+	}
+	if err != nil {
+		errMsg := "failed to update chat settings"
+		span.SetStatus(codes.Error, errMsg)
+		span.RecordError(err)
+		return errors.Join(errors.New(errMsg), err)
+	}
+
+	if len(resp.Data.Settings) != 0 {
+		s.store(broadcasterID, resp.Data.Settings[0])
+	} else {
+		s.mu.Lock()
+		delete(s.cache, broadcasterID)
+		s.mu.Unlock()
+	}
+
+	span.SetStatus(codes.Ok, "successfully updated chat settings")
+	return nil
+}
+
+// store caches chat settings of a channel.
+func (s *Service) store(broadcasterID string, settings helix.ChatSettings) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.cache[broadcasterID] = cachedSettings{settings: settings, expiresAt: s.now().Add(s.cacheTTL)}
+}
+
+// cancelReverts stops scheduled reverts of every mode changed by params.
+func (s *Service) cancelReverts(broadcasterID string, params helix.UpdateChatSettingsParams) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	changed := strings.Split(modes(params), ",")
+	for key, pending := range s.reverts {
+		keyBroadcasterID, keyModes, _ := strings.Cut(key, ":")
+		if keyBroadcasterID != broadcasterID {
+			continue
+		}
+
+		for _, mode := range strings.Split(keyModes, ",") {
+			if slices.Contains(changed, mode) {
+				pending.timer.Stop()
+				delete(s.reverts, key)
+				break
+			}
+		}
+	}
+}
+
+// Revert returns params that restore modes changed by a change to their state from previous settings.
+func Revert(previous helix.ChatSettings, change helix.UpdateChatSettingsParams) helix.UpdateChatSettingsParams {
+	var params helix.UpdateChatSettingsParams
+
+	if change.EmoteMode != nil {
+		params.EmoteMode = ptr(previous.EmoteMode)
+	}
+	if change.FollowerMode != nil {
+		params.FollowerMode = ptr(previous.FollowerMode)
+		if previous.FollowerMode {
+			params.FollowerModeDuration = ptr(previous.FollowerModeDuration)
+		}
+	}
+	if change.SlowMode != nil {
+		params.SlowMode = ptr(previous.SlowMode)
+		if previous.SlowMode {
+			params.SlowModeWaitTime = ptr(previous.SlowModeWaitTime)
+		}
+	}
+	if change.SubscriberMode != nil {
+		params.SubscriberMode = ptr(previous.SubscriberMode)
+	}
+	if change.UniqueChatMode != nil {
+		params.UniqueChatMode = ptr(previous.UniqueChatMode)
+	}
+
+	return params
+}
+
+// modes returns comma separated names of modes changed by params.
+func modes(params helix.UpdateChatSettingsParams) string {
+	var names []string
+
+	if params.EmoteMode != nil {
+		names = append(names, "emote")
+	}
+	if params.FollowerMode != nil {
+		names = append(names, "follower")
+	}
+	if params.SlowMode != nil {
+		names = append(names, "slow")
+	}
+	if params.SubscriberMode != nil {
+		names = append(names, "subscriber")
+	}
+	if params.UniqueChatMode != nil {
+		names = append(names, "unique")
+	}
+
+	return strings.Join(names, ",")
+}
+
+// revertKey identifies a scheduled revert of modes changed by params.
+func revertKey(broadcasterID string, params helix.UpdateChatSettingsParams) string {
+	return broadcasterID + ":" + modes(params)
+}
+
+func ptr[T any](v T) *T {
+	return &v
+}
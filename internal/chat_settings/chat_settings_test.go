@@ -0,0 +1,254 @@
+package chatsettings
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/danielbukowski/twitch-chatbot/internal/command"
+	"github.com/danielbukowski/twitch-chatbot/internal/helixtest"
+	"github.com/gempir/go-twitch-irc/v4"
+	"github.com/nicklaw5/helix/v2"
+	"go.uber.org/zap"
+)
+
+// twitchStandIn imitates chat settings endpoints of Twitch API.
+type twitchStandIn struct {
+	mu       sync.Mutex
+	settings helix.ChatSettings
+	gets     int
+	updates  chan helix.UpdateChatSettingsParams
+}
+
+func (ts *twitchStandIn) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	ts.mu.Lock()
+	defer ts.mu.Unlock()
+
+	switch r.Method {
+	case http.MethodGet:
+		ts.gets++
+	case http.MethodPatch:
+		var params helix.UpdateChatSettingsParams
+		_ = json.NewDecoder(r.Body).Decode(&params)
+		if params.EmoteMode != nil {
+			ts.settings.EmoteMode = *params.EmoteMode
+		}
+		if params.SlowMode != nil {
+			ts.settings.SlowMode = *params.SlowMode
+		}
+		if params.SlowModeWaitTime != nil {
+			ts.settings.SlowModeWaitTime = *params.SlowModeWaitTime
+		}
+		ts.updates <- params
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	_ = json.NewEncoder(w).Encode(helix.ManyChatSettings{Settings: []helix.ChatSettings{ts.settings}})
+}
+
+func newTestService(t *testing.T, standIn *twitchStandIn) *Service {
+	t.Helper()
+
+	helixClient := helixtest.NewClient(t, standIn)
+
+	return NewService(helixClient, "bot-id", time.Minute, "!", zap.NewNop())
+}
+
+type chatClientMock struct {
+	messages []string
+}
+
+func (c *chatClientMock) Say(_, message string) {
+	c.messages = append(c.messages, message)
+}
+
+func (c *chatClientMock) Reply(_, _, message string) {
+	c.messages = append(c.messages, message)
+}
+
+func (c *chatClientMock) Join(_ ...string) {}
+
+func (c *chatClientMock) Depart(_ string) {}
+
+func TestService(t *testing.T) {
+	t.Run("caches chat settings", func(t *testing.T) {
+		// given
+		standIn := &twitchStandIn{settings: helix.ChatSettings{SlowMode: true, SlowModeWaitTime: 5}}
+		service := newTestService(t, standIn)
+
+		// when
+		_, _ = service.Get(context.Background(), "channel-id")
+		settings, err := service.Get(context.Background(), "channel-id")
+
+		// then
+		if err != nil {
+			t.Fatalf("Expected no error, got `%v`", err)
+		}
+		if !settings.SlowMode || settings.SlowModeWaitTime != 5 {
+			t.Errorf("Expected slow mode with 5 seconds, got `%+v`", settings)
+		}
+		if standIn.gets != 1 {
+			t.Errorf("Expected 1 request to Twitch API, got %d", standIn.gets)
+		}
+	})
+
+	t.Run("reverts a change to the previous state after the duration", func(t *testing.T) {
+		// given
+		standIn := &twitchStandIn{settings: helix.ChatSettings{SlowMode: true, SlowModeWaitTime: 5}, updates: make(chan helix.UpdateChatSettingsParams, 2)}
+		service := newTestService(t, standIn)
+		change := helix.UpdateChatSettingsParams{SlowMode: ptr(true), SlowModeWaitTime: ptr(60)}
+
+		// when
+		err := service.UpdateFor(context.Background(), "channel-id", change, 10*time.Millisecond)
+
+		// then
+		if err != nil {
+			t.Fatalf("Expected no error, got `%v`", err)
+		}
+		<-standIn.updates
+
+		select {
+		case revert := <-standIn.updates:
+			if !*revert.SlowMode || *revert.SlowModeWaitTime != 5 {
+				t.Errorf("Expected slow mode with 5 seconds to be restored, got `%+v`", revert)
+			}
+		case <-time.After(time.Second):
+			t.Fatalf("Expected the change to be reverted")
+		}
+	})
+
+	t.Run("cancels a pending revert, when the mode is changed again", func(t *testing.T) {
+		// given
+		standIn := &twitchStandIn{updates: make(chan helix.UpdateChatSettingsParams, 3)}
+		service := newTestService(t, standIn)
+
+		err := service.UpdateFor(context.Background(), "channel-id", helix.UpdateChatSettingsParams{EmoteMode: ptr(true)}, 20*time.Millisecond)
+		if err != nil {
+			t.Fatalf("Expected no error, got `%v`", err)
+		}
+
+		// when
+		err = service.Update(context.Background(), "channel-id", helix.UpdateChatSettingsParams{EmoteMode: ptr(true)})
+
+		// then
+		if err != nil {
+			t.Fatalf("Expected no error, got `%v`", err)
+		}
+		time.Sleep(50 * time.Millisecond)
+		if len(standIn.updates) != 2 {
+			t.Errorf("Expected no revert after a manual change, got %d updates", len(standIn.updates))
+		}
+	})
+
+	t.Run("applies pending reverts, when it stops", func(t *testing.T) {
+		// given
+		standIn := &twitchStandIn{updates: make(chan helix.UpdateChatSettingsParams, 2)}
+		service := newTestService(t, standIn)
+		ctx, cancel := context.WithCancel(context.Background())
+
+		err := service.UpdateFor(ctx, "channel-id", helix.UpdateChatSettingsParams{EmoteMode: ptr(true)}, time.Hour)
+		if err != nil {
+			t.Fatalf("Expected no error, got `%v`", err)
+		}
+		<-standIn.updates
+
+		// when
+		cancel()
+		err = service.Run(ctx)
+
+		// then
+		if err != nil {
+			t.Fatalf("Expected no error, got `%v`", err)
+		}
+		select {
+		case revert := <-standIn.updates:
+			if revert.EmoteMode == nil || *revert.EmoteMode {
+				t.Errorf("Expected emote-only mode to be turned off, got `%+v`", revert)
+			}
+		default:
+			t.Fatal("Expected the change to be reverted on shutdown")
+		}
+	})
+}
+
+func TestSlow(t *testing.T) {
+	testCases := []struct {
+		name         string
+		args         []string
+		expectedKind command.ErrorKind
+		expectedErr  bool
+	}{
+		{name: "turns slow mode on", args: []string{"30"}},
+		{name: "turns slow mode off", args: []string{"off"}},
+		{name: "rejects a wait time longer than allowed by Twitch", args: []string{"10m"}, expectedErr: true, expectedKind: command.KindUsage},
+		{name: "rejects a missing wait time", args: []string{}, expectedErr: true, expectedKind: command.KindUsage},
+		{name: "rejects an invalid revert duration", args: []string{"30", "later"}, expectedErr: true, expectedKind: command.KindUsage},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			// given
+			standIn := &twitchStandIn{updates: make(chan helix.UpdateChatSettingsParams, 1)}
+			service := newTestService(t, standIn)
+			cmdCtx := command.NewContext("slow", &twitch.PrivateMessage{RoomID: "channel-id", User: twitch.User{DisplayName: "mod"}}, zap.NewNop())
+			ctx := command.WithContext(context.Background(), cmdCtx)
+
+			// when
+			err := service.Slow()(ctx, tc.args, &chatClientMock{})
+
+			// then
+			if (err != nil) != tc.expectedErr {
+				t.Fatalf("Expected error `%v`, got `%v`", tc.expectedErr, err)
+			}
+			var commandErr *command.Error
+			if tc.expectedErr && (!errors.As(err, &commandErr) || commandErr.Kind != tc.expectedKind) {
+				t.Errorf("Expected an error of kind `%v`, got `%v`", tc.expectedKind, err)
+			}
+		})
+	}
+}
+
+func TestFollowers(t *testing.T) {
+	testCases := []struct {
+		name             string
+		args             []string
+		expectedDuration int
+		expectedErr      bool
+	}{
+		{name: "reads a bare number as minutes", args: []string{"10"}, expectedDuration: 10},
+		{name: "reads a duration", args: []string{"1h"}, expectedDuration: 60},
+		{name: "rejects a follow time shorter than a minute", args: []string{"30s"}, expectedErr: true},
+		{name: "rejects a follow time with seconds", args: []string{"90s"}, expectedErr: true},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			// given
+			standIn := &twitchStandIn{updates: make(chan helix.UpdateChatSettingsParams, 1)}
+			service := newTestService(t, standIn)
+			cmdCtx := command.NewContext("followers", &twitch.PrivateMessage{RoomID: "channel-id", User: twitch.User{DisplayName: "mod"}}, zap.NewNop())
+			ctx := command.WithContext(context.Background(), cmdCtx)
+
+			// when
+			err := service.Followers()(ctx, tc.args, &chatClientMock{})
+
+			// then
+			if tc.expectedErr {
+				if command.Classify(err) != command.KindUsage {
+					t.Fatalf("Expected a usage error, got `%v`", err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("Expected no error, got `%v`", err)
+			}
+			if params := <-standIn.updates; params.FollowerModeDuration == nil || *params.FollowerModeDuration != tc.expectedDuration {
+				t.Errorf("Expected a follow time of %d minutes, got `%+v`", tc.expectedDuration, params)
+			}
+		})
+	}
+}
@@ -0,0 +1,192 @@
+package chatsettings
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/danielbukowski/twitch-chatbot/internal/command"
+	"github.com/nicklaw5/helix/v2"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/trace"
+)
+
+const (
+	minSlowModeWaitTime = 3 * time.Second     // minSlowModeWaitTime is the shortest wait time of slow mode allowed by Twitch.
+	maxSlowModeWaitTime = 120 * time.Second   // maxSlowModeWaitTime is the longest wait time of slow mode allowed by Twitch.
+	maxFollowerDuration = 90 * 24 * time.Hour // maxFollowerDuration is the longest follow time of followers-only mode allowed by Twitch.
+)
+
+// Slow changes slow mode.
+// Usage: `!slow <seconds>|off [revert after]`, e.g. `!slow 30` or `!slow 10 5m`.
+func (s *Service) Slow() command.Handler {
+	return func(ctx context.Context, args []string, chatClient command.ChatClient) error {
+		spanCtx, span := tracer.Start(ctx, "slowCommand")
+		defer span.End()
+
+		usage := fmt.Sprintf("usage: %sslow <seconds>|off [revert after]", s.prefix)
+		if len(args) < 1 {
+			span.SetStatus(codes.Error, "missing arguments")
+			return command.UsageError(usage)
+		}
+
+		var params helix.UpdateChatSettingsParams
+		description := "slow mode is off"
+
+		if !strings.EqualFold(args[0], "off") {
+			waitTime, err := command.ParseDuration(args[0])
+			if err != nil || waitTime < minSlowModeWaitTime || waitTime > maxSlowModeWaitTime {
+				span.SetStatus(codes.Error, "invalid wait time")
+				return command.UsageError(fmt.Sprintf("slow mode wait time must be from %d to %d seconds", int(minSlowModeWaitTime.Seconds()), int(maxSlowModeWaitTime.Seconds())))
+			}
+
+			params.SlowMode = ptr(true)
+			params.SlowModeWaitTime = ptr(int(waitTime.Seconds()))
+			description = fmt.Sprintf("slow mode is on, one message every %s", waitTime)
+		} else {
+			params.SlowMode = ptr(false)
+		}
+
+		return s.change(spanCtx, args[1:], usage, params, description, chatClient)
+	}
+}
+
+// Followers changes followers-only mode.
+// Usage: `!followers [<follow time>|off] [revert after]`, e.g. `!followers 10m` or `!followers 0 30m`.
+// The follow time is a number of minutes or a duration in whole minutes, because Twitch doesn't support seconds.
+func (s *Service) Followers() command.Handler {
+	return func(ctx context.Context, args []string, chatClient command.ChatClient) error {
+		spanCtx, span := tracer.Start(ctx, "followersCommand")
+		defer span.End()
+
+		usage := fmt.Sprintf("usage: %sfollowers [<follow time>|off] [revert after]", s.prefix)
+
+		params := helix.UpdateChatSettingsParams{FollowerMode: ptr(true), FollowerModeDuration: ptr(0)}
+		description := "followers-only mode is on"
+
+		if len(args) > 0 {
+			if strings.EqualFold(args[0], "off") {
+				params = helix.UpdateChatSettingsParams{FollowerMode: ptr(false)}
+				description = "followers-only mode is off"
+			} else {
+				followTime, err := command.ParseDurationIn(args[0], time.Minute)
+				if err != nil || followTime < 0 || followTime > maxFollowerDuration {
+					span.SetStatus(codes.Error, "invalid follow time")
+					return command.UsageError(usage)
+				}
+				if followTime%time.Minute != 0 {
+					span.SetStatus(codes.Error, "follow time is not in whole minutes")
+					return command.UsageError("follow time must be in whole minutes, like 10 or 10m")
+				}
+
+				params.FollowerModeDuration = ptr(int(followTime.Minutes()))
+				if followTime >= time.Minute {
+					description = fmt.Sprintf("followers-only mode is on, follow for %s to chat", followTime.Truncate(time.Minute))
+				}
+			}
+			args = args[1:]
+		}
+
+		return s.change(spanCtx, args, usage, params, description, chatClient)
+	}
+}
+
+// EmoteOnly changes emote-only mode.
+// Usage: `!emoteonly [on|off|<duration>]`, e.g. `!emoteonly 5m`.
+func (s *Service) EmoteOnly() command.Handler {
+	return s.toggle("emoteonly", "emote-only mode", func(params *helix.UpdateChatSettingsParams, enabled bool) {
+		params.EmoteMode = ptr(enabled)
+	})
+}
+
+// SubsOnly changes subscribers-only mode.
+// Usage: `!subsonly [on|off|<duration>]`.
+func (s *Service) SubsOnly() command.Handler {
+	return s.toggle("subsonly", "subscribers-only mode", func(params *helix.UpdateChatSettingsParams, enabled bool) {
+		params.SubscriberMode = ptr(enabled)
+	})
+}
+
+// UniqueChat changes unique chat mode, which rejects messages identical to recent ones.
+// Usage: `!uniquechat [on|off|<duration>]`.
+func (s *Service) UniqueChat() command.Handler {
+	return s.toggle("uniquechat", "unique chat mode", func(params *helix.UpdateChatSettingsParams, enabled bool) {
+		params.UniqueChatMode = ptr(enabled)
+	})
+}
+
+// toggle creates a command that turns a mode on or off, or turns it on for a duration.
+func (s *Service) toggle(name, label string, set func(params *helix.UpdateChatSettingsParams, enabled bool)) command.Handler {
+	return func(ctx context.Context, args []string, chatClient command.ChatClient) error {
+		spanCtx, span := tracer.Start(ctx, name+"Command")
+		defer span.End()
+
+		usage := fmt.Sprintf("usage: %s%s [on|off|<duration>]", s.prefix, name)
+
+		var params helix.UpdateChatSettingsParams
+		enabled := len(args) == 0 || !strings.EqualFold(args[0], "off")
+		set(&params, enabled)
+
+		if len(args) > 0 && (strings.EqualFold(args[0], "on") || strings.EqualFold(args[0], "off")) {
+			args = args[1:]
+		}
+
+		description := label + " is off"
+		if enabled {
+			description = label + " is on"
+		}
+
+		return s.change(spanCtx, args, usage, params, description, chatClient)
+	}
+}
+
+// change applies params to the channel of the command. When args hold a duration, the change is reverted after it.
+// The outcome is recorded on the span of the calling handler.
+func (s *Service) change(ctx context.Context, args []string, usage string, params helix.UpdateChatSettingsParams, description string, chatClient command.ChatClient) error {
+	span := trace.SpanFromContext(ctx)
+	cmdCtx := command.UnwrapContext(ctx)
+	broadcasterID := cmdCtx.PrivMsg.RoomID
+
+	if len(args) > 1 {
+		span.SetStatus(codes.Error, "too many arguments")
+		return command.UsageError(usage)
+	}
+
+	var err error
+	if len(args) == 1 {
+		var revertAfter time.Duration
+		revertAfter, err = parseRevertAfter(args[0])
+		if err != nil {
+			span.SetStatus(codes.Error, "invalid duration of the change")
+			return command.UsageError(usage)
+		}
+
+		err = s.UpdateFor(ctx, broadcasterID, params, revertAfter)
+		description = fmt.Sprintf("%s for %s", description, revertAfter)
+	} else {
+		err = s.Update(ctx, broadcasterID, params)
+	}
+	if err != nil {
+		span.SetStatus(codes.Error, "failed to change chat settings")
+		return command.UpstreamError(err)
+	}
+
+	span.SetStatus(codes.Ok, "successfully changed chat settings")
+	chatClient.Say(cmdCtx.PrivMsg.Channel, fmt.Sprintf("@%s, %s.", cmdCtx.PrivMsg.User.DisplayName, description))
+	return nil
+}
+
+// parseRevertAfter parses a positive duration, after which a change is reverted.
+func parseRevertAfter(arg string) (time.Duration, error) {
+	duration, err := command.ParseDuration(arg)
+	if err != nil {
+		return 0, err
+	}
+	if duration <= 0 {
+		return 0, errors.New("duration must be positive")
+	}
+
+	return duration, nil
+}
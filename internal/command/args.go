@@ -0,0 +1,26 @@
+package command
+
+import (
+	"strconv"
+	"strings"
+	"time"
+)
+
+// TrimMention returns a lowercase login of a user from an argument like `@Username`.
+func TrimMention(arg string) string {
+	return strings.ToLower(strings.TrimPrefix(arg, "@"))
+}
+
+// ParseDuration parses a number of seconds, like `600`, or a duration, like `10m`.
+func ParseDuration(arg string) (time.Duration, error) {
+	return ParseDurationIn(arg, time.Second)
+}
+
+// ParseDurationIn parses a number of the given unit, like `10` minutes, or a duration, like `10m`.
+func ParseDurationIn(arg string, unit time.Duration) (time.Duration, error) {
+	if number, err := strconv.Atoi(arg); err == nil {
+		return time.Duration(number) * unit, nil
+	}
+
+	return time.ParseDuration(arg)
+}
@@ -0,0 +1,48 @@
+package command
+
+import (
+	"testing"
+	"time"
+)
+
+func TestParseDuration(t *testing.T) {
+	testCases := []struct {
+		name        string
+		text        string
+		expected    time.Duration
+		expectedErr bool
+	}{
+		{name: "number of seconds", text: "600", expected: 10 * time.Minute},
+		{name: "go duration", text: "1h30m", expected: 90 * time.Minute},
+		{name: "invalid text", text: "soon", expectedErr: true},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			// when
+			got, err := ParseDuration(tc.text)
+
+			// then
+			if (err != nil) != tc.expectedErr {
+				t.Fatalf("Expected error `%v`, got `%v`", tc.expectedErr, err)
+			}
+			if got != tc.expected {
+				t.Errorf("Expected `%v`, got `%v`", tc.expected, got)
+			}
+		})
+	}
+}
+
+func TestParseDurationIn(t *testing.T) {
+	// when
+	minutes, minutesErr := ParseDurationIn("10", time.Minute)
+	duration, durationErr := ParseDurationIn("30s", time.Minute)
+
+	// then
+	if minutesErr != nil || minutes != 10*time.Minute {
+		t.Errorf("Expected `%v`, got `%v` and `%v`", 10*time.Minute, minutes, minutesErr)
+	}
+	if durationErr != nil || duration != 30*time.Second {
+		t.Errorf("Expected `%v`, got `%v` and `%v`", 30*time.Second, duration, durationErr)
+	}
+}
@@ -37,3 +37,8 @@ func UnwrapContext(ctx context.Context) *Context {
 	v, _ := ctx.Value(key).(*Context)
 	return v
 }
+
+// WithContext binds Command Context to a context, so handlers can be called outside of the controller, e.g. in tests.
+func WithContext(ctx context.Context, commandContext *Context) context.Context {
+	return setContextToCommand(ctx, commandContext)
+}
@@ -14,7 +14,7 @@ const blankSpace = " "
 var tracer = otel.Tracer("github.com/danielbukowski/twitch-chatbot/internal/command")
 
 // Handler represents a function for a command.
-type Handler func(ctx context.Context, args []string, chatClient chatClient) error
+type Handler func(ctx context.Context, args []string, chatClient ChatClient) error
 
 // Filter represents a function that is called after all middlewares and before a command. It is used for validation.
 type Filter func(Handler) Handler
@@ -24,7 +24,7 @@ type Filter func(Handler) Handler
 type Middleware func(Handler) Handler
 
 // ChatClient provides an interface to interact with Twitch IRC.
-type chatClient interface {
+type ChatClient interface {
 	Say(channelName, message string)                    // Say is function for sending messages to a twitch chat.
 	Reply(channelName, parentMessageID, message string) // Reply is a function that replies to a thread.
 	Join(channels ...string)                            // Join is a function that allows to join to a channel or channels.
@@ -49,7 +49,7 @@ func NewController(prefix string, logger *zap.Logger) *Controller {
 }
 
 // CallCommand searches for a command in the commands. If the method finds one, it sets up a context and executes the command.
-func (c *Controller) CallCommand(ctx context.Context, userMessage string, privateMessage twitch.PrivateMessage, chatClient chatClient) {
+func (c *Controller) CallCommand(ctx context.Context, userMessage string, privateMessage twitch.PrivateMessage, chatClient ChatClient) {
 	args := strings.Split(userMessage, blankSpace)
 	commandName := args[0]
 
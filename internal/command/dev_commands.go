@@ -7,7 +7,7 @@ import (
 	"go.opentelemetry.io/otel/codes"
 )
 
-var Ping Handler = func(ctx context.Context, _ []string, chatClient chatClient) error {
+var Ping Handler = func(ctx context.Context, _ []string, chatClient ChatClient) error {
 	_, span := tracer.Start(ctx, "ping")
 	defer span.End()
 
@@ -0,0 +1,169 @@
+package command
+
+import (
+	"context"
+	"hash/fnv"
+	"sync"
+	"time"
+
+	"github.com/gempir/go-twitch-irc/v4"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/metric"
+	"go.uber.org/zap"
+)
+
+// DropPolicy decides what happens with a message, when a queue of a worker is full.
+type DropPolicy int
+
+const (
+	DropNewest DropPolicy = iota // DropNewest rejects the incoming message.
+	Block                        // Block waits until the worker has a free slot in its queue.
+)
+
+// Observer inspects a chat message on a worker before commands are called.
+// It returns true, when the message was handled, for example removed by moderation, so later observers and commands skip it.
+type Observer func(ctx context.Context, privateMessage *twitch.PrivateMessage) bool
+
+// job represents a single chat message waiting in a queue.
+type job struct {
+	userMessage    string
+	privateMessage twitch.PrivateMessage
+	chatClient     ChatClient
+}
+
+// Dispatcher processes chat messages on a bounded pool of workers, so slow observers and commands never block reading the chat.
+// Messages from the same channel are always processed by the same worker, so their order is kept.
+type Dispatcher struct {
+	controller     *Controller         // Controller is used to call commands.
+	observers      []Observer          // Observers inspect every message before commands, in the order they were added.
+	logger         *zap.Logger         // Logger is used for logging.
+	queues         []chan job          // Queues holds a queue for every worker.
+	policy         DropPolicy          // Policy decides what happens with a message, when a queue is full.
+	drainTimeout   time.Duration       // DrainTimeout is the maximum time for finishing queued messages at shutdown.
+	droppedCounter metric.Int64Counter // DroppedCounter counts messages rejected because of a full queue.
+	mu             sync.RWMutex        // Mu guards closed and the queues from being written after closing them.
+	closed         bool                // Closed tells, if the dispatcher stopped accepting messages.
+}
+
+// NewDispatcher creates an instance of Dispatcher with the given number of workers and a size of a queue for each of them.
+func NewDispatcher(controller *Controller, workers, queueSize int, policy DropPolicy, drainTimeout time.Duration, logger *zap.Logger) (*Dispatcher, error) {
+	droppedCounter, err := meter.Int64Counter(
+		"command.dispatch.dropped.counter",
+		metric.WithDescription("Number of messages dropped because a queue of a worker was full."),
+		metric.WithUnit("{message}"),
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	workers = max(workers, 1)
+	queues := make([]chan job, workers)
+	for i := range queues {
+		queues[i] = make(chan job, queueSize)
+	}
+
+	return &Dispatcher{
+		controller:     controller,
+		logger:         logger.Named("dispatcher"),
+		queues:         queues,
+		policy:         policy,
+		drainTimeout:   drainTimeout,
+		droppedCounter: droppedCounter,
+	}, nil
+}
+
+// Observe adds observers called for every dispatched message before commands. It has to be called before Run.
+func (d *Dispatcher) Observe(observers ...Observer) {
+	d.observers = append(d.observers, observers...)
+}
+
+// Dispatch puts a message into a queue of a worker assigned to the channel of the message.
+// It returns false, when the message was not accepted.
+func (d *Dispatcher) Dispatch(userMessage string, privateMessage twitch.PrivateMessage, chatClient ChatClient) bool {
+	d.mu.RLock()
+	defer d.mu.RUnlock()
+
+	if d.closed {
+		return false
+	}
+
+	queue := d.queues[d.workerIndex(privateMessage.Channel)]
+	j := job{userMessage: userMessage, privateMessage: privateMessage, chatClient: chatClient}
+
+	if d.policy == Block {
+		queue <- j
+		return true
+	}
+
+	select {
+	case queue <- j:
+		return true
+	default:
+		d.droppedCounter.Add(context.Background(), 1, metric.WithAttributes(attribute.String("channel.name", privateMessage.Channel)))
+		d.logger.Warn("dropped a message, because the queue is full",
+			zap.String("channel_name", privateMessage.Channel),
+			zap.String("username", privateMessage.User.Name),
+		)
+		return false
+	}
+}
+
+// Run starts the workers and blocks until the context is done. After that, the dispatcher stops accepting new commands
+// and waits for the queued ones. Commands that are still running after the drain timeout get their context cancelled.
+func (d *Dispatcher) Run(ctx context.Context) error {
+	execCtx, cancelExec := context.WithCancel(context.WithoutCancel(ctx))
+	defer cancelExec()
+
+	var wg sync.WaitGroup
+	for _, queue := range d.queues {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for j := range queue {
+				d.process(execCtx, j)
+			}
+		}()
+	}
+
+	<-ctx.Done()
+
+	d.mu.Lock()
+	d.closed = true
+	for _, queue := range d.queues {
+		close(queue)
+	}
+	d.mu.Unlock()
+
+	drained := make(chan struct{})
+	go func() {
+		wg.Wait()
+		close(drained)
+	}()
+
+	select {
+	case <-drained:
+		d.logger.Info("finished all queued commands")
+	case <-time.After(d.drainTimeout):
+		d.logger.Warn("cancelled commands that did not finish before the drain timeout")
+	}
+
+	return nil
+}
+
+// process passes a message through the observers and calls a command, unless one of them handled the message.
+func (d *Dispatcher) process(ctx context.Context, j job) {
+	for _, observe := range d.observers {
+		if observe(ctx, &j.privateMessage) {
+			return
+		}
+	}
+
+	d.controller.CallCommand(ctx, j.userMessage, j.privateMessage, j.chatClient)
+}
+
+// workerIndex returns an index of a worker responsible for a channel.
+func (d *Dispatcher) workerIndex(channelName string) int {
+	h := fnv.New32a()
+	_, _ = h.Write([]byte(channelName))
+	return int(h.Sum32() % uint32(len(d.queues)))
+}
@@ -0,0 +1,132 @@
+package command
+
+import (
+	"context"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/gempir/go-twitch-irc/v4"
+	"go.uber.org/zap"
+)
+
+func TestDispatcher(t *testing.T) {
+	t.Run("executes commands from the same channel in order and finishes them at shutdown", func(t *testing.T) {
+		// given
+		var mu sync.Mutex
+		got := []string{}
+
+		controller := NewController("!", zap.NewNop())
+		controller.AddCommand("!echo", func(ctx context.Context, args []string, chatClient ChatClient) error {
+			time.Sleep(time.Millisecond)
+			mu.Lock()
+			got = append(got, args[0])
+			mu.Unlock()
+			return nil
+		}, []Filter{})
+
+		dispatcher, err := NewDispatcher(controller, 4, 10, Block, time.Second, zap.NewNop())
+		if err != nil {
+			t.Fatalf("Expected no error, got `%v`", err)
+		}
+		privateMessage := twitch.PrivateMessage{Channel: "channel"}
+		expected := []string{"1", "2", "3", "4", "5"}
+
+		ctx, cancel := context.WithCancel(context.Background())
+		done := make(chan struct{})
+		go func() {
+			_ = dispatcher.Run(ctx)
+			close(done)
+		}()
+
+		// when
+		for _, arg := range expected {
+			dispatcher.Dispatch("!echo "+arg, privateMessage, chatClientMock{})
+		}
+		cancel()
+		<-done
+
+		// then
+		if len(got) != len(expected) {
+			t.Fatalf("Expected `%v`, got `%v`", expected, got)
+		}
+		for i := range expected {
+			if expected[i] != got[i] {
+				t.Errorf("Expected `%v`, got `%v`", expected, got)
+			}
+		}
+	})
+
+	t.Run("drops a command, when the queue is full", func(t *testing.T) {
+		// given
+		controller := NewController("!", zap.NewNop())
+		dispatcher, err := NewDispatcher(controller, 1, 1, DropNewest, time.Second, zap.NewNop())
+		if err != nil {
+			t.Fatalf("Expected no error, got `%v`", err)
+		}
+		privateMessage := twitch.PrivateMessage{Channel: "channel"}
+
+		// when
+		first := dispatcher.Dispatch("!echo", privateMessage, chatClientMock{})
+		second := dispatcher.Dispatch("!echo", privateMessage, chatClientMock{})
+
+		// then
+		if !first || second {
+			t.Errorf("Expected the first command to be accepted and the second dropped, got `%v` and `%v`", first, second)
+		}
+	})
+
+	t.Run("passes messages through observers before commands and skips the ones they handled", func(t *testing.T) {
+		// given
+		var mu sync.Mutex
+		observed := []string{}
+		called := []string{}
+
+		controller := NewController("!", zap.NewNop())
+		controller.AddCommand("!echo", func(ctx context.Context, args []string, chatClient ChatClient) error {
+			mu.Lock()
+			called = append(called, args[0])
+			mu.Unlock()
+			return nil
+		}, []Filter{})
+
+		dispatcher, err := NewDispatcher(controller, 1, 10, Block, time.Second, zap.NewNop())
+		if err != nil {
+			t.Fatalf("Expected no error, got `%v`", err)
+		}
+		dispatcher.Observe(
+			func(_ context.Context, privateMessage *twitch.PrivateMessage) bool {
+				return privateMessage.User.Name == "spammer"
+			},
+			func(_ context.Context, privateMessage *twitch.PrivateMessage) bool {
+				mu.Lock()
+				observed = append(observed, privateMessage.Message)
+				mu.Unlock()
+				return false
+			},
+		)
+
+		ctx, cancel := context.WithCancel(context.Background())
+		done := make(chan struct{})
+		go func() {
+			_ = dispatcher.Run(ctx)
+			close(done)
+		}()
+
+		// when
+		dispatcher.Dispatch("!echo spam", twitch.PrivateMessage{Channel: "channel", Message: "!echo spam", User: twitch.User{Name: "spammer"}}, chatClientMock{})
+		dispatcher.Dispatch("hello", twitch.PrivateMessage{Channel: "channel", Message: "hello", User: twitch.User{Name: "viewer"}}, chatClientMock{})
+		dispatcher.Dispatch("!echo hi", twitch.PrivateMessage{Channel: "channel", Message: "!echo hi", User: twitch.User{Name: "viewer"}}, chatClientMock{})
+		cancel()
+		<-done
+
+		// then
+		expectedObserved := []string{"hello", "!echo hi"}
+		if len(observed) != len(expectedObserved) || observed[0] != expectedObserved[0] || observed[1] != expectedObserved[1] {
+			t.Errorf("Expected `%v`, got `%v`", expectedObserved, observed)
+		}
+		if len(called) != 1 || called[0] != "hi" {
+			t.Errorf("Expected `[hi]`, got `%v`", called)
+		}
+	})
+}
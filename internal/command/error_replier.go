@@ -0,0 +1,148 @@
+package command
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"time"
+
+	"go.uber.org/zap"
+)
+
+// ReplyPolicy decides how a user is informed about an error.
+type ReplyPolicy int
+
+const (
+	Silent  ReplyPolicy = iota // Silent does not inform the user.
+	Reply                      // Reply answers in the chat thread of the message that called the command.
+	Whisper                    // Whisper sends a private message to the user.
+)
+
+// DefaultLanguage is used, when there are no messages for a configured language.
+const DefaultLanguage = "en"
+
+// DefaultErrorMessages holds messages shown to users for every kind of error, grouped by a language.
+var DefaultErrorMessages = map[string]map[ErrorKind]string{
+	"en": {
+		KindInternal:         "something went wrong, try again later.",
+		KindUsage:            "wrong usage of the command.",
+		KindPermissionDenied: "you don't have permissions to use this command.",
+		KindCooldown:         "the command is on cooldown, wait a moment.",
+		KindUpstream:         "Twitch is not responding right now, try again later.",
+	},
+	"pl": {
+		KindInternal:         "coś poszło nie tak, spróbuj ponownie później.",
+		KindUsage:            "niepoprawne użycie komendy.",
+		KindPermissionDenied: "nie masz uprawnień do tej komendy.",
+		KindCooldown:         "komenda jest chwilowo niedostępna, poczekaj chwilę.",
+		KindUpstream:         "Twitch nie odpowiada, spróbuj ponownie później.",
+	},
+}
+
+// DefaultErrorPolicies decides how users are informed about every kind of error.
+var DefaultErrorPolicies = map[ErrorKind]ReplyPolicy{
+	KindInternal:         Reply,
+	KindUsage:            Reply,
+	KindPermissionDenied: Silent,
+	KindCooldown:         Silent,
+	KindUpstream:         Reply,
+}
+
+// whisperer sends private messages to users.
+type whisperer interface {
+	Whisper(ctx context.Context, toUserID, message string) error
+}
+
+// ErrorReplier informs users about errors returned from their commands.
+// Replies are rate limited per user and per channel, so errors can't be used for spamming the chat.
+type ErrorReplier struct {
+	policies        map[ErrorKind]ReplyPolicy // Policies decides how users are informed about every kind of error.
+	messages        map[ErrorKind]string      // Messages holds localized messages for every kind of error.
+	whisperer       whisperer                 // Whisperer sends private messages, it can be nil when whispers are not used.
+	userInterval    time.Duration             // UserInterval is the minimum time between replies to the same user.
+	channelInterval time.Duration             // ChannelInterval is the minimum time between replies on the same channel.
+	lastReplies     map[string]time.Time      // LastReplies stores when a user or a channel got the last reply.
+	mu              sync.Mutex                // Mu guards lastReplies.
+	now             func() time.Time          // Now returns the current time.
+	logger          *zap.Logger               // Logger is used for logging.
+}
+
+// NewErrorReplier creates an instance of ErrorReplier with messages in the given language.
+// When whisperer is nil, the Whisper policy falls back to Reply.
+func NewErrorReplier(language string, policies map[ErrorKind]ReplyPolicy, whisperer whisperer, userInterval, channelInterval time.Duration, logger *zap.Logger) *ErrorReplier {
+	messages, ok := DefaultErrorMessages[language]
+	if !ok {
+		messages = DefaultErrorMessages[DefaultLanguage]
+	}
+
+	return &ErrorReplier{
+		policies:        policies,
+		messages:        messages,
+		whisperer:       whisperer,
+		userInterval:    userInterval,
+		channelInterval: channelInterval,
+		lastReplies:     make(map[string]time.Time),
+		now:             time.Now,
+		logger:          logger.Named("error_replier"),
+	}
+}
+
+// Inform sends a message about an error to the user who called the command, according to a policy for the error.
+func (r *ErrorReplier) Inform(ctx context.Context, cmdCtx *Context, err error, chatClient ChatClient) {
+	kind := Classify(err)
+
+	policy := r.policies[kind]
+	if policy == Whisper && r.whisperer == nil {
+		policy = Reply
+	}
+	if policy == Silent {
+		return
+	}
+
+	channelName := cmdCtx.PrivMsg.Channel
+	userID := cmdCtx.PrivMsg.User.ID
+	if !r.allow(channelName, userID) {
+		return
+	}
+
+	text := userMessage(err)
+	if len(text) == 0 {
+		text = r.messages[kind]
+	}
+	message := fmt.Sprintf("@%s, %s", cmdCtx.PrivMsg.User.DisplayName, text)
+
+	if policy == Whisper {
+		whisperErr := r.whisperer.Whisper(ctx, userID, message)
+		if whisperErr == nil {
+			return
+		}
+
+		r.logger.Warn("failed to whisper an error to the user, replying in the chat instead", zap.Error(whisperErr))
+	}
+
+	chatClient.Reply(channelName, cmdCtx.PrivMsg.ID, message)
+}
+
+// allow reports whether the user and the channel can get a reply now, and if so, it records the reply.
+func (r *ErrorReplier) allow(channelName, userID string) bool {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	now := r.now()
+	userKey := "user:" + channelName + ":" + userID
+	channelKey := "channel:" + channelName
+
+	if now.Before(r.lastReplies[userKey].Add(r.userInterval)) || now.Before(r.lastReplies[channelKey].Add(r.channelInterval)) {
+		return false
+	}
+
+	for key, lastReply := range r.lastReplies {
+		if now.Sub(lastReply) > max(r.userInterval, r.channelInterval) {
+			delete(r.lastReplies, key)
+		}
+	}
+
+	r.lastReplies[userKey] = now
+	r.lastReplies[channelKey] = now
+	return true
+}
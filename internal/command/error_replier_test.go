@@ -0,0 +1,61 @@
+package command
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/gempir/go-twitch-irc/v4"
+	"go.uber.org/zap"
+)
+
+type replyRecordingChatClient struct {
+	chatClientMock
+	replies []string
+}
+
+func (r *replyRecordingChatClient) Reply(channelName, parentMessageID, message string) {
+	r.replies = append(r.replies, message)
+}
+
+func TestErrorReplier(t *testing.T) {
+	t.Run("replies with a user message of a usage error only once within the user interval", func(t *testing.T) {
+		// given
+		privateMessage := &twitch.PrivateMessage{
+			Channel: "channel",
+			User:    twitch.User{ID: "1", DisplayName: "viewer"},
+		}
+		cmdCtx := NewContext("test", privateMessage, zap.NewNop())
+		recorder := &replyRecordingChatClient{}
+		replier := NewErrorReplier("en", DefaultErrorPolicies, nil, time.Minute, 0, zap.NewNop())
+		expected := "@viewer, usage: !test <number>"
+
+		// when
+		replier.Inform(context.Background(), cmdCtx, UsageError("usage: !test <number>"), recorder)
+		replier.Inform(context.Background(), cmdCtx, UsageError("usage: !test <number>"), recorder)
+
+		// then
+		if len(recorder.replies) != 1 || recorder.replies[0] != expected {
+			t.Errorf("Expected `[%s]`, got `%v`", expected, recorder.replies)
+		}
+	})
+
+	t.Run("does not reply, when the policy for an error is silent", func(t *testing.T) {
+		// given
+		privateMessage := &twitch.PrivateMessage{
+			Channel: "channel",
+			User:    twitch.User{ID: "1", DisplayName: "viewer"},
+		}
+		cmdCtx := NewContext("test", privateMessage, zap.NewNop())
+		recorder := &replyRecordingChatClient{}
+		replier := NewErrorReplier("en", DefaultErrorPolicies, nil, time.Minute, 0, zap.NewNop())
+
+		// when
+		replier.Inform(context.Background(), cmdCtx, errCommandOnCooldown, recorder)
+
+		// then
+		if len(recorder.replies) != 0 {
+			t.Errorf("Expected no replies, got `%v`", recorder.replies)
+		}
+	})
+}
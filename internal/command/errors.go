@@ -0,0 +1,103 @@
+package command
+
+import (
+	"errors"
+	"fmt"
+)
+
+// ErrorKind classifies an error returned from a command.
+type ErrorKind int
+
+const (
+	KindInternal         ErrorKind = iota // KindInternal represents a bug or an unexpected state of the chatbot.
+	KindUsage                             // KindUsage represents a command called with wrong arguments.
+	KindPermissionDenied                  // KindPermissionDenied represents a user without permissions to a command.
+	KindCooldown                          // KindCooldown represents a command called before its cooldown passed.
+	KindUpstream                          // KindUpstream represents a failure of an external service, like Twitch API.
+)
+
+// String returns a name of the kind, which is used as a label in traces and metrics.
+func (k ErrorKind) String() string {
+	switch k {
+	case KindUsage:
+		return "usage"
+	case KindPermissionDenied:
+		return "permission_denied"
+	case KindCooldown:
+		return "cooldown"
+	case KindUpstream:
+		return "upstream"
+	default:
+		return "internal"
+	}
+}
+
+// Error represents a classified error returned from a command.
+type Error struct {
+	Kind        ErrorKind // Kind classifies the error.
+	UserMessage string    // UserMessage is an optional message shown to the user instead of a default one for the kind.
+	Err         error     // Err is the underlying error.
+}
+
+// Error returns a text of the underlying error.
+func (e *Error) Error() string {
+	if e.Err == nil {
+		return fmt.Sprintf("%s error: %s", e.Kind, e.UserMessage)
+	}
+
+	return fmt.Sprintf("%s error: %v", e.Kind, e.Err)
+}
+
+// Unwrap returns the underlying error.
+func (e *Error) Unwrap() error {
+	return e.Err
+}
+
+// NewError creates a classified error with an optional message for the user.
+func NewError(kind ErrorKind, userMessage string, err error) error {
+	return &Error{Kind: kind, UserMessage: userMessage, Err: err}
+}
+
+// UsageError tells a user how a command should be called.
+func UsageError(userMessage string) error {
+	return &Error{Kind: KindUsage, UserMessage: userMessage}
+}
+
+// PermissionDeniedError marks an error as a result of missing permissions.
+func PermissionDeniedError(err error) error {
+	return &Error{Kind: KindPermissionDenied, Err: err}
+}
+
+// UpstreamError marks an error as a failure of an external service.
+func UpstreamError(err error) error {
+	return &Error{Kind: KindUpstream, Err: err}
+}
+
+// InternalError marks an error as a bug or an unexpected state of the chatbot.
+func InternalError(err error) error {
+	return &Error{Kind: KindInternal, Err: err}
+}
+
+// Classify returns a kind of an error. Errors that were not classified by a command are treated as internal.
+func Classify(err error) ErrorKind {
+	var commandErr *Error
+	if errors.As(err, &commandErr) {
+		return commandErr.Kind
+	}
+
+	if errors.Is(err, errCommandTimedOut) {
+		return KindUpstream
+	}
+
+	return KindInternal
+}
+
+// userMessage returns a message attached to a classified error.
+func userMessage(err error) string {
+	var commandErr *Error
+	if errors.As(err, &commandErr) {
+		return commandErr.UserMessage
+	}
+
+	return ""
+}
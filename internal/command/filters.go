@@ -3,13 +3,41 @@ package command
 import (
 	"context"
 	"errors"
+	"sync"
 	"time"
 
 	"go.opentelemetry.io/otel/codes"
 )
 
-var errNoPermissions = errors.New("called a command without a needed role")
-var errCommandOnCooldown = errors.New("command has a cooldown")
+var errNoPermissions = PermissionDeniedError(errors.New("called a command without a needed role"))
+var errCommandOnCooldown = NewError(KindCooldown, "", errors.New("command has a cooldown"))
+var errNotFollower = PermissionDeniedError(errors.New("called a command without following the channel"))
+var errAccountTooYoung = PermissionDeniedError(errors.New("called a command with a too young account"))
+var errStreamOffline = UsageError("the command is available only during a stream.")
+var errStreamLive = UsageError("the command is available only when the stream is offline.")
+
+// FailurePolicy decides what a filter does, when it can't verify a user, for example, because Twitch API is down.
+type FailurePolicy int
+
+const (
+	FailClosed FailurePolicy = iota // FailClosed rejects the user.
+	FailOpen                        // FailOpen lets the user through.
+)
+
+// followerChecker tells, if a user follows a channel.
+type followerChecker interface {
+	IsFollower(ctx context.Context, broadcasterID, userID string) (bool, error)
+}
+
+// accountAgeChecker returns the time when a user created a Twitch account.
+type accountAgeChecker interface {
+	AccountCreatedAt(ctx context.Context, userID string) (time.Time, error)
+}
+
+// liveChecker tells, if a stream of a channel is live.
+type liveChecker interface {
+	IsLive(channelName string) bool
+}
 
 // From the twitch docs I found, that are available badges like:
 // ["broadcaster", "moderator", "subscriber", "artist-badge", "founder", "vip", "sub-gifter", "bits", "partner", "staff"].
@@ -19,9 +47,11 @@ func hasBadge(badgeName string, badges map[string]int) bool {
 
 // HasRole rejects user's command request, when the user does not have a role for that.
 // The roles are compared with users's twitch badges.
+//
+// Deprecated: use Require from the permission package, it supports ordered levels and per-user overrides.
 func HasRole(roles []string) Filter {
 	return func(cb Handler) Handler {
-		return func(ctx context.Context, args []string, chatClient chatClient) error {
+		return func(ctx context.Context, args []string, chatClient ChatClient) error {
 			spanCtx, span := tracer.Start(ctx, "hasRole")
 			defer span.End()
 
@@ -44,21 +74,115 @@ func HasRole(roles []string) Filter {
 // Cooldown stops from calling a command, when not enough time passed.
 func Cooldown(cooldown time.Duration) Filter {
 	lastCalled := time.Time{}
+	var mu sync.Mutex
 
 	return func(cb Handler) Handler {
-		return func(ctx context.Context, args []string, chatClient chatClient) error {
+		return func(ctx context.Context, args []string, chatClient ChatClient) error {
 			spanCtx, span := tracer.Start(ctx, "cooldown")
 			defer span.End()
 
+			mu.Lock()
 			if time.Now().Before(lastCalled.Add(cooldown)) {
+				mu.Unlock()
 				span.SetStatus(codes.Error, "not enough time passed to call a command")
 				return errCommandOnCooldown
 			}
 
 			lastCalled = time.Now()
+			mu.Unlock()
 			span.SetStatus(codes.Ok, "user passed through the filter")
 			err := cb(spanCtx, args, chatClient)
 			return err
 		}
 	}
 }
+
+// FollowersOnly rejects user's command request, when the user does not follow the channel.
+func FollowersOnly(checker followerChecker, policy FailurePolicy) Filter {
+	return func(cb Handler) Handler {
+		return func(ctx context.Context, args []string, chatClient ChatClient) error {
+			spanCtx, span := tracer.Start(ctx, "followersOnly")
+			defer span.End()
+
+			cmdCtx := UnwrapContext(ctx)
+
+			isFollower, err := checker.IsFollower(spanCtx, cmdCtx.PrivMsg.RoomID, cmdCtx.PrivMsg.User.ID)
+			if err != nil {
+				span.RecordError(err)
+				if policy == FailClosed {
+					span.SetStatus(codes.Error, "failed to check if the user follows the channel")
+					return UpstreamError(err)
+				}
+				isFollower = true
+			}
+
+			if !isFollower {
+				span.SetStatus(codes.Error, "user does not follow the channel")
+				return errNotFollower
+			}
+
+			span.SetStatus(codes.Ok, "user passed through the filter")
+			return cb(spanCtx, args, chatClient)
+		}
+	}
+}
+
+// MinAccountAge rejects user's command request, when the user's account is younger than minAge.
+func MinAccountAge(checker accountAgeChecker, minAge time.Duration, policy FailurePolicy) Filter {
+	return func(cb Handler) Handler {
+		return func(ctx context.Context, args []string, chatClient ChatClient) error {
+			spanCtx, span := tracer.Start(ctx, "minAccountAge")
+			defer span.End()
+
+			cmdCtx := UnwrapContext(ctx)
+
+			createdAt, err := checker.AccountCreatedAt(spanCtx, cmdCtx.PrivMsg.User.ID)
+			if err != nil {
+				span.RecordError(err)
+				if policy == FailClosed {
+					span.SetStatus(codes.Error, "failed to check the age of the account")
+					return UpstreamError(err)
+				}
+				createdAt = time.Time{}
+			}
+
+			if time.Since(createdAt) < minAge {
+				span.SetStatus(codes.Error, "user's account is too young")
+				return errAccountTooYoung
+			}
+
+			span.SetStatus(codes.Ok, "user passed through the filter")
+			return cb(spanCtx, args, chatClient)
+		}
+	}
+}
+
+// LiveOnly rejects user's command request, when the stream is offline.
+func LiveOnly(checker liveChecker) Filter {
+	return streamStatus("liveOnly", checker, true, errStreamOffline)
+}
+
+// OfflineOnly rejects user's command request, when the stream is live.
+func OfflineOnly(checker liveChecker) Filter {
+	return streamStatus("offlineOnly", checker, false, errStreamLive)
+}
+
+// streamStatus rejects user's command request, when the stream is not in the wanted state.
+func streamStatus(spanName string, checker liveChecker, wantLive bool, rejection error) Filter {
+	return func(cb Handler) Handler {
+		return func(ctx context.Context, args []string, chatClient ChatClient) error {
+			spanCtx, span := tracer.Start(ctx, spanName)
+			defer span.End()
+
+			cmdCtx := UnwrapContext(ctx)
+
+			if checker.IsLive(cmdCtx.PrivMsg.Channel) != wantLive {
+				span.SetStatus(codes.Error, "stream is not in the required state")
+				return rejection
+			}
+
+			span.SetStatus(codes.Ok, "user passed through the filter")
+			return cb(spanCtx, args, chatClient)
+		}
+	}
+}
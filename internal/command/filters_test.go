@@ -2,6 +2,7 @@ package command
 
 import (
 	"context"
+	"errors"
 	"testing"
 	"time"
 
@@ -40,8 +41,8 @@ func TestHasRole(t *testing.T) {
 		cmdCtx := NewContext("test", privateMessage, zap.NewNop())
 		ctx := setContextToCommand(context.Background(), cmdCtx)
 
-		var mockedChatClient chatClient = chatClientMock{}
-		var cb Handler = func(ctx context.Context, args []string, chatClient chatClient) error {
+		var mockedChatClient ChatClient = chatClientMock{}
+		var cb Handler = func(ctx context.Context, args []string, chatClient ChatClient) error {
 			return nil
 		}
 		var expected error = errNoPermissions
@@ -71,8 +72,8 @@ func TestHasRole(t *testing.T) {
 		cmdCtx := NewContext("test", privateMessage, zap.NewNop())
 		ctx := setContextToCommand(context.Background(), cmdCtx)
 
-		var mockedChatClient chatClient = chatClientMock{}
-		var cb Handler = func(ctx context.Context, args []string, chatClient chatClient) error {
+		var mockedChatClient ChatClient = chatClientMock{}
+		var cb Handler = func(ctx context.Context, args []string, chatClient ChatClient) error {
 			return nil
 		}
 		var expected error = nil
@@ -93,11 +94,11 @@ func TestCooldown(t *testing.T) {
 		// given
 		args := []string{}
 		privateMessage := &twitch.PrivateMessage{}
-		var mockedChatClient chatClient = chatClientMock{}
+		var mockedChatClient ChatClient = chatClientMock{}
 		cmdCtx := NewContext("test", privateMessage, zap.NewNop())
 		ctx := setContextToCommand(context.Background(), cmdCtx)
 		cooldown := 30 * time.Second
-		var cb Handler = func(ctx context.Context, args []string, chatClient chatClient) error {
+		var cb Handler = func(ctx context.Context, args []string, chatClient ChatClient) error {
 			return nil
 		}
 		var expected error = nil
@@ -116,11 +117,11 @@ func TestCooldown(t *testing.T) {
 		// given
 		args := []string{}
 		privateMessage := &twitch.PrivateMessage{}
-		var mockedChatClient chatClient = chatClientMock{}
+		var mockedChatClient ChatClient = chatClientMock{}
 		cmdCtx := NewContext("test", privateMessage, zap.NewNop())
 		ctx := setContextToCommand(context.Background(), cmdCtx)
 		cooldown := 30 * time.Second
-		var cb Handler = func(ctx context.Context, args []string, chatClient chatClient) error {
+		var cb Handler = func(ctx context.Context, args []string, chatClient ChatClient) error {
 			return nil
 		}
 		var expected = errCommandOnCooldown
@@ -142,11 +143,11 @@ func TestCooldown(t *testing.T) {
 		// given
 		args := []string{}
 		privateMessage := &twitch.PrivateMessage{}
-		var mockedChatClient chatClient = chatClientMock{}
+		var mockedChatClient ChatClient = chatClientMock{}
 		cmdCtx := NewContext("test", privateMessage, zap.NewNop())
 		ctx := setContextToCommand(context.Background(), cmdCtx)
 		cooldown := 3 * time.Second
-		var cb Handler = func(ctx context.Context, args []string, chatClient chatClient) error {
+		var cb Handler = func(ctx context.Context, args []string, chatClient ChatClient) error {
 			return nil
 		}
 		var expected error = nil
@@ -167,3 +168,138 @@ func TestCooldown(t *testing.T) {
 		}
 	})
 }
+
+type followerCheckerMock struct {
+	isFollower bool
+	err        error
+}
+
+func (f followerCheckerMock) IsFollower(_ context.Context, _, _ string) (bool, error) {
+	return f.isFollower, f.err
+}
+
+type accountAgeCheckerMock struct {
+	createdAt time.Time
+	err       error
+}
+
+func (a accountAgeCheckerMock) AccountCreatedAt(_ context.Context, _ string) (time.Time, error) {
+	return a.createdAt, a.err
+}
+
+func TestFollowersOnly(t *testing.T) {
+	testCases := []struct {
+		name     string
+		checker  followerCheckerMock
+		policy   FailurePolicy
+		expected ErrorKind
+		allowed  bool
+	}{
+		{name: "allows a follower", checker: followerCheckerMock{isFollower: true}, allowed: true},
+		{name: "rejects a user who does not follow the channel", checker: followerCheckerMock{}, expected: KindPermissionDenied},
+		{name: "rejects a user, when Twitch API fails and the policy is fail-closed", checker: followerCheckerMock{err: errors.New("api is down")}, policy: FailClosed, expected: KindUpstream},
+		{name: "allows a user, when Twitch API fails and the policy is fail-open", checker: followerCheckerMock{err: errors.New("api is down")}, policy: FailOpen, allowed: true},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			// given
+			cmdCtx := NewContext("test", &twitch.PrivateMessage{}, zap.NewNop())
+			ctx := setContextToCommand(context.Background(), cmdCtx)
+			var cb Handler = func(ctx context.Context, args []string, chatClient ChatClient) error {
+				return nil
+			}
+
+			// when
+			got := FollowersOnly(tc.checker, tc.policy)(cb)(ctx, []string{}, chatClientMock{})
+
+			// then
+			if tc.allowed && got != nil {
+				t.Errorf("Expected no error, got `%v`", got)
+			}
+			if !tc.allowed && (got == nil || Classify(got) != tc.expected) {
+				t.Errorf("Expected an error of kind `%v`, got `%v`", tc.expected, got)
+			}
+		})
+	}
+}
+
+func TestMinAccountAge(t *testing.T) {
+	testCases := []struct {
+		name     string
+		checker  accountAgeCheckerMock
+		policy   FailurePolicy
+		expected ErrorKind
+		allowed  bool
+	}{
+		{name: "allows an old account", checker: accountAgeCheckerMock{createdAt: time.Now().Add(-30 * 24 * time.Hour)}, allowed: true},
+		{name: "rejects a young account", checker: accountAgeCheckerMock{createdAt: time.Now().Add(-time.Hour)}, expected: KindPermissionDenied},
+		{name: "rejects a user, when Twitch API fails and the policy is fail-closed", checker: accountAgeCheckerMock{err: errors.New("api is down")}, policy: FailClosed, expected: KindUpstream},
+		{name: "allows a user, when Twitch API fails and the policy is fail-open", checker: accountAgeCheckerMock{err: errors.New("api is down")}, policy: FailOpen, allowed: true},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			// given
+			cmdCtx := NewContext("test", &twitch.PrivateMessage{}, zap.NewNop())
+			ctx := setContextToCommand(context.Background(), cmdCtx)
+			var cb Handler = func(ctx context.Context, args []string, chatClient ChatClient) error {
+				return nil
+			}
+
+			// when
+			got := MinAccountAge(tc.checker, 7*24*time.Hour, tc.policy)(cb)(ctx, []string{}, chatClientMock{})
+
+			// then
+			if tc.allowed && got != nil {
+				t.Errorf("Expected no error, got `%v`", got)
+			}
+			if !tc.allowed && (got == nil || Classify(got) != tc.expected) {
+				t.Errorf("Expected an error of kind `%v`, got `%v`", tc.expected, got)
+			}
+		})
+	}
+}
+
+type liveCheckerMock bool
+
+func (l liveCheckerMock) IsLive(_ string) bool {
+	return bool(l)
+}
+
+func TestStreamStatusFilters(t *testing.T) {
+	testCases := []struct {
+		name    string
+		filter  Filter
+		allowed bool
+	}{
+		{name: "live-only allows a command during a stream", filter: LiveOnly(liveCheckerMock(true)), allowed: true},
+		{name: "live-only rejects a command when the stream is offline", filter: LiveOnly(liveCheckerMock(false))},
+		{name: "offline-only allows a command when the stream is offline", filter: OfflineOnly(liveCheckerMock(false)), allowed: true},
+		{name: "offline-only rejects a command during a stream", filter: OfflineOnly(liveCheckerMock(true))},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			// given
+			cmdCtx := NewContext("test", &twitch.PrivateMessage{Channel: "channel"}, zap.NewNop())
+			ctx := setContextToCommand(context.Background(), cmdCtx)
+			called := false
+			var cb Handler = func(ctx context.Context, args []string, chatClient ChatClient) error {
+				called = true
+				return nil
+			}
+
+			// when
+			got := tc.filter(cb)(ctx, []string{}, chatClientMock{})
+
+			// then
+			if tc.allowed != called {
+				t.Errorf("Expected the command to be called: %v, got %v", tc.allowed, called)
+			}
+			if !tc.allowed && Classify(got) != KindUsage {
+				t.Errorf("Expected an error of kind `%v`, got `%v`", KindUsage, got)
+			}
+		})
+	}
+}
@@ -0,0 +1,180 @@
+package command
+
+import (
+	"context"
+	"time"
+	"unicode/utf8"
+
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/metric"
+)
+
+// maxMessageLength is the maximum number of characters Twitch accepts in a single chat message.
+const maxMessageLength = 500
+
+var meter = otel.Meter("github.com/danielbukowski/twitch-chatbot/internal/command")
+
+// Metrics records invocations, denials, unhandled errors and execution latency of commands.
+// The middleware has to be added after ErrorHandler, because ErrorHandler does not return errors to previous middlewares.
+func Metrics() (Middleware, error) {
+	invocationCounter, err := meter.Int64Counter(
+		"command.invocation.counter",
+		metric.WithDescription("Number of called commands."),
+		metric.WithUnit("{invocation}"),
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	denialCounter, err := meter.Int64Counter(
+		"command.denial.counter",
+		metric.WithDescription("Number of commands rejected by filters."),
+		metric.WithUnit("{denial}"),
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	errorCounter, err := meter.Int64Counter(
+		"command.error.counter",
+		metric.WithDescription("Number of commands that returned an unhandled error."),
+		metric.WithUnit("{error}"),
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	latencyHistogram, err := meter.Float64Histogram(
+		"command.duration",
+		metric.WithDescription("Time taken to execute a command."),
+		metric.WithUnit("ms"),
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	return func(cb Handler) Handler {
+		return func(ctx context.Context, args []string, chatClient ChatClient) error {
+			cmdCtx := UnwrapContext(ctx)
+			attributes := metric.WithAttributes(
+				attribute.String("command.name", cmdCtx.CommandName),
+				attribute.String("channel.name", cmdCtx.PrivMsg.Channel),
+			)
+
+			invocationCounter.Add(ctx, 1, attributes)
+
+			start := time.Now()
+			err := cb(ctx, args, chatClient)
+			latencyHistogram.Record(ctx, float64(time.Since(start).Microseconds())/1000, attributes)
+
+			if err == nil {
+				return nil
+			}
+
+			if reason, ok := denialReason(err); ok {
+				denialCounter.Add(ctx, 1, attributes, metric.WithAttributes(attribute.String("denial.reason", reason)))
+				return err
+			}
+
+			errorCounter.Add(ctx, 1, attributes, metric.WithAttributes(attribute.String("error.kind", Classify(err).String())))
+			return err
+		}
+	}, nil
+}
+
+// denialReason returns a label for errors returned by filters.
+func denialReason(err error) (string, bool) {
+	switch Classify(err) {
+	case KindPermissionDenied:
+		return "no_permissions", true
+	case KindCooldown:
+		return "cooldown", true
+	default:
+		return "", false
+	}
+}
+
+// MeteredChatClient wraps a chat client and counts sent and dropped outbound messages.
+type MeteredChatClient struct {
+	chatClient     ChatClient          // ChatClient is a wrapped client that actually sends messages.
+	sentCounter    metric.Int64Counter // SentCounter counts messages passed to the wrapped client.
+	droppedCounter metric.Int64Counter // DroppedCounter counts messages that Twitch would reject, so they are not sent at all.
+}
+
+// NewMeteredChatClient creates an instance of MeteredChatClient.
+func NewMeteredChatClient(chatClient ChatClient) (*MeteredChatClient, error) {
+	sentCounter, err := meter.Int64Counter(
+		"chat.outbound.sent.counter",
+		metric.WithDescription("Number of messages sent by the chatbot."),
+		metric.WithUnit("{message}"),
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	droppedCounter, err := meter.Int64Counter(
+		"chat.outbound.dropped.counter",
+		metric.WithDescription("Number of messages dropped before sending them to the chat."),
+		metric.WithUnit("{message}"),
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	return &MeteredChatClient{
+		chatClient:     chatClient,
+		sentCounter:    sentCounter,
+		droppedCounter: droppedCounter,
+	}, nil
+}
+
+// Say sends a message to a channel, when the message is valid.
+func (m *MeteredChatClient) Say(channelName, message string) {
+	if !m.accept(channelName, message) {
+		return
+	}
+
+	m.chatClient.Say(channelName, message)
+}
+
+// Reply replies to a thread, when the message is valid.
+func (m *MeteredChatClient) Reply(channelName, parentMessageID, message string) {
+	if !m.accept(channelName, message) {
+		return
+	}
+
+	m.chatClient.Reply(channelName, parentMessageID, message)
+}
+
+// Join joins to a channel or channels.
+func (m *MeteredChatClient) Join(channels ...string) {
+	m.chatClient.Join(channels...)
+}
+
+// Depart leaves from a channel.
+func (m *MeteredChatClient) Depart(channelName string) {
+	m.chatClient.Depart(channelName)
+}
+
+// accept records the outbound message and reports whether it should be sent.
+func (m *MeteredChatClient) accept(channelName, message string) bool {
+	ctx := context.Background()
+	channelAttribute := attribute.String("channel.name", channelName)
+
+	var reason string
+	switch {
+	case len(message) == 0:
+		reason = "empty"
+	case utf8.RuneCountInString(message) > maxMessageLength:
+		reason = "too_long"
+	}
+
+	if len(reason) != 0 {
+		m.droppedCounter.Add(ctx, 1, metric.WithAttributes(channelAttribute, attribute.String("drop.reason", reason)))
+		return false
+	}
+
+	m.sentCounter.Add(ctx, 1, metric.WithAttributes(channelAttribute))
+	return true
+}
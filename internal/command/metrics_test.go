@@ -0,0 +1,128 @@
+package command
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/gempir/go-twitch-irc/v4"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	sdkmetric "go.opentelemetry.io/otel/sdk/metric"
+	"go.opentelemetry.io/otel/sdk/metric/metricdata"
+	"go.uber.org/zap"
+)
+
+type recordingChatClient struct {
+	chatClientMock
+	messages []string
+}
+
+func (r *recordingChatClient) Say(channelName, message string) {
+	r.messages = append(r.messages, message)
+}
+
+func TestMeteredChatClient(t *testing.T) {
+	t.Run("drops messages that are empty or longer than the Twitch limit", func(t *testing.T) {
+		// given
+		recorder := &recordingChatClient{}
+		meteredChatClient, err := NewMeteredChatClient(recorder)
+		if err != nil {
+			t.Fatalf("Expected no error, got `%v`", err)
+		}
+
+		// when
+		meteredChatClient.Say("channel", "")
+		meteredChatClient.Say("channel", strings.Repeat("a", maxMessageLength+1))
+		meteredChatClient.Say("channel", "hello")
+
+		// then
+		if len(recorder.messages) != 1 || recorder.messages[0] != "hello" {
+			t.Errorf("Expected only `hello` to be sent, got `%v`", recorder.messages)
+		}
+	})
+}
+
+func TestMetrics(t *testing.T) {
+	// given
+	reader := sdkmetric.NewManualReader()
+	otel.SetMeterProvider(sdkmetric.NewMeterProvider(sdkmetric.WithReader(reader)))
+
+	metrics, err := Metrics()
+	if err != nil {
+		t.Fatalf("Expected no error, got `%v`", err)
+	}
+
+	cmdCtx := NewContext("!test", &twitch.PrivateMessage{Channel: "channel"}, zap.NewNop())
+	ctx := setContextToCommand(context.Background(), cmdCtx)
+	handlerErrors := []error{nil, UpstreamError(errors.New("api is down")), PermissionDeniedError(errors.New("not a moderator"))}
+
+	// when
+	for _, handlerErr := range handlerErrors {
+		var cb Handler = func(ctx context.Context, args []string, chatClient ChatClient) error {
+			return handlerErr
+		}
+		_ = metrics(cb)(ctx, []string{}, chatClientMock{})
+	}
+
+	// then
+	var resourceMetrics metricdata.ResourceMetrics
+	if err := reader.Collect(context.Background(), &resourceMetrics); err != nil {
+		t.Fatalf("Expected no error, got `%v`", err)
+	}
+
+	recorded := map[string]metricdata.Aggregation{}
+	for _, scopeMetrics := range resourceMetrics.ScopeMetrics {
+		for _, m := range scopeMetrics.Metrics {
+			recorded[m.Name] = m.Data
+		}
+	}
+
+	testCases := []struct {
+		name      string
+		metric    string
+		attribute attribute.KeyValue
+		expected  int64
+	}{
+		{name: "counts every invocation", metric: "command.invocation.counter", attribute: attribute.String("command.name", "!test"), expected: 3},
+		{name: "counts unhandled errors by their kind", metric: "command.error.counter", attribute: attribute.String("error.kind", "upstream"), expected: 1},
+		{name: "counts denials by their reason", metric: "command.denial.counter", attribute: attribute.String("denial.reason", "no_permissions"), expected: 1},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			sum, ok := recorded[tc.metric].(metricdata.Sum[int64])
+			if !ok {
+				t.Fatalf("Expected a counter `%s`, got `%v`", tc.metric, recorded[tc.metric])
+			}
+
+			var got int64
+			for _, point := range sum.DataPoints {
+				if value, found := point.Attributes.Value(tc.attribute.Key); found && value == tc.attribute.Value {
+					got += point.Value
+				}
+			}
+
+			if got != tc.expected {
+				t.Errorf("Expected `%d`, got `%d`", tc.expected, got)
+			}
+		})
+	}
+
+	t.Run("records a duration of every invocation", func(t *testing.T) {
+		histogram, ok := recorded["command.duration"].(metricdata.Histogram[float64])
+		if !ok {
+			t.Fatalf("Expected a histogram, got `%v`", recorded["command.duration"])
+		}
+
+		var got uint64
+		for _, point := range histogram.DataPoints {
+			got += point.Count
+		}
+
+		if got != 3 {
+			t.Errorf("Expected `3`, got `%d`", got)
+		}
+	})
+}
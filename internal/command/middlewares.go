@@ -3,16 +3,24 @@ package command
 import (
 	"context"
 	"errors"
+	"fmt"
+	"runtime/debug"
+	"time"
 
 	"go.opentelemetry.io/otel/attribute"
 	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/trace"
 	"go.uber.org/zap"
 )
 
+var errCommandPanicked = errors.New("command panicked")
+var errCommandTimedOut = errors.New("command exceeded its deadline")
+
 // ErrorHandler takes care of returned errors (if it's present) from a executed command.
-func ErrorHandler() Middleware {
+// Errors are classified and, when the replier is not nil, the user is informed about them according to the replier's policies.
+func ErrorHandler(replier *ErrorReplier) Middleware {
 	return func(cb Handler) Handler {
-		return func(ctx context.Context, args []string, chatClient chatClient) error {
+		return func(ctx context.Context, args []string, chatClient ChatClient) error {
 			spanCtx, span := tracer.Start(ctx, "errorHandler")
 			defer span.End()
 
@@ -23,17 +31,33 @@ func ErrorHandler() Middleware {
 			err := cb(spanCtx, args, chatClient)
 
 			if err != nil {
-				if errors.Is(err, errNoPermissions) {
+				kind := Classify(err)
+				span.SetAttributes(attribute.String("command.error.kind", kind.String()))
+
+				switch {
+				case errors.Is(err, errNoPermissions):
 					span.SetStatus(codes.Error, "got error NoPermissions from a command")
-					return nil
-				}
-				if errors.Is(err, errCommandOnCooldown) {
+				case errors.Is(err, errCommandOnCooldown):
 					span.SetStatus(codes.Error, "got error CommandOnCooldown from a command")
-					return nil
+				case errors.Is(err, errCommandPanicked):
+					span.SetStatus(codes.Error, "command panicked")
+					cmdCtx.Logger.Error("command panicked", zap.Error(err))
+				case errors.Is(err, errCommandTimedOut):
+					span.SetStatus(codes.Error, "command exceeded its deadline")
+					cmdCtx.Logger.Warn("command exceeded its deadline", zap.Error(err))
+				case kind == KindUsage || kind == KindPermissionDenied || kind == KindCooldown:
+					span.SetStatus(codes.Error, "command rejected the call")
+				case kind == KindUpstream:
+					span.SetStatus(codes.Error, "external service failed")
+					cmdCtx.Logger.Warn("external service failed", zap.Error(err))
+				default:
+					span.SetStatus(codes.Error, "unhandled error occurred")
+					cmdCtx.Logger.Error("unhandled error occurred", zap.Error(err))
 				}
 
-				span.SetStatus(codes.Error, "unhandled error occurred")
-				cmdCtx.Logger.Error("unhandled error occurred", zap.Error(err))
+				if replier != nil {
+					replier.Inform(spanCtx, cmdCtx, err, chatClient)
+				}
 				return nil
 			}
 			span.SetStatus(codes.Ok, "successfully executed a command without error")
@@ -41,3 +65,73 @@ func ErrorHandler() Middleware {
 		}
 	}
 }
+
+// Recover catches a panic from a command and turns it into an error, so a single broken command does not crash the chatbot.
+// The stack trace is recorded in the span and in the logger.
+func Recover() Middleware {
+	return func(cb Handler) Handler {
+		return func(ctx context.Context, args []string, chatClient ChatClient) (err error) {
+			spanCtx, span := tracer.Start(ctx, "recover")
+			defer span.End()
+
+			defer func() {
+				r := recover()
+				if r == nil {
+					return
+				}
+
+				stack := string(debug.Stack())
+				err = fmt.Errorf("%w: %v", errCommandPanicked, r)
+
+				span.RecordError(err, trace.WithAttributes(attribute.String("exception.stacktrace", stack)))
+				span.SetStatus(codes.Error, "recovered from a panic")
+
+				if cmdCtx := UnwrapContext(ctx); cmdCtx != nil {
+					cmdCtx.Logger.Error("recovered from a panic in a command",
+						zap.String("command_name", cmdCtx.CommandName),
+						zap.Any("panic", r),
+						zap.String("stacktrace", stack),
+					)
+				}
+			}()
+
+			err = cb(spanCtx, args, chatClient)
+			span.SetStatus(codes.Ok, "command did not panic")
+			return err
+		}
+	}
+}
+
+// Timeout enforces a deadline on a command. The command gets a context with the deadline,
+// and when it does not return in time, Timeout returns an error without waiting for it.
+// Recover has to be added after Timeout, because the command runs on a separate goroutine.
+func Timeout(timeout time.Duration) Middleware {
+	return func(cb Handler) Handler {
+		return func(ctx context.Context, args []string, chatClient ChatClient) error {
+			spanCtx, span := tracer.Start(ctx, "timeout")
+			defer span.End()
+
+			spanCtx, cancel := context.WithTimeout(spanCtx, timeout)
+			defer cancel()
+
+			done := make(chan error, 1)
+			go func() {
+				done <- cb(spanCtx, args, chatClient)
+			}()
+
+			select {
+			case err := <-done:
+				span.SetStatus(codes.Ok, "command finished before its deadline")
+				return err
+			case <-spanCtx.Done():
+				if err := ctx.Err(); err != nil {
+					span.SetStatus(codes.Error, "command was cancelled")
+					return err
+				}
+
+				span.SetStatus(codes.Error, "command exceeded its deadline")
+				return fmt.Errorf("%w: %s", errCommandTimedOut, timeout)
+			}
+		}
+	}
+}
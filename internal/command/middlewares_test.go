@@ -0,0 +1,71 @@
+package command
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/gempir/go-twitch-irc/v4"
+	"go.uber.org/zap"
+)
+
+func TestRecover(t *testing.T) {
+	t.Run("returns errCommandPanicked, when a command panics", func(t *testing.T) {
+		// given
+		cmdCtx := NewContext("test", &twitch.PrivateMessage{}, zap.NewNop())
+		ctx := setContextToCommand(context.Background(), cmdCtx)
+		var mockedChatClient ChatClient = chatClientMock{}
+		var cb Handler = func(ctx context.Context, args []string, chatClient ChatClient) error {
+			panic("something went wrong")
+		}
+
+		// when
+		var got = Recover()(cb)(ctx, []string{}, mockedChatClient)
+
+		// then
+		if !errors.Is(got, errCommandPanicked) {
+			t.Errorf("Expected `%v`, got `%v` error", errCommandPanicked, got)
+		}
+	})
+}
+
+func TestTimeout(t *testing.T) {
+	t.Run("returns errCommandTimedOut, when a command does not finish before its deadline", func(t *testing.T) {
+		// given
+		cmdCtx := NewContext("test", &twitch.PrivateMessage{}, zap.NewNop())
+		ctx := setContextToCommand(context.Background(), cmdCtx)
+		var mockedChatClient ChatClient = chatClientMock{}
+		var cb Handler = func(ctx context.Context, args []string, chatClient ChatClient) error {
+			time.Sleep(time.Second)
+			return nil
+		}
+
+		// when
+		var got = Timeout(10*time.Millisecond)(cb)(ctx, []string{}, mockedChatClient)
+
+		// then
+		if !errors.Is(got, errCommandTimedOut) {
+			t.Errorf("Expected `%v`, got `%v` error", errCommandTimedOut, got)
+		}
+	})
+
+	t.Run("returns an error from a command, when the command finishes before its deadline", func(t *testing.T) {
+		// given
+		cmdCtx := NewContext("test", &twitch.PrivateMessage{}, zap.NewNop())
+		ctx := setContextToCommand(context.Background(), cmdCtx)
+		var mockedChatClient ChatClient = chatClientMock{}
+		expected := errors.New("command error")
+		var cb Handler = func(ctx context.Context, args []string, chatClient ChatClient) error {
+			return expected
+		}
+
+		// when
+		var got = Timeout(time.Second)(cb)(ctx, []string{}, mockedChatClient)
+
+		// then
+		if !errors.Is(got, expected) {
+			t.Errorf("Expected `%v`, got `%v` error", expected, got)
+		}
+	})
+}
@@ -4,6 +4,7 @@ import (
 	"errors"
 	"fmt"
 	"os"
+	"time"
 
 	"github.com/joho/godotenv"
 )
@@ -21,6 +22,13 @@ type Config struct {
 	GrafanaAPIToken         string
 	OTELServiceName         string
 	OTLPExporterEndpoint    string
+	ChatbotLanguage         string
+	TwitchBotOwnerName      string
+	StrikeLadder            string
+	StrikeDecay             time.Duration
+	RedemptionRoutes        string
+	ThankYouTemplates       string
+	HTTPAddress             string
 }
 
 func New(isDevEnv bool) (*Config, error) {
@@ -35,6 +43,11 @@ func New(isDevEnv bool) (*Config, error) {
 		return nil, errors.Join(errors.New("failed to load environment variables from a file"), err)
 	}
 
+	strikeDecay, err := time.ParseDuration(getEnvOrDefault("STRIKE_DECAY", "24h"))
+	if err != nil {
+		return nil, errors.Join(errors.New("failed to parse STRIKE_DECAY"), err)
+	}
+
 	return &Config{
 		TwitchClientID:          getEnv("TWITCH_CLIENT_ID"),
 		TwitchClientSecret:      getEnv("TWITCH_CLIENT_SECRET"),
@@ -48,6 +61,13 @@ func New(isDevEnv bool) (*Config, error) {
 		GrafanaAPIToken:         getEnv("GRAFANA_API_TOKEN"),
 		OTELServiceName:         getEnv("OTEL_SERVICE_NAME"),
 		OTLPExporterEndpoint:    getEnv("OTEL_EXPORTER_OTLP_ENDPOINT"),
+		ChatbotLanguage:         getEnvOrDefault("CHATBOT_LANGUAGE", "en"),
+		TwitchBotOwnerName:      getEnvOrDefault("TWITCH_BOT_OWNER_NAME", getEnv("TWITCH_CHANNEL_NAME")),
+		StrikeLadder:            getEnvOrDefault("STRIKE_LADDER", "warn,timeout:60s,timeout:10m,ban"),
+		StrikeDecay:             strikeDecay,
+		RedemptionRoutes:        getEnvOrDefault("REDEMPTION_ROUTES", ""),
+		ThankYouTemplates:       getEnvOrDefault("THANK_YOU_TEMPLATES", ""),
+		HTTPAddress:             getEnvOrDefault("HTTP_ADDRESS", "localhost:8080"),
 	}, nil
 }
 
@@ -59,3 +79,12 @@ func getEnv(name string) string {
 
 	return env
 }
+
+func getEnvOrDefault(name, defaultValue string) string {
+	env := os.Getenv(name)
+	if len(env) == 0 {
+		return defaultValue
+	}
+
+	return env
+}
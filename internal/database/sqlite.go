@@ -0,0 +1,29 @@
+package database
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"time"
+)
+
+// RequestTimeout is the maximum time of a single database request.
+const RequestTimeout = 3 * time.Second
+
+// Open establishes a connection to SQLite, which is shared by all storages of the chatbot.
+func Open(ctx context.Context, dataSourceName, username, password string) (*sql.DB, error) {
+	db, err := sql.Open("sqlite3", fmt.Sprintf("%s?_auth&_auth_user=%s&_auth_pass=%s&_auth_crypt=SHA384&_foreign_keys=on", dataSourceName, username, password))
+	if err != nil {
+		return nil, err
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, RequestTimeout)
+	defer cancel()
+
+	err = db.PingContext(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	return db, nil
+}
@@ -0,0 +1,78 @@
+package event
+
+import (
+	"context"
+	"fmt"
+	"sync"
+
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/metric"
+	"go.uber.org/zap"
+)
+
+var meter = otel.Meter("github.com/danielbukowski/twitch-chatbot/internal/event")
+
+// Handler reacts to an event. Handlers are called synchronously, so the long-running ones should start a goroutine.
+type Handler func(ctx context.Context, event Event)
+
+// Bus delivers events to handlers subscribed to their types.
+type Bus struct {
+	handlers     map[Type][]Handler  // Handlers holds handlers of every type of event.
+	mu           sync.RWMutex        // Mu guards handlers.
+	logger       *zap.Logger         // Logger is used for logging.
+	eventCounter metric.Int64Counter // EventCounter counts published events.
+}
+
+// NewBus creates an instance of Bus without any handlers.
+func NewBus(logger *zap.Logger) (*Bus, error) {
+	eventCounter, err := meter.Int64Counter(
+		"event.counter",
+		metric.WithDescription("Number of events on the channel, like subscriptions and raids."),
+		metric.WithUnit("{event}"),
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	return &Bus{
+		handlers:     make(map[Type][]Handler),
+		logger:       logger.Named("event_bus"),
+		eventCounter: eventCounter,
+	}, nil
+}
+
+// Subscribe adds a handler of events of the given type.
+func (b *Bus) Subscribe(eventType Type, handler Handler) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	b.handlers[eventType] = append(b.handlers[eventType], handler)
+}
+
+// Publish calls every handler subscribed to the type of the event. A panic of a handler does not stop the other ones.
+func (b *Bus) Publish(ctx context.Context, event Event) {
+	b.mu.RLock()
+	handlers := b.handlers[event.Type()]
+	b.mu.RUnlock()
+
+	b.eventCounter.Add(ctx, 1, metric.WithAttributes(
+		attribute.String("event.type", string(event.Type())),
+		attribute.String("channel.name", event.Channel()),
+	))
+
+	for _, handler := range handlers {
+		b.call(ctx, handler, event)
+	}
+}
+
+// call calls a handler and recovers from its panic.
+func (b *Bus) call(ctx context.Context, handler Handler, event Event) {
+	defer func() {
+		if r := recover(); r != nil {
+			b.logger.Error("event handler panicked", zap.String("event_type", string(event.Type())), zap.String("panic", fmt.Sprint(r)))
+		}
+	}()
+
+	handler(ctx, event)
+}
@@ -0,0 +1,107 @@
+package event
+
+import "github.com/nicklaw5/helix/v2"
+
+const (
+	TypeFollow          Type = "follow"           // TypeFollow is a new follower.
+	TypeRedemption      Type = "redemption"       // TypeRedemption is a redemption of a channel points reward.
+	TypePollBegin       Type = "poll.begin"       // TypePollBegin is a start of a poll.
+	TypePollEnd         Type = "poll.end"         // TypePollEnd is an end of a poll.
+	TypePredictionBegin Type = "prediction.begin" // TypePredictionBegin is a start of a prediction.
+	TypePredictionLock  Type = "prediction.lock"  // TypePredictionLock is a prediction that stopped accepting votes.
+	TypePredictionEnd   Type = "prediction.end"   // TypePredictionEnd is a resolved or cancelled prediction.
+	TypeHypeTrainBegin  Type = "hype_train.begin" // TypeHypeTrainBegin is a start of a hype train.
+	TypeHypeTrainEnd    Type = "hype_train.end"   // TypeHypeTrainEnd is an end of a hype train.
+	TypeStreamOnline    Type = "stream.online"    // TypeStreamOnline is a start of a stream.
+	TypeStreamOffline   Type = "stream.offline"   // TypeStreamOffline is an end of a stream.
+)
+
+// The events below are delivered by EventSub, their Data holds the original payload of Twitch.
+
+// Follow is a new follower.
+type Follow struct {
+	Base
+	Data helix.EventSubChannelFollowEvent
+}
+
+func (Follow) Type() Type { return TypeFollow }
+
+// Redemption is a redemption of a channel points reward.
+type Redemption struct {
+	Base
+	Data helix.EventSubChannelPointsCustomRewardRedemptionEvent
+}
+
+func (Redemption) Type() Type { return TypeRedemption }
+
+// PollBegin is a start of a poll.
+type PollBegin struct {
+	Base
+	Data helix.EventSubChannelPollBeginEvent
+}
+
+func (PollBegin) Type() Type { return TypePollBegin }
+
+// PollEnd is an end of a poll.
+type PollEnd struct {
+	Base
+	Data helix.EventSubChannelPollEndEvent
+}
+
+func (PollEnd) Type() Type { return TypePollEnd }
+
+// PredictionBegin is a start of a prediction.
+type PredictionBegin struct {
+	Base
+	Data helix.EventSubChannelPredictionBeginEvent
+}
+
+func (PredictionBegin) Type() Type { return TypePredictionBegin }
+
+// PredictionLock is a prediction that stopped accepting votes.
+type PredictionLock struct {
+	Base
+	Data helix.EventSubChannelPredictionLockEvent
+}
+
+func (PredictionLock) Type() Type { return TypePredictionLock }
+
+// PredictionEnd is a resolved or cancelled prediction.
+type PredictionEnd struct {
+	Base
+	Data helix.EventSubChannelPredictionEndEvent
+}
+
+func (PredictionEnd) Type() Type { return TypePredictionEnd }
+
+// HypeTrainBegin is a start of a hype train.
+type HypeTrainBegin struct {
+	Base
+	Data helix.EventSubHypeTrainBeginEvent
+}
+
+func (HypeTrainBegin) Type() Type { return TypeHypeTrainBegin }
+
+// HypeTrainEnd is an end of a hype train.
+type HypeTrainEnd struct {
+	Base
+	Data helix.EventSubHypeTrainEndEvent
+}
+
+func (HypeTrainEnd) Type() Type { return TypeHypeTrainEnd }
+
+// StreamOnline is a start of a stream.
+type StreamOnline struct {
+	Base
+	Data helix.EventSubStreamOnlineEvent
+}
+
+func (StreamOnline) Type() Type { return TypeStreamOnline }
+
+// StreamOffline is an end of a stream.
+type StreamOffline struct {
+	Base
+	Data helix.EventSubStreamOfflineEvent
+}
+
+func (StreamOffline) Type() Type { return TypeStreamOffline }
@@ -0,0 +1,158 @@
+package event
+
+import (
+	"strconv"
+	"time"
+
+	"github.com/gempir/go-twitch-irc/v4"
+)
+
+// Type identifies a kind of event.
+type Type string
+
+const (
+	TypeSub          Type = "sub"          // TypeSub is a new subscription.
+	TypeResub        Type = "resub"        // TypeResub is a renewed subscription shared on the chat.
+	TypeSubGift      Type = "subgift"      // TypeSubGift is a subscription gifted to a single user.
+	TypeGiftBomb     Type = "giftbomb"     // TypeGiftBomb is a batch of subscriptions gifted to random users at once.
+	TypeRaid         Type = "raid"         // TypeRaid is a raid from another channel.
+	TypeAnnouncement Type = "announcement" // TypeAnnouncement is an announcement of a moderator.
+)
+
+// Event is something that happened on a channel.
+type Event interface {
+	Type() Type
+	Channel() string
+}
+
+// Base holds fields shared by all events.
+type Base struct {
+	ChannelName string      // ChannelName is a name of the channel, where the event happened.
+	RoomID      string      // RoomID is an ID of the channel.
+	User        twitch.User // User is a user who caused the event, like a subscriber, a gifter or a raider.
+	Time        time.Time   // Time is when the event happened.
+}
+
+// Channel returns a name of the channel, where the event happened.
+func (b Base) Channel() string {
+	return b.ChannelName
+}
+
+// Sub is a new subscription.
+type Sub struct {
+	Base
+	Plan string // Plan is a name of the subscription plan, like `Tier 1` or `Prime`.
+}
+
+func (Sub) Type() Type { return TypeSub }
+
+// Resub is a renewed subscription shared on the chat.
+type Resub struct {
+	Base
+	Plan             string // Plan is a name of the subscription plan.
+	CumulativeMonths int    // CumulativeMonths is a total number of months the user has subscribed.
+	StreakMonths     int    // StreakMonths is a number of consecutive months, it's zero when the user does not share it.
+	Message          string // Message is a message shared with the resubscription.
+}
+
+func (Resub) Type() Type { return TypeResub }
+
+// SubGift is a subscription gifted to a single user.
+type SubGift struct {
+	Base
+	Plan            string // Plan is a name of the subscription plan.
+	RecipientName   string // RecipientName is a display name of the user who got the subscription.
+	RecipientLogin  string // RecipientLogin is a login of the user who got the subscription.
+	communityGiftID string // communityGiftID links the gift with a gift bomb it belongs to.
+}
+
+func (SubGift) Type() Type { return TypeSubGift }
+
+// GiftBomb is a batch of subscriptions gifted to random users at once.
+type GiftBomb struct {
+	Base
+	Plan       string   // Plan is a name of the subscription plan.
+	Count      int      // Count is a number of gifted subscriptions.
+	TotalGifts int      // TotalGifts is a number of all subscriptions gifted by the user on the channel, it's zero when not shared.
+	Recipients []string // Recipients holds display names of users who got the subscriptions.
+	id         string   // id links the gift bomb with its single gifts.
+}
+
+func (GiftBomb) Type() Type { return TypeGiftBomb }
+
+// Raid is a raid from another channel.
+type Raid struct {
+	Base
+	Viewers int // Viewers is a number of raiding viewers.
+}
+
+func (Raid) Type() Type { return TypeRaid }
+
+// Announcement is an announcement of a moderator.
+type Announcement struct {
+	Base
+	Color   string // Color is a color of the announcement, like `PRIMARY` or `BLUE`.
+	Message string // Message is a text of the announcement.
+}
+
+func (Announcement) Type() Type { return TypeAnnouncement }
+
+// Parse turns a USERNOTICE message into a typed event. It returns false for notices that are not supported.
+func Parse(msg twitch.UserNoticeMessage) (Event, bool) {
+	base := Base{ChannelName: msg.Channel, RoomID: msg.RoomID, User: msg.User, Time: msg.Time}
+
+	switch msg.MsgID {
+	case "sub":
+		return Sub{Base: base, Plan: planName(msg.MsgParams["msg-param-sub-plan"])}, true
+	case "resub":
+		return Resub{
+			Base:             base,
+			Plan:             planName(msg.MsgParams["msg-param-sub-plan"]),
+			CumulativeMonths: intParam(msg, "msg-param-cumulative-months"),
+			StreakMonths:     intParam(msg, "msg-param-streak-months"),
+			Message:          msg.Message,
+		}, true
+	case "subgift":
+		return SubGift{
+			Base:            base,
+			Plan:            planName(msg.MsgParams["msg-param-sub-plan"]),
+			RecipientName:   msg.MsgParams["msg-param-recipient-display-name"],
+			RecipientLogin:  msg.MsgParams["msg-param-recipient-user-name"],
+			communityGiftID: msg.MsgParams["msg-param-community-gift-id"],
+		}, true
+	case "submysterygift":
+		return GiftBomb{
+			Base:       base,
+			Plan:       planName(msg.MsgParams["msg-param-sub-plan"]),
+			Count:      intParam(msg, "msg-param-mass-gift-count"),
+			TotalGifts: intParam(msg, "msg-param-sender-count"),
+			id:         msg.MsgParams["msg-param-community-gift-id"],
+		}, true
+	case "raid":
+		return Raid{Base: base, Viewers: intParam(msg, "msg-param-viewerCount")}, true
+	case "announcement":
+		return Announcement{Base: base, Color: msg.MsgParams["msg-param-color"], Message: msg.Message}, true
+	default:
+		return nil, false
+	}
+}
+
+// planName returns a readable name of a subscription plan.
+func planName(plan string) string {
+	switch plan {
+	case "Prime":
+		return "Prime"
+	case "2000":
+		return "Tier 2"
+	case "3000":
+		return "Tier 3"
+	default:
+		return "Tier 1"
+	}
+}
+
+// intParam returns a numeric parameter of a notice, or zero when it's missing.
+func intParam(msg twitch.UserNoticeMessage, name string) int {
+	v, _ := strconv.Atoi(msg.MsgParams[name])
+	return v
+}
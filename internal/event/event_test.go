@@ -0,0 +1,202 @@
+package event
+
+import (
+	"context"
+	"maps"
+	"testing"
+	"time"
+
+	"github.com/gempir/go-twitch-irc/v4"
+	"go.uber.org/zap"
+)
+
+func TestParse(t *testing.T) {
+	user := twitch.User{Name: "viewer", DisplayName: "Viewer"}
+
+	testCases := []struct {
+		name     string
+		msg      twitch.UserNoticeMessage
+		expected Event
+	}{
+		{
+			name: "parses a resub",
+			msg: twitch.UserNoticeMessage{MsgID: "resub", Channel: "channel", User: user, Message: "hi", MsgParams: map[string]string{
+				"msg-param-sub-plan":          "2000",
+				"msg-param-cumulative-months": "12",
+				"msg-param-streak-months":     "3",
+			}},
+			expected: Resub{Base: Base{ChannelName: "channel", User: user}, Plan: "Tier 2", CumulativeMonths: 12, StreakMonths: 3, Message: "hi"},
+		},
+		{
+			name: "parses a gift bomb",
+			msg: twitch.UserNoticeMessage{MsgID: "submysterygift", Channel: "channel", User: user, MsgParams: map[string]string{
+				"msg-param-sub-plan":          "1000",
+				"msg-param-mass-gift-count":   "5",
+				"msg-param-community-gift-id": "123",
+			}},
+			expected: GiftBomb{Base: Base{ChannelName: "channel", User: user}, Plan: "Tier 1", Count: 5, id: "123"},
+		},
+		{
+			name:     "parses a raid",
+			msg:      twitch.UserNoticeMessage{MsgID: "raid", Channel: "channel", User: user, MsgParams: map[string]string{"msg-param-viewerCount": "42"}},
+			expected: Raid{Base: Base{ChannelName: "channel", User: user}, Viewers: 42},
+		},
+		{
+			name: "ignores unsupported notices",
+			msg:  twitch.UserNoticeMessage{MsgID: "bitsbadgetier", Channel: "channel", User: user},
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			// when
+			got, ok := Parse(tc.msg)
+
+			// then
+			if ok != (tc.expected != nil) {
+				t.Fatalf("Expected the notice to be supported `%v`, got `%v`", tc.expected != nil, ok)
+			}
+			if ok && got.Type() != tc.expected.Type() {
+				t.Fatalf("Expected an event of type `%s`, got `%s`", tc.expected.Type(), got.Type())
+			}
+			if ok && !equalEvents(got, tc.expected) {
+				t.Errorf("Expected `%+v`, got `%+v`", tc.expected, got)
+			}
+		})
+	}
+}
+
+func equalEvents(a, b Event) bool {
+	switch a := a.(type) {
+	case Resub:
+		return a.Plan == b.(Resub).Plan && a.CumulativeMonths == b.(Resub).CumulativeMonths && a.StreakMonths == b.(Resub).StreakMonths && a.Message == b.(Resub).Message
+	case GiftBomb:
+		return a.Plan == b.(GiftBomb).Plan && a.Count == b.(GiftBomb).Count && a.id == b.(GiftBomb).id
+	case Raid:
+		return a.Viewers == b.(Raid).Viewers
+	}
+	return false
+}
+
+type chatClientMock struct {
+	messages []string
+}
+
+func (c *chatClientMock) Say(_, message string) {
+	c.messages = append(c.messages, message)
+}
+
+func TestThanker(t *testing.T) {
+	t.Run("renders a thank-you message of the event", func(t *testing.T) {
+		// given
+		chatClient := &chatClientMock{}
+		thanker, err := NewThanker(DefaultTemplates, chatClient, 3, time.Minute, zap.NewNop())
+		if err != nil {
+			t.Fatalf("Expected no error, got `%v`", err)
+		}
+
+		// when
+		thanker.Thank(context.Background(), Raid{Base: Base{ChannelName: "channel", User: twitch.User{DisplayName: "Raider"}}, Viewers: 42})
+
+		// then
+		expected := "Thank you @Raider for the raid with 42 viewers, welcome everyone!"
+		if len(chatClient.messages) != 1 || chatClient.messages[0] != expected {
+			t.Errorf("Expected `%s`, got `%v`", expected, chatClient.messages)
+		}
+	})
+
+	t.Run("throttles messages within the window", func(t *testing.T) {
+		// given
+		chatClient := &chatClientMock{}
+		thanker, err := NewThanker(DefaultTemplates, chatClient, 2, time.Minute, zap.NewNop())
+		if err != nil {
+			t.Fatalf("Expected no error, got `%v`", err)
+		}
+		now := time.Date(2026, 10, 19, 12, 0, 0, 0, time.UTC)
+		thanker.now = func() time.Time { return now }
+		gift := SubGift{Base: Base{ChannelName: "channel", User: twitch.User{DisplayName: "Gifter"}}, RecipientName: "Lucky"}
+
+		// when
+		for range 5 {
+			thanker.Thank(context.Background(), gift)
+		}
+		now = now.Add(time.Minute)
+		thanker.Thank(context.Background(), gift)
+
+		// then
+		if len(chatClient.messages) != 3 {
+			t.Errorf("Expected 3 messages, got %d", len(chatClient.messages))
+		}
+	})
+
+	t.Run("returns an error for an invalid template", func(t *testing.T) {
+		// when
+		_, err := NewThanker(map[Type]string{TypeSub: "{{.User"}, &chatClientMock{}, 1, time.Minute, zap.NewNop())
+
+		// then
+		if err == nil {
+			t.Errorf("Expected an error, got nil")
+		}
+	})
+}
+
+func TestParseTemplates(t *testing.T) {
+	testCases := []struct {
+		name        string
+		text        string
+		expected    map[Type]string
+		expectedErr bool
+	}{
+		{
+			name:     "falls back to the default templates",
+			text:     "",
+			expected: DefaultTemplates,
+		},
+		{
+			name: "overrides a template of a type",
+			text: "sub=Welcome @{{.User.DisplayName}}!",
+			expected: map[Type]string{
+				TypeSub:      "Welcome @{{.User.DisplayName}}!",
+				TypeResub:    DefaultTemplates[TypeResub],
+				TypeSubGift:  DefaultTemplates[TypeSubGift],
+				TypeGiftBomb: DefaultTemplates[TypeGiftBomb],
+				TypeRaid:     DefaultTemplates[TypeRaid],
+			},
+		},
+		{
+			name: "turns off thanks for a type with an empty template",
+			text: "RAID=; giftbomb = Thanks for {{.Count}} subs!",
+			expected: map[Type]string{
+				TypeSub:      DefaultTemplates[TypeSub],
+				TypeResub:    DefaultTemplates[TypeResub],
+				TypeSubGift:  DefaultTemplates[TypeSubGift],
+				TypeGiftBomb: "Thanks for {{.Count}} subs!",
+			},
+		},
+		{
+			name:        "rejects an unknown type",
+			text:        "follow=Thanks!",
+			expectedErr: true,
+		},
+		{
+			name:        "rejects a template without a type",
+			text:        "Thanks!",
+			expectedErr: true,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			// when
+			got, err := ParseTemplates(tc.text)
+
+			// then
+			if (err != nil) != tc.expectedErr {
+				t.Fatalf("Expected error `%v`, got `%v`", tc.expectedErr, err)
+			}
+			if !tc.expectedErr && !maps.Equal(got, tc.expected) {
+				t.Errorf("Expected `%v`, got `%v`", tc.expected, got)
+			}
+		})
+	}
+}
@@ -0,0 +1,144 @@
+package event
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"maps"
+	"strings"
+	"sync"
+	"text/template"
+	"time"
+
+	"go.uber.org/zap"
+)
+
+// DefaultTemplates holds thank-you messages for every type of event. Templates get the event as data.
+var DefaultTemplates = map[Type]string{
+	TypeSub:      "Thank you @{{.User.DisplayName}} for the {{.Plan}} sub!",
+	TypeResub:    "Thank you @{{.User.DisplayName}} for {{.CumulativeMonths}} months of support!",
+	TypeSubGift:  "Thank you @{{.User.DisplayName}} for gifting a sub to @{{.RecipientName}}!",
+	TypeGiftBomb: "Thank you @{{.User.DisplayName}} for gifting {{.Count}} subs to the community!",
+	TypeRaid:     "Thank you @{{.User.DisplayName}} for the raid with {{.Viewers}} viewers, welcome everyone!",
+}
+
+// ParseTemplates creates thank-you templates from a semicolon separated list, like `sub=Welcome @{{.User.DisplayName}}!;raid=`.
+// Types missing from the list keep their default template, and an empty template turns off thanks for its type.
+func ParseTemplates(text string) (map[Type]string, error) {
+	templates := maps.Clone(DefaultTemplates)
+
+	for _, definition := range strings.Split(text, ";") {
+		definition = strings.TrimSpace(definition)
+		if definition == "" {
+			continue
+		}
+
+		name, tmpl, ok := strings.Cut(definition, "=")
+		eventType := Type(strings.ToLower(strings.TrimSpace(name)))
+		if !ok || eventType == "" {
+			return nil, fmt.Errorf("missing a type of event in template '%s'", definition)
+		}
+		if _, ok := DefaultTemplates[eventType]; !ok {
+			return nil, fmt.Errorf("unknown type of event in template '%s'", definition)
+		}
+
+		tmpl = strings.TrimSpace(tmpl)
+		if tmpl == "" {
+			delete(templates, eventType)
+			continue
+		}
+		templates[eventType] = tmpl
+	}
+
+	return templates, nil
+}
+
+// chatClient sends messages to the chat.
+type chatClient interface {
+	Say(channelName, message string)
+}
+
+// Thanker sends thank-you messages for events. Messages are throttled per channel,
+// so a burst of events, like many single gifts, does not flood the chat.
+type Thanker struct {
+	templates   map[Type]*template.Template // Templates holds a template of a message for every type of event.
+	chatClient  chatClient                  // ChatClient is used to send messages.
+	maxMessages int                         // MaxMessages is the maximum number of messages on a channel within the window.
+	window      time.Duration               // Window is a period of time, in which messages are counted.
+	sent        map[string][]time.Time      // Sent holds times of messages recently sent on every channel.
+	mu          sync.Mutex                  // Mu guards sent.
+	now         func() time.Time            // Now returns the current time.
+	logger      *zap.Logger                 // Logger is used for logging.
+}
+
+// NewThanker creates an instance of Thanker. It returns an error, when any of the templates is invalid.
+func NewThanker(templates map[Type]string, chatClient chatClient, maxMessages int, window time.Duration, logger *zap.Logger) (*Thanker, error) {
+	parsed := make(map[Type]*template.Template, len(templates))
+	for eventType, text := range templates {
+		tmpl, err := template.New(string(eventType)).Option("missingkey=error").Parse(text)
+		if err != nil {
+			return nil, errors.Join(fmt.Errorf("failed to parse a template of %s event", eventType), err)
+		}
+		parsed[eventType] = tmpl
+	}
+
+	return &Thanker{
+		templates:   parsed,
+		chatClient:  chatClient,
+		maxMessages: maxMessages,
+		window:      window,
+		sent:        make(map[string][]time.Time),
+		now:         time.Now,
+		logger:      logger.Named("thanker"),
+	}, nil
+}
+
+// Subscribe subscribes the thanker to every type of event with a template.
+func (t *Thanker) Subscribe(bus *Bus) {
+	for eventType := range t.templates {
+		bus.Subscribe(eventType, t.Thank)
+	}
+}
+
+// Thank sends a thank-you message for an event, unless the channel reached its limit of messages.
+func (t *Thanker) Thank(_ context.Context, event Event) {
+	tmpl, ok := t.templates[event.Type()]
+	if !ok {
+		return
+	}
+
+	var b strings.Builder
+	if err := tmpl.Execute(&b, event); err != nil {
+		t.logger.Error("failed to render a thank-you message", zap.String("event_type", string(event.Type())), zap.Error(err))
+		return
+	}
+
+	if !t.allow(event.Channel()) {
+		t.logger.Info("skipped a thank-you message, because of throttling", zap.String("event_type", string(event.Type())))
+		return
+	}
+
+	t.chatClient.Say(event.Channel(), b.String())
+}
+
+// allow reports whether a message can be sent on the channel now, and if so, it records the message.
+func (t *Thanker) allow(channelName string) bool {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	now := t.now()
+	recent := t.sent[channelName][:0]
+	for _, sentAt := range t.sent[channelName] {
+		if now.Sub(sentAt) < t.window {
+			recent = append(recent, sentAt)
+		}
+	}
+
+	if len(recent) >= t.maxMessages {
+		t.sent[channelName] = recent
+		return false
+	}
+
+	t.sent[channelName] = append(recent, now)
+	return true
+}
@@ -0,0 +1,112 @@
+package event
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"github.com/gempir/go-twitch-irc/v4"
+)
+
+// pendingBomb is a gift bomb waiting for its single gifts.
+type pendingBomb struct {
+	bomb  GiftBomb
+	timer *time.Timer
+}
+
+// UserNotices turns USERNOTICE messages into events and publishes them on a bus.
+// Single gifts of a gift bomb are not published, they are collected as recipients of the gift bomb instead,
+// which is published once all of them arrived, or after a wait time.
+type UserNotices struct {
+	bus      *Bus                    // Bus delivers events to handlers.
+	bombWait time.Duration           // BombWait is the maximum time of waiting for single gifts of a gift bomb.
+	bombs    map[string]*pendingBomb // Bombs holds gift bombs waiting for their single gifts.
+	mu       sync.Mutex              // Mu guards bombs.
+}
+
+// NewUserNotices creates an instance of UserNotices.
+func NewUserNotices(bus *Bus, bombWait time.Duration) *UserNotices {
+	return &UserNotices{bus: bus, bombWait: bombWait, bombs: make(map[string]*pendingBomb)}
+}
+
+// Handle parses a USERNOTICE message and publishes its event.
+func (u *UserNotices) Handle(ctx context.Context, msg twitch.UserNoticeMessage) {
+	event, ok := Parse(msg)
+	if !ok {
+		return
+	}
+
+	switch e := event.(type) {
+	case GiftBomb:
+		u.startBomb(ctx, e)
+		return
+	case SubGift:
+		if u.addToBomb(ctx, e) {
+			return
+		}
+	}
+
+	u.bus.Publish(ctx, event)
+}
+
+// startBomb waits for single gifts of a gift bomb.
+func (u *UserNotices) startBomb(ctx context.Context, bomb GiftBomb) {
+	u.mu.Lock()
+	defer u.mu.Unlock()
+
+	key := bombKey(bomb.ChannelName, bomb.id, bomb.User.Name)
+	if previous, ok := u.bombs[key]; ok {
+		previous.timer.Stop()
+		go u.bus.Publish(context.WithoutCancel(ctx), previous.bomb)
+	}
+
+	publishCtx := context.WithoutCancel(ctx)
+	u.bombs[key] = &pendingBomb{
+		bomb:  bomb,
+		timer: time.AfterFunc(u.bombWait, func() { u.flush(publishCtx, key) }),
+	}
+}
+
+// addToBomb adds a single gift to its gift bomb. It returns false, when the gift is not a part of any gift bomb.
+func (u *UserNotices) addToBomb(ctx context.Context, gift SubGift) bool {
+	u.mu.Lock()
+
+	key := bombKey(gift.ChannelName, gift.communityGiftID, gift.User.Name)
+	pending, ok := u.bombs[key]
+	if !ok {
+		u.mu.Unlock()
+		return false
+	}
+
+	pending.bomb.Recipients = append(pending.bomb.Recipients, gift.RecipientName)
+	complete := len(pending.bomb.Recipients) >= pending.bomb.Count
+	u.mu.Unlock()
+
+	if complete {
+		pending.timer.Stop()
+		u.flush(ctx, key)
+	}
+
+	return true
+}
+
+// flush publishes a gift bomb and stops waiting for its single gifts.
+func (u *UserNotices) flush(ctx context.Context, key string) {
+	u.mu.Lock()
+	pending, ok := u.bombs[key]
+	delete(u.bombs, key)
+	u.mu.Unlock()
+
+	if ok {
+		u.bus.Publish(ctx, pending.bomb)
+	}
+}
+
+// bombKey identifies a gift bomb by its ID, or by its gifter, when Twitch did not send the ID.
+func bombKey(channelName, id, gifterLogin string) string {
+	if len(id) != 0 {
+		return channelName + ":" + id
+	}
+
+	return channelName + ":gifter:" + gifterLogin
+}
@@ -0,0 +1,116 @@
+package event
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/gempir/go-twitch-irc/v4"
+	"go.uber.org/zap"
+)
+
+type recorder struct {
+	mu     sync.Mutex
+	events []Event
+	done   chan struct{}
+}
+
+func (r *recorder) handle(_ context.Context, event Event) {
+	r.mu.Lock()
+	r.events = append(r.events, event)
+	r.mu.Unlock()
+	if event.Type() == TypeGiftBomb {
+		r.done <- struct{}{}
+	}
+}
+
+func newRecordedNotices(t *testing.T, bombWait time.Duration) (*UserNotices, *recorder) {
+	t.Helper()
+
+	bus, err := NewBus(zap.NewNop())
+	if err != nil {
+		t.Fatalf("Expected no error, got `%v`", err)
+	}
+
+	r := &recorder{done: make(chan struct{}, 1)}
+	for _, eventType := range []Type{TypeSubGift, TypeGiftBomb} {
+		bus.Subscribe(eventType, r.handle)
+	}
+
+	return NewUserNotices(bus, bombWait), r
+}
+
+func giftNotices(count int, id string) []twitch.UserNoticeMessage {
+	gifter := twitch.User{Name: "gifter", DisplayName: "Gifter"}
+	notices := []twitch.UserNoticeMessage{{MsgID: "submysterygift", Channel: "channel", User: gifter, MsgParams: map[string]string{
+		"msg-param-mass-gift-count":   fmt.Sprint(count),
+		"msg-param-community-gift-id": id,
+	}}}
+
+	for i := range count {
+		notices = append(notices, twitch.UserNoticeMessage{MsgID: "subgift", Channel: "channel", User: gifter, MsgParams: map[string]string{
+			"msg-param-recipient-display-name": fmt.Sprintf("Lucky%d", i),
+			"msg-param-community-gift-id":      id,
+		}})
+	}
+
+	return notices
+}
+
+func TestUserNotices(t *testing.T) {
+	t.Run("aggregates single gifts into a gift bomb", func(t *testing.T) {
+		// given
+		notices, r := newRecordedNotices(t, time.Minute)
+
+		// when
+		for _, msg := range giftNotices(3, "123") {
+			notices.Handle(context.Background(), msg)
+		}
+
+		// then
+		<-r.done
+		if len(r.events) != 1 {
+			t.Fatalf("Expected only the gift bomb to be published, got `%+v`", r.events)
+		}
+		if bomb := r.events[0].(GiftBomb); bomb.Count != 3 || len(bomb.Recipients) != 3 {
+			t.Errorf("Expected a gift bomb of 3 subs with all recipients, got `%+v`", bomb)
+		}
+	})
+
+	t.Run("publishes an incomplete gift bomb after the wait time", func(t *testing.T) {
+		// given
+		notices, r := newRecordedNotices(t, 10*time.Millisecond)
+
+		// when
+		for _, msg := range giftNotices(5, "456")[:3] {
+			notices.Handle(context.Background(), msg)
+		}
+
+		// then
+		select {
+		case <-r.done:
+		case <-time.After(time.Second):
+			t.Fatalf("Expected the gift bomb to be published")
+		}
+		r.mu.Lock()
+		defer r.mu.Unlock()
+		if bomb := r.events[0].(GiftBomb); len(bomb.Recipients) != 2 {
+			t.Errorf("Expected a gift bomb with 2 recipients, got `%+v`", bomb)
+		}
+	})
+
+	t.Run("publishes a gift outside of a gift bomb", func(t *testing.T) {
+		// given
+		notices, r := newRecordedNotices(t, time.Minute)
+
+		// when
+		notices.Handle(context.Background(), giftNotices(1, "")[1])
+
+		// then
+		if len(r.events) != 1 || r.events[0].Type() != TypeSubGift {
+			t.Errorf("Expected a single gift, got `%+v`", r.events)
+		}
+	})
+}
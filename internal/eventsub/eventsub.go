@@ -0,0 +1,344 @@
+package eventsub
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"sync"
+	"time"
+
+	"github.com/danielbukowski/twitch-chatbot/internal/event"
+	twitchapi "github.com/danielbukowski/twitch-chatbot/internal/twitch_api"
+	"github.com/nicklaw5/helix/v2"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.uber.org/zap"
+	"golang.org/x/net/websocket"
+)
+
+var tracer = otel.Tracer("github.com/danielbukowski/twitch-chatbot/internal/eventsub")
+
+// DefaultURL is an address of the EventSub WebSocket server of Twitch.
+const DefaultURL = "wss://eventsub.wss.twitch.tv/ws"
+
+const (
+	welcomeTimeout   = 10 * time.Second // welcomeTimeout is the maximum time of waiting for a welcome message.
+	keepaliveMargin  = 5 * time.Second  // keepaliveMargin is added to the keepalive timeout, so a slow network does not cause reconnects.
+	minBackoff       = time.Second      // minBackoff is the first wait time before reconnecting.
+	maxBackoff       = time.Minute      // maxBackoff is the longest wait time before reconnecting.
+	deduplicationTTL = 10 * time.Minute // deduplicationTTL is how long IDs of messages are remembered to drop the redelivered ones.
+)
+
+var errKeepaliveTimeout = errors.New("eventsub server did not send any message within the keepalive timeout")
+
+// Subscription is a type of events the client subscribes to.
+type Subscription struct {
+	Type      string                  // Type is a type of the subscription, like `channel.follow`.
+	Version   string                  // Version is a version of the subscription type.
+	Condition helix.EventSubCondition // Condition limits the subscription, e.g. to a single channel.
+}
+
+// ModeratorSubscriptions returns subscriptions of events of a channel, which are not delivered by IRC
+// and are available with a token of a moderator.
+func ModeratorSubscriptions(broadcasterID, moderatorID string) []Subscription {
+	channel := helix.EventSubCondition{BroadcasterUserID: broadcasterID}
+
+	return []Subscription{
+		{Type: helix.EventSubTypeChannelFollow, Version: "2", Condition: helix.EventSubCondition{BroadcasterUserID: broadcasterID, ModeratorUserID: moderatorID}},
+		{Type: helix.EventSubTypeStreamOnline, Version: "1", Condition: channel},
+		{Type: helix.EventSubTypeStreamOffline, Version: "1", Condition: channel},
+	}
+}
+
+// BroadcasterSubscriptions returns subscriptions of events of a channel, which require a token of the broadcaster,
+// like channel points, polls, predictions and hype trains.
+func BroadcasterSubscriptions(broadcasterID string) []Subscription {
+	channel := helix.EventSubCondition{BroadcasterUserID: broadcasterID}
+
+	return []Subscription{
+		{Type: helix.EventSubTypeChannelPointsCustomRewardRedemptionAdd, Version: "1", Condition: channel},
+		{Type: helix.EventSubTypeChannelPollBegin, Version: "1", Condition: channel},
+		{Type: helix.EventSubTypeChannelPollEnd, Version: "1", Condition: channel},
+		{Type: helix.EventSubTypeChannelPredictionBegin, Version: "1", Condition: channel},
+		{Type: helix.EventSubTypeChannelPredictionLock, Version: "1", Condition: channel},
+		{Type: helix.EventSubTypeChannelPredictionEnd, Version: "1", Condition: channel},
+		{Type: helix.EventSubTypeHypeTrainBegin, Version: "1", Condition: channel},
+		{Type: helix.EventSubTypeHypeTrainEnd, Version: "1", Condition: channel},
+	}
+}
+
+// message is a message sent by the EventSub server.
+type message struct {
+	Metadata struct {
+		MessageID        string    `json:"message_id"`
+		MessageType      string    `json:"message_type"`
+		MessageTimestamp time.Time `json:"message_timestamp"`
+		SubscriptionType string    `json:"subscription_type"`
+	} `json:"metadata"`
+	Payload struct {
+		Session      session                    `json:"session"`
+		Subscription helix.EventSubSubscription `json:"subscription"`
+		Event        json.RawMessage            `json:"event"`
+	} `json:"payload"`
+}
+
+// session describes a connection to the EventSub server.
+type session struct {
+	ID                      string `json:"id"`
+	KeepaliveTimeoutSeconds int    `json:"keepalive_timeout_seconds"`
+	ReconnectURL            string `json:"reconnect_url"`
+}
+
+// publisher delivers events to their handlers.
+type publisher interface {
+	Publish(ctx context.Context, event event.Event)
+}
+
+// Client receives events of channels from EventSub over WebSocket and publishes them on an event bus.
+type Client struct {
+	url           string               // URL is an address of the EventSub server.
+	helixClient   *helix.Client        // HelixClient is used to create subscriptions.
+	subscriptions []Subscription       // Subscriptions holds types of events created for every new session.
+	publisher     publisher            // Publisher delivers received events.
+	seen          map[string]time.Time // Seen holds IDs of recently received messages.
+	logger        *zap.Logger          // Logger is used for logging.
+}
+
+// NewClient creates an instance of Client.
+func NewClient(url string, helixClient *helix.Client, subscriptions []Subscription, publisher publisher, logger *zap.Logger) *Client {
+	return &Client{
+		url:           url,
+		helixClient:   helixClient,
+		subscriptions: subscriptions,
+		publisher:     publisher,
+		seen:          make(map[string]time.Time),
+		logger:        logger.Named("eventsub"),
+	}
+}
+
+// Run receives events until the context is done. A lost connection is opened again with an exponential backoff,
+// and subscriptions are created again for the new session.
+func (c *Client) Run(ctx context.Context) error {
+	backoff := minBackoff
+
+	for {
+		welcomed, err := c.runSession(ctx)
+		if ctx.Err() != nil {
+			return nil
+		}
+		if welcomed {
+			backoff = minBackoff
+		}
+
+		c.logger.Warn("lost the connection to eventsub, reconnecting", zap.Duration("backoff", backoff), zap.Error(err))
+
+		select {
+		case <-ctx.Done():
+			return nil
+		case <-time.After(backoff):
+		}
+
+		backoff = min(backoff*2, maxBackoff)
+	}
+}
+
+// runSession opens a connection, creates subscriptions and handles messages until the connection is lost.
+// It returns true, when the server welcomed the client.
+func (c *Client) runSession(ctx context.Context) (bool, error) {
+	conn, sess, err := c.connect(ctx, c.url)
+	if err != nil {
+		return false, err
+	}
+	defer func() {
+		conn.close()
+	}()
+
+	c.logger.Info("connected to eventsub", zap.String("session_id", sess.ID))
+	c.subscribe(ctx, sess.ID)
+
+	keepalive := keepaliveTimeout(sess)
+	timer := time.NewTimer(keepalive)
+	defer timer.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return true, nil
+		case err = <-conn.errs:
+			return true, err
+		case <-timer.C:
+			return true, errKeepaliveTimeout
+		case msg := <-conn.messages:
+			timer.Reset(keepalive)
+
+			switch msg.Metadata.MessageType {
+			case "notification":
+				c.handleNotification(ctx, msg)
+			case "session_reconnect":
+				newConn, newSess, err := c.connect(ctx, msg.Payload.Session.ReconnectURL)
+				if err != nil {
+					return true, errors.Join(errors.New("failed to reconnect to eventsub"), err)
+				}
+
+				conn.close()
+				conn = newConn
+				keepalive = keepaliveTimeout(newSess)
+				timer.Reset(keepalive)
+				c.logger.Info("reconnected to eventsub", zap.String("session_id", newSess.ID))
+			case "revocation":
+				c.logger.Warn("twitch revoked an eventsub subscription",
+					zap.String("type", msg.Payload.Subscription.Type),
+					zap.String("status", msg.Payload.Subscription.Status),
+				)
+			}
+		}
+	}
+}
+
+// connect opens a connection and waits for the welcome message.
+func (c *Client) connect(ctx context.Context, url string) (*connection, session, error) {
+	config, err := websocket.NewConfig(url, "http://localhost/")
+	if err != nil {
+		return nil, session{}, err
+	}
+
+	ws, err := config.DialContext(ctx)
+	if err != nil {
+		return nil, session{}, err
+	}
+
+	conn := newConnection(ws)
+
+	select {
+	case msg := <-conn.messages:
+		if msg.Metadata.MessageType != "session_welcome" {
+			conn.close()
+			return nil, session{}, errors.New("eventsub server did not start with a welcome message")
+		}
+		return conn, msg.Payload.Session, nil
+	case err = <-conn.errs:
+		conn.close()
+		return nil, session{}, err
+	case <-time.After(welcomeTimeout):
+		conn.close()
+		return nil, session{}, errors.New("eventsub server did not send a welcome message")
+	case <-ctx.Done():
+		conn.close()
+		return nil, session{}, ctx.Err()
+	}
+}
+
+// subscribe creates subscriptions for a session. Failed subscriptions are only logged, so a missing scope
+// of a single type of events does not stop the other ones.
+func (c *Client) subscribe(ctx context.Context, sessionID string) {
+	_, span := tracer.Start(ctx, "subscribe")
+	defer span.End()
+
+	failed := 0
+	for _, subscription := range c.subscriptions {
+		resp, err := c.helixClient.CreateEventSubSubscription(&helix.EventSubSubscription{
+			Type:      subscription.Type,
+			Version:   subscription.Version,
+			Condition: subscription.Condition,
+			Transport: helix.EventSubTransport{Method: "websocket", SessionID: sessionID},
+		})
+		if err == nil {
+			err = twitchapi.ResponseError(resp.ResponseCommon)
+		}
+		if err != nil {
+			failed++
+			c.logger.Error("failed to create an eventsub subscription", zap.String("type", subscription.Type), zap.Error(err))
+		}
+	}
+
+	span.SetAttributes(attribute.Int("eventsub.subscriptions.failed", failed))
+	if failed > 0 {
+		span.SetStatus(codes.Error, "failed to create some eventsub subscriptions")
+		return
+	}
+
+	span.SetStatus(codes.Ok, "successfully created eventsub subscriptions")
+}
+
+// handleNotification publishes an event of a notification, unless it was already received.
+func (c *Client) handleNotification(ctx context.Context, msg message) {
+	if c.isDuplicate(msg.Metadata.MessageID, msg.Metadata.MessageTimestamp) {
+		return
+	}
+
+	e, err := parseNotification(msg.Metadata.SubscriptionType, msg.Payload.Event, msg.Metadata.MessageTimestamp)
+	if err != nil {
+		c.logger.Warn("failed to parse an eventsub notification", zap.String("type", msg.Metadata.SubscriptionType), zap.Error(err))
+		return
+	}
+
+	c.publisher.Publish(ctx, e)
+}
+
+// isDuplicate reports whether a message was already received, and remembers it otherwise.
+func (c *Client) isDuplicate(messageID string, at time.Time) bool {
+	if _, ok := c.seen[messageID]; ok {
+		return true
+	}
+
+	for id, seenAt := range c.seen {
+		if at.Sub(seenAt) > deduplicationTTL {
+			delete(c.seen, id)
+		}
+	}
+
+	c.seen[messageID] = at
+	return false
+}
+
+// keepaliveTimeout returns the maximum time between messages of a session.
+func keepaliveTimeout(sess session) time.Duration {
+	return time.Duration(sess.KeepaliveTimeoutSeconds)*time.Second + keepaliveMargin
+}
+
+// connection reads messages of a WebSocket connection in the background.
+type connection struct {
+	ws       *websocket.Conn
+	messages chan message
+	errs     chan error
+	done     chan struct{}
+	once     sync.Once
+}
+
+func newConnection(ws *websocket.Conn) *connection {
+	conn := &connection{
+		ws:       ws,
+		messages: make(chan message),
+		errs:     make(chan error, 1),
+		done:     make(chan struct{}),
+	}
+	go conn.read()
+
+	return conn
+}
+
+// read decodes messages until the connection is closed.
+func (conn *connection) read() {
+	for {
+		var msg message
+		if err := websocket.JSON.Receive(conn.ws, &msg); err != nil {
+			conn.errs <- err
+			return
+		}
+
+		select {
+		case conn.messages <- msg:
+		case <-conn.done:
+			return
+		}
+	}
+}
+
+// close closes the connection and stops reading.
+func (conn *connection) close() {
+	conn.once.Do(func() {
+		close(conn.done)
+		_ = conn.ws.Close()
+	})
+}
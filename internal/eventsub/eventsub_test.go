@@ -0,0 +1,212 @@
+package eventsub
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/danielbukowski/twitch-chatbot/internal/event"
+	"github.com/danielbukowski/twitch-chatbot/internal/helixtest"
+	"github.com/nicklaw5/helix/v2"
+	"go.uber.org/zap"
+	"golang.org/x/net/websocket"
+)
+
+type publisherMock struct {
+	events chan event.Event
+}
+
+func (p *publisherMock) Publish(_ context.Context, e event.Event) {
+	p.events <- e
+}
+
+// readPayload returns a recorded payload of the EventSub server.
+func readPayload(t *testing.T, name string) string {
+	t.Helper()
+
+	b, err := os.ReadFile(filepath.Join("testdata", name))
+	if err != nil {
+		t.Fatalf("Expected no error, got `%v`", err)
+	}
+
+	return string(b)
+}
+
+// newEventSubServer starts a stand-in of the EventSub server, which replays payloads by a path of the connection.
+// Replays are looked up on connect, so they can point at the address of the server.
+func newEventSubServer(t *testing.T, replays map[string][]string) string {
+	t.Helper()
+
+	server := httptest.NewServer(websocket.Handler(func(ws *websocket.Conn) {
+		for _, payload := range replays[ws.Request().URL.Path] {
+			if err := websocket.Message.Send(ws, payload); err != nil {
+				return
+			}
+		}
+
+		// keep the connection open until the client closes it.
+		var discard string
+		_ = websocket.Message.Receive(ws, &discard)
+	}))
+	t.Cleanup(server.Close)
+
+	return "ws://" + strings.TrimPrefix(server.URL, "http://")
+}
+
+// newTestHelixClient starts a stand-in of Twitch API, which records created subscriptions.
+func newTestHelixClient(t *testing.T, mu *sync.Mutex, created *[]helix.EventSubSubscription) *helix.Client {
+	t.Helper()
+
+	helixClient := helixtest.NewClient(t, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodPost || r.URL.Path != "/eventsub/subscriptions" {
+			w.WriteHeader(http.StatusNotFound)
+			return
+		}
+
+		var subscription helix.EventSubSubscription
+		_ = json.NewDecoder(r.Body).Decode(&subscription)
+
+		mu.Lock()
+		*created = append(*created, subscription)
+		mu.Unlock()
+
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusAccepted)
+		_, _ = w.Write([]byte(`{"data":[],"total":0,"total_cost":0,"max_total_cost":10}`))
+	}))
+
+	return helixClient
+}
+
+func receiveEvent(t *testing.T, events <-chan event.Event) event.Event {
+	t.Helper()
+
+	select {
+	case e := <-events:
+		return e
+	case <-time.After(5 * time.Second):
+		t.Fatal("Expected an event to be published")
+		return nil
+	}
+}
+
+func TestClientRun(t *testing.T) {
+	t.Run("publishes events, drops redelivered ones and moves to a new connection on reconnect", func(t *testing.T) {
+		// given
+		welcome := readPayload(t, "session_welcome.json")
+		follow := readPayload(t, "notification_follow.json")
+		redemption := readPayload(t, "notification_redemption.json")
+		reconnect := readPayload(t, "session_reconnect.json")
+
+		replays := map[string][]string{}
+		url := newEventSubServer(t, replays)
+		replays["/ws"] = []string{welcome, follow, follow, strings.Replace(reconnect, "wss://eventsub.wss.twitch.tv/ws?reconnect=true", url+"/reconnect", 1)}
+		replays["/reconnect"] = []string{welcome, redemption}
+
+		var mu sync.Mutex
+		var created []helix.EventSubSubscription
+		helixClient := newTestHelixClient(t, &mu, &created)
+
+		publisher := &publisherMock{events: make(chan event.Event, 10)}
+		client := NewClient(url+"/ws", helixClient, append(ModeratorSubscriptions("1337", "4242")[:1], BroadcasterSubscriptions("1337")[:1]...), publisher, zap.NewNop())
+
+		ctx, cancel := context.WithCancel(context.Background())
+		done := make(chan error, 1)
+
+		// when
+		go func() { done <- client.Run(ctx) }()
+
+		first := receiveEvent(t, publisher.events)
+		second := receiveEvent(t, publisher.events)
+		cancel()
+
+		// then
+		if err := <-done; err != nil {
+			t.Fatalf("Expected no error, got `%v`", err)
+		}
+
+		followEvent, ok := first.(event.Follow)
+		if !ok {
+			t.Fatalf("Expected a follow event, got `%T`", first)
+		}
+		if followEvent.ChannelName != "cooler_user" || followEvent.User.DisplayName != "Cool_User" {
+			t.Fatalf("Expected a follow of Cool_User on cooler_user, got `%+v`", followEvent.Base)
+		}
+
+		redemptionEvent, ok := second.(event.Redemption)
+		if !ok {
+			t.Fatalf("Expected a redemption event, got `%T`", second)
+		}
+		if redemptionEvent.Data.Reward.Title != "Hydrate" || redemptionEvent.User.Name != "cooler_user_fan" {
+			t.Fatalf("Expected a redemption of Hydrate by cooler_user_fan, got `%+v`", redemptionEvent)
+		}
+
+		mu.Lock()
+		defer mu.Unlock()
+		if len(created) != 2 {
+			t.Fatalf("Expected subscriptions to be created once, got %d subscriptions", len(created))
+		}
+		for _, subscription := range created {
+			if subscription.Transport.Method != "websocket" || subscription.Transport.SessionID != "AQoQILE98gtqShGmLD7AM6yJThAB" {
+				t.Fatalf("Expected a subscription of the welcomed session, got `%+v`", subscription.Transport)
+			}
+		}
+	})
+}
+
+func TestParseNotification(t *testing.T) {
+	testCases := []struct {
+		name             string
+		subscriptionType string
+		raw              string
+		expectedType     event.Type
+		expectedErr      bool
+	}{
+		{
+			name:             "stream online is attributed to the broadcaster",
+			subscriptionType: helix.EventSubTypeStreamOnline,
+			raw:              `{"id":"1","broadcaster_user_id":"1337","broadcaster_user_login":"cooler_user","broadcaster_user_name":"Cooler_User","type":"live"}`,
+			expectedType:     event.TypeStreamOnline,
+		},
+		{
+			name:             "unsupported type",
+			subscriptionType: "channel.ban",
+			raw:              `{}`,
+			expectedErr:      true,
+		},
+		{
+			name:             "malformed payload",
+			subscriptionType: helix.EventSubTypeChannelFollow,
+			raw:              `{"user_id":`,
+			expectedErr:      true,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			// when
+			e, err := parseNotification(tc.subscriptionType, json.RawMessage(tc.raw), time.Now())
+
+			// then
+			if tc.expectedErr {
+				if err == nil {
+					t.Fatal("Expected an error, got nil")
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("Expected no error, got `%v`", err)
+			}
+			if e.Type() != tc.expectedType || e.Channel() != "cooler_user" {
+				t.Fatalf("Expected `%s` on cooler_user, got `%s` on `%s`", tc.expectedType, e.Type(), e.Channel())
+			}
+		})
+	}
+}
@@ -0,0 +1,80 @@
+package eventsub
+
+import (
+	"encoding/json"
+	"fmt"
+	"time"
+
+	"github.com/danielbukowski/twitch-chatbot/internal/event"
+	"github.com/gempir/go-twitch-irc/v4"
+	"github.com/nicklaw5/helix/v2"
+)
+
+// parsers turn payloads of notifications into typed events, by a type of subscription.
+var parsers = map[string]func(raw json.RawMessage, at time.Time) (event.Event, error){
+	helix.EventSubTypeChannelFollow: parser(func(d helix.EventSubChannelFollowEvent, at time.Time) event.Event {
+		return event.Follow{Base: base(at, d.BroadcasterUserLogin, d.BroadcasterUserID, d.UserID, d.UserLogin, d.UserName), Data: d}
+	}),
+	helix.EventSubTypeChannelPointsCustomRewardRedemptionAdd: parser(func(d helix.EventSubChannelPointsCustomRewardRedemptionEvent, at time.Time) event.Event {
+		return event.Redemption{Base: base(at, d.BroadcasterUserLogin, d.BroadcasterUserID, d.UserID, d.UserLogin, d.UserName), Data: d}
+	}),
+	helix.EventSubTypeChannelPollBegin: parser(func(d helix.EventSubChannelPollBeginEvent, at time.Time) event.Event {
+		return event.PollBegin{Base: base(at, d.BroadcasterUserLogin, d.BroadcasterUserID, d.BroadcasterUserID, d.BroadcasterUserLogin, d.BroadcasterUserName), Data: d}
+	}),
+	helix.EventSubTypeChannelPollEnd: parser(func(d helix.EventSubChannelPollEndEvent, at time.Time) event.Event {
+		return event.PollEnd{Base: base(at, d.BroadcasterUserLogin, d.BroadcasterUserID, d.BroadcasterUserID, d.BroadcasterUserLogin, d.BroadcasterUserName), Data: d}
+	}),
+	helix.EventSubTypeChannelPredictionBegin: parser(func(d helix.EventSubChannelPredictionBeginEvent, at time.Time) event.Event {
+		return event.PredictionBegin{Base: base(at, d.BroadcasterUserLogin, d.BroadcasterUserID, d.BroadcasterUserID, d.BroadcasterUserLogin, d.BroadcasterUserName), Data: d}
+	}),
+	helix.EventSubTypeChannelPredictionLock: parser(func(d helix.EventSubChannelPredictionLockEvent, at time.Time) event.Event {
+		return event.PredictionLock{Base: base(at, d.BroadcasterUserLogin, d.BroadcasterUserID, d.BroadcasterUserID, d.BroadcasterUserLogin, d.BroadcasterUserName), Data: d}
+	}),
+	helix.EventSubTypeChannelPredictionEnd: parser(func(d helix.EventSubChannelPredictionEndEvent, at time.Time) event.Event {
+		return event.PredictionEnd{Base: base(at, d.BroadcasterUserLogin, d.BroadcasterUserID, d.BroadcasterUserID, d.BroadcasterUserLogin, d.BroadcasterUserName), Data: d}
+	}),
+	helix.EventSubTypeHypeTrainBegin: parser(func(d helix.EventSubHypeTrainBeginEvent, at time.Time) event.Event {
+		return event.HypeTrainBegin{Base: base(at, d.BroadcasterUserLogin, d.BroadcasterUserID, d.BroadcasterUserID, d.BroadcasterUserLogin, d.BroadcasterUserName), Data: d}
+	}),
+	helix.EventSubTypeHypeTrainEnd: parser(func(d helix.EventSubHypeTrainEndEvent, at time.Time) event.Event {
+		return event.HypeTrainEnd{Base: base(at, d.BroadcasterUserLogin, d.BroadcasterUserID, d.BroadcasterUserID, d.BroadcasterUserLogin, d.BroadcasterUserName), Data: d}
+	}),
+	helix.EventSubTypeStreamOnline: parser(func(d helix.EventSubStreamOnlineEvent, at time.Time) event.Event {
+		return event.StreamOnline{Base: base(at, d.BroadcasterUserLogin, d.BroadcasterUserID, d.BroadcasterUserID, d.BroadcasterUserLogin, d.BroadcasterUserName), Data: d}
+	}),
+	helix.EventSubTypeStreamOffline: parser(func(d helix.EventSubStreamOfflineEvent, at time.Time) event.Event {
+		return event.StreamOffline{Base: base(at, d.BroadcasterUserLogin, d.BroadcasterUserID, d.BroadcasterUserID, d.BroadcasterUserLogin, d.BroadcasterUserName), Data: d}
+	}),
+}
+
+// parser creates a parser of a payload of the type T.
+func parser[T any](build func(data T, at time.Time) event.Event) func(raw json.RawMessage, at time.Time) (event.Event, error) {
+	return func(raw json.RawMessage, at time.Time) (event.Event, error) {
+		var data T
+		if err := json.Unmarshal(raw, &data); err != nil {
+			return nil, err
+		}
+
+		return build(data, at), nil
+	}
+}
+
+// base returns fields shared by all events. Events without a user are attributed to the broadcaster.
+func base(at time.Time, channelName, broadcasterID, userID, userLogin, userName string) event.Base {
+	return event.Base{
+		Time:        at,
+		ChannelName: channelName,
+		RoomID:      broadcasterID,
+		User:        twitch.User{ID: userID, Name: userLogin, DisplayName: userName},
+	}
+}
+
+// parseNotification turns a payload of a notification into a typed event.
+func parseNotification(subscriptionType string, raw json.RawMessage, at time.Time) (event.Event, error) {
+	parse, ok := parsers[subscriptionType]
+	if !ok {
+		return nil, fmt.Errorf("unsupported subscription type %s", subscriptionType)
+	}
+
+	return parse(raw, at)
+}
@@ -0,0 +1,294 @@
+package giveaway
+
+import (
+	"context"
+	"fmt"
+	"math/rand/v2"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/danielbukowski/twitch-chatbot/internal/command"
+	"github.com/gempir/go-twitch-irc/v4"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.uber.org/zap"
+)
+
+var tracer = otel.Tracer("github.com/danielbukowski/twitch-chatbot/internal/giveaway")
+
+// Entry is a user who entered a giveaway.
+type Entry struct {
+	UserID   string `json:"user_id"`  // UserID is an ID of the user.
+	Username string `json:"username"` // Username is a login of the user.
+	Weight   int    `json:"weight"`   // Weight is a number of tickets of the user.
+}
+
+// Draw is a drawn winner of a giveaway. The draw can be repeated by picking from the same entrants with the same seed.
+type Draw struct {
+	ChannelName string    // ChannelName is a name of the channel with the giveaway.
+	Keyword     string    // Keyword is a word users typed to enter the giveaway.
+	Winner      Entry     // Winner is the drawn entry.
+	Seed        uint64    // Seed is a seed of the random number generator used for the draw.
+	Entrants    []Entry   // Entrants are entries in the order they were drawn from.
+	Reroll      bool      // Reroll is true, when the draw replaced a previous winner.
+	DrawnAt     time.Time // DrawnAt is the time of the draw.
+}
+
+type storage interface {
+	Save(ctx context.Context, draw Draw) error
+	WinnersSince(ctx context.Context, channelName string, since time.Time) ([]string, error)
+}
+
+// giveaway is a running giveaway of a channel.
+type giveaway struct {
+	keyword  string          // Keyword is a word users type to enter.
+	closesAt time.Time       // ClosesAt is the time, after which entries are ignored.
+	entries  []Entry         // Entries holds users, who can still win, in the order they entered.
+	entered  map[string]bool // Entered holds IDs of users, who entered, including drawn winners.
+	excluded map[string]bool // Excluded holds IDs of recent winners, who can't enter.
+	drawn    bool            // Drawn is true, when a winner was drawn.
+}
+
+// Service runs giveaways, which users enter by typing a keyword in the chat.
+type Service struct {
+	storage          storage              // Storage saves the history of draws.
+	subscriberWeight int                  // SubscriberWeight is a number of tickets of subscribers, other users have one.
+	exclusion        time.Duration        // Exclusion is how long winners can't enter other giveaways.
+	giveaways        map[string]*giveaway // Giveaways holds giveaways by names of channels.
+	mu               sync.Mutex           // Mu guards giveaways.
+	now              func() time.Time     // Now returns the current time.
+	seed             func() uint64        // Seed returns seeds of draws.
+	logger           *zap.Logger          // Logger is used for logging.
+}
+
+// NewService creates an instance of Service.
+func NewService(storage storage, subscriberWeight int, exclusion time.Duration, logger *zap.Logger) *Service {
+	return &Service{
+		storage:          storage,
+		subscriberWeight: max(1, subscriberWeight),
+		exclusion:        exclusion,
+		giveaways:        make(map[string]*giveaway),
+		now:              time.Now,
+		seed:             rand.Uint64,
+		logger:           logger.Named("giveaway"),
+	}
+}
+
+// Observe enters the author of a message to the running giveaway, when the message is the keyword.
+// Every user enters once, recent winners don't enter at all.
+func (s *Service) Observe(privMsg *twitch.PrivateMessage) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	g, ok := s.giveaways[privMsg.Channel]
+	if !ok || g.drawn || s.now().After(g.closesAt) || !strings.EqualFold(strings.TrimSpace(privMsg.Message), g.keyword) {
+		return
+	}
+
+	userID := privMsg.User.ID
+	if userID == "" || g.entered[userID] || g.excluded[userID] {
+		return
+	}
+
+	weight := 1
+	if privMsg.User.Badges["subscriber"] != 0 || privMsg.User.Badges["founder"] != 0 {
+		weight = s.subscriberWeight
+	}
+
+	g.entered[userID] = true
+	g.entries = append(g.entries, Entry{UserID: userID, Username: strings.ToLower(privMsg.User.Name), Weight: weight})
+}
+
+// Giveaway starts, draws and cancels giveaways.
+// Usage: `!giveaway start <keyword> <duration>`, `!giveaway draw` or `!giveaway cancel`.
+func (s *Service) Giveaway() command.Handler {
+	return func(ctx context.Context, args []string, chatClient command.ChatClient) error {
+		usage := command.UsageError("usage: !giveaway start <keyword> <duration> | !giveaway draw | !giveaway cancel")
+		if len(args) == 0 {
+			return usage
+		}
+
+		switch strings.ToLower(args[0]) {
+		case "start":
+			if len(args) < 3 {
+				return usage
+			}
+			return s.start(ctx, args[1], args[2], chatClient)
+		case "draw":
+			return s.draw(ctx, false, chatClient)
+		case "cancel":
+			return s.cancel(ctx, chatClient)
+		default:
+			return usage
+		}
+	}
+}
+
+// Reroll draws another winner of the last giveaway, the previous winner can't win again.
+// Usage: `!reroll`.
+func (s *Service) Reroll() command.Handler {
+	return func(ctx context.Context, _ []string, chatClient command.ChatClient) error {
+		return s.draw(ctx, true, chatClient)
+	}
+}
+
+// start opens a giveaway on the channel of the command.
+func (s *Service) start(ctx context.Context, keyword, durationArg string, chatClient command.ChatClient) error {
+	ctx, span := tracer.Start(ctx, "startGiveaway")
+	defer span.End()
+
+	cmdCtx := command.UnwrapContext(ctx)
+	privMsg := cmdCtx.PrivMsg
+
+	duration, err := command.ParseDuration(durationArg)
+	if err != nil || duration <= 0 {
+		span.SetStatus(codes.Error, "wrong duration")
+		return command.UsageError(fmt.Sprintf("'%s' is not a valid duration, use values like 300 or 5m", durationArg))
+	}
+
+	s.mu.Lock()
+	current, ok := s.giveaways[privMsg.Channel]
+	s.mu.Unlock()
+	if ok && !current.drawn {
+		span.SetStatus(codes.Error, "giveaway is already running")
+		return command.UsageError("a giveaway is already running, draw a winner or cancel it first.")
+	}
+
+	now := s.now()
+	winners, err := s.storage.WinnersSince(ctx, privMsg.Channel, now.Add(-s.exclusion))
+	if err != nil {
+		span.SetStatus(codes.Error, "failed to retrieve recent winners")
+		return command.InternalError(err)
+	}
+
+	excluded := make(map[string]bool, len(winners))
+	for _, userID := range winners {
+		excluded[userID] = true
+	}
+
+	s.mu.Lock()
+	s.giveaways[privMsg.Channel] = &giveaway{
+		keyword:  keyword,
+		closesAt: now.Add(duration),
+		entered:  make(map[string]bool),
+		excluded: excluded,
+	}
+	s.mu.Unlock()
+
+	span.SetAttributes(attribute.String("giveaway.keyword", keyword), attribute.Int("giveaway.excluded", len(excluded)))
+	span.SetStatus(codes.Ok, "successfully started a giveaway")
+
+	chatClient.Say(privMsg.Channel, fmt.Sprintf("A giveaway has started! Type %s in the chat within %s to enter.", keyword, duration))
+	return nil
+}
+
+// draw closes entries of the giveaway on the channel of the command and picks a winner.
+func (s *Service) draw(ctx context.Context, reroll bool, chatClient command.ChatClient) error {
+	ctx, span := tracer.Start(ctx, "drawGiveaway")
+	defer span.End()
+
+	cmdCtx := command.UnwrapContext(ctx)
+	privMsg := cmdCtx.PrivMsg
+
+	s.mu.Lock()
+	g, ok := s.giveaways[privMsg.Channel]
+	switch {
+	case !ok:
+		s.mu.Unlock()
+		span.SetStatus(codes.Error, "no giveaway is running")
+		return command.UsageError("no giveaway is running.")
+	case reroll && !g.drawn:
+		s.mu.Unlock()
+		span.SetStatus(codes.Error, "no winner to reroll")
+		return command.UsageError("no winner was drawn yet, use !giveaway draw.")
+	case !reroll && g.drawn:
+		s.mu.Unlock()
+		span.SetStatus(codes.Error, "winner was already drawn")
+		return command.UsageError("a winner was already drawn, use !reroll to draw another one.")
+	case len(g.entries) == 0:
+		s.mu.Unlock()
+		span.SetStatus(codes.Error, "nobody can win")
+		return command.UsageError("nobody is left to win the giveaway.")
+	}
+
+	draw := Draw{
+		ChannelName: privMsg.Channel,
+		Keyword:     g.keyword,
+		Seed:        s.seed(),
+		Entrants:    g.entries,
+		Reroll:      reroll,
+		DrawnAt:     s.now(),
+	}
+	winnerIndex := pick(draw.Seed, draw.Entrants)
+	draw.Winner = draw.Entrants[winnerIndex]
+
+	g.drawn = true
+	g.entries = append(g.entries[:winnerIndex:winnerIndex], g.entries[winnerIndex+1:]...)
+	s.mu.Unlock()
+
+	span.SetAttributes(
+		attribute.String("giveaway.keyword", draw.Keyword),
+		attribute.String("giveaway.seed", strconv.FormatUint(draw.Seed, 10)),
+		attribute.Int("giveaway.entrants", len(draw.Entrants)),
+		attribute.String("giveaway.winner", draw.Winner.Username),
+	)
+	s.logger.Info("drew a winner of a giveaway",
+		zap.String("channel_name", draw.ChannelName),
+		zap.String("keyword", draw.Keyword),
+		zap.Uint64("seed", draw.Seed),
+		zap.Int("entrants", len(draw.Entrants)),
+		zap.String("winner", draw.Winner.Username),
+		zap.Bool("reroll", reroll),
+	)
+
+	chatClient.Say(privMsg.Channel, fmt.Sprintf("@%s won the giveaway! (%d entrants, seed %d)", draw.Winner.Username, len(draw.Entrants), draw.Seed))
+
+	if err := s.storage.Save(ctx, draw); err != nil {
+		span.SetStatus(codes.Error, "failed to save the draw")
+		span.RecordError(err)
+		s.logger.Error("failed to save a draw of a giveaway", zap.String("channel_name", draw.ChannelName), zap.Error(err))
+		return nil
+	}
+
+	span.SetStatus(codes.Ok, "successfully drew a winner")
+	return nil
+}
+
+// cancel stops the giveaway on the channel of the command without a winner.
+func (s *Service) cancel(ctx context.Context, chatClient command.ChatClient) error {
+	cmdCtx := command.UnwrapContext(ctx)
+	privMsg := cmdCtx.PrivMsg
+
+	s.mu.Lock()
+	_, ok := s.giveaways[privMsg.Channel]
+	delete(s.giveaways, privMsg.Channel)
+	s.mu.Unlock()
+
+	if !ok {
+		return command.UsageError("no giveaway is running.")
+	}
+
+	chatClient.Say(privMsg.Channel, fmt.Sprintf("@%s, the giveaway was cancelled.", privMsg.User.DisplayName))
+	return nil
+}
+
+// pick returns an index of a weighted random entry. The same seed and entries always give the same index.
+func pick(seed uint64, entries []Entry) int {
+	total := 0
+	for _, entry := range entries {
+		total += entry.Weight
+	}
+
+	ticket := rand.New(rand.NewPCG(seed, seed)).IntN(total)
+	for i, entry := range entries {
+		if ticket < entry.Weight {
+			return i
+		}
+		ticket -= entry.Weight
+	}
+
+	return len(entries) - 1
+}
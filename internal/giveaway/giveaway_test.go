@@ -0,0 +1,165 @@
+package giveaway
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/danielbukowski/twitch-chatbot/internal/command"
+	"github.com/gempir/go-twitch-irc/v4"
+	"go.uber.org/zap"
+)
+
+type storageMock struct {
+	draws   []Draw
+	winners []string
+}
+
+func (s *storageMock) Save(_ context.Context, draw Draw) error {
+	s.draws = append(s.draws, draw)
+	return nil
+}
+
+func (s *storageMock) WinnersSince(_ context.Context, _ string, _ time.Time) ([]string, error) {
+	return s.winners, nil
+}
+
+type chatClientMock struct {
+	messages []string
+}
+
+func (c *chatClientMock) Say(_, message string) {
+	c.messages = append(c.messages, message)
+}
+
+func (c *chatClientMock) Reply(_, _, message string) {
+	c.messages = append(c.messages, message)
+}
+
+func (c *chatClientMock) Join(_ ...string) {}
+
+func (c *chatClientMock) Depart(_ string) {}
+
+func commandContext(commandName string) context.Context {
+	privMsg := &twitch.PrivateMessage{Channel: "channel", User: twitch.User{ID: "100", Name: "moderator", DisplayName: "Moderator"}}
+	return command.WithContext(context.Background(), command.NewContext(commandName, privMsg, zap.NewNop()))
+}
+
+func message(userID, username, text string, badges map[string]int) *twitch.PrivateMessage {
+	return &twitch.PrivateMessage{Channel: "channel", Message: text, User: twitch.User{ID: userID, Name: username, Badges: badges}}
+}
+
+func TestService(t *testing.T) {
+	t.Run("enters users typing the keyword once, with more tickets for subscribers", func(t *testing.T) {
+		// given
+		storage := &storageMock{winners: []string{"4"}}
+		service := NewService(storage, 2, 24*time.Hour, zap.NewNop())
+		_ = service.Giveaway()(commandContext("!giveaway"), []string{"start", "!enter", "5m"}, &chatClientMock{})
+
+		// when
+		service.Observe(message("1", "viewer", "!ENTER", nil))
+		service.Observe(message("1", "viewer", "!enter", nil))
+		service.Observe(message("2", "subscriber", " !enter ", map[string]int{"subscriber": 3}))
+		service.Observe(message("3", "chatter", "hello", nil))
+		service.Observe(message("4", "winner", "!enter", nil))
+
+		// then
+		entries := service.giveaways["channel"].entries
+		if len(entries) != 2 || entries[0] != (Entry{UserID: "1", Username: "viewer", Weight: 1}) || entries[1] != (Entry{UserID: "2", Username: "subscriber", Weight: 2}) {
+			t.Fatalf("Expected the viewer and the subscriber to enter, got `%+v`", entries)
+		}
+	})
+
+	t.Run("ignores entries after the giveaway closes", func(t *testing.T) {
+		// given
+		service := NewService(&storageMock{}, 2, 24*time.Hour, zap.NewNop())
+		now := time.Now()
+		service.now = func() time.Time { return now }
+		_ = service.Giveaway()(commandContext("!giveaway"), []string{"start", "!enter", "5m"}, &chatClientMock{})
+
+		// when
+		now = now.Add(6 * time.Minute)
+		service.Observe(message("1", "viewer", "!enter", nil))
+
+		// then
+		if len(service.giveaways["channel"].entries) != 0 {
+			t.Fatalf("Expected no entries, got `%+v`", service.giveaways["channel"].entries)
+		}
+	})
+
+	t.Run("saves draws and does not draw the same winner on a reroll", func(t *testing.T) {
+		// given
+		storage := &storageMock{}
+		service := NewService(storage, 2, 24*time.Hour, zap.NewNop())
+		service.seed = func() uint64 { return 42 }
+		_ = service.Giveaway()(commandContext("!giveaway"), []string{"start", "!enter", "5m"}, &chatClientMock{})
+		service.Observe(message("1", "first", "!enter", nil))
+		service.Observe(message("2", "second", "!enter", nil))
+
+		// when
+		drawErr := service.Giveaway()(commandContext("!giveaway"), []string{"draw"}, &chatClientMock{})
+		rerollErr := service.Reroll()(commandContext("!reroll"), nil, &chatClientMock{})
+		lastErr := service.Reroll()(commandContext("!reroll"), nil, &chatClientMock{})
+
+		// then
+		if drawErr != nil || rerollErr != nil {
+			t.Fatalf("Expected no errors, got `%v` and `%v`", drawErr, rerollErr)
+		}
+		if command.Classify(lastErr) != command.KindUsage {
+			t.Fatalf("Expected a usage error, when nobody is left, got `%v`", lastErr)
+		}
+		if len(storage.draws) != 2 || storage.draws[0].Winner == storage.draws[1].Winner || !storage.draws[1].Reroll {
+			t.Fatalf("Expected two different winners, got `%+v`", storage.draws)
+		}
+		if storage.draws[0].Seed != 42 || len(storage.draws[0].Entrants) != 2 || len(storage.draws[1].Entrants) != 1 {
+			t.Fatalf("Expected the seed and entrants of every draw, got `%+v`", storage.draws)
+		}
+	})
+
+	t.Run("rejects a second giveaway, before the first one is drawn", func(t *testing.T) {
+		// given
+		service := NewService(&storageMock{}, 2, 24*time.Hour, zap.NewNop())
+		_ = service.Giveaway()(commandContext("!giveaway"), []string{"start", "!enter", "5m"}, &chatClientMock{})
+
+		// when
+		err := service.Giveaway()(commandContext("!giveaway"), []string{"start", "!join", "5m"}, &chatClientMock{})
+
+		// then
+		if command.Classify(err) != command.KindUsage {
+			t.Fatalf("Expected a usage error, got `%v`", err)
+		}
+	})
+}
+
+func TestPick(t *testing.T) {
+	t.Run("gives the same result for the same seed", func(t *testing.T) {
+		// given
+		entries := []Entry{{UserID: "1", Weight: 1}, {UserID: "2", Weight: 2}, {UserID: "3", Weight: 1}}
+
+		// when
+		first, second := pick(7, entries), pick(7, entries)
+
+		// then
+		if first != second {
+			t.Fatalf("Expected the same index, got %d and %d", first, second)
+		}
+	})
+
+	t.Run("respects weights of entries", func(t *testing.T) {
+		// given
+		entries := []Entry{{UserID: "1", Weight: 1}, {UserID: "2", Weight: 3}}
+
+		// when
+		wins := 0
+		for seed := range uint64(1000) {
+			if pick(seed, entries) == 1 {
+				wins++
+			}
+		}
+
+		// then
+		if wins < 700 || wins > 800 {
+			t.Fatalf("Expected the entry with 3 tickets to win about 750 of 1000 draws, got %d", wins)
+		}
+	})
+}
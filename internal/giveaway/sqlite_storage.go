@@ -0,0 +1,96 @@
+package giveaway
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"strconv"
+	"time"
+
+	"github.com/danielbukowski/twitch-chatbot/internal/database"
+	"go.opentelemetry.io/otel/codes"
+)
+
+// SQLiteStorage stores the history of draws of giveaways.
+type SQLiteStorage struct {
+	db *sql.DB
+}
+
+func NewSQLiteStorage(db *sql.DB) *SQLiteStorage {
+	return &SQLiteStorage{db: db}
+}
+
+// Save saves a draw with its seed and entrants, so the draw can be verified later.
+func (s *SQLiteStorage) Save(ctx context.Context, draw Draw) error {
+	query := `INSERT INTO giveaway_draws (channel_name, keyword, winner_id, winner_name, seed, entrants, reroll, drawn_at)
+		VALUES (?, ?, ?, ?, ?, ?, ?, ?);`
+
+	ctx, span := tracer.Start(ctx, "save")
+	defer span.End()
+
+	entrants, err := json.Marshal(draw.Entrants)
+	if err != nil {
+		errMsg := "failed to marshal entrants"
+		span.SetStatus(codes.Error, errMsg)
+		span.RecordError(err)
+		return errors.Join(errors.New(errMsg), err)
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, database.RequestTimeout)
+	defer cancel()
+
+	_, err = s.db.ExecContext(ctx, query, draw.ChannelName, draw.Keyword, draw.Winner.UserID, draw.Winner.Username,
+		strconv.FormatUint(draw.Seed, 10), string(entrants), draw.Reroll, draw.DrawnAt.Unix())
+	if err != nil {
+		errMsg := "failed to save a draw"
+		span.SetStatus(codes.Error, errMsg)
+		span.RecordError(err)
+		return errors.Join(errors.New(errMsg), err)
+	}
+
+	span.SetStatus(codes.Ok, "successfully saved a draw")
+	return nil
+}
+
+// WinnersSince returns IDs of users, who won giveaways on a channel since the given time.
+func (s *SQLiteStorage) WinnersSince(ctx context.Context, channelName string, since time.Time) ([]string, error) {
+	query := "SELECT DISTINCT winner_id FROM giveaway_draws WHERE channel_name = ? AND drawn_at >= ?;"
+
+	ctx, span := tracer.Start(ctx, "winnersSince")
+	defer span.End()
+
+	ctx, cancel := context.WithTimeout(ctx, database.RequestTimeout)
+	defer cancel()
+
+	rows, err := s.db.QueryContext(ctx, query, channelName, since.Unix())
+	if err != nil {
+		errMsg := "failed to query winners"
+		span.SetStatus(codes.Error, errMsg)
+		span.RecordError(err)
+		return nil, errors.Join(errors.New(errMsg), err)
+	}
+	defer rows.Close()
+
+	winners := []string{}
+	for rows.Next() {
+		var userID string
+		if err = rows.Scan(&userID); err != nil {
+			errMsg := "failed to copy a winner to a variable"
+			span.SetStatus(codes.Error, errMsg)
+			span.RecordError(err)
+			return nil, errors.Join(errors.New(errMsg), err)
+		}
+		winners = append(winners, userID)
+	}
+
+	if err = rows.Err(); err != nil {
+		errMsg := "failed to iterate over winners"
+		span.SetStatus(codes.Error, errMsg)
+		span.RecordError(err)
+		return nil, errors.Join(errors.New(errMsg), err)
+	}
+
+	span.SetStatus(codes.Ok, "successfully retrieved winners")
+	return winners, nil
+}
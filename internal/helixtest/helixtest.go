@@ -0,0 +1,26 @@
+// Package helixtest provides a stand-in of Twitch API for tests.
+package helixtest
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/nicklaw5/helix/v2"
+)
+
+// NewClient starts a server with the handler and returns a helix client, which calls the server instead of Twitch API.
+// The server is closed, when the test ends.
+func NewClient(t testing.TB, handler http.Handler) *helix.Client {
+	t.Helper()
+
+	server := httptest.NewServer(handler)
+	t.Cleanup(server.Close)
+
+	helixClient, err := helix.NewClient(&helix.Options{ClientID: "client-id", UserAccessToken: "token", APIBaseURL: server.URL})
+	if err != nil {
+		t.Fatalf("Expected no error, got `%v`", err)
+	}
+
+	return helixClient
+}
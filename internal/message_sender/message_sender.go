@@ -3,6 +3,7 @@ package messagesender
 import (
 	"context"
 	"fmt"
+	"sync/atomic"
 	"time"
 
 	"go.uber.org/zap"
@@ -21,6 +22,7 @@ type MessageSender struct {
 	chatClient  chatClient    // ChatClient describes a method for broadcasting messages on a channel.
 	interval    time.Duration // Interval indicates how often a message should be sent.
 	channelName string        // ChannelName represents a name for a channel, on where messages are sent on.
+	paused      atomic.Bool   // Paused tells, if messages are skipped, e.g. because the stream is offline.
 }
 
 // New creates an instance of MessageSender for regularly sending messages in the chat.
@@ -33,6 +35,16 @@ func (ms *MessageSender) AddMessages(message ...string) {
 	ms.messages = append(ms.messages, message...)
 }
 
+// Pause stops posting messages, until Resume is called. The ticker keeps running, so the order of messages is kept.
+func (ms *MessageSender) Pause() {
+	ms.paused.Store(true)
+}
+
+// Resume posts messages again after Pause.
+func (ms *MessageSender) Resume() {
+	ms.paused.Store(false)
+}
+
 // Start runs a cron job for posting messages on the chat. This method blocks the execution of your code,
 // use Goroutine with this method.
 func (ms *MessageSender) Start(ctx context.Context) {
@@ -54,6 +66,10 @@ func (ms *MessageSender) Start(ctx context.Context) {
 	for {
 		select {
 		case <-t.C:
+			if ms.paused.Load() {
+				continue
+			}
+
 			i %= len(ms.messages)
 			ms.chatClient.Say(ms.channelName, ms.messages[i])
 
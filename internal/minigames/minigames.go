@@ -0,0 +1,418 @@
+package minigames
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/danielbukowski/twitch-chatbot/internal/command"
+	"github.com/danielbukowski/twitch-chatbot/internal/points"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.uber.org/zap"
+)
+
+var tracer = otel.Tracer("github.com/danielbukowski/twitch-chatbot/internal/minigames")
+
+const (
+	duelTimeout     = time.Minute     // duelTimeout is how long a challenged user has to accept a duel.
+	heistWindow     = 2 * time.Minute // heistWindow is how long users can join a heist.
+	heistKey        = "heist"         // heistKey identifies the only heist of a channel.
+	gambleWinRoll   = 50              // gambleWinRoll is the highest losing roll of 1-100 in a gamble.
+	heistBaseChance = 40              // heistBaseChance is a chance in percents of a single robber to get away.
+	heistCrewBonus  = 5               // heistCrewBonus is a bonus to the chance for every other member of a crew.
+	heistMaxChance  = 70              // heistMaxChance is the highest chance to get away.
+	payout          = 2               // payout multiplies the stake of a winner.
+)
+
+// errGamesClosed is returned, when a game is started during shutdown of the chatbot.
+var errGamesClosed = command.UsageError("the chatbot is shutting down, your points were refunded.")
+
+// ledger takes and gives points of users.
+type ledger interface {
+	Spend(ctx context.Context, channelName string, account points.Account, amount int64) (int64, error)
+	Add(ctx context.Context, channelName string, account points.Account, amount int64) (int64, error)
+}
+
+// randomizer returns random numbers, *rand.Rand of the math/rand/v2 package satisfies it.
+type randomizer interface {
+	IntN(n int) int
+}
+
+// player is a user taking part in a game.
+type player struct {
+	account     points.Account
+	displayName string
+}
+
+// duel is a challenge waiting for the challenged user.
+type duel struct {
+	challenger player
+	amount     int64
+}
+
+// robber is a member of a heist crew.
+type robber struct {
+	player
+	stake int64
+}
+
+// Service runs mini-games, in which users bet their points.
+type Service struct {
+	ledger ledger              // Ledger takes stakes and pays winners.
+	duels  *Sessions[duel]     // Duels holds challenges by logins of challenged users.
+	heists *Sessions[[]robber] // Heists holds crews of heists.
+	random randomizer          // Random decides outcomes of games.
+	mu     sync.Mutex          // Mu guards random.
+	logger *zap.Logger         // Logger is used for logging.
+}
+
+// NewService creates an instance of Service.
+func NewService(ledger ledger, clock Clock, random randomizer, logger *zap.Logger) *Service {
+	return &Service{
+		ledger: ledger,
+		duels:  NewSessions[duel](clock),
+		heists: NewSessions[[]robber](clock),
+		random: random,
+		logger: logger.Named("minigames"),
+	}
+}
+
+// Run waits until the context is done, then ends every pending duel and heist and refunds their stakes.
+// Games started later are refused, so stakes never outlive the chatbot.
+func (s *Service) Run(ctx context.Context) error {
+	<-ctx.Done()
+
+	ctx, span := tracer.Start(context.WithoutCancel(ctx), "refundPendingGames")
+	defer span.End()
+
+	s.duels.Close(func(channelName string, d duel) {
+		s.refund(ctx, channelName, d.challenger, d.amount)
+	})
+	s.heists.Close(func(channelName string, crew []robber) {
+		for _, r := range crew {
+			s.refund(ctx, channelName, r.player, r.stake)
+		}
+	})
+
+	span.SetStatus(codes.Ok, "successfully refunded pending games")
+	return nil
+}
+
+// Gamble bets points on a roll of 1-100, rolls above 50 double the bet.
+// Usage: `!gamble <amount>`.
+func (s *Service) Gamble() command.Handler {
+	return func(ctx context.Context, args []string, chatClient command.ChatClient) error {
+		spanCtx, span := tracer.Start(ctx, "gambleCommand")
+		defer span.End()
+
+		cmdCtx := command.UnwrapContext(ctx)
+		privMsg := cmdCtx.PrivMsg
+
+		if len(args) == 0 {
+			span.SetStatus(codes.Error, "missing arguments")
+			return command.UsageError("use the command like: !gamble <amount>")
+		}
+
+		amount, err := parseAmount(args[0])
+		if err != nil {
+			span.SetStatus(codes.Error, "invalid amount")
+			return err
+		}
+
+		gambler := playerOf(cmdCtx)
+		if err = s.stake(spanCtx, privMsg.Channel, gambler, amount); err != nil {
+			span.SetStatus(codes.Error, "user could not pay the stake")
+			return err
+		}
+
+		roll := s.roll(100) + 1
+		span.SetAttributes(attribute.Int("gamble.roll", roll), attribute.Int64("gamble.amount", amount))
+		if roll <= gambleWinRoll {
+			span.SetStatus(codes.Ok, "user lost the gamble")
+			chatClient.Say(privMsg.Channel, fmt.Sprintf("@%s, rolled %d and lost %d points.", gambler.displayName, roll, amount))
+			return nil
+		}
+
+		balance, err := s.ledger.Add(spanCtx, privMsg.Channel, gambler.account, payout*amount)
+		if err != nil {
+			span.SetStatus(codes.Error, "failed to pay the winner")
+			span.RecordError(err)
+			return command.InternalError(err)
+		}
+
+		span.SetStatus(codes.Ok, "user won the gamble")
+		chatClient.Say(privMsg.Channel, fmt.Sprintf("@%s, rolled %d and won %d points, you have %d points now.", gambler.displayName, roll, amount, balance))
+		return nil
+	}
+}
+
+// Duel challenges a user to a duel, the winner takes the stakes of both users.
+// Usage: `!duel <@user> <amount>`.
+func (s *Service) Duel() command.Handler {
+	return func(ctx context.Context, args []string, chatClient command.ChatClient) error {
+		spanCtx, span := tracer.Start(ctx, "duelCommand")
+		defer span.End()
+
+		cmdCtx := command.UnwrapContext(ctx)
+		privMsg := cmdCtx.PrivMsg
+
+		if len(args) < 2 {
+			span.SetStatus(codes.Error, "missing arguments")
+			return command.UsageError("use the command like: !duel <@user> <amount>")
+		}
+
+		target := command.TrimMention(args[0])
+		challenger := playerOf(cmdCtx)
+		if target == challenger.account.Username {
+			span.SetStatus(codes.Error, "user tried to duel themselves")
+			return command.UsageError("you can't duel yourself.")
+		}
+
+		amount, err := parseAmount(args[1])
+		if err != nil {
+			span.SetStatus(codes.Error, "invalid amount")
+			return err
+		}
+
+		if err = s.stake(spanCtx, privMsg.Channel, challenger, amount); err != nil {
+			span.SetStatus(codes.Error, "user could not pay the stake")
+			return err
+		}
+
+		timeoutCtx := context.WithoutCancel(ctx)
+		err = s.duels.Start(privMsg.Channel, target, duel{challenger: challenger, amount: amount}, duelTimeout, func(d duel) {
+			s.refund(timeoutCtx, privMsg.Channel, d.challenger, d.amount)
+			chatClient.Say(privMsg.Channel, fmt.Sprintf("@%s, %s did not accept the duel, your points were refunded.", d.challenger.displayName, target))
+		})
+		if err != nil {
+			s.refund(spanCtx, privMsg.Channel, challenger, amount)
+			if errors.Is(err, ErrSessionsClosed) {
+				span.SetStatus(codes.Error, "games are closed")
+				return errGamesClosed
+			}
+			span.SetStatus(codes.Error, "target already has a pending duel")
+			return command.UsageError(fmt.Sprintf("%s already has a pending duel.", target))
+		}
+
+		span.SetStatus(codes.Ok, "successfully started a duel")
+		chatClient.Say(privMsg.Channel, fmt.Sprintf("@%s, %s challenges you to a duel for %d points, type !accept or !decline within %s.", target, challenger.displayName, amount, duelTimeout))
+		return nil
+	}
+}
+
+// Accept accepts a duel and fights it.
+// Usage: `!accept`.
+func (s *Service) Accept() command.Handler {
+	return func(ctx context.Context, _ []string, chatClient command.ChatClient) error {
+		spanCtx, span := tracer.Start(ctx, "acceptCommand")
+		defer span.End()
+
+		cmdCtx := command.UnwrapContext(ctx)
+		privMsg := cmdCtx.PrivMsg
+		opponent := playerOf(cmdCtx)
+
+		d, ok := s.duels.End(privMsg.Channel, opponent.account.Username)
+		if !ok {
+			span.SetStatus(codes.Error, "user has no pending duel")
+			return command.UsageError("you have no pending duel.")
+		}
+
+		if err := s.stake(spanCtx, privMsg.Channel, opponent, d.amount); err != nil {
+			s.refund(spanCtx, privMsg.Channel, d.challenger, d.amount)
+			span.SetStatus(codes.Error, "user could not pay the stake")
+			return err
+		}
+
+		winner, loser := d.challenger, opponent
+		if s.roll(2) == 0 {
+			winner, loser = loser, winner
+		}
+		span.SetAttributes(attribute.String("duel.winner", winner.account.Username), attribute.Int64("duel.amount", d.amount))
+
+		if _, err := s.ledger.Add(spanCtx, privMsg.Channel, winner.account, payout*d.amount); err != nil {
+			span.SetStatus(codes.Error, "failed to pay the winner")
+			span.RecordError(err)
+			return command.InternalError(err)
+		}
+
+		span.SetStatus(codes.Ok, "successfully fought a duel")
+		chatClient.Say(privMsg.Channel, fmt.Sprintf("%s won the duel against %s and %d points!", winner.displayName, loser.displayName, d.amount))
+		return nil
+	}
+}
+
+// Decline declines a duel.
+// Usage: `!decline`.
+func (s *Service) Decline() command.Handler {
+	return func(ctx context.Context, _ []string, chatClient command.ChatClient) error {
+		spanCtx, span := tracer.Start(ctx, "declineCommand")
+		defer span.End()
+
+		cmdCtx := command.UnwrapContext(ctx)
+		privMsg := cmdCtx.PrivMsg
+		opponent := playerOf(cmdCtx)
+
+		d, ok := s.duels.End(privMsg.Channel, opponent.account.Username)
+		if !ok {
+			span.SetStatus(codes.Error, "user has no pending duel")
+			return command.UsageError("you have no pending duel.")
+		}
+
+		s.refund(spanCtx, privMsg.Channel, d.challenger, d.amount)
+		span.SetStatus(codes.Ok, "successfully declined a duel")
+		chatClient.Say(privMsg.Channel, fmt.Sprintf("@%s, %s declined the duel, your points were refunded.", d.challenger.displayName, opponent.displayName))
+		return nil
+	}
+}
+
+// Heist starts or joins a heist. When the window closes, every robber gets away with double the stake or gets caught,
+// the bigger the crew, the better its chances.
+// Usage: `!heist <amount>`.
+func (s *Service) Heist() command.Handler {
+	return func(ctx context.Context, args []string, chatClient command.ChatClient) error {
+		spanCtx, span := tracer.Start(ctx, "heistCommand")
+		defer span.End()
+
+		cmdCtx := command.UnwrapContext(ctx)
+		privMsg := cmdCtx.PrivMsg
+
+		if len(args) == 0 {
+			span.SetStatus(codes.Error, "missing arguments")
+			return command.UsageError("use the command like: !heist <amount>")
+		}
+
+		amount, err := parseAmount(args[0])
+		if err != nil {
+			span.SetStatus(codes.Error, "invalid amount")
+			return err
+		}
+
+		member := robber{player: playerOf(cmdCtx), stake: amount}
+		if err = s.stake(spanCtx, privMsg.Channel, member.player, amount); err != nil {
+			span.SetStatus(codes.Error, "user could not pay the stake")
+			return err
+		}
+
+		timeoutCtx := context.WithoutCancel(ctx)
+		for {
+			joined, err := s.heists.Update(privMsg.Channel, heistKey, func(crew *[]robber) error {
+				for _, r := range *crew {
+					if r.account.UserID == member.account.UserID {
+						return command.UsageError("you are already in the crew.")
+					}
+				}
+				*crew = append(*crew, member)
+				return nil
+			})
+			if err != nil {
+				s.refund(spanCtx, privMsg.Channel, member.player, amount)
+				span.SetStatus(codes.Error, "user could not join the heist")
+				return err
+			}
+			if joined {
+				span.SetStatus(codes.Ok, "successfully joined a heist")
+				chatClient.Say(privMsg.Channel, fmt.Sprintf("@%s, joined the heist with %d points.", member.displayName, amount))
+				return nil
+			}
+
+			err = s.heists.Start(privMsg.Channel, heistKey, []robber{member}, heistWindow, func(crew []robber) {
+				s.resolveHeist(timeoutCtx, privMsg.Channel, crew, chatClient)
+			})
+			if errors.Is(err, ErrSessionsClosed) {
+				s.refund(spanCtx, privMsg.Channel, member.player, amount)
+				span.SetStatus(codes.Error, "games are closed")
+				return errGamesClosed
+			}
+			if err == nil {
+				span.SetStatus(codes.Ok, "successfully started a heist")
+				chatClient.Say(privMsg.Channel, fmt.Sprintf("%s is planning a heist, type !heist <amount> to join within %s.", member.displayName, heistWindow))
+				return nil
+			}
+		}
+	}
+}
+
+// resolveHeist decides which robbers got away and pays them.
+func (s *Service) resolveHeist(ctx context.Context, channelName string, crew []robber, chatClient command.ChatClient) {
+	ctx, span := tracer.Start(ctx, "heist")
+	defer span.End()
+
+	chance := min(heistBaseChance+heistCrewBonus*(len(crew)-1), heistMaxChance)
+	span.SetAttributes(attribute.String("channel.name", channelName), attribute.Int("heist.crew", len(crew)), attribute.Int("heist.chance", chance))
+
+	survivors, caught := []string{}, []string{}
+	for _, r := range crew {
+		if s.roll(100) >= chance {
+			caught = append(caught, r.displayName)
+			continue
+		}
+
+		winnings := payout * r.stake
+		if _, err := s.ledger.Add(ctx, channelName, r.account, winnings); err != nil {
+			span.RecordError(err)
+			s.logger.Error("failed to pay a robber", zap.String("username", r.account.Username), zap.Int64("amount", winnings), zap.Error(err))
+		}
+		survivors = append(survivors, fmt.Sprintf("%s (+%d)", r.displayName, winnings))
+	}
+
+	span.SetStatus(codes.Ok, "successfully resolved a heist")
+
+	switch {
+	case len(caught) == 0:
+		chatClient.Say(channelName, fmt.Sprintf("The heist was a success! Everybody got away: %s", strings.Join(survivors, ", ")))
+	case len(survivors) == 0:
+		chatClient.Say(channelName, fmt.Sprintf("The heist failed, everybody was caught: %s", strings.Join(caught, ", ")))
+	default:
+		chatClient.Say(channelName, fmt.Sprintf("The heist is over! Got away: %s. Caught: %s", strings.Join(survivors, ", "), strings.Join(caught, ", ")))
+	}
+}
+
+// stake takes a stake of a player.
+func (s *Service) stake(ctx context.Context, channelName string, p player, amount int64) error {
+	balance, err := s.ledger.Spend(ctx, channelName, p.account, amount)
+	if errors.Is(err, points.ErrInsufficientPoints) {
+		return command.UsageError(fmt.Sprintf("you have only %d points.", balance))
+	}
+	if err != nil {
+		return command.InternalError(err)
+	}
+
+	return nil
+}
+
+// refund gives a stake back to a player.
+func (s *Service) refund(ctx context.Context, channelName string, p player, amount int64) {
+	if _, err := s.ledger.Add(ctx, channelName, p.account, amount); err != nil {
+		s.logger.Error("failed to refund a stake", zap.String("username", p.account.Username), zap.Int64("amount", amount), zap.Error(err))
+	}
+}
+
+// roll returns a random number in [0, n).
+func (s *Service) roll(n int) int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	return s.random.IntN(n)
+}
+
+// playerOf returns the author of a command.
+func playerOf(cmdCtx *command.Context) player {
+	user := cmdCtx.PrivMsg.User
+	return player{account: points.Account{UserID: user.ID, Username: strings.ToLower(user.Name)}, displayName: user.DisplayName}
+}
+
+// parseAmount parses a positive number of points.
+func parseAmount(arg string) (int64, error) {
+	amount, err := strconv.ParseInt(arg, 10, 64)
+	if err != nil || amount <= 0 {
+		return 0, command.UsageError("the amount has to be a positive number.")
+	}
+
+	return amount, nil
+}
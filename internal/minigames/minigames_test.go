@@ -0,0 +1,323 @@
+package minigames
+
+import (
+	"context"
+	"errors"
+	"maps"
+	"slices"
+	"testing"
+	"time"
+
+	"github.com/danielbukowski/twitch-chatbot/internal/command"
+	"github.com/danielbukowski/twitch-chatbot/internal/points"
+	"github.com/gempir/go-twitch-irc/v4"
+	"go.uber.org/zap"
+)
+
+// fakeTimer is a call scheduled on fakeClock.
+type fakeTimer struct {
+	at      time.Duration
+	f       func()
+	stopped bool
+}
+
+func (t *fakeTimer) Stop() bool {
+	wasRunning := !t.stopped
+	t.stopped = true
+	return wasRunning
+}
+
+// fakeClock calls scheduled functions only, when a test advances it.
+type fakeClock struct {
+	elapsed time.Duration
+	timers  []*fakeTimer
+}
+
+func (c *fakeClock) AfterFunc(d time.Duration, f func()) Timer {
+	timer := &fakeTimer{at: c.elapsed + d, f: f}
+	c.timers = append(c.timers, timer)
+	return timer
+}
+
+func (c *fakeClock) Advance(d time.Duration) {
+	c.elapsed += d
+	for _, timer := range c.timers {
+		if !timer.stopped && timer.at <= c.elapsed {
+			timer.stopped = true
+			timer.f()
+		}
+	}
+}
+
+// scriptedRandom returns numbers in a given order.
+type scriptedRandom []int
+
+func (r *scriptedRandom) IntN(_ int) int {
+	next := (*r)[0]
+	*r = (*r)[1:]
+	return next
+}
+
+// ledgerMock keeps balances by user IDs in memory.
+type ledgerMock map[string]int64
+
+func (l ledgerMock) Spend(_ context.Context, _ string, account points.Account, amount int64) (int64, error) {
+	if l[account.UserID] < amount {
+		return l[account.UserID], points.ErrInsufficientPoints
+	}
+	l[account.UserID] -= amount
+	return l[account.UserID], nil
+}
+
+func (l ledgerMock) Add(_ context.Context, _ string, account points.Account, amount int64) (int64, error) {
+	l[account.UserID] += amount
+	return l[account.UserID], nil
+}
+
+type chatClientMock struct {
+	messages []string
+}
+
+func (c *chatClientMock) Say(_, message string) {
+	c.messages = append(c.messages, message)
+}
+
+func (c *chatClientMock) Reply(_, _, message string) {
+	c.messages = append(c.messages, message)
+}
+
+func (c *chatClientMock) Join(_ ...string) {}
+
+func (c *chatClientMock) Depart(_ string) {}
+
+func commandContext(commandName, userID, username string) context.Context {
+	privMsg := &twitch.PrivateMessage{Channel: "channel", User: twitch.User{ID: userID, Name: username, DisplayName: username}}
+	return command.WithContext(context.Background(), command.NewContext(commandName, privMsg, zap.NewNop()))
+}
+
+func TestSessions(t *testing.T) {
+	t.Run("ends a session after its timeout", func(t *testing.T) {
+		// given
+		clock := &fakeClock{}
+		sessions := NewSessions[int](clock)
+		timedOut := []int{}
+		sessions.Start("channel", "key", 1, time.Minute, func(state int) { timedOut = append(timedOut, state) })
+		_, _ = sessions.Update("channel", "key", func(state *int) error {
+			*state++
+			return nil
+		})
+
+		// when
+		clock.Advance(time.Minute)
+
+		// then
+		if !slices.Equal(timedOut, []int{2}) {
+			t.Fatalf("Expected the last state to time out, got `%v`", timedOut)
+		}
+		if _, ok := sessions.End("channel", "key"); ok {
+			t.Fatal("Expected the session to be ended")
+		}
+	})
+
+	t.Run("does not time out an ended session", func(t *testing.T) {
+		// given
+		clock := &fakeClock{}
+		sessions := NewSessions[int](clock)
+		timedOut := false
+		sessions.Start("channel", "key", 1, time.Minute, func(_ int) { timedOut = true })
+
+		// when
+		state, ok := sessions.End("channel", "key")
+		clock.Advance(time.Minute)
+
+		// then
+		if !ok || state != 1 || timedOut {
+			t.Fatalf("Expected the session to end without a timeout, got state %d, ended %t, timed out %t", state, ok, timedOut)
+		}
+	})
+
+	t.Run("keeps sessions of channels apart", func(t *testing.T) {
+		// given
+		sessions := NewSessions[int](&fakeClock{})
+		sessions.Start("first", "key", 1, time.Minute, func(_ int) {})
+
+		// when
+		err := sessions.Start("second", "key", 2, time.Minute, func(_ int) {})
+		errAgain := sessions.Start("first", "key", 3, time.Minute, func(_ int) {})
+
+		// then
+		if err != nil || !errors.Is(errAgain, ErrSessionRunning) {
+			t.Fatalf("Expected only the session of another channel to start, got `%v` and `%v`", err, errAgain)
+		}
+	})
+
+	t.Run("ends every session and refuses new ones after closing", func(t *testing.T) {
+		// given
+		clock := &fakeClock{}
+		sessions := NewSessions[int](clock)
+		timedOut := false
+		sessions.Start("first", "key", 1, time.Minute, func(_ int) { timedOut = true })
+		sessions.Start("second", "key", 2, time.Minute, func(_ int) { timedOut = true })
+
+		// when
+		closed := map[string]int{}
+		sessions.Close(func(channelName string, state int) { closed[channelName] = state })
+		err := sessions.Start("first", "key", 3, time.Minute, func(_ int) {})
+		clock.Advance(time.Minute)
+
+		// then
+		if !maps.Equal(closed, map[string]int{"first": 1, "second": 2}) {
+			t.Fatalf("Expected both sessions to be closed, got `%v`", closed)
+		}
+		if !errors.Is(err, ErrSessionsClosed) {
+			t.Fatalf("Expected `%v`, got `%v`", ErrSessionsClosed, err)
+		}
+		if timedOut {
+			t.Fatal("Expected closed sessions not to time out")
+		}
+	})
+}
+
+func TestRun(t *testing.T) {
+	// given
+	ledger := ledgerMock{"1": 100, "2": 100, "3": 100}
+	service := NewService(ledger, &fakeClock{}, &scriptedRandom{}, zap.NewNop())
+	_ = service.Duel()(commandContext("!duel", "1", "challenger"), []string{"opponent", "50"}, &chatClientMock{})
+	_ = service.Heist()(commandContext("!heist", "2", "robber"), []string{"30"}, &chatClientMock{})
+	ctx, cancel := context.WithCancel(context.Background())
+
+	// when
+	cancel()
+	err := service.Run(ctx)
+	heistErr := service.Heist()(commandContext("!heist", "3", "latecomer"), []string{"10"}, &chatClientMock{})
+
+	// then
+	if err != nil {
+		t.Fatalf("Expected no error, got `%v`", err)
+	}
+	if command.Classify(heistErr) != command.KindUsage {
+		t.Fatalf("Expected a usage error after shutdown, got `%v`", heistErr)
+	}
+	if ledger["1"] != 100 || ledger["2"] != 100 || ledger["3"] != 100 {
+		t.Fatalf("Expected every stake to be refunded, got `%v`", ledger)
+	}
+}
+
+func TestGamble(t *testing.T) {
+	testCases := []struct {
+		name            string
+		roll            int
+		expectedBalance int64
+	}{
+		{name: "wins double the bet above 50", roll: 50, expectedBalance: 200},
+		{name: "loses the bet up to 50", roll: 49, expectedBalance: 0},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			// given
+			ledger := ledgerMock{"1": 100}
+			service := NewService(ledger, &fakeClock{}, &scriptedRandom{tc.roll}, zap.NewNop())
+
+			// when
+			err := service.Gamble()(commandContext("!gamble", "1", "viewer"), []string{"100"}, &chatClientMock{})
+
+			// then
+			if err != nil {
+				t.Fatalf("Expected no error, got `%v`", err)
+			}
+			if ledger["1"] != tc.expectedBalance {
+				t.Fatalf("Expected a balance of %d, got %d", tc.expectedBalance, ledger["1"])
+			}
+		})
+	}
+}
+
+func TestDuel(t *testing.T) {
+	t.Run("pays the winner with stakes of both users", func(t *testing.T) {
+		// given
+		ledger := ledgerMock{"1": 100, "2": 100}
+		service := NewService(ledger, &fakeClock{}, &scriptedRandom{1}, zap.NewNop())
+		chatClient := &chatClientMock{}
+		_ = service.Duel()(commandContext("!duel", "1", "challenger"), []string{"@Opponent", "50"}, chatClient)
+
+		// when
+		err := service.Accept()(commandContext("!accept", "2", "opponent"), nil, chatClient)
+
+		// then
+		if err != nil {
+			t.Fatalf("Expected no error, got `%v`", err)
+		}
+		if ledger["1"] != 150 || ledger["2"] != 50 {
+			t.Fatalf("Expected the challenger to win, got `%v`", ledger)
+		}
+		if chatClient.messages[len(chatClient.messages)-1] != "challenger won the duel against opponent and 50 points!" {
+			t.Fatalf("Expected an announcement of the winner, got `%v`", chatClient.messages)
+		}
+	})
+
+	t.Run("refunds the challenger, when the duel is not accepted in time", func(t *testing.T) {
+		// given
+		ledger := ledgerMock{"1": 100}
+		clock := &fakeClock{}
+		service := NewService(ledger, clock, &scriptedRandom{}, zap.NewNop())
+		chatClient := &chatClientMock{}
+		_ = service.Duel()(commandContext("!duel", "1", "challenger"), []string{"opponent", "50"}, chatClient)
+
+		// when
+		clock.Advance(duelTimeout)
+		err := service.Accept()(commandContext("!accept", "2", "opponent"), nil, chatClient)
+
+		// then
+		if command.Classify(err) != command.KindUsage {
+			t.Fatalf("Expected a usage error after the timeout, got `%v`", err)
+		}
+		if ledger["1"] != 100 {
+			t.Fatalf("Expected the stake to be refunded, got `%v`", ledger)
+		}
+	})
+
+	t.Run("refunds the challenger, when the opponent does not have enough points", func(t *testing.T) {
+		// given
+		ledger := ledgerMock{"1": 100, "2": 10}
+		service := NewService(ledger, &fakeClock{}, &scriptedRandom{}, zap.NewNop())
+		_ = service.Duel()(commandContext("!duel", "1", "challenger"), []string{"opponent", "50"}, &chatClientMock{})
+
+		// when
+		err := service.Accept()(commandContext("!accept", "2", "opponent"), nil, &chatClientMock{})
+
+		// then
+		if command.Classify(err) != command.KindUsage {
+			t.Fatalf("Expected a usage error, got `%v`", err)
+		}
+		if ledger["1"] != 100 || ledger["2"] != 10 {
+			t.Fatalf("Expected balances not to change, got `%v`", ledger)
+		}
+	})
+}
+
+func TestHeist(t *testing.T) {
+	// given
+	ledger := ledgerMock{"1": 100, "2": 100, "3": 100}
+	clock := &fakeClock{}
+	// A crew of three has a 50% chance, so the first robber gets away and the others are caught.
+	service := NewService(ledger, clock, &scriptedRandom{49, 50, 99}, zap.NewNop())
+	chatClient := &chatClientMock{}
+	for _, user := range [][2]string{{"1", "first"}, {"2", "second"}, {"3", "third"}} {
+		if err := service.Heist()(commandContext("!heist", user[0], user[1]), []string{"100"}, chatClient); err != nil {
+			t.Fatalf("Expected no error, got `%v`", err)
+		}
+	}
+
+	// when
+	clock.Advance(heistWindow)
+
+	// then
+	if ledger["1"] != 200 || ledger["2"] != 0 || ledger["3"] != 0 {
+		t.Fatalf("Expected only the first robber to get away, got `%v`", ledger)
+	}
+	expected := "The heist is over! Got away: first (+200). Caught: second, third"
+	if chatClient.messages[len(chatClient.messages)-1] != expected {
+		t.Fatalf("Expected `%s`, got `%v`", expected, chatClient.messages)
+	}
+}
@@ -0,0 +1,138 @@
+package minigames
+
+import (
+	"errors"
+	"sync"
+	"time"
+)
+
+var (
+	// ErrSessionRunning is returned, when a session with the same key is already running.
+	ErrSessionRunning = errors.New("session is already running")
+	// ErrSessionsClosed is returned, when a session is started after sessions were closed.
+	ErrSessionsClosed = errors.New("sessions are closed")
+)
+
+// Timer is a scheduled call, which can be cancelled.
+type Timer interface {
+	// Stop cancels the call. It returns false, when the call already happened or was cancelled.
+	Stop() bool
+}
+
+// Clock schedules timeouts of game sessions, tests replace it to control time.
+type Clock interface {
+	// AfterFunc calls f in its own goroutine after the duration.
+	AfterFunc(d time.Duration, f func()) Timer
+}
+
+// SystemClock schedules calls with timers of the time package.
+type SystemClock struct{}
+
+// AfterFunc calls f in its own goroutine after the duration.
+func (SystemClock) AfterFunc(d time.Duration, f func()) Timer {
+	return time.AfterFunc(d, f)
+}
+
+// session is a state of a game with a timer, which ends the game, when nobody ended it earlier.
+type session[T any] struct {
+	channelName string
+	state       T
+	timer       Timer
+}
+
+// Sessions holds states of running games per channel. Every session is identified by its key within a channel
+// and ends either explicitly, or after its timeout, whichever happens first.
+type Sessions[T any] struct {
+	clock    Clock                  // Clock schedules timeouts.
+	sessions map[string]*session[T] // Sessions holds running games by their channels and keys.
+	closed   bool                   // Closed tells, if new sessions are refused.
+	mu       sync.Mutex             // Mu guards sessions and closed.
+}
+
+// NewSessions creates an instance of Sessions.
+func NewSessions[T any](clock Clock) *Sessions[T] {
+	return &Sessions[T]{clock: clock, sessions: make(map[string]*session[T])}
+}
+
+// Start starts a session with an initial state. After the timeout, the session ends and onTimeout is called with its last state.
+// It returns ErrSessionRunning, when the session is already running, and ErrSessionsClosed after Close.
+func (s *Sessions[T]) Start(channelName, key string, state T, timeout time.Duration, onTimeout func(state T)) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.closed {
+		return ErrSessionsClosed
+	}
+
+	id := sessionID(channelName, key)
+	if _, ok := s.sessions[id]; ok {
+		return ErrSessionRunning
+	}
+
+	s.sessions[id] = &session[T]{
+		channelName: channelName,
+		state:       state,
+		timer: s.clock.AfterFunc(timeout, func() {
+			if state, ok := s.End(channelName, key); ok {
+				onTimeout(state)
+			}
+		}),
+	}
+
+	return nil
+}
+
+// Update changes a state of a running session. It returns false, when the session is not running.
+// The change must not call other methods of Sessions.
+func (s *Sessions[T]) Update(channelName, key string, change func(state *T) error) (bool, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	current, ok := s.sessions[sessionID(channelName, key)]
+	if !ok {
+		return false, nil
+	}
+
+	return true, change(&current.state)
+}
+
+// End ends a running session before its timeout and returns its last state. It returns false, when the session is not running.
+func (s *Sessions[T]) End(channelName, key string) (T, bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	id := sessionID(channelName, key)
+	current, ok := s.sessions[id]
+	if !ok {
+		var zero T
+		return zero, false
+	}
+
+	delete(s.sessions, id)
+	current.timer.Stop()
+
+	return current.state, true
+}
+
+// Close ends every running session without calling its onTimeout and refuses new sessions.
+// The onClose is called with a channel and the last state of every ended session.
+func (s *Sessions[T]) Close(onClose func(channelName string, state T)) {
+	s.mu.Lock()
+	s.closed = true
+	ended := make([]*session[T], 0, len(s.sessions))
+	for id, current := range s.sessions {
+		delete(s.sessions, id)
+		current.timer.Stop()
+		ended = append(ended, current)
+	}
+	s.mu.Unlock()
+
+	for _, current := range ended {
+		onClose(current.channelName, current.state)
+	}
+}
+
+// sessionID identifies a session by its channel and key.
+func sessionID(channelName, key string) string {
+	return channelName + ":" + key
+}
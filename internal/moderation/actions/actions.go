@@ -0,0 +1,310 @@
+package actions
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/danielbukowski/twitch-chatbot/internal/command"
+	"github.com/danielbukowski/twitch-chatbot/internal/moderation"
+	twitchapi "github.com/danielbukowski/twitch-chatbot/internal/twitch_api"
+	"github.com/gempir/go-twitch-irc/v4"
+	"github.com/nicklaw5/helix/v2"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/trace"
+	"go.uber.org/zap"
+)
+
+// maxTimeout is the longest timeout allowed by Twitch.
+const maxTimeout = 14 * 24 * time.Hour
+
+// Record describes a moderation action saved in the audit log.
+type Record struct {
+	ChannelName   string        // ChannelName is a name of the channel, where the action was executed.
+	BroadcasterID string        // BroadcasterID is an ID of the channel.
+	Action        string        // Action is a type of the action, like `timeout` or `ban`.
+	UserID        string        // UserID is an ID of the punished user.
+	Username      string        // Username is a login of the punished user.
+	MessageID     string        // MessageID is an ID of a deleted message, it's used only for deleting messages.
+	Moderator     string        // Moderator is a login of the user who requested the action, or the chatbot for automatic actions.
+	Reason        string        // Reason is a reason of the action.
+	Trigger       string        // Trigger tells what caused the action, like `rule:caps` or `command:!ban`.
+	Duration      time.Duration // Duration is a length of a timeout.
+}
+
+type storage interface {
+	Save(ctx context.Context, record Record) error
+}
+
+// userResolver returns a user with the given login.
+type userResolver interface {
+	UserByLogin(ctx context.Context, login string) (helix.User, error)
+}
+
+// chatClient replies to messages on the chat.
+type chatClient interface {
+	Reply(channelName, parentMessageID, message string)
+}
+
+// Service executes moderation actions through Twitch API and records every one of them in the audit log.
+// It requires a user access token of a moderator with the moderator:manage:banned_users and moderator:manage:chat_messages scopes.
+type Service struct {
+	helixClient  *helix.Client // HelixClient is used to call Twitch API.
+	storage      storage       // Storage persists the audit log.
+	userResolver userResolver  // UserResolver resolves logins of users to their IDs.
+	chatClient   chatClient    // ChatClient is used to tell users why they were punished by rules.
+	moderatorID  string        // ModeratorID is an ID of the user that executes actions, usually the chatbot.
+	chatbotName  string        // ChatbotName is saved as a moderator of actions triggered by rules.
+	prefix       string        // Prefix is a prefix of commands.
+	logger       *zap.Logger   // Logger is used for logging.
+}
+
+// NewService creates an instance of Service.
+func NewService(helixClient *helix.Client, storage storage, userResolver userResolver, chatClient chatClient, moderatorID, chatbotName, prefix string, logger *zap.Logger) *Service {
+	return &Service{
+		helixClient:  helixClient,
+		storage:      storage,
+		userResolver: userResolver,
+		chatClient:   chatClient,
+		moderatorID:  moderatorID,
+		chatbotName:  chatbotName,
+		prefix:       prefix,
+		logger:       logger.Named("moderation/actions"),
+	}
+}
+
+// Timeout prevents a user from chatting for the duration of the record.
+func (s *Service) Timeout(ctx context.Context, record Record) error {
+	record.Action = "timeout"
+	record.Duration = min(max(record.Duration, time.Second), maxTimeout)
+
+	return s.execute(ctx, record, func() (helix.ResponseCommon, error) {
+		resp, err := s.helixClient.BanUser(&helix.BanUserParams{
+			BroadcasterID: record.BroadcasterID,
+			ModeratorId:   s.moderatorID,
+			Body:          helix.BanUserRequestBody{UserId: record.UserID, Reason: record.Reason, Duration: int(record.Duration.Seconds())},
+		})
+		if err != nil {
+			return helix.ResponseCommon{}, err
+		}
+		return resp.ResponseCommon, nil
+	})
+}
+
+// Ban permanently prevents a user from chatting.
+func (s *Service) Ban(ctx context.Context, record Record) error {
+	record.Action = "ban"
+	record.Duration = 0
+
+	return s.execute(ctx, record, func() (helix.ResponseCommon, error) {
+		resp, err := s.helixClient.BanUser(&helix.BanUserParams{
+			BroadcasterID: record.BroadcasterID,
+			ModeratorId:   s.moderatorID,
+			Body:          helix.BanUserRequestBody{UserId: record.UserID, Reason: record.Reason},
+		})
+		if err != nil {
+			return helix.ResponseCommon{}, err
+		}
+		return resp.ResponseCommon, nil
+	})
+}
+
+// Unban removes a ban or a timeout of a user.
+func (s *Service) Unban(ctx context.Context, record Record) error {
+	record.Action = "unban"
+
+	return s.execute(ctx, record, func() (helix.ResponseCommon, error) {
+		resp, err := s.helixClient.UnbanUser(&helix.UnbanUserParams{
+			BroadcasterID: record.BroadcasterID,
+			ModeratorID:   s.moderatorID,
+			UserID:        record.UserID,
+		})
+		if err != nil {
+			return helix.ResponseCommon{}, err
+		}
+		return resp.ResponseCommon, nil
+	})
+}
+
+// DeleteMessage removes a single message from the chat.
+func (s *Service) DeleteMessage(ctx context.Context, record Record) error {
+	record.Action = "delete"
+
+	return s.execute(ctx, record, func() (helix.ResponseCommon, error) {
+		resp, err := s.helixClient.DeleteChatMessage(&helix.DeleteChatMessageParams{
+			BroadcasterID: record.BroadcasterID,
+			ModeratorID:   s.moderatorID,
+			MessageID:     record.MessageID,
+		})
+		if err != nil {
+			return helix.ResponseCommon{}, err
+		}
+		return resp.ResponseCommon, nil
+	})
+}
+
+// execute calls Twitch API and saves the action in the audit log.
+// A failure of the audit log does not fail the action, because the action was already executed.
+func (s *Service) execute(ctx context.Context, record Record, call func() (helix.ResponseCommon, error)) error {
+	spanCtx, span := tracer.Start(ctx, record.Action)
+	defer span.End()
+
+	span.SetAttributes(
+		attribute.String("moderation.action", record.Action),
+		attribute.String("moderation.trigger", record.Trigger),
+		attribute.String("moderation.target", record.Username),
+	)
+
+	resp, err := call()
+	if err == nil {
+		err = twitchapi.ResponseError(resp)
+	}
+	if err != nil {
+		span.SetStatus(codes.Error, "failed to execute a moderation action")
+		span.RecordError(err)
+		return err
+	}
+
+	if err = s.storage.Save(spanCtx, record); err != nil {
+		s.logger.Error("failed to save a moderation action to the audit log", zap.String("action", record.Action), zap.Error(err))
+	}
+
+	span.SetStatus(codes.Ok, "successfully executed a moderation action")
+	return nil
+}
+
+// Enforce executes a punishment for a broken rule and tells the user why it happened.
+func (s *Service) Enforce(ctx context.Context, privMsg *twitch.PrivateMessage, violation moderation.Violation) error {
+	record := Record{
+		ChannelName:   privMsg.Channel,
+		BroadcasterID: privMsg.RoomID,
+		UserID:        privMsg.User.ID,
+		Username:      strings.ToLower(privMsg.User.Name),
+		MessageID:     privMsg.ID,
+		Moderator:     s.chatbotName,
+		Reason:        violation.Reason,
+		Trigger:       "rule:" + violation.Rule,
+		Duration:      violation.Punishment.Duration,
+	}
+
+	var err error
+	switch violation.Punishment.Action {
+	case moderation.ActionDelete:
+		err = s.DeleteMessage(ctx, record)
+	case moderation.ActionTimeout:
+		err = s.Timeout(ctx, record)
+	case moderation.ActionBan:
+		err = s.Ban(ctx, record)
+	}
+	if err != nil {
+		return err
+	}
+
+	if violation.Punishment.Action != moderation.ActionBan {
+		s.chatClient.Reply(privMsg.Channel, privMsg.ID, fmt.Sprintf("@%s, %s", privMsg.User.DisplayName, violation.Reason))
+	}
+
+	return nil
+}
+
+// TimeoutCommand times out a user.
+// Usage: `!timeout @user <duration> [reason]`, where duration is a number of seconds or a value like `10m`.
+func (s *Service) TimeoutCommand() command.Handler {
+	return func(ctx context.Context, args []string, chatClient command.ChatClient) error {
+		spanCtx, span := tracer.Start(ctx, "timeoutCommand")
+		defer span.End()
+
+		if len(args) < 2 {
+			span.SetStatus(codes.Error, "missing arguments")
+			return command.UsageError(fmt.Sprintf("usage: %stimeout @user <duration> [reason]", s.prefix))
+		}
+
+		duration, err := command.ParseDuration(args[1])
+		if err != nil {
+			span.SetStatus(codes.Error, "invalid duration")
+			return command.UsageError(fmt.Sprintf("invalid duration '%s', use seconds or a value like 10m", args[1]))
+		}
+
+		return s.moderateUser(spanCtx, "timeout", args[0], strings.Join(args[2:], " "), duration, chatClient, s.Timeout)
+	}
+}
+
+// BanCommand bans a user.
+// Usage: `!ban @user [reason]`.
+func (s *Service) BanCommand() command.Handler {
+	return func(ctx context.Context, args []string, chatClient command.ChatClient) error {
+		spanCtx, span := tracer.Start(ctx, "banCommand")
+		defer span.End()
+
+		if len(args) < 1 {
+			span.SetStatus(codes.Error, "missing arguments")
+			return command.UsageError(fmt.Sprintf("usage: %sban @user [reason]", s.prefix))
+		}
+
+		return s.moderateUser(spanCtx, "ban", args[0], strings.Join(args[1:], " "), 0, chatClient, s.Ban)
+	}
+}
+
+// UnbanCommand removes a ban or a timeout of a user.
+// Usage: `!unban @user`.
+func (s *Service) UnbanCommand() command.Handler {
+	return func(ctx context.Context, args []string, chatClient command.ChatClient) error {
+		spanCtx, span := tracer.Start(ctx, "unbanCommand")
+		defer span.End()
+
+		if len(args) < 1 {
+			span.SetStatus(codes.Error, "missing arguments")
+			return command.UsageError(fmt.Sprintf("usage: %sunban @user", s.prefix))
+		}
+
+		return s.moderateUser(spanCtx, "unban", args[0], "", 0, chatClient, s.Unban)
+	}
+}
+
+// moderateUser resolves a mentioned user and executes an action requested by a moderator.
+// The command name is given without the prefix, and the outcome is recorded on the span of the calling handler.
+func (s *Service) moderateUser(ctx context.Context, commandName, mention, reason string, duration time.Duration, chatClient command.ChatClient, action func(context.Context, Record) error) error {
+	span := trace.SpanFromContext(ctx)
+	cmdCtx := command.UnwrapContext(ctx)
+	username := command.TrimMention(mention)
+	span.SetAttributes(attribute.String("moderation.target", username))
+
+	user, err := s.userResolver.UserByLogin(ctx, username)
+	if errors.Is(err, twitchapi.ErrUserNotFound) {
+		span.SetStatus(codes.Error, "user does not exist")
+		return command.UsageError(fmt.Sprintf("user %s does not exist", username))
+	}
+	if err != nil {
+		span.SetStatus(codes.Error, "failed to resolve the user")
+		span.RecordError(err)
+		return command.UpstreamError(err)
+	}
+
+	if len(reason) == 0 {
+		reason = "no reason given"
+	}
+
+	record := Record{
+		ChannelName:   cmdCtx.PrivMsg.Channel,
+		BroadcasterID: cmdCtx.PrivMsg.RoomID,
+		UserID:        user.ID,
+		Username:      user.Login,
+		Moderator:     strings.ToLower(cmdCtx.PrivMsg.User.Name),
+		Reason:        reason,
+		Trigger:       "command:" + s.prefix + commandName,
+		Duration:      duration,
+	}
+
+	if err = action(ctx, record); err != nil {
+		span.SetStatus(codes.Error, "failed to execute the moderation action")
+		span.RecordError(err)
+		return command.UpstreamError(err)
+	}
+
+	span.SetStatus(codes.Ok, "successfully moderated the user")
+	chatClient.Say(cmdCtx.PrivMsg.Channel, fmt.Sprintf("@%s, done: %s %s", cmdCtx.PrivMsg.User.DisplayName, commandName, user.DisplayName))
+	return nil
+}
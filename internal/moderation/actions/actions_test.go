@@ -0,0 +1,149 @@
+package actions
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/danielbukowski/twitch-chatbot/internal/command"
+	"github.com/danielbukowski/twitch-chatbot/internal/helixtest"
+	"github.com/danielbukowski/twitch-chatbot/internal/moderation"
+	"github.com/gempir/go-twitch-irc/v4"
+	"github.com/nicklaw5/helix/v2"
+	"go.uber.org/zap"
+)
+
+type storageMock struct {
+	records []Record
+}
+
+func (s *storageMock) Save(_ context.Context, record Record) error {
+	s.records = append(s.records, record)
+	return nil
+}
+
+type replyChatClientMock struct {
+	replies []string
+}
+
+func (r *replyChatClientMock) Reply(_, _, message string) {
+	r.replies = append(r.replies, message)
+}
+
+func TestServiceEnforce(t *testing.T) {
+	t.Run("times out a user through Twitch API and records it in the audit log", func(t *testing.T) {
+		// given
+		var got helix.BanUserParams
+		helixClient := helixtest.NewClient(t, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if r.Method != http.MethodPost || r.URL.Path != "/moderation/bans" {
+				w.WriteHeader(http.StatusNotFound)
+				return
+			}
+			got.BroadcasterID = r.URL.Query().Get("broadcaster_id")
+			got.ModeratorId = r.URL.Query().Get("moderator_id")
+			_ = json.NewDecoder(r.Body).Decode(&got)
+
+			w.Header().Set("Content-Type", "application/json")
+			_, _ = w.Write([]byte(`{"data":[]}`))
+		}))
+
+		storage := &storageMock{}
+		chatClient := &replyChatClientMock{}
+		service := NewService(helixClient, storage, nil, chatClient, "bot-id", "chatbot", "!", zap.NewNop())
+		privMsg := &twitch.PrivateMessage{Channel: "channel", RoomID: "channel-id", User: twitch.User{ID: "user-id", Name: "Viewer", DisplayName: "Viewer"}}
+		violation := moderation.Violation{Rule: "caps", Reason: "too many capital letters", Punishment: moderation.Punishment{Action: moderation.ActionTimeout, Duration: time.Minute}}
+
+		// when
+		err := service.Enforce(context.Background(), privMsg, violation)
+
+		// then
+		if err != nil {
+			t.Fatalf("Expected no error, got `%v`", err)
+		}
+		if got.BroadcasterID != "channel-id" || got.ModeratorId != "bot-id" || got.Body.UserId != "user-id" || got.Body.Duration != 60 {
+			t.Errorf("Expected a timeout of user-id for 60 seconds, got `%+v`", got)
+		}
+		if len(chatClient.replies) != 1 {
+			t.Errorf("Expected the user to be told about the timeout, got `%v`", chatClient.replies)
+		}
+		if len(storage.records) != 1 || storage.records[0].Trigger != "rule:caps" || storage.records[0].Moderator != "chatbot" || storage.records[0].Username != "viewer" {
+			t.Errorf("Expected the timeout to be recorded as triggered by the caps rule, got `%+v`", storage.records)
+		}
+	})
+
+	t.Run("returns an error and does not record anything, when Twitch API rejects the punishment", func(t *testing.T) {
+		// given
+		helixClient := helixtest.NewClient(t, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			w.Header().Set("Content-Type", "application/json")
+			w.WriteHeader(http.StatusForbidden)
+			_, _ = w.Write([]byte(`{"error":"Forbidden","status":403,"message":"missing scope"}`))
+		}))
+
+		storage := &storageMock{}
+		service := NewService(helixClient, storage, nil, &replyChatClientMock{}, "bot-id", "chatbot", "!", zap.NewNop())
+		privMsg := &twitch.PrivateMessage{ID: "message-id", RoomID: "channel-id", User: twitch.User{ID: "user-id"}}
+
+		// when
+		err := service.Enforce(context.Background(), privMsg, moderation.Violation{Punishment: moderation.Punishment{Action: moderation.ActionDelete}})
+
+		// then
+		if err == nil {
+			t.Errorf("Expected an error, got nil")
+		}
+		if len(storage.records) != 0 {
+			t.Errorf("Expected no records in the audit log, got `%+v`", storage.records)
+		}
+	})
+}
+
+type userResolverMock struct{}
+
+func (userResolverMock) UserByLogin(_ context.Context, login string) (helix.User, error) {
+	return helix.User{ID: login + "-id", Login: login, DisplayName: login}, nil
+}
+
+func TestBanCommand(t *testing.T) {
+	// given
+	helixClient := helixtest.NewClient(t, http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		_, _ = w.Write([]byte(`{"data":[]}`))
+	}))
+
+	storage := &storageMock{}
+	service := NewService(helixClient, storage, userResolverMock{}, &replyChatClientMock{}, "bot-id", "chatbot", "?", zap.NewNop())
+	privMsg := &twitch.PrivateMessage{Channel: "channel", RoomID: "channel-id", User: twitch.User{ID: "moderator-id", Name: "Moderator", DisplayName: "Moderator"}}
+	ctx := command.WithContext(context.Background(), command.NewContext("?ban", privMsg, zap.NewNop()))
+	chatClient := &sayChatClientMock{}
+
+	// when
+	err := service.BanCommand()(ctx, []string{"@spammer", "spam"}, chatClient)
+
+	// then
+	if err != nil {
+		t.Fatalf("Expected no error, got `%v`", err)
+	}
+	if len(storage.records) != 1 || storage.records[0].Trigger != "command:?ban" || storage.records[0].Username != "spammer" {
+		t.Errorf("Expected the ban to be recorded as triggered by ?ban, got `%+v`", storage.records)
+	}
+	if len(chatClient.messages) != 1 || chatClient.messages[0] != "@Moderator, done: ban spammer" {
+		t.Errorf("Expected a confirmation, got `%v`", chatClient.messages)
+	}
+}
+
+type sayChatClientMock struct {
+	messages []string
+}
+
+func (c *sayChatClientMock) Say(_, message string) {
+	c.messages = append(c.messages, message)
+}
+
+func (c *sayChatClientMock) Reply(_, _, message string) {
+	c.messages = append(c.messages, message)
+}
+
+func (c *sayChatClientMock) Join(_ ...string) {}
+
+func (c *sayChatClientMock) Depart(_ string) {}
@@ -0,0 +1,55 @@
+package actions
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+
+	"github.com/danielbukowski/twitch-chatbot/internal/database"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/codes"
+)
+
+var tracer = otel.Tracer("github.com/danielbukowski/twitch-chatbot/internal/moderation/actions")
+
+// SQLiteStorage stores the audit log of moderation actions.
+type SQLiteStorage struct {
+	db *sql.DB
+}
+
+func NewSQLiteStorage(db *sql.DB) *SQLiteStorage {
+	return &SQLiteStorage{db: db}
+}
+
+// Save adds an executed action to the audit log.
+func (s *SQLiteStorage) Save(ctx context.Context, record Record) error {
+	query := `INSERT INTO moderation_audit (channel_name, action, target_user_id, target_username, message_id, moderator, reason, trigger, duration_seconds)
+		VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?);`
+
+	ctx, span := tracer.Start(ctx, "save")
+	defer span.End()
+
+	ctx, cancel := context.WithTimeout(ctx, database.RequestTimeout)
+	defer cancel()
+
+	_, err := s.db.ExecContext(ctx, query,
+		record.ChannelName,
+		record.Action,
+		record.UserID,
+		record.Username,
+		record.MessageID,
+		record.Moderator,
+		record.Reason,
+		record.Trigger,
+		int64(record.Duration.Seconds()),
+	)
+	if err != nil {
+		errMsg := "failed to save a moderation action to the audit log"
+		span.SetStatus(codes.Error, errMsg)
+		span.RecordError(err)
+		return errors.Join(errors.New(errMsg), err)
+	}
+
+	span.SetStatus(codes.Ok, "successfully saved a moderation action to the audit log")
+	return nil
+}
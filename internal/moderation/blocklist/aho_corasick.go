@@ -0,0 +1,91 @@
+package blocklist
+
+import "unicode/utf8"
+
+// ahoCorasick finds all patterns in a text in a single pass, no matter how many patterns there are.
+type ahoCorasick struct {
+	nodes []acNode
+}
+
+type acNode struct {
+	next    map[rune]int // Next holds transitions to child nodes.
+	fail    int          // Fail is a node with the longest suffix of this node, that is also a prefix of any pattern.
+	outputs []int        // Outputs holds indexes of patterns that end in this node, including ones reached by fail links.
+}
+
+// newAhoCorasick builds an automaton for the patterns. Empty patterns are ignored.
+func newAhoCorasick(patterns []string) *ahoCorasick {
+	ac := &ahoCorasick{nodes: []acNode{{next: make(map[rune]int)}}}
+
+	for i, pattern := range patterns {
+		if len(pattern) == 0 {
+			continue
+		}
+
+		current := 0
+		for _, c := range pattern {
+			next, ok := ac.nodes[current].next[c]
+			if !ok {
+				ac.nodes = append(ac.nodes, acNode{next: make(map[rune]int)})
+				next = len(ac.nodes) - 1
+				ac.nodes[current].next[c] = next
+			}
+			current = next
+		}
+		ac.nodes[current].outputs = append(ac.nodes[current].outputs, i)
+	}
+
+	queue := make([]int, 0, len(ac.nodes))
+	for _, child := range ac.nodes[0].next {
+		queue = append(queue, child)
+	}
+
+	for len(queue) != 0 {
+		current := queue[0]
+		queue = queue[1:]
+
+		for c, child := range ac.nodes[current].next {
+			fail := ac.nodes[current].fail
+			for fail != 0 {
+				if _, ok := ac.nodes[fail].next[c]; ok {
+					break
+				}
+				fail = ac.nodes[fail].fail
+			}
+
+			if next, ok := ac.nodes[fail].next[c]; ok && next != child {
+				ac.nodes[child].fail = next
+			}
+
+			ac.nodes[child].outputs = append(ac.nodes[child].outputs, ac.nodes[ac.nodes[child].fail].outputs...)
+			queue = append(queue, child)
+		}
+	}
+
+	return ac
+}
+
+// find calls found with an index of every pattern that occurs in the text and a byte offset, where the occurrence ends.
+// It stops, when found returns false.
+func (ac *ahoCorasick) find(text string, found func(pattern, end int) bool) {
+	current := 0
+
+	for i, c := range text {
+		for current != 0 {
+			if _, ok := ac.nodes[current].next[c]; ok {
+				break
+			}
+			current = ac.nodes[current].fail
+		}
+
+		if next, ok := ac.nodes[current].next[c]; ok {
+			current = next
+		}
+
+		for _, pattern := range ac.nodes[current].outputs {
+			if !found(pattern, i+utf8.RuneLen(c)) {
+				return
+			}
+		}
+	}
+}
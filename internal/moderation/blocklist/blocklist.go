@@ -0,0 +1,175 @@
+package blocklist
+
+import (
+	"context"
+	"fmt"
+	"slices"
+	"strings"
+	"sync"
+
+	"github.com/danielbukowski/twitch-chatbot/internal/command"
+	"github.com/danielbukowski/twitch-chatbot/internal/moderation"
+	"github.com/gempir/go-twitch-irc/v4"
+	"go.opentelemetry.io/otel/codes"
+)
+
+type storage interface {
+	List(ctx context.Context) (map[string][]Entry, error)
+	Save(ctx context.Context, channelName string, entry Entry, addedBy string) (bool, error)
+	Delete(ctx context.Context, channelName, term string) (bool, error)
+}
+
+// Blocklist is a moderation rule that rejects messages with blocked phrases. Every channel has its own list of phrases.
+type Blocklist struct {
+	storage    storage               // Storage persists blocked phrases.
+	punishment moderation.Punishment // Punishment is used for every message with a blocked phrase.
+	prefix     string                // Prefix is a prefix of commands.
+	entries    map[string][]Entry    // Entries holds blocked phrases of every channel.
+	matchers   map[string]*Matcher   // Matchers holds compiled blocked phrases of every channel.
+	mu         sync.RWMutex          // Mu guards entries and matchers.
+}
+
+// New creates an instance of Blocklist. Call Load to read blocked phrases from the storage.
+func New(storage storage, punishment moderation.Punishment, prefix string) *Blocklist {
+	return &Blocklist{
+		storage:    storage,
+		punishment: punishment,
+		prefix:     prefix,
+		entries:    make(map[string][]Entry),
+		matchers:   make(map[string]*Matcher),
+	}
+}
+
+// Load reads blocked phrases of all channels from the storage.
+func (b *Blocklist) Load(ctx context.Context) error {
+	entries, err := b.storage.List(ctx)
+	if err != nil {
+		return err
+	}
+
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	for channelName, channelEntries := range entries {
+		matcher, err := NewMatcher(channelEntries)
+		if err != nil {
+			return err
+		}
+
+		b.entries[channelName] = channelEntries
+		b.matchers[channelName] = matcher
+	}
+
+	return nil
+}
+
+func (b *Blocklist) Name() string {
+	return "blocked_phrase"
+}
+
+func (b *Blocklist) Check(privMsg *twitch.PrivateMessage) (moderation.Violation, bool) {
+	b.mu.RLock()
+	matcher, ok := b.matchers[privMsg.Channel]
+	b.mu.RUnlock()
+
+	if !ok {
+		return moderation.Violation{}, false
+	}
+
+	if _, found := matcher.Match(privMsg.Message); !found {
+		return moderation.Violation{}, false
+	}
+
+	return moderation.Violation{Rule: b.Name(), Reason: "your message contains a blocked phrase", Punishment: b.punishment}, true
+}
+
+// BlockWord manages blocked phrases of a channel.
+// Usage: `!blockword add <phrase>`, `!blockword remove <phrase>`.
+func (b *Blocklist) BlockWord() command.Handler {
+	return func(ctx context.Context, args []string, chatClient command.ChatClient) error {
+		spanCtx, span := tracer.Start(ctx, "blockWord")
+		defer span.End()
+
+		if len(args) < 2 {
+			span.SetStatus(codes.Error, "wrong usage of the command")
+			return command.UsageError(fmt.Sprintf("usage: %sblockword add/remove <phrase>, use * as a wildcard or /regex/", b.prefix))
+		}
+
+		cmdCtx := command.UnwrapContext(ctx)
+		channelName := cmdCtx.PrivMsg.Channel
+		term := strings.Join(args[1:], " ")
+
+		var reply string
+
+		switch strings.ToLower(args[0]) {
+		case "add":
+			entry, err := ParseEntry(term)
+			if err != nil {
+				span.SetStatus(codes.Error, "invalid phrase")
+				return command.UsageError(err.Error())
+			}
+
+			saved, err := b.storage.Save(spanCtx, channelName, entry, cmdCtx.PrivMsg.User.Name)
+			if err != nil {
+				span.SetStatus(codes.Error, "failed to save a blocked phrase")
+				return command.InternalError(err)
+			}
+
+			if !saved {
+				reply = "the phrase is already blocked"
+				break
+			}
+
+			if err = b.update(channelName, func(entries []Entry) []Entry { return append(entries, entry) }); err != nil {
+				span.SetStatus(codes.Error, "failed to compile blocked phrases")
+				return command.InternalError(err)
+			}
+			reply = fmt.Sprintf("blocked a %s phrase", entry.Kind)
+		case "remove":
+			deleted, err := b.storage.Delete(spanCtx, channelName, term)
+			if err != nil {
+				span.SetStatus(codes.Error, "failed to delete a blocked phrase")
+				return command.InternalError(err)
+			}
+
+			if !deleted {
+				reply = "the phrase is not blocked"
+				break
+			}
+
+			err = b.update(channelName, func(entries []Entry) []Entry {
+				return slices.DeleteFunc(entries, func(e Entry) bool { return e.Term == term })
+			})
+			if err != nil {
+				span.SetStatus(codes.Error, "failed to compile blocked phrases")
+				return command.InternalError(err)
+			}
+			reply = "unblocked the phrase"
+		default:
+			span.SetStatus(codes.Error, "unknown subcommand")
+			return command.UsageError(fmt.Sprintf("usage: %sblockword add/remove <phrase>", b.prefix))
+		}
+
+		chatClient.Say(channelName, fmt.Sprintf("@%s, %s", cmdCtx.PrivMsg.User.DisplayName, reply))
+
+		span.SetStatus(codes.Ok, "successfully changed blocked phrases")
+		return nil
+	}
+}
+
+// update changes blocked phrases of a channel and compiles them again.
+func (b *Blocklist) update(channelName string, change func([]Entry) []Entry) error {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	entries := change(slices.Clone(b.entries[channelName]))
+
+	matcher, err := NewMatcher(entries)
+	if err != nil {
+		return err
+	}
+
+	b.entries[channelName] = entries
+	b.matchers[channelName] = matcher
+	return nil
+}
@@ -0,0 +1,197 @@
+package blocklist
+
+import (
+	"errors"
+	"fmt"
+	"regexp"
+	"strings"
+)
+
+// Kind represents a type of an entry on the blocklist.
+type Kind string
+
+const (
+	KindLiteral  Kind = "literal"  // KindLiteral matches whole words of a phrase anywhere in a message.
+	KindWildcard Kind = "wildcard" // KindWildcard matches a phrase, where `*` stands for any text, like `free*followers`.
+	KindRegex    Kind = "regex"    // KindRegex matches a regular expression written between slashes, like `/f[o]+llow/`.
+)
+
+var errEmptyEntry = errors.New("entry has no letters or digits after normalization")
+
+// Entry represents a single blocked phrase.
+type Entry struct {
+	Term string // Term is the phrase in the form it was added.
+	Kind Kind   // Kind decides how the term is matched.
+}
+
+// ParseEntry creates an entry from a phrase. Phrases between slashes are regular expressions,
+// phrases with `*` are wildcards and all other phrases are literals.
+func ParseEntry(term string) (Entry, error) {
+	term = strings.TrimSpace(term)
+
+	switch {
+	case len(term) > 2 && strings.HasPrefix(term, "/") && strings.HasSuffix(term, "/"):
+		if _, err := regexp.Compile(term[1 : len(term)-1]); err != nil {
+			return Entry{}, fmt.Errorf("invalid regular expression: %w", err)
+		}
+		return Entry{Term: term, Kind: KindRegex}, nil
+	case strings.Contains(term, "*"):
+		if len(Normalize(strings.ReplaceAll(term, "*", ""))) == 0 {
+			return Entry{}, errEmptyEntry
+		}
+		return Entry{Term: term, Kind: KindWildcard}, nil
+	default:
+		if len(Normalize(term)) == 0 {
+			return Entry{}, errEmptyEntry
+		}
+		return Entry{Term: term, Kind: KindLiteral}, nil
+	}
+}
+
+// wildcard is a compiled wildcard entry. The automaton finds its fragments,
+// and only when all of them are present, the regular expression is checked.
+// The expression requires the first fragment to start a word and the last one to end a word.
+type wildcard struct {
+	entry     int
+	fragments []int
+	re        *regexp.Regexp
+}
+
+// Matcher finds blocked phrases in messages. Literals and fragments of wildcards are searched with the Aho-Corasick
+// algorithm, so the time of matching barely depends on the number of entries. Literals match only on word boundaries
+// of the normalized message, so `ass` doesn't match `was`. Regular expressions are checked one by one,
+// against both the original and the normalized message.
+type Matcher struct {
+	entries          []Entry
+	automaton        *ahoCorasick
+	patternLengths   []int         // PatternLengths holds lengths in bytes of patterns of the automaton.
+	literalEntries   map[int]int   // LiteralEntries maps a pattern of the automaton to a literal entry.
+	patternWildcards map[int][]int // PatternWildcards maps a pattern of the automaton to wildcards that contain it.
+	wildcards        []wildcard
+	regexps          map[int]*regexp.Regexp // Regexps maps an entry to its compiled regular expression.
+}
+
+// NewMatcher compiles entries into a matcher. All phrases and messages are normalized before matching.
+func NewMatcher(entries []Entry) (*Matcher, error) {
+	m := &Matcher{
+		entries:          entries,
+		literalEntries:   make(map[int]int),
+		patternWildcards: make(map[int][]int),
+		regexps:          make(map[int]*regexp.Regexp),
+	}
+
+	patterns := []string{}
+	patternIndexes := make(map[string]int)
+	addPattern := func(pattern string) int {
+		if i, ok := patternIndexes[pattern]; ok {
+			return i
+		}
+		patterns = append(patterns, pattern)
+		patternIndexes[pattern] = len(patterns) - 1
+		return len(patterns) - 1
+	}
+
+	for i, entry := range entries {
+		switch entry.Kind {
+		case KindLiteral:
+			pattern := Normalize(entry.Term)
+			if len(pattern) == 0 {
+				continue
+			}
+			m.literalEntries[addPattern(pattern)] = i
+		case KindWildcard:
+			w := wildcard{entry: i}
+			quoted := []string{}
+			for _, fragment := range strings.Split(entry.Term, "*") {
+				fragment = Normalize(fragment)
+				if len(fragment) == 0 {
+					continue
+				}
+				w.fragments = append(w.fragments, addPattern(fragment))
+				quoted = append(quoted, regexp.QuoteMeta(fragment))
+			}
+			if len(w.fragments) == 0 {
+				continue
+			}
+
+			re, err := regexp.Compile("(?:^| )" + strings.Join(quoted, ".*") + "(?: |$)")
+			if err != nil {
+				return nil, err
+			}
+			w.re = re
+
+			m.wildcards = append(m.wildcards, w)
+			for _, fragment := range w.fragments {
+				m.patternWildcards[fragment] = append(m.patternWildcards[fragment], len(m.wildcards)-1)
+			}
+		case KindRegex:
+			re, err := regexp.Compile("(?i)" + entry.Term[1:len(entry.Term)-1])
+			if err != nil {
+				return nil, fmt.Errorf("invalid regular expression '%s': %w", entry.Term, err)
+			}
+			m.regexps[i] = re
+		}
+	}
+
+	m.automaton = newAhoCorasick(patterns)
+	for _, pattern := range patterns {
+		m.patternLengths = append(m.patternLengths, len(pattern))
+	}
+	return m, nil
+}
+
+// Match returns the first blocked entry found in a message.
+func (m *Matcher) Match(message string) (Entry, bool) {
+	text := Normalize(message)
+
+	found := -1
+	seenPatterns := make(map[int]bool)
+	m.automaton.find(text, func(pattern, end int) bool {
+		if entry, ok := m.literalEntries[pattern]; ok && isWord(text, end-m.patternLengths[pattern], end) {
+			found = entry
+			return false
+		}
+		seenPatterns[pattern] = true
+		return true
+	})
+	if found != -1 {
+		return m.entries[found], true
+	}
+
+	checked := make(map[int]bool)
+	for pattern := range seenPatterns {
+		for _, i := range m.patternWildcards[pattern] {
+			if checked[i] {
+				continue
+			}
+			checked[i] = true
+
+			if m.hasAllFragments(m.wildcards[i], seenPatterns) && m.wildcards[i].re.MatchString(text) {
+				return m.entries[m.wildcards[i].entry], true
+			}
+		}
+	}
+
+	for i, re := range m.regexps {
+		if re.MatchString(text) || re.MatchString(message) {
+			return m.entries[i], true
+		}
+	}
+
+	return Entry{}, false
+}
+
+func (m *Matcher) hasAllFragments(w wildcard, seenPatterns map[int]bool) bool {
+	for _, fragment := range w.fragments {
+		if !seenPatterns[fragment] {
+			return false
+		}
+	}
+
+	return true
+}
+
+// isWord reports whether the text between start and end is surrounded by spaces or edges of the text.
+func isWord(text string, start, end int) bool {
+	return (start == 0 || text[start-1] == ' ') && (end == len(text) || text[end] == ' ')
+}
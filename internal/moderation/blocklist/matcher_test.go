@@ -0,0 +1,95 @@
+package blocklist
+
+import (
+	"fmt"
+	"testing"
+)
+
+func TestNormalize(t *testing.T) {
+	testCases := []struct {
+		name     string
+		text     string
+		expected string
+	}{
+		{name: "replaces leetspeak", text: "fr33 f0llowers", expected: "free followers"},
+		{name: "replaces Cyrillic lookalikes", text: "frее fоllоwеrs", expected: "free followers"},
+		{name: "removes zero width characters", text: "fr​ee", expected: "free"},
+		{name: "removes diacritics and fullwidth letters", text: "ｆｒéé", expected: "free"},
+		{name: "drops punctuation and collapses spaces", text: "f.r.e.e   followers!!!", expected: "free followers"},
+		{name: "shortens runs of 3 or more repeated characters", text: "freeeee folllowers", expected: "free followers"},
+		{name: "replaces symbols inside words", text: "$h!t", expected: "shit"},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			// when
+			got := Normalize(tc.text)
+
+			// then
+			if got != tc.expected {
+				t.Errorf("Expected `%s`, got `%s`", tc.expected, got)
+			}
+		})
+	}
+}
+
+func TestMatcher(t *testing.T) {
+	entries := []Entry{
+		{Term: "free followers", Kind: KindLiteral},
+		{Term: "cheap*viewers", Kind: KindWildcard},
+		{Term: "/b[i1]g\\s?follows/", Kind: KindRegex},
+		{Term: "ass", Kind: KindLiteral},
+		{Term: "butt", Kind: KindLiteral},
+	}
+
+	testCases := []struct {
+		name     string
+		message  string
+		expected bool
+	}{
+		{name: "matches an evasive literal", message: "get FR33 f0ll0wers now", expected: true},
+		{name: "matches a wildcard", message: "buy cheap and fast viewers", expected: true},
+		{name: "does not match a wildcard with fragments in the wrong order", message: "viewers are not cheap", expected: false},
+		{name: "matches a regular expression", message: "BIG follows here", expected: true},
+		{name: "does not match an innocent message", message: "thanks for the follow!", expected: false},
+		{name: "matches a literal on word boundaries", message: "what an ass, really", expected: true},
+		{name: "does not match a literal inside a word", message: "what was that", expected: false},
+		{name: "does not match a literal with fewer repeated letters", message: "I like it but no", expected: false},
+		{name: "matches a literal with stretched letters", message: "butttttt", expected: true},
+		{name: "does not match a wildcard inside a word", message: "supercheap viewers", expected: false},
+	}
+
+	matcher, err := NewMatcher(entries)
+	if err != nil {
+		t.Fatalf("Expected no error, got `%v`", err)
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			// when
+			_, got := matcher.Match(tc.message)
+
+			// then
+			if got != tc.expected {
+				t.Errorf("Expected `%v`, got `%v`", tc.expected, got)
+			}
+		})
+	}
+}
+
+func BenchmarkMatcher(b *testing.B) {
+	entries := make([]Entry, 0, 5000)
+	for i := range 5000 {
+		entries = append(entries, Entry{Term: fmt.Sprintf("blocked phrase number %d", i), Kind: KindLiteral})
+	}
+
+	matcher, err := NewMatcher(entries)
+	if err != nil {
+		b.Fatalf("Expected no error, got `%v`", err)
+	}
+
+	b.ResetTimer()
+	for range b.N {
+		matcher.Match("this is a completely normal message from a viewer who enjoys the stream")
+	}
+}
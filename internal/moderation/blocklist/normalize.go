@@ -0,0 +1,110 @@
+package blocklist
+
+import (
+	"strings"
+	"unicode"
+
+	"golang.org/x/text/unicode/norm"
+)
+
+// zeroWidth holds invisible characters used for splitting words, so they are not matched.
+var zeroWidth = map[rune]bool{
+	'\u00AD': true, // soft hyphen
+	'\u200B': true, // zero width space
+	'\u200C': true, // zero width non-joiner
+	'\u200D': true, // zero width joiner
+	'\u2060': true, // word joiner
+	'\uFEFF': true, // zero width no-break space
+}
+
+// confusables maps letters from other alphabets to Latin letters they look like.
+var confusables = map[rune]rune{
+	// Cyrillic
+	'а': 'a', 'в': 'b', 'е': 'e', 'ё': 'e', 'к': 'k', 'м': 'm', 'н': 'h', 'о': 'o', 'р': 'p',
+	'с': 'c', 'т': 't', 'у': 'y', 'х': 'x', 'ѕ': 's', 'і': 'i', 'ї': 'i', 'ј': 'j', 'ԁ': 'd',
+	'ӏ': 'l', 'ԛ': 'q', 'ԝ': 'w', 'ь': 'b', 'п': 'n', 'г': 'r',
+	// Greek
+	'α': 'a', 'β': 'b', 'ε': 'e', 'η': 'n', 'ι': 'i', 'κ': 'k', 'ν': 'v', 'ο': 'o', 'ρ': 'p',
+	'τ': 't', 'υ': 'u', 'χ': 'x', 'ω': 'w', 'μ': 'u',
+}
+
+// leetspeak maps digits to letters they replace.
+var leetspeak = map[rune]rune{
+	'0': 'o', '1': 'i', '3': 'e', '4': 'a', '5': 's', '7': 't', '8': 'b', '9': 'g',
+}
+
+// symbolLeetspeak maps symbols to letters they replace. Symbols are replaced only inside words, so punctuation
+// at the end of a message, like `followers!!!`, is dropped instead of becoming letters.
+var symbolLeetspeak = map[rune]rune{
+	'@': 'a', '$': 's', '!': 'i', '|': 'l', '€': 'e', '+': 't',
+}
+
+// maxRepeats is the longest run of a repeated character kept by Normalize.
+// Longer runs are shortened to it, so `freeeee` matches `free`, but `butt` never becomes `but`.
+const maxRepeats = 2
+
+// Normalize turns a text into a form used for matching blocked phrases. It applies the Unicode NFKC normalization,
+// removes diacritics and zero width characters, replaces lookalike letters and leetspeak with Latin letters,
+// drops punctuation and shortens runs of 3 or more repeated characters, so `Fr33   f0lll0wers!!` becomes `free followers`.
+func Normalize(text string) string {
+	text = norm.NFD.String(norm.NFKC.String(text))
+
+	runes := make([]rune, 0, len(text))
+	for _, c := range strings.ToLower(text) {
+		if zeroWidth[c] || unicode.Is(unicode.Mn, c) {
+			continue
+		}
+		if replacement, ok := confusables[c]; ok {
+			c = replacement
+		}
+		runes = append(runes, c)
+	}
+
+	var b strings.Builder
+	b.Grow(len(text))
+	var previous rune
+	repeats := 0
+
+	for i, c := range runes {
+		if replacement, ok := leetspeak[c]; ok {
+			c = replacement
+		}
+		if replacement, ok := symbolLeetspeak[c]; ok && isFollowedByWord(runes[i+1:]) {
+			c = replacement
+		}
+
+		switch {
+		case unicode.IsLetter(c), unicode.IsDigit(c):
+		case unicode.IsSpace(c):
+			c = ' '
+		default:
+			continue
+		}
+
+		if c == previous {
+			repeats++
+			if repeats > maxRepeats || c == ' ' {
+				continue
+			}
+		} else {
+			repeats = 1
+		}
+
+		previous = c
+		b.WriteRune(c)
+	}
+
+	return strings.TrimSpace(b.String())
+}
+
+// isFollowedByWord reports whether a letter or a digit follows, skipping other leetspeak symbols.
+func isFollowedByWord(runes []rune) bool {
+	for _, c := range runes {
+		if _, ok := symbolLeetspeak[c]; ok {
+			continue
+		}
+		return unicode.IsLetter(c) || unicode.IsDigit(c)
+	}
+
+	return false
+}
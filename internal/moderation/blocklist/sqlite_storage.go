@@ -0,0 +1,125 @@
+package blocklist
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+
+	"github.com/danielbukowski/twitch-chatbot/internal/database"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/codes"
+)
+
+var tracer = otel.Tracer("github.com/danielbukowski/twitch-chatbot/internal/moderation/blocklist")
+
+// SQLiteStorage stores blocked phrases of all channels.
+type SQLiteStorage struct {
+	db *sql.DB
+}
+
+func NewSQLiteStorage(db *sql.DB) *SQLiteStorage {
+	return &SQLiteStorage{db: db}
+}
+
+// List returns all blocked phrases grouped by a channel.
+func (s *SQLiteStorage) List(ctx context.Context) (map[string][]Entry, error) {
+	query := "SELECT channel_name, term, kind FROM blocked_phrases ORDER BY blocked_phrase_id;"
+
+	ctx, span := tracer.Start(ctx, "list")
+	defer span.End()
+
+	ctx, cancel := context.WithTimeout(ctx, database.RequestTimeout)
+	defer cancel()
+
+	rows, err := s.db.QueryContext(ctx, query)
+	if err != nil {
+		errMsg := "failed to query blocked phrases"
+		span.SetStatus(codes.Error, errMsg)
+		span.RecordError(err)
+		return nil, errors.Join(errors.New(errMsg), err)
+	}
+	defer rows.Close()
+
+	entries := make(map[string][]Entry)
+	for rows.Next() {
+		var channelName string
+		var entry Entry
+		if err = rows.Scan(&channelName, &entry.Term, &entry.Kind); err != nil {
+			errMsg := "failed to copy a blocked phrase to a struct"
+			span.SetStatus(codes.Error, errMsg)
+			span.RecordError(err)
+			return nil, errors.Join(errors.New(errMsg), err)
+		}
+		entries[channelName] = append(entries[channelName], entry)
+	}
+
+	if err = rows.Err(); err != nil {
+		errMsg := "failed to iterate over blocked phrases"
+		span.SetStatus(codes.Error, errMsg)
+		span.RecordError(err)
+		return nil, errors.Join(errors.New(errMsg), err)
+	}
+
+	span.SetStatus(codes.Ok, "successfully retrieved blocked phrases")
+	return entries, nil
+}
+
+// Save adds a blocked phrase to a channel. It returns false, when the phrase was already blocked.
+func (s *SQLiteStorage) Save(ctx context.Context, channelName string, entry Entry, addedBy string) (bool, error) {
+	query := "INSERT INTO blocked_phrases (channel_name, term, kind, added_by) VALUES (?, ?, ?, ?) ON CONFLICT (channel_name, term) DO NOTHING;"
+
+	ctx, span := tracer.Start(ctx, "save")
+	defer span.End()
+
+	ctx, cancel := context.WithTimeout(ctx, database.RequestTimeout)
+	defer cancel()
+
+	res, err := s.db.ExecContext(ctx, query, channelName, entry.Term, entry.Kind, addedBy)
+	if err != nil {
+		errMsg := "failed to save a blocked phrase"
+		span.SetStatus(codes.Error, errMsg)
+		span.RecordError(err)
+		return false, errors.Join(errors.New(errMsg), err)
+	}
+
+	rows, err := res.RowsAffected()
+	if err != nil {
+		errMsg := "failed to check if a blocked phrase was saved"
+		span.SetStatus(codes.Error, errMsg)
+		span.RecordError(err)
+		return false, errors.Join(errors.New(errMsg), err)
+	}
+
+	span.SetStatus(codes.Ok, "successfully saved a blocked phrase")
+	return rows != 0, nil
+}
+
+// Delete removes a blocked phrase from a channel. It returns false, when the phrase was not blocked.
+func (s *SQLiteStorage) Delete(ctx context.Context, channelName, term string) (bool, error) {
+	query := "DELETE FROM blocked_phrases WHERE channel_name = ? AND term = ?;"
+
+	ctx, span := tracer.Start(ctx, "delete")
+	defer span.End()
+
+	ctx, cancel := context.WithTimeout(ctx, database.RequestTimeout)
+	defer cancel()
+
+	res, err := s.db.ExecContext(ctx, query, channelName, term)
+	if err != nil {
+		errMsg := "failed to delete a blocked phrase"
+		span.SetStatus(codes.Error, errMsg)
+		span.RecordError(err)
+		return false, errors.Join(errors.New(errMsg), err)
+	}
+
+	rows, err := res.RowsAffected()
+	if err != nil {
+		errMsg := "failed to check if a blocked phrase was deleted"
+		span.SetStatus(codes.Error, errMsg)
+		span.RecordError(err)
+		return false, errors.Join(errors.New(errMsg), err)
+	}
+
+	span.SetStatus(codes.Ok, "successfully deleted a blocked phrase")
+	return rows != 0, nil
+}
@@ -0,0 +1,229 @@
+package moderation
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"github.com/danielbukowski/twitch-chatbot/internal/permission"
+	"github.com/gempir/go-twitch-irc/v4"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/metric"
+	"go.uber.org/zap"
+)
+
+var tracer = otel.Tracer("github.com/danielbukowski/twitch-chatbot/internal/moderation")
+var meter = otel.Meter("github.com/danielbukowski/twitch-chatbot/internal/moderation")
+
+// Action represents a punishment for breaking a rule. Actions are ordered from the least to the most severe.
+type Action int
+
+const (
+	ActionNone Action = iota
+	ActionWarn
+	ActionDelete
+	ActionTimeout
+	ActionBan
+)
+
+// String returns a name of the action.
+func (a Action) String() string {
+	switch a {
+	case ActionWarn:
+		return "warn"
+	case ActionDelete:
+		return "delete"
+	case ActionTimeout:
+		return "timeout"
+	case ActionBan:
+		return "ban"
+	default:
+		return "none"
+	}
+}
+
+// Punishment describes what happens to a user who broke a rule.
+type Punishment struct {
+	Action   Action        // Action is a type of the punishment.
+	Duration time.Duration // Duration is a length of a timeout, it's used only with ActionTimeout.
+}
+
+// MoreSevereThan reports whether the punishment is more severe than the other one.
+func (p Punishment) MoreSevereThan(other Punishment) bool {
+	if p.Action != other.Action {
+		return p.Action > other.Action
+	}
+
+	return p.Duration > other.Duration
+}
+
+// Violation describes a message that broke a rule.
+type Violation struct {
+	Rule       string     // Rule is a name of the broken rule.
+	Reason     string     // Reason is a message shown to the user and saved in logs.
+	Punishment Punishment // Punishment decides what happens to the user.
+}
+
+// Rule checks, if a message is allowed on the chat.
+type Rule interface {
+	Name() string
+	Check(privMsg *twitch.PrivateMessage) (Violation, bool)
+}
+
+// Enforcer executes punishments for violations.
+type Enforcer interface {
+	Enforce(ctx context.Context, privMsg *twitch.PrivateMessage, violation Violation) error
+}
+
+// levelResolver returns a permission level of the author of a message.
+type levelResolver interface {
+	LevelOf(ctx context.Context, privMsg *twitch.PrivateMessage, required permission.Level) (permission.Level, error)
+}
+
+// exemptRule is a rule with a level of users who are not checked by it.
+type exemptRule struct {
+	rule        Rule
+	exemptLevel permission.Level
+}
+
+// Engine checks every message on the chat against moderation rules and punishes users who broke them.
+type Engine struct {
+	rules            []exemptRule         // Rules holds all rules checked by the engine.
+	enforcer         Enforcer             // Enforcer executes punishments.
+	levelResolver    levelResolver        // LevelResolver is used for exempting users from rules.
+	logger           *zap.Logger          // Logger is used for logging.
+	violationCounter metric.Int64Counter  // ViolationCounter counts broken rules.
+	relaxed          map[string]time.Time // Relaxed holds until when a rule is not checked on a channel.
+	mu               sync.Mutex           // Mu guards relaxed.
+	now              func() time.Time     // Now returns the current time.
+}
+
+// NewEngine creates an instance of Engine without any rules.
+func NewEngine(enforcer Enforcer, levelResolver levelResolver, logger *zap.Logger) (*Engine, error) {
+	violationCounter, err := meter.Int64Counter(
+		"moderation.violation.counter",
+		metric.WithDescription("Number of messages that broke a moderation rule."),
+		metric.WithUnit("{violation}"),
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	return &Engine{
+		enforcer:         enforcer,
+		levelResolver:    levelResolver,
+		logger:           logger.Named("moderation"),
+		violationCounter: violationCounter,
+		relaxed:          make(map[string]time.Time),
+		now:              time.Now,
+	}, nil
+}
+
+// AddRule adds a rule to the engine. Users with exemptLevel or higher are not checked by the rule.
+func (e *Engine) AddRule(rule Rule, exemptLevel permission.Level) {
+	e.rules = append(e.rules, exemptRule{rule: rule, exemptLevel: exemptLevel})
+}
+
+// Relax stops checking the given rules on a channel for a duration, e.g. when a raid brings many new chatters.
+func (e *Engine) Relax(channelName string, duration time.Duration, ruleNames ...string) {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+
+	until := e.now().Add(duration)
+	for _, ruleName := range ruleNames {
+		e.relaxed[channelName+":"+ruleName] = until
+	}
+}
+
+// isRelaxed reports whether a rule is not checked on a channel right now.
+func (e *Engine) isRelaxed(channelName, ruleName string) bool {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+
+	key := channelName + ":" + ruleName
+	until, ok := e.relaxed[key]
+	if ok && !e.now().Before(until) {
+		delete(e.relaxed, key)
+		return false
+	}
+
+	return ok
+}
+
+// Process checks a message against all rules. When the message broke any of them, the most severe punishment is executed
+// in the background and Process returns true, so the message should not be processed any further, e.g. as a command.
+func (e *Engine) Process(ctx context.Context, privMsg *twitch.PrivateMessage) bool {
+	ctx, span := tracer.Start(ctx, "process")
+	defer span.End()
+
+	violation, found := e.check(ctx, privMsg)
+	if !found {
+		span.SetStatus(codes.Ok, "message did not break any rule")
+		return false
+	}
+
+	span.SetAttributes(
+		attribute.String("moderation.rule", violation.Rule),
+		attribute.String("moderation.action", violation.Punishment.Action.String()),
+	)
+	e.violationCounter.Add(ctx, 1, metric.WithAttributes(
+		attribute.String("moderation.rule", violation.Rule),
+		attribute.String("channel.name", privMsg.Channel),
+	))
+
+	e.logger.Info("message broke a moderation rule",
+		zap.String("channel_name", privMsg.Channel),
+		zap.String("username", privMsg.User.Name),
+		zap.String("rule", violation.Rule),
+		zap.String("action", violation.Punishment.Action.String()),
+	)
+
+	enforceCtx := context.WithoutCancel(ctx)
+	msg := *privMsg
+	go func() {
+		err := e.enforcer.Enforce(enforceCtx, &msg, violation)
+		if err != nil {
+			e.logger.Error("failed to enforce a punishment", zap.String("rule", violation.Rule), zap.Error(err))
+		}
+	}()
+
+	span.SetStatus(codes.Ok, "message broke a rule")
+	return true
+}
+
+// check returns the most severe violation of the message.
+func (e *Engine) check(ctx context.Context, privMsg *twitch.PrivateMessage) (Violation, bool) {
+	var worst Violation
+	found := false
+
+	for _, r := range e.rules {
+		if e.isRelaxed(privMsg.Channel, r.rule.Name()) || e.isExempt(ctx, privMsg, r.exemptLevel) {
+			continue
+		}
+
+		violation, broken := r.rule.Check(privMsg)
+		if !broken {
+			continue
+		}
+
+		if !found || violation.Punishment.MoreSevereThan(worst.Punishment) {
+			worst = violation
+			found = true
+		}
+	}
+
+	return worst, found
+}
+
+// isExempt reports whether the author of the message has at least the exempt level.
+func (e *Engine) isExempt(ctx context.Context, privMsg *twitch.PrivateMessage, exemptLevel permission.Level) bool {
+	level, err := e.levelResolver.LevelOf(ctx, privMsg, exemptLevel)
+	if err != nil {
+		e.logger.Warn("failed to resolve a permission level of a user", zap.Error(err))
+		return false
+	}
+
+	return level >= exemptLevel
+}
@@ -0,0 +1,50 @@
+package moderation
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/danielbukowski/twitch-chatbot/internal/permission"
+	"github.com/gempir/go-twitch-irc/v4"
+	"go.uber.org/zap"
+)
+
+type levelResolverMock struct{}
+
+func (levelResolverMock) LevelOf(_ context.Context, _ *twitch.PrivateMessage, _ permission.Level) (permission.Level, error) {
+	return permission.Everyone, nil
+}
+
+type enforcerMock struct{}
+
+func (enforcerMock) Enforce(_ context.Context, _ *twitch.PrivateMessage, _ Violation) error {
+	return nil
+}
+
+func TestEngineRelax(t *testing.T) {
+	// given
+	engine, err := NewEngine(enforcerMock{}, levelResolverMock{}, zap.NewNop())
+	if err != nil {
+		t.Fatalf("Expected no error, got `%v`", err)
+	}
+	engine.AddRule(CapsRule{MinLength: 10, MaxRatio: 0.7, Punishment: Punishment{Action: ActionWarn}}, permission.Moderator)
+
+	now := time.Date(2026, 10, 19, 12, 0, 0, 0, time.UTC)
+	engine.now = func() time.Time { return now }
+	privMsg := &twitch.PrivateMessage{Channel: "channel", Message: "RAID HYPE RAID HYPE RAID HYPE"}
+
+	// when
+	engine.Relax("channel", time.Minute, "caps")
+	relaxed := engine.Process(context.Background(), privMsg)
+	now = now.Add(time.Minute)
+	afterRelax := engine.Process(context.Background(), privMsg)
+
+	// then
+	if relaxed {
+		t.Errorf("Expected the relaxed rule to be skipped")
+	}
+	if !afterRelax {
+		t.Errorf("Expected the rule to be checked again after the duration")
+	}
+}
@@ -0,0 +1,149 @@
+package raidguard
+
+import (
+	"hash/fnv"
+	"math"
+	"time"
+
+	"github.com/danielbukowski/twitch-chatbot/internal/moderation/blocklist"
+	"github.com/gempir/go-twitch-irc/v4"
+)
+
+const (
+	signatureSize = 64  // signatureSize is a number of hash functions used by MinHash.
+	shingleSize   = 4   // shingleSize is a number of characters in a single shingle.
+	maxEntries    = 500 // maxEntries limits messages kept in a window of a channel.
+)
+
+// signature is a MinHash signature of a message, it estimates the Jaccard similarity of shingles of two messages.
+type signature [signatureSize]uint64
+
+// newSignature returns a MinHash signature of character shingles of a normalized text.
+// It returns false, when nothing is left of the text after normalization.
+func newSignature(text string) (signature, bool) {
+	var sig signature
+	runes := []rune(blocklist.Normalize(text))
+	if len(runes) == 0 {
+		return sig, false
+	}
+
+	for i := range sig {
+		sig[i] = math.MaxUint64
+	}
+
+	size := min(shingleSize, len(runes))
+	for i := 0; i+size <= len(runes); i++ {
+		h := fnv.New64a()
+		_, _ = h.Write([]byte(string(runes[i : i+size])))
+		base := h.Sum64()
+
+		for j := range sig {
+			if v := mix(base ^ (uint64(j+1) * 0x9E3779B97F4A7C15)); v < sig[j] {
+				sig[j] = v
+			}
+		}
+	}
+
+	return sig, true
+}
+
+// similarity returns an estimated similarity of two messages, from 0 to 1.
+func (s signature) similarity(other signature) float64 {
+	equal := 0
+	for i := range s {
+		if s[i] == other[i] {
+			equal++
+		}
+	}
+
+	return float64(equal) / signatureSize
+}
+
+// mix is the finalizer of SplitMix64, it turns a hash into a value of an independent hash function.
+func mix(x uint64) uint64 {
+	x ^= x >> 30
+	x *= 0xBF58476D1CE4E5B9
+	x ^= x >> 27
+	x *= 0x94D049BB133111EB
+	x ^= x >> 31
+	return x
+}
+
+// entry is a message remembered in a sliding window.
+type entry struct {
+	at           time.Time
+	userID       string
+	username     string
+	firstMessage bool
+	signature    signature
+}
+
+// detector finds waves of near-identical messages sent by many, mostly new, chatters in a short time.
+type detector struct {
+	window            time.Duration      // Window is how long messages are remembered.
+	similarity        float64            // Similarity is the minimum similarity of messages in a wave.
+	minFirstTimeRatio float64            // MinFirstTimeRatio is the minimum part of users in a wave, who chat for the first time.
+	entries           map[string][]entry // Entries holds recent messages of every channel.
+}
+
+func newDetector(config Config) *detector {
+	return &detector{
+		window:            config.Window,
+		similarity:        config.Similarity,
+		minFirstTimeRatio: config.MinFirstTimeRatio,
+		entries:           make(map[string][]entry),
+	}
+}
+
+// observe remembers a message and returns authors of messages similar to it, when they form a wave
+// of at least minUsers users. Every user is returned only once, with the first of their similar messages.
+func (d *detector) observe(now time.Time, privMsg *twitch.PrivateMessage, minUsers int) []entry {
+	sig, ok := newSignature(privMsg.Message)
+	if !ok {
+		return nil
+	}
+
+	entries := d.entries[privMsg.Channel]
+
+	expired := 0
+	for expired < len(entries) && now.Sub(entries[expired].at) > d.window {
+		expired++
+	}
+	entries = entries[expired:]
+
+	current := entry{
+		at:           now,
+		userID:       privMsg.User.ID,
+		username:     privMsg.User.Name,
+		firstMessage: privMsg.FirstMessage,
+		signature:    sig,
+	}
+
+	if len(entries) >= maxEntries {
+		entries = entries[1:]
+	}
+	entries = append(entries, current)
+	d.entries[privMsg.Channel] = entries
+
+	seen := make(map[string]bool)
+	var wave []entry
+	firstTimers := 0
+
+	for _, e := range entries {
+		if seen[e.userID] || sig.similarity(e.signature) < d.similarity {
+			continue
+		}
+
+		seen[e.userID] = true
+		wave = append(wave, e)
+		if e.firstMessage {
+			firstTimers++
+		}
+	}
+
+	if len(wave) < minUsers || float64(firstTimers)/float64(len(wave)) < d.minFirstTimeRatio {
+		return nil
+	}
+
+	return wave
+}
@@ -0,0 +1,356 @@
+package raidguard
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"sync"
+	"time"
+
+	chatsettings "github.com/danielbukowski/twitch-chatbot/internal/chat_settings"
+	"github.com/danielbukowski/twitch-chatbot/internal/moderation/actions"
+	"github.com/danielbukowski/twitch-chatbot/internal/moderation/blocklist"
+	"github.com/danielbukowski/twitch-chatbot/internal/permission"
+	"github.com/gempir/go-twitch-irc/v4"
+	"github.com/nicklaw5/helix/v2"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/metric"
+	"go.uber.org/zap"
+)
+
+var tracer = otel.Tracer("github.com/danielbukowski/twitch-chatbot/internal/moderation/raidguard")
+var meter = otel.Meter("github.com/danielbukowski/twitch-chatbot/internal/moderation/raidguard")
+
+// Config decides when a spam wave is detected and how the chat is locked down.
+type Config struct {
+	Window            time.Duration    // Window is how long messages are compared with new ones.
+	Similarity        float64          // Similarity is the minimum similarity of messages in a wave, from 0 to 1.
+	MinUsers          int              // MinUsers is the minimum number of distinct users posting similar messages.
+	RelaxedMinUsers   int              // RelaxedMinUsers replaces MinUsers for a while after a friendly raid, see Guard.Relax.
+	MinFirstTimeRatio float64          // MinFirstTimeRatio is the minimum part of users in a wave, who chat for the first time.
+	MinMessageLength  int              // MinMessageLength is the minimum number of characters of a normalized message compared by the guard.
+	Calm              time.Duration    // Calm is how long the chat must be free of the wave before the lockdown is lifted.
+	TimeoutDuration   time.Duration    // TimeoutDuration is a length of timeouts given to users in a wave, zero disables timeouts.
+	ExemptLevel       permission.Level // ExemptLevel is the lowest level of users ignored by the guard.
+	Lockdown          Lockdown         // Lockdown holds chat settings enabled during a lockdown.
+}
+
+// Lockdown holds chat settings enabled, when a spam wave is detected.
+type Lockdown struct {
+	FollowersOnly         bool          // FollowersOnly allows only followers to chat.
+	FollowersOnlyDuration time.Duration // FollowersOnlyDuration is how long a user must follow the channel to chat.
+	EmoteOnly             bool          // EmoteOnly allows only emotes in messages.
+	SlowMode              time.Duration // SlowMode is the minimum time between messages of a user, zero leaves slow mode unchanged.
+}
+
+// DefaultConfig is a configuration suitable for most channels.
+var DefaultConfig = Config{
+	Window:            20 * time.Second,
+	Similarity:        0.7,
+	MinUsers:          6,
+	RelaxedMinUsers:   25,
+	MinFirstTimeRatio: 0.5,
+	MinMessageLength:  10,
+	Calm:              2 * time.Minute,
+	TimeoutDuration:   10 * time.Minute,
+	ExemptLevel:       permission.VIP,
+	Lockdown: Lockdown{
+		FollowersOnly:         true,
+		FollowersOnlyDuration: 10 * time.Minute,
+		SlowMode:              10 * time.Second,
+	},
+}
+
+// chatSettings reads and changes chat settings of a channel.
+type chatSettings interface {
+	Get(ctx context.Context, broadcasterID string) (helix.ChatSettings, error)
+	Update(ctx context.Context, broadcasterID string, params helix.UpdateChatSettingsParams) error
+}
+
+// moderator times out users.
+type moderator interface {
+	Timeout(ctx context.Context, record actions.Record) error
+}
+
+// chatClient sends messages to the chat.
+type chatClient interface {
+	Say(channelName, message string)
+}
+
+// levelResolver returns a permission level of the author of a message.
+type levelResolver interface {
+	LevelOf(ctx context.Context, privMsg *twitch.PrivateMessage, required permission.Level) (permission.Level, error)
+}
+
+// lockdown holds a state of a locked channel.
+type lockdown struct {
+	channelName   string
+	broadcasterID string
+	previous      helix.ChatSettings // Previous holds chat settings from before the lockdown, they are restored after it.
+	locked        bool               // Locked tells, if chat settings were changed.
+	lockDone      chan struct{}      // LockDone is closed, when changing chat settings has finished, successfully or not.
+	lastWave      time.Time          // LastWave is when the last message of the wave was seen.
+	timedOut      map[string]bool    // TimedOut holds IDs of users already timed out during the lockdown.
+}
+
+// Guard watches the chat for spam waves, like hate raids, and locks the chat down until it calms.
+type Guard struct {
+	config          Config
+	detector        *detector
+	chatSettings    chatSettings         // ChatSettings is used to lock and unlock the chat.
+	moderator       moderator            // Moderator times out users in a wave.
+	chatClient      chatClient           // ChatClient is used to alert moderators.
+	levelResolver   levelResolver        // LevelResolver is used for exempting trusted users.
+	chatbotName     string               // ChatbotName is saved as a moderator of timeouts.
+	prefix          string               // Prefix is a prefix of commands, which are never a part of a wave.
+	lockdowns       map[string]*lockdown // Lockdowns holds locked channels.
+	relaxed         map[string]time.Time // Relaxed holds until when the guard needs more users for a wave on a channel.
+	mu              sync.Mutex           // Mu guards the detector and lockdowns.
+	now             func() time.Time     // Now returns the current time.
+	logger          *zap.Logger          // Logger is used for logging.
+	lockdownCounter metric.Int64Counter  // LockdownCounter counts lockdowns.
+}
+
+// NewGuard creates an instance of Guard.
+func NewGuard(config Config, chatSettings chatSettings, moderator moderator, chatClient chatClient, levelResolver levelResolver, chatbotName, prefix string, logger *zap.Logger) (*Guard, error) {
+	lockdownCounter, err := meter.Int64Counter(
+		"moderation.raidguard.lockdown.counter",
+		metric.WithDescription("Number of chat lockdowns caused by spam waves."),
+		metric.WithUnit("{lockdown}"),
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	return &Guard{
+		config:          config,
+		detector:        newDetector(config),
+		chatSettings:    chatSettings,
+		moderator:       moderator,
+		chatClient:      chatClient,
+		levelResolver:   levelResolver,
+		chatbotName:     chatbotName,
+		prefix:          prefix,
+		lockdowns:       make(map[string]*lockdown),
+		relaxed:         make(map[string]time.Time),
+		now:             time.Now,
+		logger:          logger.Named("raidguard"),
+		lockdownCounter: lockdownCounter,
+	}, nil
+}
+
+// Observe checks, if a message is a part of a spam wave. The first message of a wave locks the chat down,
+// and authors of all messages in the wave are timed out in the background.
+// It returns true for messages of the wave, so they should not be processed any further.
+// Commands and short messages are ignored, because many viewers legitimately post the same ones,
+// like `!join`, votes in a poll or a keyword of a giveaway.
+func (g *Guard) Observe(ctx context.Context, privMsg *twitch.PrivateMessage) bool {
+	if g.isIgnored(privMsg.Message) || g.isExempt(ctx, privMsg) {
+		return false
+	}
+
+	g.mu.Lock()
+	now := g.now()
+	minUsers := g.config.MinUsers
+	if now.Before(g.relaxed[privMsg.Channel]) {
+		minUsers = max(minUsers, g.config.RelaxedMinUsers)
+	}
+
+	wave := g.detector.observe(now, privMsg, minUsers)
+	if wave == nil {
+		g.mu.Unlock()
+		return false
+	}
+
+	l, ok := g.lockdowns[privMsg.Channel]
+	if !ok {
+		l = &lockdown{channelName: privMsg.Channel, broadcasterID: privMsg.RoomID, lockDone: make(chan struct{}), timedOut: make(map[string]bool)}
+		g.lockdowns[privMsg.Channel] = l
+	}
+	l.lastWave = now
+
+	var targets []entry
+	for _, e := range wave {
+		if !l.timedOut[e.userID] {
+			l.timedOut[e.userID] = true
+			targets = append(targets, e)
+		}
+	}
+	g.mu.Unlock()
+
+	bgCtx := context.WithoutCancel(ctx)
+	if !ok {
+		g.lockdownCounter.Add(ctx, 1, metric.WithAttributes(attribute.String("channel.name", privMsg.Channel)))
+		g.logger.Warn("detected a spam wave, locking the chat down",
+			zap.String("channel_name", privMsg.Channel),
+			zap.Int("users", len(wave)),
+		)
+
+		go g.lock(bgCtx, l, len(wave))
+	}
+
+	if g.config.TimeoutDuration > 0 && len(targets) > 0 {
+		go g.timeout(bgCtx, privMsg, targets)
+	}
+
+	return true
+}
+
+// Relax raises the number of users needed for a wave on a channel for a duration,
+// e.g. when a friendly raid brings many new chatters posting the same message.
+// The guard keeps watching the channel, so a hate raid disguised as a friendly one is still stopped.
+func (g *Guard) Relax(channelName string, duration time.Duration) {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+
+	g.relaxed[channelName] = g.now().Add(duration)
+}
+
+// Run lifts lockdowns of channels that calmed down, until the context is done.
+// After that, all remaining lockdowns are lifted, so the chat is not left locked when the chatbot stops.
+func (g *Guard) Run(ctx context.Context) error {
+	ticker := time.NewTicker(max(g.config.Calm/4, time.Second))
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			g.unlockCalm(context.WithoutCancel(ctx), true)
+			return nil
+		case <-ticker.C:
+			g.unlockCalm(ctx, false)
+		}
+	}
+}
+
+// isIgnored reports whether a message is a command or too short to be compared with other messages.
+func (g *Guard) isIgnored(message string) bool {
+	message = strings.TrimSpace(message)
+	if g.prefix != "" && strings.HasPrefix(message, g.prefix) {
+		return true
+	}
+
+	return len([]rune(blocklist.Normalize(message))) < g.config.MinMessageLength
+}
+
+// isExempt reports whether the author of the message is trusted enough to be ignored by the guard.
+func (g *Guard) isExempt(ctx context.Context, privMsg *twitch.PrivateMessage) bool {
+	level, err := g.levelResolver.LevelOf(ctx, privMsg, g.config.ExemptLevel)
+	if err != nil {
+		g.logger.Warn("failed to resolve a permission level of a user", zap.Error(err))
+		return false
+	}
+
+	return level >= g.config.ExemptLevel
+}
+
+// lock enables the lockdown chat settings and alerts moderators.
+func (g *Guard) lock(ctx context.Context, l *lockdown, users int) {
+	defer close(l.lockDone)
+
+	ctx, span := tracer.Start(ctx, "lock")
+	defer span.End()
+
+	span.SetAttributes(attribute.String("channel.name", l.channelName))
+
+	previous, err := g.chatSettings.Get(ctx, l.broadcasterID)
+	if err == nil {
+		err = g.chatSettings.Update(ctx, l.broadcasterID, g.lockdownParams())
+	}
+	if err != nil {
+		span.SetStatus(codes.Error, "failed to lock the chat down")
+		span.RecordError(err)
+		g.logger.Error("failed to lock the chat down", zap.String("channel_name", l.channelName), zap.Error(err))
+		g.chatClient.Say(l.channelName, fmt.Sprintf("Spam wave detected (%d accounts), but I could not lock the chat down. Moderators, please act!", users))
+		return
+	}
+
+	g.mu.Lock()
+	l.previous = previous
+	l.locked = true
+	g.mu.Unlock()
+
+	g.chatClient.Say(l.channelName, fmt.Sprintf("Spam wave detected (%d accounts), the chat is locked down until it calms. Moderators, please review.", users))
+	span.SetStatus(codes.Ok, "successfully locked the chat down")
+}
+
+// timeout times out users of a wave.
+func (g *Guard) timeout(ctx context.Context, privMsg *twitch.PrivateMessage, targets []entry) {
+	for _, e := range targets {
+		err := g.moderator.Timeout(ctx, actions.Record{
+			ChannelName:   privMsg.Channel,
+			BroadcasterID: privMsg.RoomID,
+			UserID:        e.userID,
+			Username:      strings.ToLower(e.username),
+			Moderator:     g.chatbotName,
+			Reason:        "spam wave",
+			Trigger:       "rule:raidguard",
+			Duration:      g.config.TimeoutDuration,
+		})
+		if err != nil {
+			g.logger.Error("failed to time out a user of a spam wave", zap.String("username", e.username), zap.Error(err))
+		}
+	}
+}
+
+// unlockCalm lifts lockdowns of channels without a wave for the calm period, or all of them, when force is true.
+func (g *Guard) unlockCalm(ctx context.Context, force bool) {
+	g.mu.Lock()
+	now := g.now()
+	var calm []*lockdown
+	for channelName, l := range g.lockdowns {
+		if force || now.Sub(l.lastWave) >= g.config.Calm {
+			calm = append(calm, l)
+			delete(g.lockdowns, channelName)
+		}
+	}
+	g.mu.Unlock()
+
+	for _, l := range calm {
+		// The lockdown may still be in progress, so wait for it. Otherwise the chat would stay locked.
+		<-l.lockDone
+
+		g.mu.Lock()
+		locked := l.locked
+		g.mu.Unlock()
+		if !locked {
+			continue
+		}
+
+		err := g.chatSettings.Update(ctx, l.broadcasterID, chatsettings.Revert(l.previous, g.lockdownParams()))
+		if err != nil {
+			g.logger.Error("failed to lift a lockdown", zap.String("channel_name", l.channelName), zap.Error(err))
+			g.chatClient.Say(l.channelName, "I could not lift the lockdown, moderators, please restore chat settings.")
+			continue
+		}
+
+		g.logger.Info("lifted a lockdown", zap.String("channel_name", l.channelName))
+		g.chatClient.Say(l.channelName, "The chat calmed down, the lockdown is lifted.")
+	}
+}
+
+// lockdownParams returns chat settings changed by a lockdown.
+func (g *Guard) lockdownParams() helix.UpdateChatSettingsParams {
+	var params helix.UpdateChatSettingsParams
+	lockdown := g.config.Lockdown
+
+	if lockdown.FollowersOnly {
+		params.FollowerMode = ptr(true)
+		params.FollowerModeDuration = ptr(int(lockdown.FollowersOnlyDuration.Minutes()))
+	}
+	if lockdown.EmoteOnly {
+		params.EmoteMode = ptr(true)
+	}
+	if lockdown.SlowMode > 0 {
+		params.SlowMode = ptr(true)
+		params.SlowModeWaitTime = ptr(int(lockdown.SlowMode.Seconds()))
+	}
+
+	return params
+}
+
+func ptr[T any](v T) *T {
+	return &v
+}
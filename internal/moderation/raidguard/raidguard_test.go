@@ -0,0 +1,299 @@
+package raidguard
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/danielbukowski/twitch-chatbot/internal/moderation/actions"
+	"github.com/danielbukowski/twitch-chatbot/internal/permission"
+	"github.com/gempir/go-twitch-irc/v4"
+	"github.com/nicklaw5/helix/v2"
+	"go.uber.org/zap"
+)
+
+type chatSettingsMock struct {
+	mu      sync.Mutex
+	current helix.ChatSettings
+	updates []helix.UpdateChatSettingsParams
+	updated chan struct{}
+	release chan struct{} // Release, when it's not nil, holds Get until it's closed.
+}
+
+func (c *chatSettingsMock) Get(_ context.Context, _ string) (helix.ChatSettings, error) {
+	if c.release != nil {
+		<-c.release
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.current, nil
+}
+
+func (c *chatSettingsMock) Update(_ context.Context, _ string, params helix.UpdateChatSettingsParams) error {
+	c.mu.Lock()
+	c.updates = append(c.updates, params)
+	c.mu.Unlock()
+	c.updated <- struct{}{}
+	return nil
+}
+
+type moderatorMock struct {
+	timedOut chan string
+}
+
+func (m *moderatorMock) Timeout(_ context.Context, record actions.Record) error {
+	m.timedOut <- record.UserID
+	return nil
+}
+
+type chatClientMock struct{}
+
+func (chatClientMock) Say(_, _ string) {}
+
+type levelResolverMock struct{}
+
+func (levelResolverMock) LevelOf(_ context.Context, privMsg *twitch.PrivateMessage, _ permission.Level) (permission.Level, error) {
+	if privMsg.User.Badges["moderator"] == 1 {
+		return permission.Moderator, nil
+	}
+	return permission.Everyone, nil
+}
+
+func message(userID, text string, firstMessage bool) *twitch.PrivateMessage {
+	return &twitch.PrivateMessage{
+		Channel:      "channel",
+		RoomID:       "channel-id",
+		Message:      text,
+		FirstMessage: firstMessage,
+		User:         twitch.User{ID: userID, Name: "user" + userID},
+	}
+}
+
+func TestDetector(t *testing.T) {
+	config := Config{Window: 10 * time.Second, Similarity: 0.7, MinUsers: 5, MinFirstTimeRatio: 0.5}
+	start := time.Date(2026, 10, 19, 12, 0, 0, 0, time.UTC)
+
+	testCases := []struct {
+		name         string
+		text         func(i int) string
+		first        bool
+		interval     time.Duration
+		expectedWave bool
+	}{
+		{
+			name:         "detects near-identical messages of new chatters",
+			text:         func(i int) string { return fmt.Sprintf("this channel is garbage, follow my friend instead %d", i) },
+			first:        true,
+			interval:     time.Second,
+			expectedWave: true,
+		},
+		{
+			name: "detects messages obfuscated with leetspeak",
+			text: func(i int) string {
+				return []string{"get free followers at spam dot com", "g3t fr33 f0ll0wers at sp4m d0t c0m"}[i%2]
+			},
+			first:        true,
+			interval:     time.Second,
+			expectedWave: true,
+		},
+		{
+			name: "ignores different messages",
+			text: func(i int) string {
+				return []string{"hello", "nice play", "what game is this", "gg", "lol that was close", "pog"}[i]
+			},
+			first:    true,
+			interval: time.Second,
+		},
+		{
+			name:     "ignores a copy pasta of regular chatters",
+			text:     func(int) string { return "this is a copy pasta everyone posts together" },
+			interval: time.Second,
+		},
+		{
+			name:     "ignores similar messages spread over a longer time than the window",
+			text:     func(int) string { return "this channel is garbage, follow my friend instead" },
+			first:    true,
+			interval: 5 * time.Second,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			// given
+			d := newDetector(config)
+
+			// when
+			var wave []entry
+			for i := range 6 {
+				wave = d.observe(start.Add(time.Duration(i)*tc.interval), message(fmt.Sprint(i), tc.text(i), tc.first), config.MinUsers)
+			}
+
+			// then
+			if (wave != nil) != tc.expectedWave {
+				t.Errorf("Expected a wave `%v`, got `%v`", tc.expectedWave, wave)
+			}
+		})
+	}
+}
+
+func TestGuard(t *testing.T) {
+	t.Run("locks the chat down, times out the wave and restores settings after calm", func(t *testing.T) {
+		// given
+		chatSettings := &chatSettingsMock{current: helix.ChatSettings{SlowMode: true, SlowModeWaitTime: 3}, updated: make(chan struct{}, 2)}
+		moderator := &moderatorMock{timedOut: make(chan string, 10)}
+		config := DefaultConfig
+		config.MinUsers = 3
+
+		guard, err := NewGuard(config, chatSettings, moderator, chatClientMock{}, levelResolverMock{}, "chatbot", "!", zap.NewNop())
+		if err != nil {
+			t.Fatalf("Expected no error, got `%v`", err)
+		}
+
+		now := time.Date(2026, 10, 19, 12, 0, 0, 0, time.UTC)
+		guard.now = func() time.Time { return now }
+
+		// when
+		var stopped []bool
+		for i := range 4 {
+			stopped = append(stopped, guard.Observe(context.Background(), message(fmt.Sprint(i), "follow my friend, this stream is garbage", true)))
+		}
+		<-chatSettings.updated
+
+		// then
+		if fmt.Sprint(stopped) != "[false false true true]" {
+			t.Errorf("Expected only messages of the detected wave to be stopped, got `%v`", stopped)
+		}
+		for range 4 {
+			select {
+			case <-moderator.timedOut:
+			case <-time.After(time.Second):
+				t.Fatalf("Expected all 4 users of the wave to be timed out")
+			}
+		}
+		if lockdown := chatSettings.updates[0]; lockdown.FollowerMode == nil || !*lockdown.FollowerMode || *lockdown.SlowModeWaitTime != 10 {
+			t.Errorf("Expected followers-only and slow mode to be enabled, got `%+v`", lockdown)
+		}
+
+		// when
+		now = now.Add(config.Calm)
+		guard.unlockCalm(context.Background(), false)
+		<-chatSettings.updated
+
+		// then
+		if restored := chatSettings.updates[1]; *restored.FollowerMode || !*restored.SlowMode || *restored.SlowModeWaitTime != 3 {
+			t.Errorf("Expected settings from before the lockdown to be restored, got `%+v`", restored)
+		}
+	})
+
+	t.Run("lifts a lockdown, which was still in progress, after it finishes", func(t *testing.T) {
+		// given
+		chatSettings := &chatSettingsMock{updated: make(chan struct{}, 2), release: make(chan struct{})}
+		config := DefaultConfig
+		config.MinUsers = 3
+		config.TimeoutDuration = 0
+
+		guard, err := NewGuard(config, chatSettings, &moderatorMock{}, chatClientMock{}, levelResolverMock{}, "chatbot", "!", zap.NewNop())
+		if err != nil {
+			t.Fatalf("Expected no error, got `%v`", err)
+		}
+
+		now := time.Date(2026, 10, 19, 12, 0, 0, 0, time.UTC)
+		guard.now = func() time.Time { return now }
+
+		for i := range 3 {
+			guard.Observe(context.Background(), message(fmt.Sprint(i), "follow my friend, this stream is garbage", true))
+		}
+
+		// when
+		now = now.Add(config.Calm)
+		unlocked := make(chan struct{})
+		go func() {
+			guard.unlockCalm(context.Background(), false)
+			close(unlocked)
+		}()
+		close(chatSettings.release)
+
+		// then
+		select {
+		case <-unlocked:
+		case <-time.After(time.Second):
+			t.Fatalf("Expected the lockdown to be lifted")
+		}
+		chatSettings.mu.Lock()
+		defer chatSettings.mu.Unlock()
+		if len(chatSettings.updates) != 2 {
+			t.Errorf("Expected the chat to be locked and unlocked, got `%+v`", chatSettings.updates)
+		}
+	})
+
+	t.Run("needs more users for a wave after a raid, but still detects it", func(t *testing.T) {
+		// given
+		config := DefaultConfig
+		config.MinUsers = 3
+		config.RelaxedMinUsers = 5
+		config.TimeoutDuration = 0
+
+		guard, err := NewGuard(config, &chatSettingsMock{updated: make(chan struct{}, 1)}, &moderatorMock{}, chatClientMock{}, levelResolverMock{}, "chatbot", "!", zap.NewNop())
+		if err != nil {
+			t.Fatalf("Expected no error, got `%v`", err)
+		}
+		guard.Relax("channel", time.Minute)
+
+		// when
+		var stopped []bool
+		for i := range 5 {
+			stopped = append(stopped, guard.Observe(context.Background(), message(fmt.Sprint(i), "follow my friend, this stream is garbage", true)))
+		}
+
+		// then
+		if fmt.Sprint(stopped) != "[false false false false true]" {
+			t.Errorf("Expected a wave to be detected only with 5 users, got `%v`", stopped)
+		}
+	})
+
+	t.Run("ignores commands and short messages", func(t *testing.T) {
+		// given
+		config := DefaultConfig
+		config.MinUsers = 3
+
+		guard, err := NewGuard(config, &chatSettingsMock{}, &moderatorMock{}, chatClientMock{}, levelResolverMock{}, "chatbot", "!", zap.NewNop())
+		if err != nil {
+			t.Fatalf("Expected no error, got `%v`", err)
+		}
+
+		// when
+		stopped := false
+		for i := range 10 {
+			stopped = stopped || guard.Observe(context.Background(), message(fmt.Sprint(i), "!join the giveaway please", true))
+			stopped = stopped || guard.Observe(context.Background(), message(fmt.Sprint(i), "1", true))
+		}
+
+		// then
+		if stopped {
+			t.Errorf("Expected commands and votes to be ignored")
+		}
+	})
+
+	t.Run("ignores exempt users", func(t *testing.T) {
+		// given
+		guard, err := NewGuard(DefaultConfig, &chatSettingsMock{}, &moderatorMock{}, chatClientMock{}, levelResolverMock{}, "chatbot", "!", zap.NewNop())
+		if err != nil {
+			t.Fatalf("Expected no error, got `%v`", err)
+		}
+
+		// when
+		stopped := false
+		for i := range 10 {
+			privMsg := message(fmt.Sprint(i), "please vote in the poll", true)
+			privMsg.User.Badges = map[string]int{"moderator": 1}
+			stopped = stopped || guard.Observe(context.Background(), privMsg)
+		}
+
+		// then
+		if stopped {
+			t.Errorf("Expected messages of moderators to be ignored")
+		}
+	})
+}
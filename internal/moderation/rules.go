@@ -0,0 +1,222 @@
+package moderation
+
+import (
+	"fmt"
+	"net/url"
+	"regexp"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+
+	"github.com/gempir/go-twitch-irc/v4"
+)
+
+var linkRegexp = regexp.MustCompile(`(?i)\b(https?://|www\.)?((?:[a-z0-9](?:[a-z0-9-]*[a-z0-9])?\.)+([a-z]{2,}))(:\d+)?([/?#]\S+)?`)
+
+// commonTLDs are top-level domains, which make a bare `name.tld` a link. Other words with a dot,
+// like `node.js` or `file.txt`, are links only with a scheme, `www.`, a port or a path.
+var commonTLDs = map[string]bool{
+	"com": true, "net": true, "org": true, "info": true, "biz": true, "io": true, "co": true, "tv": true,
+	"gg": true, "ly": true, "xyz": true, "ru": true, "de": true, "uk": true, "us": true, "app": true,
+	"dev": true, "live": true, "link": true, "site": true, "online": true, "shop": true, "store": true,
+	"top": true, "click": true, "club": true,
+}
+
+// LinkRule rejects messages with links to domains outside of the allowlist.
+// Subdomains of allowed domains are allowed too.
+type LinkRule struct {
+	AllowedDomains []string
+	Punishment     Punishment
+}
+
+func (r LinkRule) Name() string {
+	return "link"
+}
+
+func (r LinkRule) Check(privMsg *twitch.PrivateMessage) (Violation, bool) {
+	for _, match := range linkRegexp.FindAllStringSubmatch(privMsg.Message, -1) {
+		prefix, host, tld, port, path := match[1], strings.ToLower(match[2]), strings.ToLower(match[3]), match[4], match[5]
+		if prefix == "" && port == "" && path == "" && !commonTLDs[tld] {
+			continue
+		}
+		if u, err := url.Parse("//" + host); err == nil {
+			host = u.Hostname()
+		}
+
+		if !r.isAllowed(host) {
+			return Violation{Rule: r.Name(), Reason: "links are not allowed", Punishment: r.Punishment}, true
+		}
+	}
+
+	return Violation{}, false
+}
+
+func (r LinkRule) isAllowed(host string) bool {
+	for _, domain := range r.AllowedDomains {
+		domain = strings.ToLower(domain)
+		if host == domain || strings.HasSuffix(host, "."+domain) {
+			return true
+		}
+	}
+
+	return false
+}
+
+// CapsRule rejects messages with too many capital letters. Emotes are not counted.
+type CapsRule struct {
+	MinLength  int     // MinLength is a minimum number of letters in a message to check it, short messages like `GG` are fine.
+	MaxRatio   float64 // MaxRatio is a maximum ratio of capital letters to all letters.
+	Punishment Punishment
+}
+
+func (r CapsRule) Name() string {
+	return "caps"
+}
+
+func (r CapsRule) Check(privMsg *twitch.PrivateMessage) (Violation, bool) {
+	letters, upper := 0, 0
+	for _, c := range withoutEmotes(privMsg) {
+		if !unicode.IsLetter(c) {
+			continue
+		}
+
+		letters++
+		if unicode.IsUpper(c) {
+			upper++
+		}
+	}
+
+	if letters < r.MinLength || float64(upper)/float64(letters) <= r.MaxRatio {
+		return Violation{}, false
+	}
+
+	return Violation{Rule: r.Name(), Reason: "too many capital letters", Punishment: r.Punishment}, true
+}
+
+// SymbolRule rejects messages with too many symbols. Emotes are not counted.
+type SymbolRule struct {
+	MinLength  int     // MinLength is a minimum number of characters in a message, excluding spaces, to check it.
+	MaxRatio   float64 // MaxRatio is a maximum ratio of symbols to all characters, excluding spaces.
+	Punishment Punishment
+}
+
+func (r SymbolRule) Name() string {
+	return "symbols"
+}
+
+func (r SymbolRule) Check(privMsg *twitch.PrivateMessage) (Violation, bool) {
+	characters, symbols := 0, 0
+	for _, c := range withoutEmotes(privMsg) {
+		if unicode.IsSpace(c) {
+			continue
+		}
+
+		characters++
+		if !unicode.IsLetter(c) && !unicode.IsDigit(c) {
+			symbols++
+		}
+	}
+
+	if characters < r.MinLength || float64(symbols)/float64(characters) <= r.MaxRatio {
+		return Violation{}, false
+	}
+
+	return Violation{Rule: r.Name(), Reason: "too many symbols", Punishment: r.Punishment}, true
+}
+
+// EmoteSpamRule rejects messages with too many emotes, it uses emote metadata of a message.
+type EmoteSpamRule struct {
+	MaxEmotes  int
+	Punishment Punishment
+}
+
+func (r EmoteSpamRule) Name() string {
+	return "emote_spam"
+}
+
+func (r EmoteSpamRule) Check(privMsg *twitch.PrivateMessage) (Violation, bool) {
+	count := 0
+	for _, emote := range privMsg.Emotes {
+		count += emote.Count
+	}
+
+	if count <= r.MaxEmotes {
+		return Violation{}, false
+	}
+
+	return Violation{Rule: r.Name(), Reason: "too many emotes", Punishment: r.Punishment}, true
+}
+
+// LengthRule rejects too long messages.
+type LengthRule struct {
+	MaxLength  int
+	Punishment Punishment
+}
+
+func (r LengthRule) Name() string {
+	return "length"
+}
+
+func (r LengthRule) Check(privMsg *twitch.PrivateMessage) (Violation, bool) {
+	if utf8.RuneCountInString(privMsg.Message) <= r.MaxLength {
+		return Violation{}, false
+	}
+
+	return Violation{Rule: r.Name(), Reason: fmt.Sprintf("messages can't be longer than %d characters", r.MaxLength), Punishment: r.Punishment}, true
+}
+
+// RepeatedCharsRule rejects messages with the same character repeated too many times in a row, like `heeeeeeeey`.
+type RepeatedCharsRule struct {
+	MaxRepeats int
+	Punishment Punishment
+}
+
+func (r RepeatedCharsRule) Name() string {
+	return "repeated_characters"
+}
+
+func (r RepeatedCharsRule) Check(privMsg *twitch.PrivateMessage) (Violation, bool) {
+	var previous rune
+	repeats := 0
+
+	for _, c := range privMsg.Message {
+		if c == previous && !unicode.IsSpace(c) {
+			repeats++
+		} else {
+			previous = c
+			repeats = 1
+		}
+
+		if repeats > r.MaxRepeats {
+			return Violation{Rule: r.Name(), Reason: "too many repeated characters", Punishment: r.Punishment}, true
+		}
+	}
+
+	return Violation{}, false
+}
+
+// withoutEmotes returns characters of a message without Twitch emotes. Positions of emotes are indexes of characters.
+func withoutEmotes(privMsg *twitch.PrivateMessage) []rune {
+	runes := []rune(privMsg.Message)
+	if len(privMsg.Emotes) == 0 {
+		return runes
+	}
+
+	isEmote := make([]bool, len(runes))
+	for _, emote := range privMsg.Emotes {
+		for _, position := range emote.Positions {
+			for i := max(position.Start, 0); i <= position.End && i < len(runes); i++ {
+				isEmote[i] = true
+			}
+		}
+	}
+
+	result := make([]rune, 0, len(runes))
+	for i, c := range runes {
+		if !isEmote[i] {
+			result = append(result, c)
+		}
+	}
+
+	return result
+}
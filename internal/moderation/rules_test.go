@@ -0,0 +1,112 @@
+package moderation
+
+import (
+	"testing"
+
+	"github.com/gempir/go-twitch-irc/v4"
+)
+
+func TestRules(t *testing.T) {
+	testCases := []struct {
+		name     string
+		rule     Rule
+		privMsg  *twitch.PrivateMessage
+		expected bool
+	}{
+		{
+			name:     "link rule rejects a link to a domain outside of the allowlist",
+			rule:     LinkRule{AllowedDomains: []string{"twitch.tv"}},
+			privMsg:  &twitch.PrivateMessage{Message: "buy followers at cheap-followers.com/now"},
+			expected: true,
+		},
+		{
+			name:     "link rule allows a link to a subdomain of an allowed domain",
+			rule:     LinkRule{AllowedDomains: []string{"twitch.tv"}},
+			privMsg:  &twitch.PrivateMessage{Message: "look at https://clips.twitch.tv/SomeClip"},
+			expected: false,
+		},
+		{
+			name:     "link rule rejects a bare domain with a common top-level domain",
+			rule:     LinkRule{AllowedDomains: []string{"twitch.tv"}},
+			privMsg:  &twitch.PrivateMessage{Message: "visit spam.com for free stuff"},
+			expected: true,
+		},
+		{
+			name:     "link rule rejects a link with a scheme and an unusual top-level domain",
+			rule:     LinkRule{AllowedDomains: []string{"twitch.tv"}},
+			privMsg:  &twitch.PrivateMessage{Message: "see http://spam.zz"},
+			expected: true,
+		},
+		{
+			name:     "link rule allows a name of a library",
+			rule:     LinkRule{AllowedDomains: []string{"twitch.tv"}},
+			privMsg:  &twitch.PrivateMessage{Message: "the overlay is written in node.js"},
+			expected: false,
+		},
+		{
+			name:     "link rule allows a name of a file",
+			rule:     LinkRule{AllowedDomains: []string{"twitch.tv"}},
+			privMsg:  &twitch.PrivateMessage{Message: "open file.txt and read it"},
+			expected: false,
+		},
+		{
+			name:     "link rule allows words joined by a dot",
+			rule:     LinkRule{AllowedDomains: []string{"twitch.tv"}},
+			privMsg:  &twitch.PrivateMessage{Message: "ok.so what now?"},
+			expected: false,
+		},
+		{
+			name:     "caps rule rejects a shouting message",
+			rule:     CapsRule{MinLength: 10, MaxRatio: 0.7},
+			privMsg:  &twitch.PrivateMessage{Message: "WHY IS NOBODY LISTENING TO ME"},
+			expected: true,
+		},
+		{
+			name: "caps rule does not count emotes",
+			rule: CapsRule{MinLength: 5, MaxRatio: 0.7},
+			privMsg: &twitch.PrivateMessage{
+				Message: "that was funny LUL LUL LUL",
+				Emotes: []*twitch.Emote{{Name: "LUL", Count: 3, Positions: []twitch.EmotePosition{
+					{Start: 15, End: 17}, {Start: 19, End: 21}, {Start: 23, End: 25},
+				}}},
+			},
+			expected: false,
+		},
+		{
+			name:     "symbol rule rejects a message made of symbols",
+			rule:     SymbolRule{MinLength: 5, MaxRatio: 0.5},
+			privMsg:  &twitch.PrivateMessage{Message: "hi ▓▓▓▓▓▓▓▓▓▓"},
+			expected: true,
+		},
+		{
+			name:     "emote spam rule rejects too many emotes",
+			rule:     EmoteSpamRule{MaxEmotes: 5},
+			privMsg:  &twitch.PrivateMessage{Emotes: []*twitch.Emote{{Name: "Kappa", Count: 4}, {Name: "LUL", Count: 3}}},
+			expected: true,
+		},
+		{
+			name:     "length rule allows a short message",
+			rule:     LengthRule{MaxLength: 10},
+			privMsg:  &twitch.PrivateMessage{Message: "hello"},
+			expected: false,
+		},
+		{
+			name:     "repeated characters rule rejects a long run of the same character",
+			rule:     RepeatedCharsRule{MaxRepeats: 5},
+			privMsg:  &twitch.PrivateMessage{Message: "heeeeeeeeey"},
+			expected: true,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			// when
+			_, got := tc.rule.Check(tc.privMsg)
+
+			// then
+			if got != tc.expected {
+				t.Errorf("Expected `%v`, got `%v`", tc.expected, got)
+			}
+		})
+	}
+}
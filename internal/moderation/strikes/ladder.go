@@ -0,0 +1,58 @@
+package strikes
+
+import (
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/danielbukowski/twitch-chatbot/internal/moderation"
+)
+
+// Ladder holds punishments for the following strikes. The last step is used for all strikes above the length of the ladder.
+type Ladder []moderation.Punishment
+
+// DefaultLadder warns for the first offense, times out for 60 seconds for the second one,
+// times out for 10 minutes for the third one and bans for the fourth one.
+var DefaultLadder = Ladder{
+	{Action: moderation.ActionWarn},
+	{Action: moderation.ActionTimeout, Duration: 60 * time.Second},
+	{Action: moderation.ActionTimeout, Duration: 10 * time.Minute},
+	{Action: moderation.ActionBan},
+}
+
+// Step returns a punishment for the given number of active strikes.
+func (l Ladder) Step(strikes int) moderation.Punishment {
+	if len(l) == 0 {
+		return moderation.Punishment{Action: moderation.ActionWarn}
+	}
+
+	return l[min(max(strikes, 1), len(l))-1]
+}
+
+// ParseLadder creates a ladder from a comma separated list of steps, like `warn,timeout:60s,timeout:10m,ban`.
+func ParseLadder(text string) (Ladder, error) {
+	ladder := Ladder{}
+
+	for _, step := range strings.Split(text, ",") {
+		action, duration, _ := strings.Cut(strings.TrimSpace(step), ":")
+
+		switch strings.ToLower(action) {
+		case "warn":
+			ladder = append(ladder, moderation.Punishment{Action: moderation.ActionWarn})
+		case "delete":
+			ladder = append(ladder, moderation.Punishment{Action: moderation.ActionDelete})
+		case "timeout":
+			d, err := time.ParseDuration(duration)
+			if err != nil {
+				return nil, fmt.Errorf("invalid duration of a timeout in step '%s': %w", step, err)
+			}
+			ladder = append(ladder, moderation.Punishment{Action: moderation.ActionTimeout, Duration: d})
+		case "ban":
+			ladder = append(ladder, moderation.Punishment{Action: moderation.ActionBan})
+		default:
+			return nil, fmt.Errorf("unknown action in step '%s'", step)
+		}
+	}
+
+	return ladder, nil
+}
@@ -0,0 +1,125 @@
+package strikes
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"time"
+
+	"github.com/danielbukowski/twitch-chatbot/internal/database"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/codes"
+)
+
+var tracer = otel.Tracer("github.com/danielbukowski/twitch-chatbot/internal/moderation/strikes")
+
+// SQLiteStorage stores strikes of users.
+type SQLiteStorage struct {
+	db *sql.DB
+}
+
+func NewSQLiteStorage(db *sql.DB) *SQLiteStorage {
+	return &SQLiteStorage{db: db}
+}
+
+// Save adds a strike to a user and returns a number of the user's strikes given after since, including the new one.
+func (s *SQLiteStorage) Save(ctx context.Context, channelName, userID, username, rule string, createdAt, since time.Time) (int, error) {
+	insertQuery := "INSERT INTO strikes (channel_name, user_id, username, rule, created_at) VALUES (?, ?, ?, ?, ?);"
+	countQuery := "SELECT COUNT(*) FROM strikes WHERE channel_name = ? AND user_id = ? AND created_at > ?;"
+
+	ctx, span := tracer.Start(ctx, "save")
+	defer span.End()
+
+	ctx, cancel := context.WithTimeout(ctx, database.RequestTimeout)
+	defer cancel()
+
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		errMsg := "failed to begin a transaction"
+		span.SetStatus(codes.Error, errMsg)
+		span.RecordError(err)
+		return 0, errors.Join(errors.New(errMsg), err)
+	}
+	//nolint:errcheck // rollback after commit returns an error that doesn't matter
+	defer tx.Rollback()
+
+	_, err = tx.ExecContext(ctx, insertQuery, channelName, userID, username, rule, createdAt.Unix())
+	if err != nil {
+		errMsg := "failed to save a strike"
+		span.SetStatus(codes.Error, errMsg)
+		span.RecordError(err)
+		return 0, errors.Join(errors.New(errMsg), err)
+	}
+
+	var count int
+	err = tx.QueryRowContext(ctx, countQuery, channelName, userID, since.Unix()).Scan(&count)
+	if err != nil {
+		errMsg := "failed to count strikes"
+		span.SetStatus(codes.Error, errMsg)
+		span.RecordError(err)
+		return 0, errors.Join(errors.New(errMsg), err)
+	}
+
+	if err = tx.Commit(); err != nil {
+		errMsg := "failed to commit a transaction"
+		span.SetStatus(codes.Error, errMsg)
+		span.RecordError(err)
+		return 0, errors.Join(errors.New(errMsg), err)
+	}
+
+	span.SetStatus(codes.Ok, "successfully saved a strike")
+	return count, nil
+}
+
+// CountByUsername returns a number of user's strikes given after since.
+func (s *SQLiteStorage) CountByUsername(ctx context.Context, channelName, username string, since time.Time) (int, error) {
+	query := "SELECT COUNT(*) FROM strikes WHERE channel_name = ? AND username = ? AND created_at > ?;"
+
+	ctx, span := tracer.Start(ctx, "countByUsername")
+	defer span.End()
+
+	ctx, cancel := context.WithTimeout(ctx, database.RequestTimeout)
+	defer cancel()
+
+	var count int
+	err := s.db.QueryRowContext(ctx, query, channelName, username, since.Unix()).Scan(&count)
+	if err != nil {
+		errMsg := "failed to count strikes"
+		span.SetStatus(codes.Error, errMsg)
+		span.RecordError(err)
+		return 0, errors.Join(errors.New(errMsg), err)
+	}
+
+	span.SetStatus(codes.Ok, "successfully counted strikes")
+	return count, nil
+}
+
+// DeleteByUsername removes all strikes of a user and returns a number of removed strikes.
+func (s *SQLiteStorage) DeleteByUsername(ctx context.Context, channelName, username string) (int64, error) {
+	query := "DELETE FROM strikes WHERE channel_name = ? AND username = ?;"
+
+	ctx, span := tracer.Start(ctx, "deleteByUsername")
+	defer span.End()
+
+	ctx, cancel := context.WithTimeout(ctx, database.RequestTimeout)
+	defer cancel()
+
+	res, err := s.db.ExecContext(ctx, query, channelName, username)
+	if err != nil {
+		errMsg := "failed to delete strikes"
+		span.SetStatus(codes.Error, errMsg)
+		span.RecordError(err)
+		return 0, errors.Join(errors.New(errMsg), err)
+	}
+
+	rows, err := res.RowsAffected()
+	if err != nil {
+		errMsg := "failed to count deleted strikes"
+		span.SetStatus(codes.Error, errMsg)
+		span.RecordError(err)
+		return 0, errors.Join(errors.New(errMsg), err)
+	}
+
+	span.SetStatus(codes.Ok, "successfully deleted strikes")
+	return rows, nil
+}
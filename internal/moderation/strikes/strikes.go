@@ -0,0 +1,138 @@
+package strikes
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/danielbukowski/twitch-chatbot/internal/command"
+	"github.com/danielbukowski/twitch-chatbot/internal/moderation"
+	"github.com/gempir/go-twitch-irc/v4"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+)
+
+type storage interface {
+	Save(ctx context.Context, channelName, userID, username, rule string, createdAt, since time.Time) (int, error)
+	CountByUsername(ctx context.Context, channelName, username string, since time.Time) (int, error)
+	DeleteByUsername(ctx context.Context, channelName, username string) (int64, error)
+}
+
+// Enforcer escalates punishments of users who keep breaking rules. Every violation gives the user a strike,
+// and the punishment comes from the ladder, based on the number of strikes given within the decay window.
+// When the rule itself has a more severe punishment than the ladder, the rule's punishment is used.
+type Enforcer struct {
+	storage storage             // Storage persists strikes.
+	next    moderation.Enforcer // Next executes the escalated punishment.
+	ladder  Ladder              // Ladder holds punishments for the following strikes.
+	decay   time.Duration       // Decay is a time after which a strike is no longer counted.
+	prefix  string              // Prefix is a prefix of commands.
+	now     func() time.Time    // Now returns the current time.
+}
+
+// NewEnforcer creates an instance of Enforcer.
+func NewEnforcer(storage storage, next moderation.Enforcer, ladder Ladder, decay time.Duration, prefix string) *Enforcer {
+	return &Enforcer{
+		storage: storage,
+		next:    next,
+		ladder:  ladder,
+		decay:   decay,
+		prefix:  prefix,
+		now:     time.Now,
+	}
+}
+
+// Enforce gives the user a strike and executes the escalated punishment.
+func (e *Enforcer) Enforce(ctx context.Context, privMsg *twitch.PrivateMessage, violation moderation.Violation) error {
+	spanCtx, span := tracer.Start(ctx, "enforce")
+	defer span.End()
+
+	now := e.now()
+	count, err := e.storage.Save(spanCtx, privMsg.Channel, privMsg.User.ID, strings.ToLower(privMsg.User.Name), violation.Rule, now, now.Add(-e.decay))
+	if err != nil {
+		span.SetStatus(codes.Error, "failed to give a strike")
+		return err
+	}
+
+	span.SetAttributes(attribute.Int("moderation.strikes", count))
+
+	if step := e.ladder.Step(count); step.MoreSevereThan(violation.Punishment) {
+		violation.Punishment = step
+	}
+	violation.Reason = fmt.Sprintf("%s (strike %d)", violation.Reason, count)
+
+	err = e.next.Enforce(spanCtx, privMsg, violation)
+	if err != nil {
+		span.SetStatus(codes.Error, "failed to execute the escalated punishment")
+		return err
+	}
+
+	span.SetStatus(codes.Ok, "successfully executed the escalated punishment")
+	return nil
+}
+
+// Strikes shows a number of active strikes of a user.
+// Usage: `!strikes @user`.
+func (e *Enforcer) Strikes() command.Handler {
+	return func(ctx context.Context, args []string, chatClient command.ChatClient) error {
+		spanCtx, span := tracer.Start(ctx, "strikes")
+		defer span.End()
+
+		if len(args) < 1 {
+			span.SetStatus(codes.Error, "wrong usage of the command")
+			return command.UsageError(fmt.Sprintf("usage: %sstrikes @user", e.prefix))
+		}
+
+		cmdCtx := command.UnwrapContext(ctx)
+		username := command.TrimMention(args[0])
+
+		count, err := e.storage.CountByUsername(spanCtx, cmdCtx.PrivMsg.Channel, username, e.now().Add(-e.decay))
+		if err != nil {
+			span.SetStatus(codes.Error, "failed to count strikes")
+			return command.InternalError(err)
+		}
+
+		chatClient.Say(cmdCtx.PrivMsg.Channel, fmt.Sprintf("@%s, %s has %d active strike(s), next punishment: %s",
+			cmdCtx.PrivMsg.User.DisplayName, username, count, describe(e.ladder.Step(count+1))))
+
+		span.SetStatus(codes.Ok, "successfully showed strikes")
+		return nil
+	}
+}
+
+// Pardon removes all strikes of a user.
+// Usage: `!pardon @user`.
+func (e *Enforcer) Pardon() command.Handler {
+	return func(ctx context.Context, args []string, chatClient command.ChatClient) error {
+		spanCtx, span := tracer.Start(ctx, "pardon")
+		defer span.End()
+
+		if len(args) < 1 {
+			span.SetStatus(codes.Error, "wrong usage of the command")
+			return command.UsageError(fmt.Sprintf("usage: %spardon @user", e.prefix))
+		}
+
+		cmdCtx := command.UnwrapContext(ctx)
+		username := command.TrimMention(args[0])
+
+		deleted, err := e.storage.DeleteByUsername(spanCtx, cmdCtx.PrivMsg.Channel, username)
+		if err != nil {
+			span.SetStatus(codes.Error, "failed to delete strikes")
+			return command.InternalError(err)
+		}
+
+		chatClient.Say(cmdCtx.PrivMsg.Channel, fmt.Sprintf("@%s, removed %d strike(s) of %s", cmdCtx.PrivMsg.User.DisplayName, deleted, username))
+
+		span.SetStatus(codes.Ok, "successfully pardoned a user")
+		return nil
+	}
+}
+
+func describe(punishment moderation.Punishment) string {
+	if punishment.Action == moderation.ActionTimeout {
+		return fmt.Sprintf("timeout for %s", punishment.Duration)
+	}
+
+	return punishment.Action.String()
+}
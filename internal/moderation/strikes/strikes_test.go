@@ -0,0 +1,128 @@
+package strikes
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/danielbukowski/twitch-chatbot/internal/moderation"
+	"github.com/gempir/go-twitch-irc/v4"
+)
+
+type storageMock struct {
+	createdAt []time.Time
+}
+
+func (s *storageMock) Save(_ context.Context, _, _, _, _ string, createdAt, since time.Time) (int, error) {
+	s.createdAt = append(s.createdAt, createdAt)
+
+	count := 0
+	for _, c := range s.createdAt {
+		if c.After(since) {
+			count++
+		}
+	}
+	return count, nil
+}
+
+func (s *storageMock) CountByUsername(_ context.Context, _, _ string, _ time.Time) (int, error) {
+	return len(s.createdAt), nil
+}
+
+func (s *storageMock) DeleteByUsername(_ context.Context, _, _ string) (int64, error) {
+	return int64(len(s.createdAt)), nil
+}
+
+type enforcerMock struct {
+	punishments []moderation.Punishment
+}
+
+func (e *enforcerMock) Enforce(_ context.Context, _ *twitch.PrivateMessage, violation moderation.Violation) error {
+	e.punishments = append(e.punishments, violation.Punishment)
+	return nil
+}
+
+func TestEnforcer(t *testing.T) {
+	t.Run("escalates punishments of a user who keeps breaking rules", func(t *testing.T) {
+		// given
+		next := &enforcerMock{}
+		enforcer := NewEnforcer(&storageMock{}, next, DefaultLadder, time.Hour, "!")
+		violation := moderation.Violation{Rule: "caps", Punishment: moderation.Punishment{Action: moderation.ActionWarn}}
+
+		// when
+		for range 5 {
+			_ = enforcer.Enforce(context.Background(), &twitch.PrivateMessage{}, violation)
+		}
+
+		// then
+		expected := append(DefaultLadder, DefaultLadder[3])
+		for i := range expected {
+			if next.punishments[i] != expected[i] {
+				t.Errorf("Expected `%v`, got `%v`", expected, next.punishments)
+				break
+			}
+		}
+	})
+
+	t.Run("does not count strikes older than the decay window", func(t *testing.T) {
+		// given
+		next := &enforcerMock{}
+		now := time.Now()
+		enforcer := NewEnforcer(&storageMock{}, next, DefaultLadder, time.Hour, "!")
+		violation := moderation.Violation{Rule: "caps", Punishment: moderation.Punishment{Action: moderation.ActionWarn}}
+
+		// when
+		enforcer.now = func() time.Time { return now.Add(-2 * time.Hour) }
+		_ = enforcer.Enforce(context.Background(), &twitch.PrivateMessage{}, violation)
+		enforcer.now = func() time.Time { return now }
+		_ = enforcer.Enforce(context.Background(), &twitch.PrivateMessage{}, violation)
+
+		// then
+		if next.punishments[1] != DefaultLadder[0] {
+			t.Errorf("Expected `%v`, got `%v`", DefaultLadder[0], next.punishments[1])
+		}
+	})
+
+	t.Run("keeps the punishment of the rule, when it is more severe than the ladder", func(t *testing.T) {
+		// given
+		next := &enforcerMock{}
+		enforcer := NewEnforcer(&storageMock{}, next, DefaultLadder, time.Hour, "!")
+		expected := moderation.Punishment{Action: moderation.ActionDelete}
+
+		// when
+		_ = enforcer.Enforce(context.Background(), &twitch.PrivateMessage{}, moderation.Violation{Rule: "link", Punishment: expected})
+
+		// then
+		if next.punishments[0] != expected {
+			t.Errorf("Expected `%v`, got `%v`", expected, next.punishments[0])
+		}
+	})
+}
+
+func TestParseLadder(t *testing.T) {
+	t.Run("parses the default ladder", func(t *testing.T) {
+		// when
+		got, err := ParseLadder("warn, timeout:60s, timeout:10m, ban")
+
+		// then
+		if err != nil {
+			t.Fatalf("Expected no error, got `%v`", err)
+		}
+		for i := range DefaultLadder {
+			if got[i] != DefaultLadder[i] {
+				t.Errorf("Expected `%v`, got `%v`", DefaultLadder, got)
+				break
+			}
+		}
+	})
+
+	t.Run("returns an error for an unknown action", func(t *testing.T) {
+		// when
+		_, err := ParseLadder("warn,kick")
+
+		// then
+		if err == nil {
+			t.Errorf("Expected an error, got nil")
+		}
+	})
+}
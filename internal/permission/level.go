@@ -0,0 +1,70 @@
+package permission
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/gempir/go-twitch-irc/v4"
+)
+
+// Level represents a permission level of a user. Levels are ordered, a higher level includes all lower ones.
+type Level int
+
+const (
+	Everyone Level = iota
+	Follower
+	Subscriber
+	VIP
+	Moderator
+	Broadcaster
+	BotOwner
+)
+
+var levelNames = map[Level]string{
+	Everyone:    "everyone",
+	Follower:    "follower",
+	Subscriber:  "subscriber",
+	VIP:         "vip",
+	Moderator:   "moderator",
+	Broadcaster: "broadcaster",
+	BotOwner:    "owner",
+}
+
+// String returns a name of the level, the same one that is accepted by ParseLevel.
+func (l Level) String() string {
+	return levelNames[l]
+}
+
+// ParseLevel returns a level with the given name.
+func ParseLevel(name string) (Level, error) {
+	name = strings.ToLower(name)
+
+	for level, levelName := range levelNames {
+		if levelName == name {
+			return level, nil
+		}
+	}
+
+	return Everyone, fmt.Errorf("unknown permission level '%s'", name)
+}
+
+// badgeLevel returns the highest level granted by user's Twitch badges.
+func badgeLevel(badges map[string]int) Level {
+	switch {
+	case badges["broadcaster"] != 0:
+		return Broadcaster
+	case badges["moderator"] != 0:
+		return Moderator
+	case badges["vip"] != 0:
+		return VIP
+	case badges["subscriber"] != 0, badges["founder"] != 0:
+		return Subscriber
+	default:
+		return Everyone
+	}
+}
+
+// isOwner reports whether the user is the owner of the chatbot.
+func isOwner(user twitch.User, ownerName string) bool {
+	return len(ownerName) != 0 && strings.EqualFold(user.Name, ownerName)
+}
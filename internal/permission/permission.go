@@ -0,0 +1,297 @@
+package permission
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/danielbukowski/twitch-chatbot/internal/command"
+	"github.com/gempir/go-twitch-irc/v4"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+)
+
+var errLevelTooLow = errors.New("user's permission level is too low for the command")
+var errDenied = errors.New("user is explicitly denied to call the command")
+var errGrantAboveLevel = errors.New("user tried to grant a command above the user's own permission level")
+var errGrantProtected = errors.New("user tried to grant a command of the broadcaster or the owner")
+
+type storage interface {
+	Override(ctx context.Context, channelName, username, commandName string) (isAllowed, found bool, err error)
+	SaveOverride(ctx context.Context, channelName, username, commandName string, isAllowed bool, grantedBy string) error
+	DeleteOverride(ctx context.Context, channelName, username, commandName string) error
+	CommandLevel(ctx context.Context, channelName, commandName string) (Level, bool, error)
+	SaveCommandLevel(ctx context.Context, channelName, commandName string, level Level) error
+}
+
+// followerChecker tells, if a user follows a channel.
+type followerChecker interface {
+	IsFollower(ctx context.Context, broadcasterID, userID string) (bool, error)
+}
+
+// Service decides, if a user can call a command. It combines Twitch badges, the follow relationship,
+// levels of commands changed by moderators and explicit allow/deny lists of users.
+type Service struct {
+	storage         storage          // Storage holds overrides of users and levels of commands.
+	followerChecker followerChecker  // FollowerChecker is used for the Follower level, it can be nil, then nobody is treated as a follower.
+	ownerName       string           // OwnerName is a login of the owner of the chatbot.
+	prefix          string           // Prefix is a prefix of commands.
+	defaults        map[string]Level // Defaults holds default levels of commands added with AddCommand, it is written only at startup.
+}
+
+// NewService creates an instance of Service.
+func NewService(storage storage, followerChecker followerChecker, ownerName, prefix string) *Service {
+	return &Service{
+		storage:         storage,
+		followerChecker: followerChecker,
+		ownerName:       ownerName,
+		prefix:          prefix,
+		defaults:        make(map[string]Level),
+	}
+}
+
+// AddCommand adds a command to the controller behind a filter requiring the default level, followed by the other filters.
+// Unlike using Require directly, the service remembers the level, so `!permit` can't grant a command above the level of the granting user.
+func (s *Service) AddCommand(controller *command.Controller, commandName string, handler command.Handler, defaultLevel Level, filters ...command.Filter) {
+	s.defaults[commandName] = defaultLevel
+	controller.AddCommand(commandName, handler, append([]command.Filter{s.Require(defaultLevel)}, filters...))
+}
+
+// Require rejects user's command request, when the user's level is lower than the level of the command.
+// The level of the command is defaultLevel, unless a moderator changed it with `!perm set`.
+// Users explicitly allowed to the command pass, unless it requires the Broadcaster level or higher, users explicitly denied never pass, except the owner.
+func (s *Service) Require(defaultLevel Level) command.Filter {
+	return func(cb command.Handler) command.Handler {
+		return func(ctx context.Context, args []string, chatClient command.ChatClient) error {
+			spanCtx, span := tracer.Start(ctx, "requirePermission")
+			defer span.End()
+
+			cmdCtx := command.UnwrapContext(ctx)
+
+			err := s.authorize(spanCtx, cmdCtx.PrivMsg, cmdCtx.CommandName, defaultLevel)
+			if err != nil {
+				span.SetStatus(codes.Error, "user did not pass through the filter")
+				span.RecordError(err)
+				return err
+			}
+
+			span.SetStatus(codes.Ok, "user passed through the filter")
+			return cb(spanCtx, args, chatClient)
+		}
+	}
+}
+
+// authorize returns an error, when the user can't call the command.
+// An allow override lifts any level, except the Broadcaster and BotOwner levels.
+func (s *Service) authorize(ctx context.Context, privMsg *twitch.PrivateMessage, commandName string, defaultLevel Level) error {
+	if isOwner(privMsg.User, s.ownerName) {
+		return nil
+	}
+
+	username := strings.ToLower(privMsg.User.Name)
+
+	isAllowed, hasOverride, err := s.storage.Override(ctx, privMsg.Channel, username, commandName)
+	if err != nil {
+		return command.InternalError(err)
+	}
+	if hasOverride && !isAllowed {
+		return command.PermissionDeniedError(errDenied)
+	}
+
+	required, found, err := s.storage.CommandLevel(ctx, privMsg.Channel, commandName)
+	if err != nil {
+		return command.InternalError(err)
+	}
+	if !found {
+		required = defaultLevel
+	}
+
+	if hasOverride && required < Broadcaster {
+		return nil
+	}
+
+	level, err := s.LevelOf(ctx, privMsg, required)
+	if err != nil {
+		return command.UpstreamError(err)
+	}
+
+	if level < required {
+		return command.PermissionDeniedError(errLevelTooLow)
+	}
+
+	return nil
+}
+
+// authorizeGrant returns an error, when the author of the message can't allow other users to call the command.
+// Users can grant only commands they could call themselves, and never commands of the broadcaster or the owner.
+func (s *Service) authorizeGrant(ctx context.Context, privMsg *twitch.PrivateMessage, commandName string) error {
+	required, found, err := s.storage.CommandLevel(ctx, privMsg.Channel, commandName)
+	if err != nil {
+		return command.InternalError(err)
+	}
+	if !found {
+		required, found = s.defaults[commandName]
+	}
+	if !found {
+		return command.UsageError(fmt.Sprintf("command %s does not exist", commandName))
+	}
+
+	if required >= Broadcaster {
+		return command.PermissionDeniedError(errGrantProtected)
+	}
+
+	level, err := s.LevelOf(ctx, privMsg, required)
+	if err != nil {
+		return command.UpstreamError(err)
+	}
+
+	if level < required {
+		return command.PermissionDeniedError(errGrantAboveLevel)
+	}
+
+	return nil
+}
+
+// LevelOf returns a permission level of the author of the message.
+// The follow relationship is checked only when it matters, that is when required is the Follower level.
+func (s *Service) LevelOf(ctx context.Context, privMsg *twitch.PrivateMessage, required Level) (Level, error) {
+	if isOwner(privMsg.User, s.ownerName) {
+		return BotOwner, nil
+	}
+
+	level := badgeLevel(privMsg.User.Badges)
+	if level != Everyone || required != Follower || s.followerChecker == nil {
+		return level, nil
+	}
+
+	isFollower, err := s.followerChecker.IsFollower(ctx, privMsg.RoomID, privMsg.User.ID)
+	if err != nil {
+		return Everyone, err
+	}
+
+	if isFollower {
+		return Follower, nil
+	}
+
+	return Everyone, nil
+}
+
+// Permit allows a user to call a command regardless of the user's level.
+// The author can permit only commands up to the author's own level, except commands of the broadcaster and the owner.
+// Usage: `!permit @user !command`.
+func (s *Service) Permit() command.Handler {
+	return func(ctx context.Context, args []string, chatClient command.ChatClient) error {
+		spanCtx, span := tracer.Start(ctx, "permit")
+		defer span.End()
+
+		if len(args) < 2 {
+			span.SetStatus(codes.Error, "wrong usage of the command")
+			return command.UsageError(fmt.Sprintf("usage: %spermit @user %scommand", s.prefix, s.prefix))
+		}
+
+		cmdCtx := command.UnwrapContext(ctx)
+		username := command.TrimMention(args[0])
+		commandName := s.commandName(args[1])
+		span.SetAttributes(attribute.String("permission.username", username), attribute.String("permission.command", commandName))
+
+		if err := s.authorizeGrant(spanCtx, cmdCtx.PrivMsg, commandName); err != nil {
+			span.SetStatus(codes.Error, "user can't permit the command")
+			span.RecordError(err)
+			return err
+		}
+
+		err := s.storage.SaveOverride(spanCtx, cmdCtx.PrivMsg.Channel, username, commandName, true, cmdCtx.PrivMsg.User.Name)
+		if err != nil {
+			span.SetStatus(codes.Error, "failed to permit the user")
+			return command.InternalError(err)
+		}
+
+		chatClient.Say(cmdCtx.PrivMsg.Channel, fmt.Sprintf("@%s, %s can now use %s", cmdCtx.PrivMsg.User.DisplayName, username, commandName))
+
+		span.SetStatus(codes.Ok, "successfully permitted the user")
+		return nil
+	}
+}
+
+// Perm manages permissions of commands and users.
+// Usage: `!perm set !command <level>`, `!perm allow @user !command`, `!perm deny @user !command`, `!perm reset @user !command`.
+func (s *Service) Perm() command.Handler {
+	return func(ctx context.Context, args []string, chatClient command.ChatClient) error {
+		spanCtx, span := tracer.Start(ctx, "perm")
+		defer span.End()
+
+		usage := command.UsageError(fmt.Sprintf("usage: %sperm set %scommand <level> | %sperm allow/deny/reset @user %scommand", s.prefix, s.prefix, s.prefix, s.prefix))
+		if len(args) < 3 {
+			span.SetStatus(codes.Error, "wrong usage of the command")
+			return usage
+		}
+
+		cmdCtx := command.UnwrapContext(ctx)
+		channelName := cmdCtx.PrivMsg.Channel
+		caller := cmdCtx.PrivMsg.User
+
+		var reply string
+		var err error
+
+		switch strings.ToLower(args[0]) {
+		case "set":
+			commandName := s.commandName(args[1])
+			level, parseErr := ParseLevel(args[2])
+			if parseErr != nil {
+				span.SetStatus(codes.Error, "unknown permission level")
+				return command.UsageError(fmt.Sprintf("unknown level '%s', available levels: everyone, follower, subscriber, vip, moderator, broadcaster, owner", args[2]))
+			}
+
+			err = s.storage.SaveCommandLevel(spanCtx, channelName, commandName, level)
+			reply = fmt.Sprintf("%s now requires the %s level", commandName, level)
+		case "allow", "deny":
+			username := command.TrimMention(args[1])
+			commandName := s.commandName(args[2])
+			isAllowed := strings.EqualFold(args[0], "allow")
+
+			if isAllowed {
+				if grantErr := s.authorizeGrant(spanCtx, cmdCtx.PrivMsg, commandName); grantErr != nil {
+					span.SetStatus(codes.Error, "user can't allow the command")
+					span.RecordError(grantErr)
+					return grantErr
+				}
+			}
+
+			err = s.storage.SaveOverride(spanCtx, channelName, username, commandName, isAllowed, caller.Name)
+			reply = fmt.Sprintf("%s is now denied to use %s", username, commandName)
+			if isAllowed {
+				reply = fmt.Sprintf("%s is now allowed to use %s", username, commandName)
+			}
+		case "reset":
+			username := command.TrimMention(args[1])
+			commandName := s.commandName(args[2])
+
+			err = s.storage.DeleteOverride(spanCtx, channelName, username, commandName)
+			reply = fmt.Sprintf("%s has default permissions to %s again", username, commandName)
+		default:
+			span.SetStatus(codes.Error, "unknown subcommand")
+			return usage
+		}
+
+		if err != nil {
+			span.SetStatus(codes.Error, "failed to change permissions")
+			return command.InternalError(err)
+		}
+
+		chatClient.Say(channelName, fmt.Sprintf("@%s, %s", caller.DisplayName, reply))
+
+		span.SetStatus(codes.Ok, "successfully changed permissions")
+		return nil
+	}
+}
+
+// commandName adds the prefix to a name of a command, when it is missing.
+func (s *Service) commandName(arg string) string {
+	arg = strings.ToLower(arg)
+	if strings.HasPrefix(arg, s.prefix) {
+		return arg
+	}
+
+	return s.prefix + arg
+}
@@ -0,0 +1,170 @@
+package permission
+
+import (
+	"context"
+	"testing"
+
+	"github.com/danielbukowski/twitch-chatbot/internal/command"
+	"github.com/gempir/go-twitch-irc/v4"
+	"go.uber.org/zap"
+)
+
+type chatClientMock struct{}
+
+func (c chatClientMock) Say(_, _ string) {}
+
+func (c chatClientMock) Reply(_, _, _ string) {}
+
+func (c chatClientMock) Join(_ ...string) {}
+
+func (c chatClientMock) Depart(_ string) {}
+
+type storageMock struct {
+	overrides map[string]bool
+	levels    map[string]Level
+}
+
+func (s storageMock) Override(_ context.Context, _, username, commandName string) (isAllowed, found bool, err error) {
+	isAllowed, found = s.overrides[username+commandName]
+	return isAllowed, found, nil
+}
+
+func (s storageMock) SaveOverride(_ context.Context, _, _, _ string, _ bool, _ string) error {
+	return nil
+}
+
+func (s storageMock) DeleteOverride(_ context.Context, _, _, _ string) error {
+	return nil
+}
+
+func (s storageMock) CommandLevel(_ context.Context, _, commandName string) (Level, bool, error) {
+	level, found := s.levels[commandName]
+	return level, found, nil
+}
+
+func (s storageMock) SaveCommandLevel(_ context.Context, _, _ string, _ Level) error {
+	return nil
+}
+
+func TestAuthorize(t *testing.T) {
+	testCases := []struct {
+		name         string
+		user         twitch.User
+		storage      storageMock
+		defaultLevel Level
+		expected     command.ErrorKind
+		allowed      bool
+	}{
+		{
+			name:         "allows a moderator to call a moderator command",
+			user:         twitch.User{Name: "mod", Badges: map[string]int{"moderator": 1}},
+			defaultLevel: Moderator,
+			allowed:      true,
+		},
+		{
+			name:         "denies a subscriber to call a moderator command",
+			user:         twitch.User{Name: "sub", Badges: map[string]int{"subscriber": 1}},
+			defaultLevel: Moderator,
+			expected:     command.KindPermissionDenied,
+		},
+		{
+			name:         "allows a user with an explicit allow override",
+			user:         twitch.User{Name: "viewer"},
+			storage:      storageMock{overrides: map[string]bool{"viewer!cmd": true}},
+			defaultLevel: Moderator,
+			allowed:      true,
+		},
+		{
+			name:         "denies a moderator with an explicit deny override",
+			user:         twitch.User{Name: "mod", Badges: map[string]int{"moderator": 1}},
+			storage:      storageMock{overrides: map[string]bool{"mod!cmd": false}},
+			defaultLevel: Everyone,
+			expected:     command.KindPermissionDenied,
+		},
+		{
+			name:         "uses a level of the command changed by a moderator",
+			user:         twitch.User{Name: "vip", Badges: map[string]int{"vip": 1}},
+			storage:      storageMock{levels: map[string]Level{"!cmd": Broadcaster}},
+			defaultLevel: Everyone,
+			expected:     command.KindPermissionDenied,
+		},
+		{
+			name:         "does not lift the broadcaster level with an allow override",
+			user:         twitch.User{Name: "mod", Badges: map[string]int{"moderator": 1}},
+			storage:      storageMock{overrides: map[string]bool{"mod!cmd": true}},
+			defaultLevel: Broadcaster,
+			expected:     command.KindPermissionDenied,
+		},
+		{
+			name:         "allows the owner even with an explicit deny override",
+			user:         twitch.User{Name: "Owner"},
+			storage:      storageMock{overrides: map[string]bool{"owner!cmd": false}},
+			defaultLevel: Broadcaster,
+			allowed:      true,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			// given
+			service := NewService(tc.storage, nil, "owner", "!")
+			privMsg := &twitch.PrivateMessage{Channel: "channel", User: tc.user}
+
+			// when
+			got := service.authorize(context.Background(), privMsg, "!cmd", tc.defaultLevel)
+
+			// then
+			if tc.allowed && got != nil {
+				t.Errorf("Expected no error, got `%v`", got)
+			}
+			if !tc.allowed && (got == nil || command.Classify(got) != tc.expected) {
+				t.Errorf("Expected an error of kind `%v`, got `%v`", tc.expected, got)
+			}
+		})
+	}
+}
+
+func TestPermit(t *testing.T) {
+	moderator := twitch.User{Name: "mod", DisplayName: "mod", Badges: map[string]int{"moderator": 1}}
+
+	testCases := []struct {
+		name     string
+		user     twitch.User
+		storage  storageMock
+		target   string
+		expected command.ErrorKind
+		allowed  bool
+	}{
+		{name: "lets a moderator permit a moderator command", user: moderator, target: "!so", allowed: true},
+		{name: "rejects permitting a command of the broadcaster", user: moderator, target: "!perm", expected: command.KindPermissionDenied},
+		{name: "rejects permitting a command of the owner", user: twitch.User{Name: "streamer", Badges: map[string]int{"broadcaster": 1}}, target: "!join", expected: command.KindPermissionDenied},
+		{name: "rejects permitting a command above the level of the author", user: twitch.User{Name: "vip", Badges: map[string]int{"vip": 1}}, target: "!so", expected: command.KindPermissionDenied},
+		{name: "uses a level of the command changed by the broadcaster", user: moderator, storage: storageMock{levels: map[string]Level{"!so": Broadcaster}}, target: "so", expected: command.KindPermissionDenied},
+		{name: "rejects an unknown command", user: moderator, target: "!unknown", expected: command.KindUsage},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			// given
+			service := NewService(tc.storage, nil, "owner", "!")
+			controller := command.NewController("!", zap.NewNop())
+			service.AddCommand(controller, "!so", nil, Moderator)
+			service.AddCommand(controller, "!perm", nil, Broadcaster)
+			service.AddCommand(controller, "!join", nil, BotOwner)
+
+			privMsg := &twitch.PrivateMessage{Channel: "channel", User: tc.user}
+			ctx := command.WithContext(context.Background(), command.NewContext("!permit", privMsg, zap.NewNop()))
+
+			// when
+			got := service.Permit()(ctx, []string{"@mod", tc.target}, chatClientMock{})
+
+			// then
+			if tc.allowed && got != nil {
+				t.Errorf("Expected no error, got `%v`", got)
+			}
+			if !tc.allowed && (got == nil || command.Classify(got) != tc.expected) {
+				t.Errorf("Expected an error of kind `%v`, got `%v`", tc.expected, got)
+			}
+		})
+	}
+}
@@ -0,0 +1,152 @@
+package permission
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+
+	"github.com/danielbukowski/twitch-chatbot/internal/database"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/codes"
+)
+
+var tracer = otel.Tracer("github.com/danielbukowski/twitch-chatbot/internal/permission")
+
+// SQLiteStorage stores per-user overrides and per-command levels.
+type SQLiteStorage struct {
+	db *sql.DB
+}
+
+func NewSQLiteStorage(db *sql.DB) *SQLiteStorage {
+	return &SQLiteStorage{db: db}
+}
+
+// Override returns whether the user is explicitly allowed or denied to call the command.
+// The second returned value is false, when there is no override for the user.
+func (s *SQLiteStorage) Override(ctx context.Context, channelName, username, commandName string) (isAllowed, found bool, err error) {
+	query := "SELECT is_allowed FROM permission_overrides WHERE channel_name = ? AND username = ? AND command_name = ?;"
+
+	ctx, span := tracer.Start(ctx, "override")
+	defer span.End()
+
+	ctx, cancel := context.WithTimeout(ctx, database.RequestTimeout)
+	defer cancel()
+
+	err = s.db.QueryRowContext(ctx, query, channelName, username, commandName).Scan(&isAllowed)
+	if errors.Is(err, sql.ErrNoRows) {
+		span.SetStatus(codes.Ok, "user has no override for the command")
+		return false, false, nil
+	}
+	if err != nil {
+		errMsg := "failed to retrieve a permission override"
+		span.SetStatus(codes.Error, errMsg)
+		span.RecordError(err)
+		return false, false, errors.Join(errors.New(errMsg), err)
+	}
+
+	span.SetStatus(codes.Ok, "successfully retrieved a permission override")
+	return isAllowed, true, nil
+}
+
+// SaveOverride explicitly allows or denies the user to call the command.
+func (s *SQLiteStorage) SaveOverride(ctx context.Context, channelName, username, commandName string, isAllowed bool, grantedBy string) error {
+	query := `INSERT INTO permission_overrides (channel_name, username, command_name, is_allowed, granted_by) VALUES (?, ?, ?, ?, ?)
+		ON CONFLICT (channel_name, username, command_name) DO UPDATE SET is_allowed = excluded.is_allowed, granted_by = excluded.granted_by, created_at = CURRENT_TIMESTAMP;`
+
+	ctx, span := tracer.Start(ctx, "saveOverride")
+	defer span.End()
+
+	ctx, cancel := context.WithTimeout(ctx, database.RequestTimeout)
+	defer cancel()
+
+	_, err := s.db.ExecContext(ctx, query, channelName, username, commandName, isAllowed, grantedBy)
+	if err != nil {
+		errMsg := "failed to save a permission override"
+		span.SetStatus(codes.Error, errMsg)
+		span.RecordError(err)
+		return errors.Join(errors.New(errMsg), err)
+	}
+
+	span.SetStatus(codes.Ok, "successfully saved a permission override")
+	return nil
+}
+
+// DeleteOverride removes an explicit permission of the user to the command.
+func (s *SQLiteStorage) DeleteOverride(ctx context.Context, channelName, username, commandName string) error {
+	query := "DELETE FROM permission_overrides WHERE channel_name = ? AND username = ? AND command_name = ?;"
+
+	ctx, span := tracer.Start(ctx, "deleteOverride")
+	defer span.End()
+
+	ctx, cancel := context.WithTimeout(ctx, database.RequestTimeout)
+	defer cancel()
+
+	_, err := s.db.ExecContext(ctx, query, channelName, username, commandName)
+	if err != nil {
+		errMsg := "failed to delete a permission override"
+		span.SetStatus(codes.Error, errMsg)
+		span.RecordError(err)
+		return errors.Join(errors.New(errMsg), err)
+	}
+
+	span.SetStatus(codes.Ok, "successfully deleted a permission override")
+	return nil
+}
+
+// CommandLevel returns a level required to call the command on the channel.
+// The second returned value is false, when the level was not changed from the default one.
+func (s *SQLiteStorage) CommandLevel(ctx context.Context, channelName, commandName string) (Level, bool, error) {
+	query := "SELECT level FROM command_permission_levels WHERE channel_name = ? AND command_name = ?;"
+
+	ctx, span := tracer.Start(ctx, "commandLevel")
+	defer span.End()
+
+	ctx, cancel := context.WithTimeout(ctx, database.RequestTimeout)
+	defer cancel()
+
+	var levelName string
+	err := s.db.QueryRowContext(ctx, query, channelName, commandName).Scan(&levelName)
+	if errors.Is(err, sql.ErrNoRows) {
+		span.SetStatus(codes.Ok, "command has the default level")
+		return Everyone, false, nil
+	}
+	if err != nil {
+		errMsg := "failed to retrieve a command level"
+		span.SetStatus(codes.Error, errMsg)
+		span.RecordError(err)
+		return Everyone, false, errors.Join(errors.New(errMsg), err)
+	}
+
+	level, err := ParseLevel(levelName)
+	if err != nil {
+		span.SetStatus(codes.Error, "stored command level is invalid")
+		span.RecordError(err)
+		return Everyone, false, err
+	}
+
+	span.SetStatus(codes.Ok, "successfully retrieved a command level")
+	return level, true, nil
+}
+
+// SaveCommandLevel changes a level required to call the command on the channel.
+func (s *SQLiteStorage) SaveCommandLevel(ctx context.Context, channelName, commandName string, level Level) error {
+	query := `INSERT INTO command_permission_levels (channel_name, command_name, level) VALUES (?, ?, ?)
+		ON CONFLICT (channel_name, command_name) DO UPDATE SET level = excluded.level;`
+
+	ctx, span := tracer.Start(ctx, "saveCommandLevel")
+	defer span.End()
+
+	ctx, cancel := context.WithTimeout(ctx, database.RequestTimeout)
+	defer cancel()
+
+	_, err := s.db.ExecContext(ctx, query, channelName, commandName, level.String())
+	if err != nil {
+		errMsg := "failed to save a command level"
+		span.SetStatus(codes.Error, errMsg)
+		span.RecordError(err)
+		return errors.Join(errors.New(errMsg), err)
+	}
+
+	span.SetStatus(codes.Ok, "successfully saved a command level")
+	return nil
+}
@@ -0,0 +1,204 @@
+package points
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/danielbukowski/twitch-chatbot/internal/command"
+	twitchapi "github.com/danielbukowski/twitch-chatbot/internal/twitch_api"
+	"github.com/nicklaw5/helix/v2"
+	"go.opentelemetry.io/otel/codes"
+)
+
+// topPointsLimit is a number of viewers shown on the leaderboard.
+const topPointsLimit = 5
+
+// userResolver resolves users by their logins.
+type userResolver interface {
+	UserByLogin(ctx context.Context, login string) (helix.User, error)
+}
+
+// Commands lets viewers check, give and receive points.
+type Commands struct {
+	storage storage      // Storage saves balances.
+	users   userResolver // Users resolves receivers of points.
+}
+
+// NewCommands creates an instance of Commands.
+func NewCommands(storage storage, users userResolver) *Commands {
+	return &Commands{storage: storage, users: users}
+}
+
+// Points tells how many points a user has.
+// Usage: `!points [@user]`.
+func (c *Commands) Points() command.Handler {
+	return func(ctx context.Context, args []string, chatClient command.ChatClient) error {
+		spanCtx, span := tracer.Start(ctx, "pointsCommand")
+		defer span.End()
+
+		cmdCtx := command.UnwrapContext(ctx)
+		privMsg := cmdCtx.PrivMsg
+
+		username := strings.ToLower(privMsg.User.Name)
+		if len(args) > 0 {
+			username = command.TrimMention(args[0])
+		}
+
+		account, found, err := c.storage.Balance(spanCtx, privMsg.Channel, username)
+		if err != nil {
+			span.SetStatus(codes.Error, "failed to read the balance")
+			span.RecordError(err)
+			return command.InternalError(err)
+		}
+
+		if !found {
+			account.Username = username
+		}
+
+		span.SetStatus(codes.Ok, "successfully got the balance")
+		chatClient.Say(privMsg.Channel, fmt.Sprintf("@%s, %s has %d points.", privMsg.User.DisplayName, account.Username, account.Balance))
+		return nil
+	}
+}
+
+// Give transfers points of a user to another user.
+// Usage: `!give <@user> <amount>`.
+func (c *Commands) Give() command.Handler {
+	return func(ctx context.Context, args []string, chatClient command.ChatClient) error {
+		spanCtx, span := tracer.Start(ctx, "giveCommand")
+		defer span.End()
+
+		cmdCtx := command.UnwrapContext(ctx)
+		privMsg := cmdCtx.PrivMsg
+
+		if len(args) < 2 {
+			span.SetStatus(codes.Error, "missing arguments")
+			return command.UsageError("use the command like: !give <@user> <amount>")
+		}
+
+		amount, err := strconv.ParseInt(args[1], 10, 64)
+		if err != nil || amount <= 0 {
+			span.SetStatus(codes.Error, "invalid amount")
+			return command.UsageError("the amount has to be a positive number.")
+		}
+
+		receiver, err := c.resolve(spanCtx, args[0])
+		if err != nil {
+			span.SetStatus(codes.Error, "failed to resolve the receiver")
+			return err
+		}
+
+		if receiver.UserID == privMsg.User.ID {
+			span.SetStatus(codes.Error, "user tried to give points to themselves")
+			return command.UsageError("you can't give points to yourself.")
+		}
+
+		sender := Account{UserID: privMsg.User.ID, Username: strings.ToLower(privMsg.User.Name)}
+		balance, err := c.storage.Transfer(spanCtx, privMsg.Channel, sender, receiver, amount)
+		if errors.Is(err, ErrInsufficientPoints) {
+			span.SetStatus(codes.Error, "user does not have enough points")
+			return command.UsageError(fmt.Sprintf("you have only %d points.", balance))
+		}
+		if err != nil {
+			span.SetStatus(codes.Error, "failed to transfer points")
+			span.RecordError(err)
+			return command.InternalError(err)
+		}
+
+		span.SetStatus(codes.Ok, "successfully transferred points")
+		chatClient.Say(privMsg.Channel, fmt.Sprintf("@%s, gave %d points to %s, you have %d points left.", privMsg.User.DisplayName, amount, receiver.Username, balance))
+		return nil
+	}
+}
+
+// AddPoints adds points to a user, a negative amount takes points away, but never below zero.
+// Usage: `!addpoints <@user> <amount>`.
+func (c *Commands) AddPoints() command.Handler {
+	return func(ctx context.Context, args []string, chatClient command.ChatClient) error {
+		spanCtx, span := tracer.Start(ctx, "addPointsCommand")
+		defer span.End()
+
+		cmdCtx := command.UnwrapContext(ctx)
+		privMsg := cmdCtx.PrivMsg
+
+		if len(args) < 2 {
+			span.SetStatus(codes.Error, "missing arguments")
+			return command.UsageError("use the command like: !addpoints <@user> <amount>")
+		}
+
+		amount, err := strconv.ParseInt(args[1], 10, 64)
+		if err != nil || amount == 0 {
+			span.SetStatus(codes.Error, "invalid amount")
+			return command.UsageError("the amount has to be a number other than zero.")
+		}
+
+		receiver, err := c.resolve(spanCtx, args[0])
+		if err != nil {
+			span.SetStatus(codes.Error, "failed to resolve the receiver")
+			return err
+		}
+
+		balance, err := c.storage.Add(spanCtx, privMsg.Channel, receiver, amount)
+		if err != nil {
+			span.SetStatus(codes.Error, "failed to add points")
+			span.RecordError(err)
+			return command.InternalError(err)
+		}
+
+		span.SetStatus(codes.Ok, "successfully added points")
+		chatClient.Say(privMsg.Channel, fmt.Sprintf("@%s, %s has %d points now.", privMsg.User.DisplayName, receiver.Username, balance))
+		return nil
+	}
+}
+
+// TopPoints shows viewers with the most points.
+// Usage: `!toppoints`.
+func (c *Commands) TopPoints() command.Handler {
+	return func(ctx context.Context, _ []string, chatClient command.ChatClient) error {
+		spanCtx, span := tracer.Start(ctx, "topPointsCommand")
+		defer span.End()
+
+		cmdCtx := command.UnwrapContext(ctx)
+		privMsg := cmdCtx.PrivMsg
+
+		accounts, err := c.storage.Top(spanCtx, privMsg.Channel, topPointsLimit)
+		if err != nil {
+			span.SetStatus(codes.Error, "failed to read top balances")
+			span.RecordError(err)
+			return command.InternalError(err)
+		}
+
+		if len(accounts) == 0 {
+			span.SetStatus(codes.Ok, "nobody has any points")
+			chatClient.Say(privMsg.Channel, fmt.Sprintf("@%s, nobody has any points yet.", privMsg.User.DisplayName))
+			return nil
+		}
+
+		places := make([]string, 0, len(accounts))
+		for i, account := range accounts {
+			places = append(places, fmt.Sprintf("%d. %s (%d)", i+1, account.Username, account.Balance))
+		}
+
+		span.SetStatus(codes.Ok, "successfully got top balances")
+		chatClient.Say(privMsg.Channel, fmt.Sprintf("@%s, top points: %s", privMsg.User.DisplayName, strings.Join(places, ", ")))
+		return nil
+	}
+}
+
+// resolve returns an account of a mentioned user.
+func (c *Commands) resolve(ctx context.Context, mention string) (Account, error) {
+	login := command.TrimMention(mention)
+
+	user, err := c.users.UserByLogin(ctx, login)
+	if errors.Is(err, twitchapi.ErrUserNotFound) {
+		return Account{}, command.UsageError(fmt.Sprintf("user %s does not exist", login))
+	}
+	if err != nil {
+		return Account{}, command.UpstreamError(err)
+	}
+
+	return Account{UserID: user.ID, Username: strings.ToLower(user.Login)}, nil
+}
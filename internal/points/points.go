@@ -0,0 +1,219 @@
+package points
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math"
+	"strings"
+	"time"
+
+	"github.com/danielbukowski/twitch-chatbot/internal/command"
+	"github.com/danielbukowski/twitch-chatbot/internal/presence"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.uber.org/zap"
+)
+
+var tracer = otel.Tracer("github.com/danielbukowski/twitch-chatbot/internal/points")
+
+// ErrInsufficientPoints is returned, when a viewer has fewer points than needed.
+var ErrInsufficientPoints = errors.New("not enough points")
+
+// Account is a balance of points of a viewer.
+type Account struct {
+	UserID   string // UserID is an ID of the viewer.
+	Username string // Username is a login of the viewer.
+	Balance  int64  // Balance is a number of points the viewer has.
+}
+
+type storage interface {
+	Accrue(ctx context.Context, channelName string, bucket time.Time, awards []Account) (bool, error)
+	Balance(ctx context.Context, channelName, username string) (Account, bool, error)
+	Add(ctx context.Context, channelName string, account Account, amount int64) (int64, error)
+	Spend(ctx context.Context, channelName string, account Account, amount int64) (int64, error)
+	Transfer(ctx context.Context, channelName string, from, to Account, amount int64) (int64, error)
+	Top(ctx context.Context, channelName string, limit int) ([]Account, error)
+}
+
+// liveChecker tells, if a stream of a channel is live.
+type liveChecker interface {
+	IsLive(channelName string) bool
+}
+
+// Multipliers scale points earned by viewers with badges. When a viewer has several badges, the highest multiplier is used.
+type Multipliers struct {
+	Subscriber float64 // Subscriber is a multiplier of subscribers and founders.
+	VIP        float64 // VIP is a multiplier of VIPs.
+}
+
+// activityReader returns viewers active in chat.
+type activityReader interface {
+	Active(channelName string) []presence.Viewer
+	Channels() []string
+	Forget(channelName string)
+}
+
+// Service pays viewers active on live streams with points and lets commands charge points.
+// A viewer is active, when the viewer wrote a message within the active window of the activity tracker.
+type Service struct {
+	storage     storage          // Storage saves balances.
+	status      liveChecker      // Status tells, if streams are live.
+	activity    activityReader   // Activity tells, which viewers are active in chat.
+	amount      int64            // Amount is a number of points earned in every interval.
+	interval    time.Duration    // Interval is how often points are paid.
+	multipliers Multipliers      // Multipliers scale points of viewers with badges.
+	now         func() time.Time // Now returns the current time.
+	logger      *zap.Logger      // Logger is used for logging.
+}
+
+// NewService creates an instance of Service.
+func NewService(storage storage, status liveChecker, activity activityReader, amount int64, interval time.Duration, multipliers Multipliers, logger *zap.Logger) *Service {
+	return &Service{
+		storage:     storage,
+		status:      status,
+		activity:    activity,
+		amount:      amount,
+		interval:    interval,
+		multipliers: multipliers,
+		now:         time.Now,
+		logger:      logger.Named("points"),
+	}
+}
+
+// Run pays active viewers at every interval, until the context is done.
+// Payments happen in the middle of buckets, so a drift of the ticker never moves a payment into a neighbouring bucket.
+func (s *Service) Run(ctx context.Context) error {
+	timer := time.NewTimer(s.untilMidBucket())
+	defer timer.Stop()
+
+	select {
+	case <-ctx.Done():
+		return nil
+	case <-timer.C:
+	}
+
+	ticker := time.NewTicker(s.interval)
+	defer ticker.Stop()
+
+	for {
+		for _, channelName := range s.activity.Channels() {
+			s.accrue(ctx, channelName)
+		}
+
+		select {
+		case <-ctx.Done():
+			return nil
+		case <-ticker.C:
+		}
+	}
+}
+
+// untilMidBucket returns time left to the next middle of a bucket.
+func (s *Service) untilMidBucket() time.Duration {
+	now := s.now()
+	mid := now.Truncate(s.interval).Add(s.interval / 2)
+	if !mid.After(now) {
+		mid = mid.Add(s.interval)
+	}
+
+	return mid.Sub(now)
+}
+
+// accrue pays every viewer active on a live stream of a channel. Points of an interval are paid at most once,
+// even if the chatbot restarts within the interval.
+func (s *Service) accrue(ctx context.Context, channelName string) {
+	if !s.status.IsLive(channelName) {
+		s.activity.Forget(channelName)
+		return
+	}
+
+	spanCtx, span := tracer.Start(ctx, "accrue")
+	defer span.End()
+
+	now := s.now()
+	awards := s.awards(channelName)
+	span.SetAttributes(attribute.String("channel.name", channelName), attribute.Int("points.viewers", len(awards)))
+	if len(awards) == 0 {
+		span.SetStatus(codes.Ok, "no viewers were active")
+		return
+	}
+
+	accrued, err := s.storage.Accrue(spanCtx, channelName, now.Truncate(s.interval), awards)
+	if err != nil {
+		span.SetStatus(codes.Error, "failed to pay points")
+		s.logger.Error("failed to pay points", zap.String("channel_name", channelName), zap.Error(err))
+		return
+	}
+
+	if !accrued {
+		span.SetStatus(codes.Ok, "points of the interval were already paid")
+		return
+	}
+
+	span.SetStatus(codes.Ok, "successfully paid points")
+}
+
+// awards returns points earned by viewers active in chat of a channel.
+func (s *Service) awards(channelName string) []Account {
+	awards := []Account{}
+	for _, viewer := range s.activity.Active(channelName) {
+		amount := int64(math.Round(float64(s.amount) * s.multiplierOf(viewer.Badges)))
+		awards = append(awards, Account{UserID: viewer.UserID, Username: viewer.Username, Balance: amount})
+	}
+
+	return awards
+}
+
+// multiplierOf returns the highest multiplier of badges.
+func (s *Service) multiplierOf(badges map[string]int) float64 {
+	multiplier := 1.0
+	if badges["subscriber"] != 0 || badges["founder"] != 0 {
+		multiplier = max(multiplier, s.multipliers.Subscriber)
+	}
+	if badges["vip"] != 0 {
+		multiplier = max(multiplier, s.multipliers.VIP)
+	}
+
+	return multiplier
+}
+
+// Cost charges a user with points, before the command runs. The points are refunded, when the command returns an error.
+func (s *Service) Cost(amount int64) command.Filter {
+	return func(cb command.Handler) command.Handler {
+		return func(ctx context.Context, args []string, chatClient command.ChatClient) error {
+			spanCtx, span := tracer.Start(ctx, "pointsCost")
+			defer span.End()
+
+			cmdCtx := command.UnwrapContext(ctx)
+			privMsg := cmdCtx.PrivMsg
+			account := Account{UserID: privMsg.User.ID, Username: strings.ToLower(privMsg.User.Name)}
+
+			balance, err := s.storage.Spend(spanCtx, privMsg.Channel, account, amount)
+			if errors.Is(err, ErrInsufficientPoints) {
+				span.SetStatus(codes.Error, "user does not have enough points")
+				return command.UsageError(fmt.Sprintf("the command costs %d points, but you have %d.", amount, balance))
+			}
+			if err != nil {
+				span.SetStatus(codes.Error, "failed to charge the user")
+				span.RecordError(err)
+				return command.InternalError(err)
+			}
+
+			err = cb(spanCtx, args, chatClient)
+			if err == nil {
+				span.SetStatus(codes.Ok, "user paid for the command")
+				return nil
+			}
+
+			if _, refundErr := s.storage.Add(spanCtx, privMsg.Channel, account, amount); refundErr != nil {
+				span.RecordError(refundErr)
+				s.logger.Error("failed to refund points", zap.String("username", account.Username), zap.Int64("amount", amount), zap.Error(refundErr))
+			}
+
+			span.SetStatus(codes.Error, "command failed, so points were refunded")
+			return err
+		}
+	}
+}
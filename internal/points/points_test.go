@@ -0,0 +1,283 @@
+package points
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/danielbukowski/twitch-chatbot/internal/command"
+	"github.com/danielbukowski/twitch-chatbot/internal/presence"
+	"github.com/gempir/go-twitch-irc/v4"
+	"github.com/nicklaw5/helix/v2"
+	"go.uber.org/zap"
+)
+
+// storageMock keeps balances by user IDs in memory.
+type storageMock struct {
+	balances map[string]int64
+	buckets  map[time.Time]bool
+}
+
+func newStorageMock(balances map[string]int64) *storageMock {
+	return &storageMock{balances: balances, buckets: map[time.Time]bool{}}
+}
+
+func (s *storageMock) Accrue(_ context.Context, _ string, bucket time.Time, awards []Account) (bool, error) {
+	if s.buckets[bucket] {
+		return false, nil
+	}
+	s.buckets[bucket] = true
+
+	for _, award := range awards {
+		s.balances[award.UserID] += award.Balance
+	}
+	return true, nil
+}
+
+func (s *storageMock) Balance(_ context.Context, _, username string) (Account, bool, error) {
+	balance, ok := s.balances[username]
+	return Account{Username: username, Balance: balance}, ok, nil
+}
+
+func (s *storageMock) Add(_ context.Context, _ string, account Account, amount int64) (int64, error) {
+	s.balances[account.UserID] = max(0, s.balances[account.UserID]+amount)
+	return s.balances[account.UserID], nil
+}
+
+func (s *storageMock) Spend(_ context.Context, _ string, account Account, amount int64) (int64, error) {
+	if s.balances[account.UserID] < amount {
+		return s.balances[account.UserID], ErrInsufficientPoints
+	}
+	s.balances[account.UserID] -= amount
+	return s.balances[account.UserID], nil
+}
+
+func (s *storageMock) Transfer(ctx context.Context, channelName string, from, to Account, amount int64) (int64, error) {
+	balance, err := s.Spend(ctx, channelName, from, amount)
+	if err != nil {
+		return balance, err
+	}
+	s.balances[to.UserID] += amount
+	return balance, nil
+}
+
+func (s *storageMock) Top(_ context.Context, _ string, _ int) ([]Account, error) {
+	return nil, nil
+}
+
+type liveCheckerMock struct {
+	live bool
+}
+
+func (l *liveCheckerMock) IsLive(_ string) bool {
+	return l.live
+}
+
+type chatClientMock struct {
+	messages []string
+}
+
+func (c *chatClientMock) Say(_, message string) {
+	c.messages = append(c.messages, message)
+}
+
+func (c *chatClientMock) Reply(_, _, message string) {
+	c.messages = append(c.messages, message)
+}
+
+func (c *chatClientMock) Join(_ ...string) {}
+
+func (c *chatClientMock) Depart(_ string) {}
+
+type userResolverMock map[string]string
+
+func (u userResolverMock) UserByLogin(_ context.Context, login string) (helix.User, error) {
+	return helix.User{ID: u[login], Login: login}, nil
+}
+
+func newTestService(storage *storageMock, status *liveCheckerMock) (*Service, *presence.Tracker) {
+	activity := presence.NewTracker("chatbot", 10*time.Minute)
+	return NewService(storage, status, activity, 10, 5*time.Minute, Multipliers{Subscriber: 2, VIP: 1.5}, zap.NewNop()), activity
+}
+
+func message(userID, username string, badges map[string]int) *twitch.PrivateMessage {
+	return &twitch.PrivateMessage{Channel: "channel", User: twitch.User{ID: userID, Name: username, DisplayName: username, Badges: badges}}
+}
+
+func TestService(t *testing.T) {
+	t.Run("pays active viewers with multipliers of their badges", func(t *testing.T) {
+		// given
+		storage := newStorageMock(map[string]int64{})
+		service, activity := newTestService(storage, &liveCheckerMock{live: true})
+		activity.Observe(message("1", "viewer", nil))
+		activity.Observe(message("2", "subscriber", map[string]int{"subscriber": 12}))
+		activity.Observe(message("3", "vip", map[string]int{"vip": 1, "founder": 0}))
+		activity.Observe(message("4", "channel", map[string]int{"broadcaster": 1}))
+		activity.Observe(message("5", "chatbot", nil))
+
+		// when
+		service.accrue(context.Background(), "channel")
+
+		// then
+		expected := map[string]int64{"1": 10, "2": 20, "3": 15}
+		if len(storage.balances) != len(expected) {
+			t.Fatalf("Expected balances `%v`, got `%v`", expected, storage.balances)
+		}
+		for userID, balance := range expected {
+			if storage.balances[userID] != balance {
+				t.Fatalf("Expected balances `%v`, got `%v`", expected, storage.balances)
+			}
+		}
+	})
+
+	t.Run("pays an interval only once", func(t *testing.T) {
+		// given
+		storage := newStorageMock(map[string]int64{})
+		service, activity := newTestService(storage, &liveCheckerMock{live: true})
+		now := time.Date(2026, 10, 19, 12, 1, 0, 0, time.UTC)
+		service.now = func() time.Time { return now }
+		activity.Observe(message("1", "viewer", nil))
+
+		// when
+		service.accrue(context.Background(), "channel")
+		now = now.Add(time.Minute)
+		service.accrue(context.Background(), "channel")
+
+		// then
+		if storage.balances["1"] != 10 {
+			t.Fatalf("Expected 10 points, got %d", storage.balances["1"])
+		}
+	})
+
+	t.Run("waits for the middle of a bucket", func(t *testing.T) {
+		testCases := []struct {
+			name     string
+			now      time.Time
+			expected time.Duration
+		}{
+			{name: "at the start of a bucket", now: time.Date(2026, 10, 19, 12, 0, 0, 0, time.UTC), expected: 150 * time.Second},
+			{name: "before the middle of a bucket", now: time.Date(2026, 10, 19, 12, 1, 0, 0, time.UTC), expected: 90 * time.Second},
+			{name: "at the middle of a bucket", now: time.Date(2026, 10, 19, 12, 2, 30, 0, time.UTC), expected: 5 * time.Minute},
+			{name: "after the middle of a bucket", now: time.Date(2026, 10, 19, 12, 4, 0, 0, time.UTC), expected: 210 * time.Second},
+		}
+
+		for _, tc := range testCases {
+			t.Run(tc.name, func(t *testing.T) {
+				// given
+				service, _ := newTestService(newStorageMock(map[string]int64{}), &liveCheckerMock{})
+				service.now = func() time.Time { return tc.now }
+
+				// when
+				got := service.untilMidBucket()
+
+				// then
+				if got != tc.expected {
+					t.Errorf("Expected %s, got %s", tc.expected, got)
+				}
+			})
+		}
+	})
+
+	t.Run("does not pay viewers while the stream is offline", func(t *testing.T) {
+		// given
+		storage := newStorageMock(map[string]int64{})
+		status := &liveCheckerMock{}
+		service, activity := newTestService(storage, status)
+		activity.Observe(message("1", "viewer", nil))
+
+		// when
+		service.accrue(context.Background(), "channel")
+		status.live = true
+		service.accrue(context.Background(), "channel")
+
+		// then
+		if len(storage.balances) != 0 {
+			t.Fatalf("Expected no points, got `%v`", storage.balances)
+		}
+	})
+}
+
+func TestCost(t *testing.T) {
+	testCases := []struct {
+		name            string
+		balance         int64
+		handlerErr      error
+		expectedBalance int64
+		expectedCalled  bool
+		expectedKind    command.ErrorKind
+	}{
+		{name: "charges the user", balance: 100, expectedBalance: 70, expectedCalled: true},
+		{name: "refunds the user, when the command fails", balance: 100, handlerErr: command.UsageError("wrong arguments"), expectedBalance: 100, expectedCalled: true, expectedKind: command.KindUsage},
+		{name: "rejects the user without enough points", balance: 20, expectedBalance: 20, expectedKind: command.KindUsage},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			// given
+			storage := newStorageMock(map[string]int64{"1": tc.balance})
+			service, _ := newTestService(storage, &liveCheckerMock{})
+			called := false
+			handler := service.Cost(30)(func(_ context.Context, _ []string, _ command.ChatClient) error {
+				called = true
+				return tc.handlerErr
+			})
+			ctx := command.WithContext(context.Background(), command.NewContext("!hug", message("1", "viewer", nil), zap.NewNop()))
+
+			// when
+			err := handler(ctx, nil, &chatClientMock{})
+
+			// then
+			if (err != nil) != (tc.handlerErr != nil || !tc.expectedCalled) {
+				t.Fatalf("Unexpected error `%v`", err)
+			}
+			if err != nil && command.Classify(err) != tc.expectedKind {
+				t.Fatalf("Expected an error of kind %s, got `%v`", tc.expectedKind, err)
+			}
+			if called != tc.expectedCalled {
+				t.Fatalf("Expected the command to be called: %t, got %t", tc.expectedCalled, called)
+			}
+			if storage.balances["1"] != tc.expectedBalance {
+				t.Fatalf("Expected a balance of %d, got %d", tc.expectedBalance, storage.balances["1"])
+			}
+		})
+	}
+}
+
+func TestGive(t *testing.T) {
+	testCases := []struct {
+		name             string
+		args             []string
+		expectedBalances map[string]int64
+		expectedErr      bool
+	}{
+		{name: "transfers points", args: []string{"@Friend", "40"}, expectedBalances: map[string]int64{"1": 60, "2": 40}},
+		{name: "rejects a transfer above the balance", args: []string{"friend", "101"}, expectedBalances: map[string]int64{"1": 100, "2": 0}, expectedErr: true},
+		{name: "rejects a transfer to oneself", args: []string{"viewer", "10"}, expectedBalances: map[string]int64{"1": 100, "2": 0}, expectedErr: true},
+		{name: "rejects a negative amount", args: []string{"friend", "-10"}, expectedBalances: map[string]int64{"1": 100, "2": 0}, expectedErr: true},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			// given
+			storage := newStorageMock(map[string]int64{"1": 100, "2": 0})
+			commands := NewCommands(storage, userResolverMock{"viewer": "1", "friend": "2"})
+			ctx := command.WithContext(context.Background(), command.NewContext("!give", message("1", "viewer", nil), zap.NewNop()))
+
+			// when
+			err := commands.Give()(ctx, tc.args, &chatClientMock{})
+
+			// then
+			if tc.expectedErr && command.Classify(err) != command.KindUsage {
+				t.Fatalf("Expected a usage error, got `%v`", err)
+			}
+			if !tc.expectedErr && err != nil {
+				t.Fatalf("Expected no error, got `%v`", err)
+			}
+			for userID, balance := range tc.expectedBalances {
+				if storage.balances[userID] != balance {
+					t.Fatalf("Expected balances `%v`, got `%v`", tc.expectedBalances, storage.balances)
+				}
+			}
+		})
+	}
+}
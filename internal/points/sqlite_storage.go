@@ -0,0 +1,316 @@
+package points
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"time"
+
+	"github.com/danielbukowski/twitch-chatbot/internal/database"
+	"go.opentelemetry.io/otel/codes"
+)
+
+// SQLiteStorage stores balances of points of viewers per channel.
+type SQLiteStorage struct {
+	db *sql.DB
+}
+
+func NewSQLiteStorage(db *sql.DB) *SQLiteStorage {
+	return &SQLiteStorage{db: db}
+}
+
+// addQuery adds an amount to a balance of a viewer, the balance never drops below zero.
+const addQuery = `INSERT INTO points_balances (channel_name, user_id, username, balance, updated_at) VALUES (?, ?, ?, MAX(0, ?), ?)
+	ON CONFLICT (channel_name, user_id) DO UPDATE SET username = excluded.username, balance = MAX(0, balance + ?), updated_at = excluded.updated_at;`
+
+// balanceQuery selects a balance of a viewer by the viewer's ID.
+const balanceQuery = "SELECT balance FROM points_balances WHERE channel_name = ? AND user_id = ?;"
+
+// Accrue adds awards to balances of viewers, unless awards of the bucket were already added.
+// The returned value is false, when the bucket was already paid.
+func (s *SQLiteStorage) Accrue(ctx context.Context, channelName string, bucket time.Time, awards []Account) (bool, error) {
+	ctx, span := tracer.Start(ctx, "accrue")
+	defer span.End()
+
+	ctx, cancel := context.WithTimeout(ctx, database.RequestTimeout)
+	defer cancel()
+
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		errMsg := "failed to begin a transaction"
+		span.SetStatus(codes.Error, errMsg)
+		span.RecordError(err)
+		return false, errors.Join(errors.New(errMsg), err)
+	}
+	//nolint:errcheck // rollback after commit returns an error that doesn't matter
+	defer tx.Rollback()
+
+	result, err := tx.ExecContext(ctx, "INSERT OR IGNORE INTO points_accruals (channel_name, bucket) VALUES (?, ?);", channelName, bucket.Unix())
+	if err != nil {
+		errMsg := "failed to save the accrual"
+		span.SetStatus(codes.Error, errMsg)
+		span.RecordError(err)
+		return false, errors.Join(errors.New(errMsg), err)
+	}
+
+	rows, err := result.RowsAffected()
+	if err != nil {
+		errMsg := "failed to check the accrual"
+		span.SetStatus(codes.Error, errMsg)
+		span.RecordError(err)
+		return false, errors.Join(errors.New(errMsg), err)
+	}
+
+	if rows == 0 {
+		span.SetStatus(codes.Ok, "points of the bucket were already paid")
+		return false, nil
+	}
+
+	now := time.Now().Unix()
+	for _, award := range awards {
+		_, err = tx.ExecContext(ctx, addQuery, channelName, award.UserID, award.Username, award.Balance, now, award.Balance)
+		if err != nil {
+			errMsg := "failed to add points"
+			span.SetStatus(codes.Error, errMsg)
+			span.RecordError(err)
+			return false, errors.Join(errors.New(errMsg), err)
+		}
+	}
+
+	if err = tx.Commit(); err != nil {
+		errMsg := "failed to commit a transaction"
+		span.SetStatus(codes.Error, errMsg)
+		span.RecordError(err)
+		return false, errors.Join(errors.New(errMsg), err)
+	}
+
+	span.SetStatus(codes.Ok, "successfully paid points")
+	return true, nil
+}
+
+// Balance returns an account of a viewer on a channel. The second returned value is false, when the viewer has no account.
+func (s *SQLiteStorage) Balance(ctx context.Context, channelName, username string) (Account, bool, error) {
+	query := "SELECT user_id, username, balance FROM points_balances WHERE channel_name = ? AND username = ?;"
+
+	ctx, span := tracer.Start(ctx, "balance")
+	defer span.End()
+
+	ctx, cancel := context.WithTimeout(ctx, database.RequestTimeout)
+	defer cancel()
+
+	var account Account
+	err := s.db.QueryRowContext(ctx, query, channelName, username).Scan(&account.UserID, &account.Username, &account.Balance)
+	if errors.Is(err, sql.ErrNoRows) {
+		span.SetStatus(codes.Ok, "viewer has no account")
+		return Account{}, false, nil
+	}
+	if err != nil {
+		errMsg := "failed to retrieve a balance"
+		span.SetStatus(codes.Error, errMsg)
+		span.RecordError(err)
+		return Account{}, false, errors.Join(errors.New(errMsg), err)
+	}
+
+	span.SetStatus(codes.Ok, "successfully retrieved a balance")
+	return account, true, nil
+}
+
+// Add adds an amount to a balance of a viewer and returns the new balance. A negative amount takes points away, but never below zero.
+func (s *SQLiteStorage) Add(ctx context.Context, channelName string, account Account, amount int64) (int64, error) {
+	ctx, span := tracer.Start(ctx, "add")
+	defer span.End()
+
+	ctx, cancel := context.WithTimeout(ctx, database.RequestTimeout)
+	defer cancel()
+
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		errMsg := "failed to begin a transaction"
+		span.SetStatus(codes.Error, errMsg)
+		span.RecordError(err)
+		return 0, errors.Join(errors.New(errMsg), err)
+	}
+	//nolint:errcheck // rollback after commit returns an error that doesn't matter
+	defer tx.Rollback()
+
+	_, err = tx.ExecContext(ctx, addQuery, channelName, account.UserID, account.Username, amount, time.Now().Unix(), amount)
+	if err != nil {
+		errMsg := "failed to add points"
+		span.SetStatus(codes.Error, errMsg)
+		span.RecordError(err)
+		return 0, errors.Join(errors.New(errMsg), err)
+	}
+
+	var balance int64
+	if err = tx.QueryRowContext(ctx, balanceQuery, channelName, account.UserID).Scan(&balance); err != nil {
+		errMsg := "failed to retrieve a balance"
+		span.SetStatus(codes.Error, errMsg)
+		span.RecordError(err)
+		return 0, errors.Join(errors.New(errMsg), err)
+	}
+
+	if err = tx.Commit(); err != nil {
+		errMsg := "failed to commit a transaction"
+		span.SetStatus(codes.Error, errMsg)
+		span.RecordError(err)
+		return 0, errors.Join(errors.New(errMsg), err)
+	}
+
+	span.SetStatus(codes.Ok, "successfully added points")
+	return balance, nil
+}
+
+// Spend takes an amount from a balance of a viewer and returns the new balance.
+// When the viewer has fewer points, it returns the current balance and ErrInsufficientPoints.
+func (s *SQLiteStorage) Spend(ctx context.Context, channelName string, account Account, amount int64) (int64, error) {
+	ctx, span := tracer.Start(ctx, "spend")
+	defer span.End()
+
+	ctx, cancel := context.WithTimeout(ctx, database.RequestTimeout)
+	defer cancel()
+
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		errMsg := "failed to begin a transaction"
+		span.SetStatus(codes.Error, errMsg)
+		span.RecordError(err)
+		return 0, errors.Join(errors.New(errMsg), err)
+	}
+	//nolint:errcheck // rollback after commit returns an error that doesn't matter
+	defer tx.Rollback()
+
+	balance, err := debit(ctx, tx, channelName, account, amount)
+	if errors.Is(err, ErrInsufficientPoints) {
+		span.SetStatus(codes.Ok, "viewer does not have enough points")
+		return balance, err
+	}
+	if err != nil {
+		errMsg := "failed to take points"
+		span.SetStatus(codes.Error, errMsg)
+		span.RecordError(err)
+		return 0, errors.Join(errors.New(errMsg), err)
+	}
+
+	if err = tx.Commit(); err != nil {
+		errMsg := "failed to commit a transaction"
+		span.SetStatus(codes.Error, errMsg)
+		span.RecordError(err)
+		return 0, errors.Join(errors.New(errMsg), err)
+	}
+
+	span.SetStatus(codes.Ok, "successfully took points")
+	return balance, nil
+}
+
+// Transfer moves an amount from one viewer to another in a single transaction and returns the new balance of the sender.
+// When the sender has fewer points, it returns the current balance of the sender and ErrInsufficientPoints.
+func (s *SQLiteStorage) Transfer(ctx context.Context, channelName string, from, to Account, amount int64) (int64, error) {
+	ctx, span := tracer.Start(ctx, "transfer")
+	defer span.End()
+
+	ctx, cancel := context.WithTimeout(ctx, database.RequestTimeout)
+	defer cancel()
+
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		errMsg := "failed to begin a transaction"
+		span.SetStatus(codes.Error, errMsg)
+		span.RecordError(err)
+		return 0, errors.Join(errors.New(errMsg), err)
+	}
+	//nolint:errcheck // rollback after commit returns an error that doesn't matter
+	defer tx.Rollback()
+
+	balance, err := debit(ctx, tx, channelName, from, amount)
+	if errors.Is(err, ErrInsufficientPoints) {
+		span.SetStatus(codes.Ok, "sender does not have enough points")
+		return balance, err
+	}
+	if err != nil {
+		errMsg := "failed to take points from the sender"
+		span.SetStatus(codes.Error, errMsg)
+		span.RecordError(err)
+		return 0, errors.Join(errors.New(errMsg), err)
+	}
+
+	_, err = tx.ExecContext(ctx, addQuery, channelName, to.UserID, to.Username, amount, time.Now().Unix(), amount)
+	if err != nil {
+		errMsg := "failed to add points to the receiver"
+		span.SetStatus(codes.Error, errMsg)
+		span.RecordError(err)
+		return 0, errors.Join(errors.New(errMsg), err)
+	}
+
+	if err = tx.Commit(); err != nil {
+		errMsg := "failed to commit a transaction"
+		span.SetStatus(codes.Error, errMsg)
+		span.RecordError(err)
+		return 0, errors.Join(errors.New(errMsg), err)
+	}
+
+	span.SetStatus(codes.Ok, "successfully transferred points")
+	return balance, nil
+}
+
+// Top returns viewers with the most points on a channel.
+func (s *SQLiteStorage) Top(ctx context.Context, channelName string, limit int) ([]Account, error) {
+	query := "SELECT user_id, username, balance FROM points_balances WHERE channel_name = ? AND balance > 0 ORDER BY balance DESC, username LIMIT ?;"
+
+	ctx, span := tracer.Start(ctx, "top")
+	defer span.End()
+
+	ctx, cancel := context.WithTimeout(ctx, database.RequestTimeout)
+	defer cancel()
+
+	rows, err := s.db.QueryContext(ctx, query, channelName, limit)
+	if err != nil {
+		errMsg := "failed to query top balances"
+		span.SetStatus(codes.Error, errMsg)
+		span.RecordError(err)
+		return nil, errors.Join(errors.New(errMsg), err)
+	}
+	defer rows.Close()
+
+	accounts := []Account{}
+	for rows.Next() {
+		var account Account
+		if err = rows.Scan(&account.UserID, &account.Username, &account.Balance); err != nil {
+			errMsg := "failed to copy a balance to a struct"
+			span.SetStatus(codes.Error, errMsg)
+			span.RecordError(err)
+			return nil, errors.Join(errors.New(errMsg), err)
+		}
+		accounts = append(accounts, account)
+	}
+
+	if err = rows.Err(); err != nil {
+		errMsg := "failed to iterate over top balances"
+		span.SetStatus(codes.Error, errMsg)
+		span.RecordError(err)
+		return nil, errors.Join(errors.New(errMsg), err)
+	}
+
+	span.SetStatus(codes.Ok, "successfully retrieved top balances")
+	return accounts, nil
+}
+
+// debit takes an amount from a balance of a viewer within a transaction and returns the new balance.
+func debit(ctx context.Context, tx *sql.Tx, channelName string, account Account, amount int64) (int64, error) {
+	var balance int64
+	err := tx.QueryRowContext(ctx, balanceQuery, channelName, account.UserID).Scan(&balance)
+	if err != nil && !errors.Is(err, sql.ErrNoRows) {
+		return 0, err
+	}
+
+	if balance < amount {
+		return balance, ErrInsufficientPoints
+	}
+
+	_, err = tx.ExecContext(ctx, "UPDATE points_balances SET balance = balance - ?, username = ?, updated_at = ? WHERE channel_name = ? AND user_id = ?;",
+		amount, account.Username, time.Now().Unix(), channelName, account.UserID)
+	if err != nil {
+		return 0, err
+	}
+
+	return balance - amount, nil
+}
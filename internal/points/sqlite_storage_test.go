@@ -0,0 +1,62 @@
+package points
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/danielbukowski/twitch-chatbot/internal/sqlitetest"
+)
+
+// pointsMigration creates tables of points.
+const pointsMigration = "20261019120500_create_points_tables.sql"
+
+func TestSQLiteStorage(t *testing.T) {
+	t.Run("pays a bucket only once", func(t *testing.T) {
+		// given
+		storage := NewSQLiteStorage(sqlitetest.Open(t, pointsMigration))
+		bucket := time.Date(2026, 10, 19, 12, 0, 0, 0, time.UTC)
+		awards := []Account{{UserID: "1", Username: "viewer", Balance: 10}}
+
+		// when
+		first, firstErr := storage.Accrue(context.Background(), "channel", bucket, awards)
+		second, secondErr := storage.Accrue(context.Background(), "channel", bucket, awards)
+		next, nextErr := storage.Accrue(context.Background(), "channel", bucket.Add(5*time.Minute), awards)
+
+		// then
+		for _, err := range []error{firstErr, secondErr, nextErr} {
+			if err != nil {
+				t.Fatalf("Expected no error, got `%v`", err)
+			}
+		}
+		if !first || second || !next {
+			t.Errorf("Expected only the first accrual of a bucket to pay, got `%t`, `%t`, `%t`", first, second, next)
+		}
+		account, found, err := storage.Balance(context.Background(), "channel", "viewer")
+		if err != nil || !found {
+			t.Fatalf("Expected a balance, got `%v`", err)
+		}
+		if account.Balance != 20 {
+			t.Errorf("Expected 20 points, got %d", account.Balance)
+		}
+	})
+
+	t.Run("pays the same bucket on different channels", func(t *testing.T) {
+		// given
+		storage := NewSQLiteStorage(sqlitetest.Open(t, pointsMigration))
+		bucket := time.Date(2026, 10, 19, 12, 0, 0, 0, time.UTC)
+		awards := []Account{{UserID: "1", Username: "viewer", Balance: 10}}
+
+		// when
+		_, _ = storage.Accrue(context.Background(), "channel", bucket, awards)
+		accrued, err := storage.Accrue(context.Background(), "other_channel", bucket, awards)
+
+		// then
+		if err != nil {
+			t.Fatalf("Expected no error, got `%v`", err)
+		}
+		if !accrued {
+			t.Errorf("Expected the bucket to be paid on another channel")
+		}
+	})
+}
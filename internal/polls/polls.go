@@ -0,0 +1,370 @@
+package polls
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"slices"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+	"unicode"
+
+	"github.com/danielbukowski/twitch-chatbot/internal/command"
+	"github.com/danielbukowski/twitch-chatbot/internal/event"
+	twitchapi "github.com/danielbukowski/twitch-chatbot/internal/twitch_api"
+	"github.com/gempir/go-twitch-irc/v4"
+	"github.com/nicklaw5/helix/v2"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.uber.org/zap"
+)
+
+var tracer = otel.Tracer("github.com/danielbukowski/twitch-chatbot/internal/polls")
+
+var errNoBroadcasterToken = command.UsageError("predictions need access credentials of the broadcaster.")
+var errNoActivePoll = command.UsageError("no poll is running.")
+
+const (
+	defaultDuration = time.Minute      // defaultDuration is a duration of polls and predictions without a given duration.
+	minDuration     = 15 * time.Second // minDuration is the shortest poll accepted by Twitch.
+	maxDuration     = 30 * time.Minute // maxDuration is the longest poll and prediction window accepted by Twitch.
+	maxChatChoices  = 9                // maxChatChoices is the largest number of choices of a chat poll, so every choice is a single digit.
+	maxPollChoices  = 5                // maxPollChoices is the largest number of choices of a Twitch poll.
+	maxPollTitle    = 60               // maxPollTitle is the longest title of a Twitch poll.
+	maxChoiceTitle  = 25               // maxChoiceTitle is the longest choice of a Twitch poll or prediction.
+)
+
+// question is a parsed poll or prediction.
+type question struct {
+	title    string
+	choices  []string
+	duration time.Duration
+}
+
+// chatPoll is a poll, in which users vote by typing a number of a choice in the chat.
+type chatPoll struct {
+	question
+	votes map[string]int // Votes holds indexes of choices by IDs of voters.
+	timer *time.Timer    // Timer ends the poll.
+}
+
+// Service creates polls and predictions of Twitch and announces their results in the chat.
+// Without access credentials of the broadcaster, or on channels without polls, it runs polls in the chat instead.
+type Service struct {
+	helixClient *helix.Client        // HelixClient uses access credentials of the broadcaster, it is nil, when they are missing.
+	chatClient  command.ChatClient   // ChatClient announces results.
+	chatPolls   map[string]*chatPoll // ChatPolls holds running chat polls by names of channels.
+	mu          sync.Mutex           // Mu guards chatPolls.
+	logger      *zap.Logger          // Logger is used for logging.
+}
+
+// NewService creates an instance of Service. The helix client needs the channel:manage:polls and channel:manage:predictions scopes
+// of the broadcaster, it may be nil.
+func NewService(helixClient *helix.Client, chatClient command.ChatClient, logger *zap.Logger) *Service {
+	return &Service{
+		helixClient: helixClient,
+		chatClient:  chatClient,
+		chatPolls:   make(map[string]*chatPoll),
+		logger:      logger.Named("polls"),
+	}
+}
+
+// Poll starts a poll or ends the running one.
+// Usage: `!poll "<title>" <choice> | <choice> [| <choice>...] [duration]` or `!poll end`.
+func (s *Service) Poll() command.Handler {
+	return func(ctx context.Context, args []string, chatClient command.ChatClient) error {
+		spanCtx, span := tracer.Start(ctx, "poll")
+		defer span.End()
+
+		cmdCtx := command.UnwrapContext(ctx)
+		privMsg := cmdCtx.PrivMsg
+
+		if len(args) == 1 && strings.EqualFold(args[0], "end") {
+			return s.endPoll(spanCtx, privMsg)
+		}
+
+		q, err := parseQuestion(args, maxChatChoices)
+		if err != nil {
+			span.SetStatus(codes.Error, "wrong usage of the command")
+			return err
+		}
+		span.SetAttributes(attribute.String("poll.title", q.title), attribute.Int("poll.choices", len(q.choices)))
+
+		if s.helixClient != nil && fitsTwitchPoll(q) {
+			created, err := s.createPoll(privMsg.RoomID, q)
+			if err != nil {
+				span.SetStatus(codes.Error, "failed to create a poll")
+				return command.UpstreamError(err)
+			}
+			if created {
+				span.SetStatus(codes.Ok, "successfully created a poll")
+				chatClient.Say(privMsg.Channel, fmt.Sprintf("@%s, started a poll: %s", privMsg.User.DisplayName, q.title))
+				return nil
+			}
+		}
+
+		if !s.startChatPoll(privMsg.Channel, q) {
+			span.SetStatus(codes.Error, "chat poll is already running")
+			return command.UsageError("a poll is already running, end it with !poll end.")
+		}
+
+		span.SetStatus(codes.Ok, "successfully started a chat poll")
+
+		choices := make([]string, 0, len(q.choices))
+		for i, choice := range q.choices {
+			choices = append(choices, fmt.Sprintf("%d) %s", i+1, choice))
+		}
+		chatClient.Say(privMsg.Channel, fmt.Sprintf("Poll: %s Type the number of your choice within %s: %s", q.title, q.duration, strings.Join(choices, ", ")))
+		return nil
+	}
+}
+
+// Observe counts a vote in a chat poll, when a message is a number of a choice. Only the last vote of a user counts.
+func (s *Service) Observe(privMsg *twitch.PrivateMessage) {
+	choice, err := strconv.Atoi(strings.TrimSpace(privMsg.Message))
+	if err != nil {
+		return
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	poll, ok := s.chatPolls[privMsg.Channel]
+	if !ok || choice < 1 || choice > len(poll.choices) || privMsg.User.ID == "" {
+		return
+	}
+
+	poll.votes[privMsg.User.ID] = choice - 1
+}
+
+// HandleEvent announces results of polls and predictions of Twitch.
+func (s *Service) HandleEvent(ctx context.Context, e event.Event) {
+	switch e := e.(type) {
+	case event.PollEnd:
+		s.announcePoll(ctx, e)
+	case event.PredictionEnd:
+		s.announcePrediction(ctx, e)
+	}
+}
+
+// createPoll creates a poll of Twitch. It returns false, when the channel can't have polls, for example, because it is not affiliated.
+func (s *Service) createPoll(broadcasterID string, q question) (bool, error) {
+	choices := make([]helix.PollChoiceParam, 0, len(q.choices))
+	for _, choice := range q.choices {
+		choices = append(choices, helix.PollChoiceParam{Title: choice})
+	}
+
+	resp, err := s.helixClient.CreatePoll(&helix.CreatePollParams{
+		BroadcasterID: broadcasterID,
+		Title:         q.title,
+		Choices:       choices,
+		Duration:      int(q.duration.Seconds()),
+	})
+	if err != nil {
+		return false, err
+	}
+
+	if resp.StatusCode == http.StatusForbidden {
+		s.logger.Info("channel can't have polls, falling back to a chat poll", zap.String("reason", resp.ErrorMessage))
+		return false, nil
+	}
+
+	return true, twitchapi.ResponseError(resp.ResponseCommon)
+}
+
+// endPoll ends the running chat poll, or the running poll of Twitch.
+func (s *Service) endPoll(ctx context.Context, privMsg *twitch.PrivateMessage) error {
+	if s.endChatPoll(privMsg.Channel) {
+		return nil
+	}
+
+	if s.helixClient == nil {
+		return errNoActivePoll
+	}
+
+	_, span := tracer.Start(ctx, "endPoll")
+	defer span.End()
+
+	resp, err := s.helixClient.GetPolls(&helix.PollsParams{BroadcasterID: privMsg.RoomID, First: "1"})
+	if err == nil {
+		err = twitchapi.ResponseError(resp.ResponseCommon)
+	}
+	if err != nil {
+		span.SetStatus(codes.Error, "failed to retrieve polls")
+		return command.UpstreamError(err)
+	}
+
+	if len(resp.Data.Polls) == 0 || resp.Data.Polls[0].Status != "ACTIVE" {
+		span.SetStatus(codes.Error, "no poll is running")
+		return errNoActivePoll
+	}
+
+	endResp, err := s.helixClient.EndPoll(&helix.EndPollParams{BroadcasterID: privMsg.RoomID, ID: resp.Data.Polls[0].ID, Status: "TERMINATED"})
+	if err == nil {
+		err = twitchapi.ResponseError(endResp.ResponseCommon)
+	}
+	if err != nil {
+		span.SetStatus(codes.Error, "failed to end a poll")
+		return command.UpstreamError(err)
+	}
+
+	span.SetStatus(codes.Ok, "successfully ended a poll")
+	return nil
+}
+
+// startChatPoll starts a chat poll. It returns false, when a chat poll is already running on the channel.
+func (s *Service) startChatPoll(channelName string, q question) bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if _, ok := s.chatPolls[channelName]; ok {
+		return false
+	}
+
+	s.chatPolls[channelName] = &chatPoll{
+		question: q,
+		votes:    make(map[string]int),
+		timer:    time.AfterFunc(q.duration, func() { s.endChatPoll(channelName) }),
+	}
+
+	return true
+}
+
+// endChatPoll ends a chat poll and announces its results. It returns false, when no chat poll is running on the channel.
+func (s *Service) endChatPoll(channelName string) bool {
+	s.mu.Lock()
+	poll, ok := s.chatPolls[channelName]
+	delete(s.chatPolls, channelName)
+	s.mu.Unlock()
+
+	if !ok {
+		return false
+	}
+
+	poll.timer.Stop()
+
+	votes := make([]int, len(poll.choices))
+	for _, choice := range poll.votes {
+		votes[choice]++
+	}
+
+	s.chatClient.Say(channelName, fmt.Sprintf("Poll %s ended! %s", poll.title, results(poll.choices, votes)))
+	return true
+}
+
+// announcePoll announces results of a poll of Twitch.
+func (s *Service) announcePoll(ctx context.Context, e event.PollEnd) {
+	_, span := tracer.Start(ctx, "announcePoll")
+	defer span.End()
+
+	if strings.EqualFold(e.Data.Status, "archived") {
+		span.SetStatus(codes.Ok, "poll was archived")
+		return
+	}
+
+	choices := make([]string, 0, len(e.Data.Choices))
+	votes := make([]int, 0, len(e.Data.Choices))
+	for _, choice := range e.Data.Choices {
+		choices = append(choices, choice.Title)
+		votes = append(votes, choice.Votes)
+	}
+
+	span.SetStatus(codes.Ok, "successfully announced a poll")
+	s.chatClient.Say(e.ChannelName, fmt.Sprintf("Poll %s ended! %s", e.Data.Title, results(choices, votes)))
+}
+
+// results describes votes for choices, like `a wins with 3 votes (60%). a: 3, b: 2`.
+func results(choices []string, votes []int) string {
+	total := 0
+	for _, v := range votes {
+		total += v
+	}
+
+	if total == 0 {
+		return "Nobody voted."
+	}
+
+	most := slices.Max(votes)
+	winners := []string{}
+	tally := make([]string, 0, len(choices))
+	for i, choice := range choices {
+		if votes[i] == most {
+			winners = append(winners, choice)
+		}
+		tally = append(tally, fmt.Sprintf("%s: %d", choice, votes[i]))
+	}
+
+	verdict := fmt.Sprintf("%s wins with %d votes (%d%%).", winners[0], most, most*100/total)
+	if len(winners) > 1 {
+		verdict = fmt.Sprintf("It's a tie between %s with %d votes each.", strings.Join(winners, " and "), most)
+	}
+
+	return fmt.Sprintf("%s %s", verdict, strings.Join(tally, ", "))
+}
+
+// parseQuestion parses a quoted title, choices separated by `|` and an optional duration after the last choice.
+// The duration needs a unit, like `90s` or `2m`, so a bare number ending the last choice, like in `Mario Kart 64`, stays a part of it.
+func parseQuestion(args []string, maxChoices int) (question, error) {
+	usage := command.UsageError(`use the command like: "<title>" <choice> | <choice> [duration]`)
+
+	text := strings.TrimSpace(strings.Join(args, " "))
+	if !strings.HasPrefix(text, `"`) {
+		return question{}, usage
+	}
+
+	title, rest, found := strings.Cut(text[1:], `"`)
+	title = strings.TrimSpace(title)
+	if !found || title == "" {
+		return question{}, usage
+	}
+
+	choices := strings.Split(rest, "|")
+	duration := defaultDuration
+
+	last := strings.Fields(choices[len(choices)-1])
+	if len(last) > 1 {
+		if d, err := parseDuration(last[len(last)-1]); err == nil {
+			duration = d
+			choices[len(choices)-1] = strings.Join(last[:len(last)-1], " ")
+		}
+	}
+
+	for i := range choices {
+		choices[i] = strings.TrimSpace(choices[i])
+		if choices[i] == "" {
+			return question{}, usage
+		}
+	}
+
+	if len(choices) < 2 || len(choices) > maxChoices {
+		return question{}, command.UsageError(fmt.Sprintf("give from 2 to %d choices.", maxChoices))
+	}
+
+	if duration < minDuration || duration > maxDuration {
+		return question{}, command.UsageError(fmt.Sprintf("the duration has to be from %s to %s.", minDuration, maxDuration))
+	}
+
+	return question{title: title, choices: choices, duration: duration}, nil
+}
+
+// parseDuration parses a duration with a unit. Bare numbers are rejected.
+func parseDuration(arg string) (time.Duration, error) {
+	if arg == "" || unicode.IsDigit(rune(arg[len(arg)-1])) {
+		return 0, errors.New("duration has no unit")
+	}
+
+	return time.ParseDuration(arg)
+}
+
+// fitsTwitchPoll tells, if a question can be a poll of Twitch.
+func fitsTwitchPoll(q question) bool {
+	if len(q.choices) > maxPollChoices || len([]rune(q.title)) > maxPollTitle {
+		return false
+	}
+
+	return !slices.ContainsFunc(q.choices, func(choice string) bool { return len([]rune(choice)) > maxChoiceTitle })
+}
@@ -0,0 +1,215 @@
+package polls
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"slices"
+	"testing"
+	"time"
+
+	"github.com/danielbukowski/twitch-chatbot/internal/command"
+	"github.com/danielbukowski/twitch-chatbot/internal/event"
+	"github.com/danielbukowski/twitch-chatbot/internal/helixtest"
+	"github.com/gempir/go-twitch-irc/v4"
+	"github.com/nicklaw5/helix/v2"
+	"go.uber.org/zap"
+)
+
+type chatClientMock struct {
+	messages []string
+}
+
+func (c *chatClientMock) Say(_, message string) {
+	c.messages = append(c.messages, message)
+}
+
+func (c *chatClientMock) Reply(_, _, message string) {
+	c.messages = append(c.messages, message)
+}
+
+func (c *chatClientMock) Join(_ ...string) {}
+
+func (c *chatClientMock) Depart(_ string) {}
+
+// newTestHelixClient creates a helix client with a stand-in of Twitch API, which responds to creating polls with the given status.
+func newTestHelixClient(t *testing.T, status int, created *[]helix.CreatePollParams) *helix.Client {
+	t.Helper()
+
+	helixClient := helixtest.NewClient(t, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodPost || r.URL.Path != "/polls" {
+			w.WriteHeader(http.StatusNotFound)
+			return
+		}
+
+		var params helix.CreatePollParams
+		_ = json.NewDecoder(r.Body).Decode(&params)
+		*created = append(*created, params)
+
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(status)
+		if status != http.StatusOK {
+			_ = json.NewEncoder(w).Encode(map[string]any{"error": http.StatusText(status), "status": status, "message": "unavailable"})
+			return
+		}
+		_, _ = w.Write([]byte(`{"data":[{"id":"poll-id","status":"ACTIVE"}]}`))
+	}))
+
+	return helixClient
+}
+
+func commandContext(commandName string) context.Context {
+	privMsg := &twitch.PrivateMessage{Channel: "channel", RoomID: "channel-id", User: twitch.User{ID: "1", Name: "moderator", DisplayName: "Moderator"}}
+	return command.WithContext(context.Background(), command.NewContext(commandName, privMsg, zap.NewNop()))
+}
+
+func TestPoll(t *testing.T) {
+	args := []string{`"Which`, `map?"`, "a", "|", "b", "|", "c", "2m"}
+
+	t.Run("creates a poll of Twitch", func(t *testing.T) {
+		// given
+		created := []helix.CreatePollParams{}
+		service := NewService(newTestHelixClient(t, http.StatusOK, &created), &chatClientMock{}, zap.NewNop())
+
+		// when
+		err := service.Poll()(commandContext("!poll"), args, &chatClientMock{})
+
+		// then
+		if err != nil {
+			t.Fatalf("Expected no error, got `%v`", err)
+		}
+		if len(created) != 1 || created[0].Title != "Which map?" || len(created[0].Choices) != 3 || created[0].Duration != 120 || created[0].BroadcasterID != "channel-id" {
+			t.Fatalf("Expected a poll to be created, got `%+v`", created)
+		}
+		if len(service.chatPolls) != 0 {
+			t.Fatal("Expected no chat poll")
+		}
+	})
+
+	t.Run("falls back to a chat poll, when the channel can't have polls", func(t *testing.T) {
+		// given
+		created := []helix.CreatePollParams{}
+		service := NewService(newTestHelixClient(t, http.StatusForbidden, &created), &chatClientMock{}, zap.NewNop())
+
+		// when
+		err := service.Poll()(commandContext("!poll"), args, &chatClientMock{})
+
+		// then
+		if err != nil {
+			t.Fatalf("Expected no error, got `%v`", err)
+		}
+		if _, ok := service.chatPolls["channel"]; !ok {
+			t.Fatal("Expected a chat poll")
+		}
+		service.endChatPoll("channel")
+	})
+
+	t.Run("counts the last vote of every user in a chat poll", func(t *testing.T) {
+		// given
+		announcer := &chatClientMock{}
+		service := NewService(nil, announcer, zap.NewNop())
+		_ = service.Poll()(commandContext("!poll"), args, &chatClientMock{})
+		for _, vote := range []struct{ userID, message string }{{"1", "1"}, {"2", "2"}, {"1", "2"}, {"3", "4"}, {"3", "hello"}, {"4", " 2 "}, {"5", "3"}} {
+			service.Observe(&twitch.PrivateMessage{Channel: "channel", Message: vote.message, User: twitch.User{ID: vote.userID}})
+		}
+
+		// when
+		err := service.Poll()(commandContext("!poll"), []string{"end"}, &chatClientMock{})
+
+		// then
+		if err != nil {
+			t.Fatalf("Expected no error, got `%v`", err)
+		}
+		expected := []string{"Poll Which map? ended! b wins with 3 votes (75%). a: 0, b: 3, c: 1"}
+		if !slices.Equal(announcer.messages, expected) {
+			t.Fatalf("Expected `%v`, got `%v`", expected, announcer.messages)
+		}
+	})
+}
+
+func TestParseQuestion(t *testing.T) {
+	testCases := []struct {
+		name        string
+		args        []string
+		expected    question
+		expectedErr bool
+	}{
+		{name: "title, choices and duration", args: []string{`"Which`, `map?"`, "de_dust", "2", "|", "inferno", "90s"}, expected: question{title: "Which map?", choices: []string{"de_dust 2", "inferno"}, duration: 90 * time.Second}},
+		{name: "number ending the last choice", args: []string{`"Best`, `game?"`, "Halo", "2", "|", "Mario", "Kart", "64"}, expected: question{title: "Best game?", choices: []string{"Halo 2", "Mario Kart 64"}, duration: defaultDuration}},
+		{name: "default duration", args: []string{`"Tea?"`, "yes|no"}, expected: question{title: "Tea?", choices: []string{"yes", "no"}, duration: defaultDuration}},
+		{name: "missing quotes", args: []string{"Tea?", "yes", "|", "no"}, expectedErr: true},
+		{name: "single choice", args: []string{`"Tea?"`, "yes", "1m"}, expectedErr: true},
+		{name: "empty choice", args: []string{`"Tea?"`, "yes", "|", "|", "no"}, expectedErr: true},
+		{name: "too long duration", args: []string{`"Tea?"`, "yes", "|", "no", "1h"}, expectedErr: true},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			// when
+			got, err := parseQuestion(tc.args, maxChatChoices)
+
+			// then
+			if tc.expectedErr {
+				if command.Classify(err) != command.KindUsage {
+					t.Fatalf("Expected a usage error, got `%v`", err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("Expected no error, got `%v`", err)
+			}
+			if got.title != tc.expected.title || !slices.Equal(got.choices, tc.expected.choices) || got.duration != tc.expected.duration {
+				t.Fatalf("Expected `%+v`, got `%+v`", tc.expected, got)
+			}
+		})
+	}
+}
+
+func TestHandleEvent(t *testing.T) {
+	testCases := []struct {
+		name     string
+		event    event.Event
+		expected string
+	}{
+		{
+			name: "poll with a tie",
+			event: event.PollEnd{Base: event.Base{ChannelName: "channel"}, Data: helix.EventSubChannelPollEndEvent{
+				Title:   "Tea?",
+				Status:  "completed",
+				Choices: []helix.PollChoice{{Title: "yes", Votes: 2}, {Title: "no", Votes: 2}},
+			}},
+			expected: "Poll Tea? ended! It's a tie between yes and no with 2 votes each. yes: 2, no: 2",
+		},
+		{
+			name: "resolved prediction",
+			event: event.PredictionEnd{Base: event.Base{ChannelName: "channel"}, Data: helix.EventSubChannelPredictionEndEvent{
+				Title:            "Win?",
+				Status:           "resolved",
+				WinningOutcomeID: "2",
+				Outcomes:         []helix.EventSubOutcome{{ID: "1", Title: "yes", Users: 3, ChannelPoints: 500}, {ID: "2", Title: "no", Users: 2, ChannelPoints: 1000}},
+			}},
+			expected: "Prediction Win? ended! no won, 2 users share 1500 channel points.",
+		},
+		{
+			name:     "cancelled prediction",
+			event:    event.PredictionEnd{Base: event.Base{ChannelName: "channel"}, Data: helix.EventSubChannelPredictionEndEvent{Title: "Win?", Status: "canceled"}},
+			expected: "Prediction Win? was cancelled, channel points were refunded.",
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			// given
+			chatClient := &chatClientMock{}
+			service := NewService(nil, chatClient, zap.NewNop())
+
+			// when
+			service.HandleEvent(context.Background(), tc.event)
+
+			// then
+			if len(chatClient.messages) != 1 || chatClient.messages[0] != tc.expected {
+				t.Fatalf("Expected `%s`, got `%v`", tc.expected, chatClient.messages)
+			}
+		})
+	}
+}
@@ -0,0 +1,167 @@
+package polls
+
+import (
+	"context"
+	"fmt"
+	"slices"
+	"strconv"
+	"strings"
+
+	"github.com/danielbukowski/twitch-chatbot/internal/command"
+	"github.com/danielbukowski/twitch-chatbot/internal/event"
+	twitchapi "github.com/danielbukowski/twitch-chatbot/internal/twitch_api"
+	"github.com/gempir/go-twitch-irc/v4"
+	"github.com/nicklaw5/helix/v2"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+)
+
+const (
+	maxPredictionTitle    = 45 // maxPredictionTitle is the longest title of a prediction.
+	maxPredictionOutcomes = 10 // maxPredictionOutcomes is the largest number of outcomes of a prediction.
+)
+
+// Predict starts a prediction, or locks, resolves or cancels the running one.
+// Usage: `!predict "<title>" <outcome> | <outcome> [| <outcome>...] [window]`, `!predict lock`, `!predict resolve <number>` or `!predict cancel`.
+func (s *Service) Predict() command.Handler {
+	return func(ctx context.Context, args []string, chatClient command.ChatClient) error {
+		spanCtx, span := tracer.Start(ctx, "predict")
+		defer span.End()
+
+		cmdCtx := command.UnwrapContext(ctx)
+		privMsg := cmdCtx.PrivMsg
+
+		if s.helixClient == nil {
+			span.SetStatus(codes.Error, "missing access credentials of the broadcaster")
+			return errNoBroadcasterToken
+		}
+
+		if len(args) > 0 {
+			switch strings.ToLower(args[0]) {
+			case "lock":
+				return s.endPrediction(spanCtx, privMsg, "LOCKED", args[1:], chatClient)
+			case "resolve":
+				return s.endPrediction(spanCtx, privMsg, "RESOLVED", args[1:], chatClient)
+			case "cancel":
+				return s.endPrediction(spanCtx, privMsg, "CANCELED", args[1:], chatClient)
+			}
+		}
+
+		q, err := parseQuestion(args, maxPredictionOutcomes)
+		if err != nil {
+			span.SetStatus(codes.Error, "wrong usage of the command")
+			return err
+		}
+
+		if len([]rune(q.title)) > maxPredictionTitle || slices.ContainsFunc(q.choices, func(choice string) bool { return len([]rune(choice)) > maxChoiceTitle }) {
+			span.SetStatus(codes.Error, "title or outcomes are too long")
+			return command.UsageError(fmt.Sprintf("the title can have up to %d characters and outcomes up to %d.", maxPredictionTitle, maxChoiceTitle))
+		}
+
+		outcomes := make([]helix.PredictionChoiceParam, 0, len(q.choices))
+		for _, choice := range q.choices {
+			outcomes = append(outcomes, helix.PredictionChoiceParam{Title: choice})
+		}
+
+		resp, err := s.helixClient.CreatePrediction(&helix.CreatePredictionParams{
+			BroadcasterID:    privMsg.RoomID,
+			Title:            q.title,
+			Outcomes:         outcomes,
+			PredictionWindow: int(q.duration.Seconds()),
+		})
+		if err == nil {
+			err = twitchapi.ResponseError(resp.ResponseCommon)
+		}
+		if err != nil {
+			span.SetStatus(codes.Error, "failed to create a prediction")
+			return command.UpstreamError(err)
+		}
+
+		span.SetAttributes(attribute.String("prediction.title", q.title), attribute.Int("prediction.outcomes", len(q.choices)))
+		span.SetStatus(codes.Ok, "successfully created a prediction")
+
+		chatClient.Say(privMsg.Channel, fmt.Sprintf("@%s, started a prediction: %s", privMsg.User.DisplayName, q.title))
+		return nil
+	}
+}
+
+// endPrediction changes a status of the running prediction. Resolving needs a number of the winning outcome.
+func (s *Service) endPrediction(ctx context.Context, privMsg *twitch.PrivateMessage, status string, args []string, chatClient command.ChatClient) error {
+	_, span := tracer.Start(ctx, "endPrediction")
+	defer span.End()
+
+	resp, err := s.helixClient.GetPredictions(&helix.PredictionsParams{BroadcasterID: privMsg.RoomID, First: "1"})
+	if err == nil {
+		err = twitchapi.ResponseError(resp.ResponseCommon)
+	}
+	if err != nil {
+		span.SetStatus(codes.Error, "failed to retrieve predictions")
+		return command.UpstreamError(err)
+	}
+
+	if len(resp.Data.Predictions) == 0 || (resp.Data.Predictions[0].Status != "ACTIVE" && resp.Data.Predictions[0].Status != "LOCKED") {
+		span.SetStatus(codes.Error, "no prediction is running")
+		return command.UsageError("no prediction is running.")
+	}
+
+	prediction := resp.Data.Predictions[0]
+	params := &helix.EndPredictionParams{BroadcasterID: privMsg.RoomID, ID: prediction.ID, Status: status}
+
+	if status == "RESOLVED" {
+		outcome := 0
+		if len(args) > 0 {
+			outcome, _ = strconv.Atoi(args[0])
+		}
+		if outcome < 1 || outcome > len(prediction.Outcomes) {
+			span.SetStatus(codes.Error, "wrong winning outcome")
+			return command.UsageError(fmt.Sprintf("give a number of the winning outcome from 1 to %d.", len(prediction.Outcomes)))
+		}
+		params.WinningOutcomeID = prediction.Outcomes[outcome-1].ID
+	}
+
+	endResp, err := s.helixClient.EndPrediction(params)
+	if err == nil {
+		err = twitchapi.ResponseError(endResp.ResponseCommon)
+	}
+	if err != nil {
+		span.SetStatus(codes.Error, "failed to end a prediction")
+		return command.UpstreamError(err)
+	}
+
+	span.SetStatus(codes.Ok, "successfully changed a prediction")
+
+	if status == "LOCKED" {
+		chatClient.Say(privMsg.Channel, fmt.Sprintf("@%s, the prediction is locked.", privMsg.User.DisplayName))
+	}
+
+	return nil
+}
+
+// announcePrediction announces a result of a prediction.
+func (s *Service) announcePrediction(ctx context.Context, e event.PredictionEnd) {
+	_, span := tracer.Start(ctx, "announcePrediction")
+	defer span.End()
+
+	channelName := e.ChannelName
+
+	if !strings.EqualFold(e.Data.Status, "resolved") {
+		span.SetStatus(codes.Ok, "prediction was cancelled")
+		s.chatClient.Say(channelName, fmt.Sprintf("Prediction %s was cancelled, channel points were refunded.", e.Data.Title))
+		return
+	}
+
+	i := slices.IndexFunc(e.Data.Outcomes, func(outcome helix.EventSubOutcome) bool { return outcome.ID == e.Data.WinningOutcomeID })
+	if i == -1 {
+		span.SetStatus(codes.Error, "winning outcome is missing")
+		return
+	}
+
+	spent := 0
+	for _, outcome := range e.Data.Outcomes {
+		spent += outcome.ChannelPoints
+	}
+
+	winner := e.Data.Outcomes[i]
+	span.SetStatus(codes.Ok, "successfully announced a prediction")
+	s.chatClient.Say(channelName, fmt.Sprintf("Prediction %s ended! %s won, %d users share %d channel points.", e.Data.Title, winner.Title, winner.Users, spent))
+}
@@ -0,0 +1,98 @@
+package presence
+
+import (
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/gempir/go-twitch-irc/v4"
+)
+
+// Viewer is a viewer active in chat of a channel.
+type Viewer struct {
+	UserID   string         // UserID is an ID of the viewer.
+	Username string         // Username is a login of the viewer.
+	Badges   map[string]int // Badges are badges of the viewer from the last message.
+	LastSeen time.Time      // LastSeen is when the viewer last wrote a message.
+}
+
+// Tracker remembers viewers, who wrote a message within the active window. It's shared by features
+// rewarding activity in chat, like watch time and points. The chatbot and broadcasters are not tracked.
+type Tracker struct {
+	ignored      map[string]bool              // Ignored holds logins of users who are not tracked, like the chatbot.
+	activeWindow time.Duration                // ActiveWindow is how long a viewer is active after the viewer was last seen.
+	viewers      map[string]map[string]Viewer // Viewers holds viewers seen on every channel by their IDs.
+	mu           sync.Mutex                   // Mu guards viewers.
+	now          func() time.Time             // Now returns the current time.
+}
+
+// NewTracker creates an instance of Tracker.
+func NewTracker(chatbotName string, activeWindow time.Duration) *Tracker {
+	return &Tracker{
+		ignored:      map[string]bool{strings.ToLower(chatbotName): true},
+		activeWindow: activeWindow,
+		viewers:      make(map[string]map[string]Viewer),
+		now:          time.Now,
+	}
+}
+
+// Observe marks the author of a message as active.
+func (t *Tracker) Observe(privMsg *twitch.PrivateMessage) {
+	username := strings.ToLower(privMsg.User.Name)
+	if privMsg.User.ID == "" || t.ignored[username] || privMsg.User.Badges["broadcaster"] != 0 {
+		return
+	}
+
+	channelName := strings.ToLower(privMsg.Channel)
+
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	viewers, ok := t.viewers[channelName]
+	if !ok {
+		viewers = make(map[string]Viewer)
+		t.viewers[channelName] = viewers
+	}
+	viewers[privMsg.User.ID] = Viewer{UserID: privMsg.User.ID, Username: username, Badges: privMsg.User.Badges, LastSeen: t.now()}
+}
+
+// Active returns viewers of a channel seen within the active window, and forgets the others.
+func (t *Tracker) Active(channelName string) []Viewer {
+	channelName = strings.ToLower(channelName)
+	now := t.now()
+
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	active := []Viewer{}
+	for userID, viewer := range t.viewers[channelName] {
+		if now.Sub(viewer.LastSeen) > t.activeWindow {
+			delete(t.viewers[channelName], userID)
+			continue
+		}
+		active = append(active, viewer)
+	}
+
+	return active
+}
+
+// Channels returns names of channels with viewers seen since they were last forgotten.
+func (t *Tracker) Channels() []string {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	channelNames := make([]string, 0, len(t.viewers))
+	for channelName := range t.viewers {
+		channelNames = append(channelNames, channelName)
+	}
+
+	return channelNames
+}
+
+// Forget removes all viewers of a channel, for example, when its stream goes offline.
+func (t *Tracker) Forget(channelName string) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	delete(t.viewers, strings.ToLower(channelName))
+}
@@ -0,0 +1,69 @@
+package presence
+
+import (
+	"testing"
+	"time"
+
+	"github.com/gempir/go-twitch-irc/v4"
+)
+
+func message(userID, username string, badges map[string]int) *twitch.PrivateMessage {
+	return &twitch.PrivateMessage{Channel: "Channel", User: twitch.User{ID: userID, Name: username, Badges: badges}}
+}
+
+func TestTracker(t *testing.T) {
+	t.Run("tracks authors of messages, but not the chatbot and the broadcaster", func(t *testing.T) {
+		// given
+		tracker := NewTracker("Chatbot", 10*time.Minute)
+
+		// when
+		tracker.Observe(message("1", "Viewer", map[string]int{"vip": 1}))
+		tracker.Observe(message("2", "chatbot", nil))
+		tracker.Observe(message("3", "channel", map[string]int{"broadcaster": 1}))
+		tracker.Observe(message("", "anonymous", nil))
+		active := tracker.Active("channel")
+
+		// then
+		if len(active) != 1 || active[0].UserID != "1" || active[0].Username != "viewer" || active[0].Badges["vip"] != 1 {
+			t.Fatalf("Expected only the viewer to be active, got `%+v`", active)
+		}
+	})
+
+	t.Run("forgets viewers after the active window", func(t *testing.T) {
+		// given
+		tracker := NewTracker("chatbot", 10*time.Minute)
+		now := time.Date(2026, 10, 19, 12, 0, 0, 0, time.UTC)
+		tracker.now = func() time.Time { return now }
+		tracker.Observe(message("1", "viewer", nil))
+
+		// when
+		now = now.Add(11 * time.Minute)
+		active := tracker.Active("channel")
+
+		// then
+		if len(active) != 0 {
+			t.Fatalf("Expected no active viewers, got `%+v`", active)
+		}
+		if len(tracker.viewers["channel"]) != 0 {
+			t.Fatalf("Expected the inactive viewer to be forgotten, got `%+v`", tracker.viewers["channel"])
+		}
+	})
+
+	t.Run("forgets all viewers of a channel", func(t *testing.T) {
+		// given
+		tracker := NewTracker("chatbot", 10*time.Minute)
+		tracker.Observe(message("1", "viewer", nil))
+
+		// when
+		channelsBefore := tracker.Channels()
+		tracker.Forget("CHANNEL")
+
+		// then
+		if len(channelsBefore) != 1 || channelsBefore[0] != "channel" {
+			t.Fatalf("Expected the channel to be tracked, got `%v`", channelsBefore)
+		}
+		if len(tracker.Channels()) != 0 || len(tracker.Active("channel")) != 0 {
+			t.Fatal("Expected the channel to be forgotten")
+		}
+	})
+}
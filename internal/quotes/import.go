@@ -0,0 +1,219 @@
+package quotes
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+)
+
+var errNoTextColumn = errors.New("the export has no column with texts of quotes")
+
+// fieldAliases maps names of columns used by other bots to fields of a quote.
+var fieldAliases = map[string]string{
+	"id":         "number",
+	"number":     "number",
+	"#":          "number",
+	"quote id":   "number",
+	"quoteid":    "number",
+	"quote":      "text",
+	"text":       "text",
+	"message":    "text",
+	"content":    "text",
+	"game":       "game",
+	"category":   "game",
+	"added by":   "added_by",
+	"addedby":    "added_by",
+	"added_by":   "added_by",
+	"user":       "added_by",
+	"username":   "added_by",
+	"author":     "added_by",
+	"creator":    "added_by",
+	"date":       "created_at",
+	"created":    "created_at",
+	"created at": "created_at",
+	"created_at": "created_at",
+	"createdat":  "created_at",
+	"timestamp":  "created_at",
+	"time":       "created_at",
+}
+
+// dateLayouts are formats of dates used by other bots.
+var dateLayouts = []string{
+	time.RFC3339,
+	time.DateTime,
+	time.DateOnly,
+	"01/02/2006 15:04:05",
+	"1/2/2006 3:04:05 PM",
+	"01/02/2006",
+	"1/2/2006",
+}
+
+// ReadFile reads quotes exported by other bots from a CSV or JSON file.
+// A CSV file needs a header, a JSON file has an array of objects, which may be nested under a "quotes" key.
+// Columns are matched by names commonly used by bots, like "quote", "game", "user" and "date".
+func ReadFile(path string) ([]Quote, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+
+	switch strings.ToLower(filepath.Ext(path)) {
+	case ".csv":
+		return parseCSV(file, time.Now())
+	case ".json":
+		return parseJSON(file, time.Now())
+	default:
+		return nil, fmt.Errorf("unsupported file %s, expected a .csv or .json file", path)
+	}
+}
+
+// parseCSV reads quotes from a CSV export with a header.
+// Quotes without a date get the time of the import.
+func parseCSV(r io.Reader, importedAt time.Time) ([]Quote, error) {
+	reader := csv.NewReader(r)
+	reader.FieldsPerRecord = -1
+	reader.TrimLeadingSpace = true
+
+	header, err := reader.Read()
+	if err != nil {
+		return nil, errors.Join(errors.New("failed to read the header"), err)
+	}
+
+	columns := make(map[string]int, len(header))
+	for i, name := range header {
+		if field, ok := fieldAliases[strings.ToLower(strings.TrimSpace(strings.TrimPrefix(name, "\ufeff")))]; ok {
+			if _, taken := columns[field]; !taken {
+				columns[field] = i
+			}
+		}
+	}
+	if _, ok := columns["text"]; !ok {
+		return nil, errNoTextColumn
+	}
+
+	var quotes []Quote
+	for {
+		record, err := reader.Read()
+		if errors.Is(err, io.EOF) {
+			break
+		}
+		if err != nil {
+			return nil, errors.Join(errors.New("failed to read a record"), err)
+		}
+
+		fields := make(map[string]string, len(columns))
+		for field, i := range columns {
+			if i < len(record) {
+				fields[field] = record[i]
+			}
+		}
+
+		if quote, ok := toQuote(fields, importedAt); ok {
+			quotes = append(quotes, quote)
+		}
+	}
+
+	return quotes, nil
+}
+
+// parseJSON reads quotes from a JSON export.
+// Quotes without a date get the time of the import.
+func parseJSON(r io.Reader, importedAt time.Time) ([]Quote, error) {
+	var raw json.RawMessage
+	if err := json.NewDecoder(r).Decode(&raw); err != nil {
+		return nil, errors.Join(errors.New("failed to decode the export"), err)
+	}
+
+	var objects []map[string]any
+	if err := json.Unmarshal(raw, &objects); err != nil {
+		var wrapped struct {
+			Quotes []map[string]any `json:"quotes"`
+		}
+		if err := json.Unmarshal(raw, &wrapped); err != nil {
+			return nil, errors.Join(errors.New("expected an array of quotes"), err)
+		}
+		objects = wrapped.Quotes
+	}
+
+	var quotes []Quote
+	for _, object := range objects {
+		fields := make(map[string]string, len(object))
+		for key, value := range object {
+			field, ok := fieldAliases[strings.ToLower(key)]
+			if !ok {
+				continue
+			}
+			if _, taken := fields[field]; taken {
+				continue
+			}
+
+			switch v := value.(type) {
+			case string:
+				fields[field] = v
+			case float64:
+				fields[field] = strconv.FormatFloat(v, 'f', -1, 64)
+			}
+		}
+
+		if quote, ok := toQuote(fields, importedAt); ok {
+			quotes = append(quotes, quote)
+		}
+	}
+
+	return quotes, nil
+}
+
+// toQuote creates a quote from fields of an export. The returned value is false, when the quote has no text.
+func toQuote(fields map[string]string, importedAt time.Time) (Quote, bool) {
+	text := strings.TrimSpace(fields["text"])
+	if text == "" {
+		return Quote{}, false
+	}
+
+	quote := Quote{
+		Text:      text,
+		Game:      strings.TrimSpace(fields["game"]),
+		AddedBy:   strings.ToLower(strings.TrimPrefix(strings.TrimSpace(fields["added_by"]), "@")),
+		CreatedAt: importedAt,
+	}
+
+	if number, err := strconv.ParseInt(strings.TrimPrefix(strings.TrimSpace(fields["number"]), "#"), 10, 64); err == nil && number > 0 {
+		quote.Number = number
+	}
+
+	if createdAt, ok := parseDate(strings.TrimSpace(fields["created_at"])); ok {
+		quote.CreatedAt = createdAt
+	}
+
+	return quote, true
+}
+
+// parseDate parses a date in one of the known layouts or a Unix timestamp in seconds or milliseconds.
+func parseDate(value string) (time.Time, bool) {
+	if value == "" {
+		return time.Time{}, false
+	}
+
+	if timestamp, err := strconv.ParseInt(value, 10, 64); err == nil {
+		if timestamp > 1e12 {
+			return time.UnixMilli(timestamp), true
+		}
+		return time.Unix(timestamp, 0), true
+	}
+
+	for _, layout := range dateLayouts {
+		if date, err := time.Parse(layout, value); err == nil {
+			return date, true
+		}
+	}
+
+	return time.Time{}, false
+}
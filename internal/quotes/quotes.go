@@ -0,0 +1,251 @@
+package quotes
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/danielbukowski/twitch-chatbot/internal/command"
+	twitchapi "github.com/danielbukowski/twitch-chatbot/internal/twitch_api"
+	"github.com/nicklaw5/helix/v2"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/trace"
+	"go.uber.org/zap"
+)
+
+var tracer = otel.Tracer("github.com/danielbukowski/twitch-chatbot/internal/quotes")
+
+const (
+	maxQuoteLength = 400 // maxQuoteLength is the longest quote, which still fits in a chat message with its details.
+	searchLimit    = 5   // searchLimit is a number of quotes found by a search.
+)
+
+// Quote is a memorable message saved by moderators.
+type Quote struct {
+	Number    int64     // Number identifies a quote on a channel.
+	Text      string    // Text is the quoted message.
+	Game      string    // Game is a name of the category played, when the quote was added.
+	AddedBy   string    // AddedBy is a login of the user, who added the quote.
+	CreatedAt time.Time // CreatedAt is a time of adding the quote.
+}
+
+// storage saves quotes of channels.
+type storage interface {
+	Add(ctx context.Context, channelName string, quote Quote) (int64, error)
+	Get(ctx context.Context, channelName string, number int64) (Quote, bool, error)
+	Random(ctx context.Context, channelName string) (Quote, bool, error)
+	Search(ctx context.Context, channelName, text string, limit int) ([]Quote, error)
+	Delete(ctx context.Context, channelName string, number int64) (bool, error)
+}
+
+// Service shows, searches, adds and deletes quotes.
+type Service struct {
+	storage storage                                    // Storage saves quotes.
+	game    func(broadcasterID string) (string, error) // Game returns a name of the category played on a channel.
+	now     func() time.Time                           // Now returns the current time.
+	logger  *zap.Logger                                // Logger is used for logging.
+}
+
+// NewService creates an instance of Service.
+func NewService(storage storage, helixClient *helix.Client, logger *zap.Logger) *Service {
+	return &Service{
+		storage: storage,
+		game: func(broadcasterID string) (string, error) {
+			info, err := twitchapi.FetchChannelInformation(helixClient, broadcasterID)
+			return info.GameName, err
+		},
+		now:    time.Now,
+		logger: logger.Named("quotes"),
+	}
+}
+
+// Quote shows a random quote, a quote with the given number or the quotes matching a text.
+// Usage: `!quote [number | search <text>]`.
+func (s *Service) Quote() command.Handler {
+	return func(ctx context.Context, args []string, chatClient command.ChatClient) error {
+		spanCtx, span := tracer.Start(ctx, "quoteCommand")
+		defer span.End()
+
+		cmdCtx := command.UnwrapContext(ctx)
+		privMsg := cmdCtx.PrivMsg
+
+		if len(args) > 0 && strings.EqualFold(args[0], "search") {
+			return s.search(spanCtx, strings.Join(args[1:], " "), chatClient)
+		}
+
+		var (
+			quote Quote
+			found bool
+			err   error
+		)
+		if len(args) == 0 {
+			quote, found, err = s.storage.Random(spanCtx, privMsg.Channel)
+		} else {
+			number, parseErr := parseNumber(args[0])
+			if parseErr != nil {
+				span.SetStatus(codes.Error, "invalid number of a quote")
+				return parseErr
+			}
+			quote, found, err = s.storage.Get(spanCtx, privMsg.Channel, number)
+		}
+		if err != nil {
+			span.SetStatus(codes.Error, "failed to read a quote")
+			span.RecordError(err)
+			return command.InternalError(err)
+		}
+
+		if !found {
+			span.SetStatus(codes.Ok, "there is no such quote")
+			chatClient.Say(privMsg.Channel, fmt.Sprintf("@%s, there is no such quote.", privMsg.User.DisplayName))
+			return nil
+		}
+
+		span.SetStatus(codes.Ok, "successfully got a quote")
+		chatClient.Say(privMsg.Channel, format(quote))
+		return nil
+	}
+}
+
+// AddQuote saves a new quote with the category currently played.
+// Usage: `!addquote <text>`.
+func (s *Service) AddQuote() command.Handler {
+	return func(ctx context.Context, args []string, chatClient command.ChatClient) error {
+		spanCtx, span := tracer.Start(ctx, "addQuoteCommand")
+		defer span.End()
+
+		cmdCtx := command.UnwrapContext(ctx)
+		privMsg := cmdCtx.PrivMsg
+
+		text := strings.TrimSpace(strings.Join(args, " "))
+		if text == "" {
+			span.SetStatus(codes.Error, "missing a text of the quote")
+			return command.UsageError("give a text of the quote")
+		}
+		if len([]rune(text)) > maxQuoteLength {
+			span.SetStatus(codes.Error, "quote is too long")
+			return command.UsageError(fmt.Sprintf("a quote can have at most %d characters", maxQuoteLength))
+		}
+
+		// The game is only a detail of a quote, so the quote is saved without it, when Twitch API is unavailable.
+		game, err := s.game(privMsg.RoomID)
+		if err != nil {
+			span.RecordError(err)
+			cmdCtx.Logger.Warn("failed to fetch the game of a quote", zap.Error(err))
+		}
+
+		quote := Quote{Text: text, Game: game, AddedBy: strings.ToLower(privMsg.User.Name), CreatedAt: s.now()}
+		quote.Number, err = s.storage.Add(spanCtx, privMsg.Channel, quote)
+		if err != nil {
+			span.SetStatus(codes.Error, "failed to save the quote")
+			span.RecordError(err)
+			return command.InternalError(err)
+		}
+
+		span.SetStatus(codes.Ok, "successfully added a quote")
+		chatClient.Say(privMsg.Channel, fmt.Sprintf("@%s, added quote #%d.", privMsg.User.DisplayName, quote.Number))
+		return nil
+	}
+}
+
+// DelQuote deletes a quote with the given number.
+// Usage: `!delquote <number>`.
+func (s *Service) DelQuote() command.Handler {
+	return func(ctx context.Context, args []string, chatClient command.ChatClient) error {
+		spanCtx, span := tracer.Start(ctx, "delQuoteCommand")
+		defer span.End()
+
+		cmdCtx := command.UnwrapContext(ctx)
+		privMsg := cmdCtx.PrivMsg
+
+		if len(args) == 0 {
+			span.SetStatus(codes.Error, "missing a number of the quote")
+			return command.UsageError("give a number of the quote")
+		}
+
+		number, err := parseNumber(args[0])
+		if err != nil {
+			span.SetStatus(codes.Error, "invalid number of a quote")
+			return err
+		}
+
+		deleted, err := s.storage.Delete(spanCtx, privMsg.Channel, number)
+		if err != nil {
+			span.SetStatus(codes.Error, "failed to delete the quote")
+			span.RecordError(err)
+			return command.InternalError(err)
+		}
+
+		if !deleted {
+			span.SetStatus(codes.Ok, "there is no such quote")
+			chatClient.Say(privMsg.Channel, fmt.Sprintf("@%s, there is no quote #%d.", privMsg.User.DisplayName, number))
+			return nil
+		}
+
+		span.SetStatus(codes.Ok, "successfully deleted a quote")
+		chatClient.Say(privMsg.Channel, fmt.Sprintf("@%s, deleted quote #%d.", privMsg.User.DisplayName, number))
+		return nil
+	}
+}
+
+// search shows the first quote matching a text and numbers of the other ones.
+// The outcome is recorded on the span of the calling handler.
+func (s *Service) search(ctx context.Context, text string, chatClient command.ChatClient) error {
+	span := trace.SpanFromContext(ctx)
+	cmdCtx := command.UnwrapContext(ctx)
+	privMsg := cmdCtx.PrivMsg
+
+	text = strings.TrimSpace(text)
+	if text == "" {
+		span.SetStatus(codes.Error, "missing a text to search for")
+		return command.UsageError("give a text to search for")
+	}
+
+	found, err := s.storage.Search(ctx, privMsg.Channel, text, searchLimit)
+	if err != nil {
+		span.SetStatus(codes.Error, "failed to search quotes")
+		span.RecordError(err)
+		return command.InternalError(err)
+	}
+
+	if len(found) == 0 {
+		span.SetStatus(codes.Ok, "no quote matched the text")
+		chatClient.Say(privMsg.Channel, fmt.Sprintf("@%s, no quote matches %s.", privMsg.User.DisplayName, text))
+		return nil
+	}
+
+	message := format(found[0])
+	if len(found) > 1 {
+		others := make([]string, 0, len(found)-1)
+		for _, quote := range found[1:] {
+			others = append(others, fmt.Sprintf("#%d", quote.Number))
+		}
+		message += fmt.Sprintf(" Also matching: %s", strings.Join(others, ", "))
+	}
+
+	span.SetStatus(codes.Ok, "successfully searched quotes")
+	chatClient.Say(privMsg.Channel, message)
+	return nil
+}
+
+// format turns a quote into a chat message.
+func format(quote Quote) string {
+	details := quote.CreatedAt.Format(time.DateOnly)
+	if quote.Game != "" {
+		details = quote.Game + ", " + details
+	}
+
+	return fmt.Sprintf("#%d: \"%s\" [%s]", quote.Number, quote.Text, details)
+}
+
+// parseNumber parses a number of a quote, which may start with #.
+func parseNumber(arg string) (int64, error) {
+	number, err := strconv.ParseInt(strings.TrimPrefix(arg, "#"), 10, 64)
+	if err != nil || number <= 0 {
+		return 0, command.UsageError(fmt.Sprintf("%s is not a number of a quote", arg))
+	}
+
+	return number, nil
+}
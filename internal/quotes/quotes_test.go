@@ -0,0 +1,255 @@
+package quotes
+
+import (
+	"context"
+	"errors"
+	"io"
+	"slices"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/danielbukowski/twitch-chatbot/internal/command"
+	"github.com/gempir/go-twitch-irc/v4"
+	"go.uber.org/zap"
+)
+
+// storageMock keeps quotes of a single channel in memory.
+type storageMock struct {
+	quotes []Quote
+}
+
+func (s *storageMock) Add(_ context.Context, _ string, quote Quote) (int64, error) {
+	quote.Number = int64(len(s.quotes) + 1)
+	s.quotes = append(s.quotes, quote)
+	return quote.Number, nil
+}
+
+func (s *storageMock) Get(_ context.Context, _ string, number int64) (Quote, bool, error) {
+	i := slices.IndexFunc(s.quotes, func(q Quote) bool { return q.Number == number })
+	if i == -1 {
+		return Quote{}, false, nil
+	}
+	return s.quotes[i], true, nil
+}
+
+func (s *storageMock) Random(_ context.Context, _ string) (Quote, bool, error) {
+	if len(s.quotes) == 0 {
+		return Quote{}, false, nil
+	}
+	return s.quotes[0], true, nil
+}
+
+func (s *storageMock) Search(_ context.Context, _, text string, limit int) ([]Quote, error) {
+	var found []Quote
+	for _, quote := range s.quotes {
+		if strings.Contains(strings.ToLower(quote.Text), strings.ToLower(text)) && len(found) < limit {
+			found = append(found, quote)
+		}
+	}
+	return found, nil
+}
+
+func (s *storageMock) Delete(_ context.Context, _ string, number int64) (bool, error) {
+	before := len(s.quotes)
+	s.quotes = slices.DeleteFunc(s.quotes, func(q Quote) bool { return q.Number == number })
+	return len(s.quotes) != before, nil
+}
+
+type chatClientMock struct {
+	messages []string
+}
+
+func (c *chatClientMock) Say(_, message string) {
+	c.messages = append(c.messages, message)
+}
+
+func (c *chatClientMock) Reply(_, _, message string) {
+	c.messages = append(c.messages, message)
+}
+
+func (c *chatClientMock) Join(_ ...string) {}
+
+func (c *chatClientMock) Depart(_ string) {}
+
+func commandContext(commandName string) context.Context {
+	privMsg := &twitch.PrivateMessage{Channel: "channel", RoomID: "channel-id", User: twitch.User{ID: "1", Name: "Moderator", DisplayName: "Moderator"}}
+	return command.WithContext(context.Background(), command.NewContext(commandName, privMsg, zap.NewNop()))
+}
+
+func TestQuote(t *testing.T) {
+	createdAt := time.Date(2026, 10, 19, 12, 0, 0, 0, time.UTC)
+	quotes := []Quote{
+		{Number: 1, Text: "I meant to do that", Game: "Celeste", CreatedAt: createdAt},
+		{Number: 2, Text: "That was not a bug", CreatedAt: createdAt},
+		{Number: 3, Text: "This bug is a feature", Game: "Factorio", CreatedAt: createdAt},
+	}
+
+	testCases := []struct {
+		name        string
+		args        []string
+		expected    string
+		expectedErr bool
+	}{
+		{name: "shows a random quote", expected: `#1: "I meant to do that" [Celeste, 2026-10-19]`},
+		{name: "shows a quote with the given number", args: []string{"#2"}, expected: `#2: "That was not a bug" [2026-10-19]`},
+		{name: "tells about a missing quote", args: []string{"42"}, expected: "@Moderator, there is no such quote."},
+		{name: "shows quotes matching a text", args: []string{"search", "BUG"}, expected: `#2: "That was not a bug" [2026-10-19] Also matching: #3`},
+		{name: "tells about no matching quotes", args: []string{"search", "speedrun"}, expected: "@Moderator, no quote matches speedrun."},
+		{name: "rejects an invalid number", args: []string{"first"}, expectedErr: true},
+		{name: "rejects a search without a text", args: []string{"search"}, expectedErr: true},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			// given
+			service := NewService(&storageMock{quotes: slices.Clone(quotes)}, nil, zap.NewNop())
+			chatClient := &chatClientMock{}
+
+			// when
+			err := service.Quote()(commandContext("!quote"), tc.args, chatClient)
+
+			// then
+			if tc.expectedErr {
+				if command.Classify(err) != command.KindUsage {
+					t.Fatalf("Expected a usage error, got `%v`", err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("Expected no error, got `%v`", err)
+			}
+			if len(chatClient.messages) != 1 || chatClient.messages[0] != tc.expected {
+				t.Fatalf("Expected `%s`, got `%v`", tc.expected, chatClient.messages)
+			}
+		})
+	}
+}
+
+func TestAddQuote(t *testing.T) {
+	now := time.Date(2026, 10, 19, 12, 0, 0, 0, time.UTC)
+
+	testCases := []struct {
+		name         string
+		game         func(string) (string, error)
+		expectedGame string
+	}{
+		{name: "saves the game played", game: func(string) (string, error) { return "Celeste", nil }, expectedGame: "Celeste"},
+		{name: "saves the quote, when the game is unavailable", game: func(string) (string, error) { return "", errors.New("twitch api is down") }},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			// given
+			storage := &storageMock{}
+			service := NewService(storage, nil, zap.NewNop())
+			service.game = tc.game
+			service.now = func() time.Time { return now }
+			chatClient := &chatClientMock{}
+
+			// when
+			err := service.AddQuote()(commandContext("!addquote"), []string{"I", "meant", "to", "do", "that"}, chatClient)
+
+			// then
+			if err != nil {
+				t.Fatalf("Expected no error, got `%v`", err)
+			}
+			expected := Quote{Number: 1, Text: "I meant to do that", Game: tc.expectedGame, AddedBy: "moderator", CreatedAt: now}
+			if len(storage.quotes) != 1 || storage.quotes[0] != expected {
+				t.Fatalf("Expected `%+v`, got `%+v`", expected, storage.quotes)
+			}
+			if len(chatClient.messages) != 1 || chatClient.messages[0] != "@Moderator, added quote #1." {
+				t.Fatalf("Expected a confirmation, got `%v`", chatClient.messages)
+			}
+		})
+	}
+}
+
+func TestDelQuote(t *testing.T) {
+	// given
+	storage := &storageMock{quotes: []Quote{{Number: 1, Text: "first"}, {Number: 2, Text: "second"}}}
+	service := NewService(storage, nil, zap.NewNop())
+	chatClient := &chatClientMock{}
+
+	// when
+	deleteErr := service.DelQuote()(commandContext("!delquote"), []string{"1"}, chatClient)
+	missingErr := service.DelQuote()(commandContext("!delquote"), []string{"1"}, chatClient)
+
+	// then
+	if deleteErr != nil || missingErr != nil {
+		t.Fatalf("Expected no errors, got `%v` and `%v`", deleteErr, missingErr)
+	}
+	expected := []string{"@Moderator, deleted quote #1.", "@Moderator, there is no quote #1."}
+	if !slices.Equal(chatClient.messages, expected) {
+		t.Fatalf("Expected `%v`, got `%v`", expected, chatClient.messages)
+	}
+	if len(storage.quotes) != 1 || storage.quotes[0].Number != 2 {
+		t.Fatalf("Expected only the second quote to be left, got `%+v`", storage.quotes)
+	}
+}
+
+func TestImport(t *testing.T) {
+	importedAt := time.Date(2026, 10, 19, 12, 0, 0, 0, time.UTC)
+
+	testCases := []struct {
+		name        string
+		parse       func(io.Reader, time.Time) ([]Quote, error)
+		export      string
+		expected    []Quote
+		expectedErr bool
+	}{
+		{
+			name:  "csv with a header",
+			parse: parseCSV,
+			export: "\ufeffID,Quote,Game,Added By,Date\n" +
+				"7,\"I meant to do that, really\",Celeste,@Mod,2025-01-02\n" +
+				",That was not a bug,,mod,\n" +
+				"8,,Celeste,mod,2025-01-02\n",
+			expected: []Quote{
+				{Number: 7, Text: "I meant to do that, really", Game: "Celeste", AddedBy: "mod", CreatedAt: time.Date(2025, 1, 2, 0, 0, 0, 0, time.UTC)},
+				{Text: "That was not a bug", AddedBy: "mod", CreatedAt: importedAt},
+			},
+		},
+		{
+			name:        "csv without a column of texts",
+			parse:       parseCSV,
+			export:      "id,game\n1,Celeste\n",
+			expectedErr: true,
+		},
+		{
+			name:     "json array",
+			parse:    parseJSON,
+			export:   `[{"id": 3, "text": "This bug is a feature", "category": "Factorio", "user": "Mod", "createdAt": 1735776000000}]`,
+			expected: []Quote{{Number: 3, Text: "This bug is a feature", Game: "Factorio", AddedBy: "mod", CreatedAt: time.UnixMilli(1735776000000)}},
+		},
+		{
+			name:     "json object with quotes",
+			parse:    parseJSON,
+			export:   `{"quotes": [{"quote": "GG", "date": "01/02/2025"}]}`,
+			expected: []Quote{{Text: "GG", CreatedAt: time.Date(2025, 1, 2, 0, 0, 0, 0, time.UTC)}},
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			// when
+			got, err := tc.parse(strings.NewReader(tc.export), importedAt)
+
+			// then
+			if tc.expectedErr {
+				if err == nil {
+					t.Fatal("Expected an error, got nil")
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("Expected no error, got `%v`", err)
+			}
+			if !slices.EqualFunc(got, tc.expected, func(a, b Quote) bool {
+				return a.Number == b.Number && a.Text == b.Text && a.Game == b.Game && a.AddedBy == b.AddedBy && a.CreatedAt.Equal(b.CreatedAt)
+			}) {
+				t.Fatalf("Expected `%+v`, got `%+v`", tc.expected, got)
+			}
+		})
+	}
+}
@@ -0,0 +1,42 @@
+//go:build sqlite_fts5
+
+package quotes
+
+import (
+	"context"
+	"database/sql"
+	"strings"
+)
+
+// searchQuery selects quotes of a channel matching a full-text query, the best matches first.
+const searchQuery = `SELECT q.number, q.text, q.game, q.added_by, q.created_at FROM quotes_fts JOIN quotes q ON q.id = quotes_fts.rowid
+	WHERE quotes_fts MATCH ? AND q.channel_name = ? ORDER BY quotes_fts.rank LIMIT ?;`
+
+// searchArgument turns a text into a full-text query matching quotes containing all of its words.
+// Every word is quoted, so characters with a special meaning in FTS5 queries are matched literally.
+func searchArgument(text string) string {
+	words := strings.Fields(text)
+	for i, word := range words {
+		words[i] = `"` + strings.ReplaceAll(word, `"`, `""`) + `"`
+	}
+
+	return strings.Join(words, " ")
+}
+
+// indexQuote adds a quote to the full-text index.
+func indexQuote(ctx context.Context, tx *sql.Tx, id int64, text string) error {
+	_, err := tx.ExecContext(ctx, "INSERT INTO quotes_fts (rowid, text) VALUES (?, ?);", id, text)
+	return err
+}
+
+// unindexQuote removes a quote from the full-text index.
+func unindexQuote(ctx context.Context, tx *sql.Tx, id int64, text string) error {
+	_, err := tx.ExecContext(ctx, "INSERT INTO quotes_fts (quotes_fts, rowid, text) VALUES ('delete', ?, ?);", id, text)
+	return err
+}
+
+// rebuildIndex recreates the full-text index from the quotes table, which brings back quotes written by a build without FTS5.
+func rebuildIndex(ctx context.Context, db *sql.DB) error {
+	_, err := db.ExecContext(ctx, "INSERT INTO quotes_fts (quotes_fts) VALUES ('rebuild');")
+	return err
+}
@@ -0,0 +1,35 @@
+//go:build !sqlite_fts5
+
+package quotes
+
+import (
+	"context"
+	"database/sql"
+	"strings"
+)
+
+// searchQuery selects quotes of a channel containing a text, the oldest first.
+// It is used, when SQLite is built without FTS5.
+const searchQuery = `SELECT number, text, game, added_by, created_at FROM quotes
+	WHERE text LIKE ? ESCAPE '\' AND channel_name = ? ORDER BY number LIMIT ?;`
+
+// searchArgument turns a text into a LIKE pattern matching quotes containing it.
+func searchArgument(text string) string {
+	escaped := strings.NewReplacer(`\`, `\\`, "%", `\%`, "_", `\_`).Replace(text)
+	return "%" + escaped + "%"
+}
+
+// indexQuote does nothing without FTS5.
+func indexQuote(_ context.Context, _ *sql.Tx, _ int64, _ string) error {
+	return nil
+}
+
+// unindexQuote does nothing without FTS5.
+func unindexQuote(_ context.Context, _ *sql.Tx, _ int64, _ string) error {
+	return nil
+}
+
+// rebuildIndex does nothing without FTS5.
+func rebuildIndex(_ context.Context, _ *sql.DB) error {
+	return nil
+}
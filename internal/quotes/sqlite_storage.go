@@ -0,0 +1,346 @@
+package quotes
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"time"
+
+	"github.com/danielbukowski/twitch-chatbot/internal/database"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// SQLiteStorage stores quotes of channels.
+// Searching uses FTS5, when the chatbot is built with the sqlite_fts5 tag, otherwise it falls back to LIKE.
+type SQLiteStorage struct {
+	db *sql.DB
+}
+
+func NewSQLiteStorage(db *sql.DB) *SQLiteStorage {
+	return &SQLiteStorage{db: db}
+}
+
+// nextNumberQuery increments the counter of quotes of a channel and returns a number for a new quote.
+const nextNumberQuery = `INSERT INTO quote_counters (channel_name, last_number) VALUES (?, 1)
+	ON CONFLICT (channel_name) DO UPDATE SET last_number = last_number + 1 RETURNING last_number;`
+
+// raiseCounterQuery raises the counter of quotes of a channel to a number of an imported quote, so new quotes don't take it.
+const raiseCounterQuery = `INSERT INTO quote_counters (channel_name, last_number) VALUES (?, ?)
+	ON CONFLICT (channel_name) DO UPDATE SET last_number = MAX(last_number, excluded.last_number);`
+
+// textExistsQuery tells, if a channel has a quote with the given text.
+const textExistsQuery = "SELECT EXISTS(SELECT 1 FROM quotes WHERE channel_name = ? AND text = ?);"
+
+// Add saves a new quote and returns its number.
+func (s *SQLiteStorage) Add(ctx context.Context, channelName string, quote Quote) (int64, error) {
+	ctx, span := tracer.Start(ctx, "add")
+	defer span.End()
+
+	ctx, cancel := context.WithTimeout(ctx, database.RequestTimeout)
+	defer cancel()
+
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		errMsg := "failed to begin a transaction"
+		span.SetStatus(codes.Error, errMsg)
+		span.RecordError(err)
+		return 0, errors.Join(errors.New(errMsg), err)
+	}
+	//nolint:errcheck // rollback after commit returns an error that doesn't matter
+	defer tx.Rollback()
+
+	if err = tx.QueryRowContext(ctx, nextNumberQuery, channelName).Scan(&quote.Number); err != nil {
+		errMsg := "failed to retrieve a number for the quote"
+		span.SetStatus(codes.Error, errMsg)
+		span.RecordError(err)
+		return 0, errors.Join(errors.New(errMsg), err)
+	}
+
+	if _, err = insert(ctx, tx, channelName, quote); err != nil {
+		errMsg := "failed to save the quote"
+		span.SetStatus(codes.Error, errMsg)
+		span.RecordError(err)
+		return 0, errors.Join(errors.New(errMsg), err)
+	}
+
+	if err = tx.Commit(); err != nil {
+		errMsg := "failed to commit a transaction"
+		span.SetStatus(codes.Error, errMsg)
+		span.RecordError(err)
+		return 0, errors.Join(errors.New(errMsg), err)
+	}
+
+	span.SetStatus(codes.Ok, "successfully saved the quote")
+	return quote.Number, nil
+}
+
+// Import saves quotes exported by other bots and returns a number of saved quotes.
+// Quotes keep their numbers, unless the number is already taken, then they are skipped. Quotes without a number get the next one,
+// unless the channel already has a quote with the same text, so importing the same file again doesn't duplicate them.
+func (s *SQLiteStorage) Import(ctx context.Context, channelName string, quotes []Quote) (int, error) {
+	ctx, span := tracer.Start(ctx, "import")
+	defer span.End()
+
+	ctx, cancel := context.WithTimeout(ctx, database.RequestTimeout)
+	defer cancel()
+
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		errMsg := "failed to begin a transaction"
+		span.SetStatus(codes.Error, errMsg)
+		span.RecordError(err)
+		return 0, errors.Join(errors.New(errMsg), err)
+	}
+	//nolint:errcheck // rollback after commit returns an error that doesn't matter
+	defer tx.Rollback()
+
+	imported := 0
+	for _, quote := range quotes {
+		if quote.Number <= 0 {
+			var exists bool
+			if err = tx.QueryRowContext(ctx, textExistsQuery, channelName, quote.Text).Scan(&exists); err != nil {
+				errMsg := "failed to check, if the quote already exists"
+				span.SetStatus(codes.Error, errMsg)
+				span.RecordError(err)
+				return 0, errors.Join(errors.New(errMsg), err)
+			}
+			if exists {
+				continue
+			}
+
+			if err = tx.QueryRowContext(ctx, nextNumberQuery, channelName).Scan(&quote.Number); err != nil {
+				errMsg := "failed to retrieve a number for the quote"
+				span.SetStatus(codes.Error, errMsg)
+				span.RecordError(err)
+				return 0, errors.Join(errors.New(errMsg), err)
+			}
+		}
+
+		saved, err := insert(ctx, tx, channelName, quote)
+		if err != nil {
+			errMsg := "failed to save the quote"
+			span.SetStatus(codes.Error, errMsg)
+			span.RecordError(err)
+			return 0, errors.Join(errors.New(errMsg), err)
+		}
+
+		if !saved {
+			continue
+		}
+		imported++
+
+		if _, err = tx.ExecContext(ctx, raiseCounterQuery, channelName, quote.Number); err != nil {
+			errMsg := "failed to update the counter of quotes"
+			span.SetStatus(codes.Error, errMsg)
+			span.RecordError(err)
+			return 0, errors.Join(errors.New(errMsg), err)
+		}
+	}
+
+	if err = tx.Commit(); err != nil {
+		errMsg := "failed to commit a transaction"
+		span.SetStatus(codes.Error, errMsg)
+		span.RecordError(err)
+		return 0, errors.Join(errors.New(errMsg), err)
+	}
+
+	span.SetStatus(codes.Ok, "successfully imported quotes")
+	return imported, nil
+}
+
+// Get returns a quote with the given number. The second returned value is false, when there is no such quote.
+func (s *SQLiteStorage) Get(ctx context.Context, channelName string, number int64) (Quote, bool, error) {
+	query := "SELECT number, text, game, added_by, created_at FROM quotes WHERE channel_name = ? AND number = ?;"
+
+	ctx, span := tracer.Start(ctx, "get")
+	defer span.End()
+
+	return s.queryRow(ctx, query, channelName, number)
+}
+
+// Random returns a random quote of a channel. The second returned value is false, when the channel has no quotes.
+func (s *SQLiteStorage) Random(ctx context.Context, channelName string) (Quote, bool, error) {
+	query := "SELECT number, text, game, added_by, created_at FROM quotes WHERE channel_name = ? ORDER BY RANDOM() LIMIT 1;"
+
+	ctx, span := tracer.Start(ctx, "random")
+	defer span.End()
+
+	return s.queryRow(ctx, query, channelName)
+}
+
+// Search returns quotes of a channel containing a text.
+func (s *SQLiteStorage) Search(ctx context.Context, channelName, text string, limit int) ([]Quote, error) {
+	ctx, span := tracer.Start(ctx, "search")
+	defer span.End()
+
+	ctx, cancel := context.WithTimeout(ctx, database.RequestTimeout)
+	defer cancel()
+
+	rows, err := s.db.QueryContext(ctx, searchQuery, searchArgument(text), channelName, limit)
+	if err != nil {
+		errMsg := "failed to search quotes"
+		span.SetStatus(codes.Error, errMsg)
+		span.RecordError(err)
+		return nil, errors.Join(errors.New(errMsg), err)
+	}
+	defer rows.Close()
+
+	var quotes []Quote
+	for rows.Next() {
+		quote, err := scan(rows)
+		if err != nil {
+			errMsg := "failed to copy the result to a struct"
+			span.SetStatus(codes.Error, errMsg)
+			span.RecordError(err)
+			return nil, errors.Join(errors.New(errMsg), err)
+		}
+		quotes = append(quotes, quote)
+	}
+
+	if err = rows.Err(); err != nil {
+		errMsg := "failed to iterate over quotes"
+		span.SetStatus(codes.Error, errMsg)
+		span.RecordError(err)
+		return nil, errors.Join(errors.New(errMsg), err)
+	}
+
+	span.SetStatus(codes.Ok, "successfully searched quotes")
+	return quotes, nil
+}
+
+// Delete removes a quote with the given number. The returned value is false, when there was no such quote.
+func (s *SQLiteStorage) Delete(ctx context.Context, channelName string, number int64) (bool, error) {
+	ctx, span := tracer.Start(ctx, "delete")
+	defer span.End()
+
+	ctx, cancel := context.WithTimeout(ctx, database.RequestTimeout)
+	defer cancel()
+
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		errMsg := "failed to begin a transaction"
+		span.SetStatus(codes.Error, errMsg)
+		span.RecordError(err)
+		return false, errors.Join(errors.New(errMsg), err)
+	}
+	//nolint:errcheck // rollback after commit returns an error that doesn't matter
+	defer tx.Rollback()
+
+	var (
+		id   int64
+		text string
+	)
+	err = tx.QueryRowContext(ctx, "DELETE FROM quotes WHERE channel_name = ? AND number = ? RETURNING id, text;", channelName, number).Scan(&id, &text)
+	if errors.Is(err, sql.ErrNoRows) {
+		span.SetStatus(codes.Ok, "there is no such quote")
+		return false, nil
+	}
+	if err != nil {
+		errMsg := "failed to delete the quote"
+		span.SetStatus(codes.Error, errMsg)
+		span.RecordError(err)
+		return false, errors.Join(errors.New(errMsg), err)
+	}
+
+	if err = unindexQuote(ctx, tx, id, text); err != nil {
+		errMsg := "failed to remove the quote from the search index"
+		span.SetStatus(codes.Error, errMsg)
+		span.RecordError(err)
+		return false, errors.Join(errors.New(errMsg), err)
+	}
+
+	if err = tx.Commit(); err != nil {
+		errMsg := "failed to commit a transaction"
+		span.SetStatus(codes.Error, errMsg)
+		span.RecordError(err)
+		return false, errors.Join(errors.New(errMsg), err)
+	}
+
+	span.SetStatus(codes.Ok, "successfully deleted the quote")
+	return true, nil
+}
+
+// RebuildSearchIndex recreates the full-text index of quotes. It does nothing, when the chatbot is built without FTS5.
+func (s *SQLiteStorage) RebuildSearchIndex(ctx context.Context) error {
+	ctx, span := tracer.Start(ctx, "rebuildSearchIndex")
+	defer span.End()
+
+	if err := rebuildIndex(ctx, s.db); err != nil {
+		errMsg := "failed to rebuild the search index"
+		span.SetStatus(codes.Error, errMsg)
+		span.RecordError(err)
+		return errors.Join(errors.New(errMsg), err)
+	}
+
+	span.SetStatus(codes.Ok, "successfully rebuilt the search index")
+	return nil
+}
+
+// queryRow returns a single quote selected by a query. The second returned value is false, when no quote was selected.
+func (s *SQLiteStorage) queryRow(ctx context.Context, query string, args ...any) (Quote, bool, error) {
+	span := trace.SpanFromContext(ctx)
+
+	ctx, cancel := context.WithTimeout(ctx, database.RequestTimeout)
+	defer cancel()
+
+	quote, err := scan(s.db.QueryRowContext(ctx, query, args...))
+	if errors.Is(err, sql.ErrNoRows) {
+		span.SetStatus(codes.Ok, "there is no such quote")
+		return Quote{}, false, nil
+	}
+	if err != nil {
+		errMsg := "failed to retrieve a quote"
+		span.SetStatus(codes.Error, errMsg)
+		span.RecordError(err)
+		return Quote{}, false, errors.Join(errors.New(errMsg), err)
+	}
+
+	span.SetStatus(codes.Ok, "successfully retrieved a quote")
+	return quote, true, nil
+}
+
+// insert saves a quote with its number and adds it to the search index.
+// The returned value is false, when the number is already taken.
+func insert(ctx context.Context, tx *sql.Tx, channelName string, quote Quote) (bool, error) {
+	query := "INSERT OR IGNORE INTO quotes (channel_name, number, text, game, added_by, created_at) VALUES (?, ?, ?, ?, ?, ?);"
+
+	result, err := tx.ExecContext(ctx, query, channelName, quote.Number, quote.Text, quote.Game, quote.AddedBy, quote.CreatedAt.Unix())
+	if err != nil {
+		return false, err
+	}
+
+	rows, err := result.RowsAffected()
+	if err != nil {
+		return false, err
+	}
+	if rows == 0 {
+		return false, nil
+	}
+
+	id, err := result.LastInsertId()
+	if err != nil {
+		return false, err
+	}
+
+	return true, indexQuote(ctx, tx, id, quote.Text)
+}
+
+// scanner is a row or rows of a query.
+type scanner interface {
+	Scan(dest ...any) error
+}
+
+// scan copies a selected quote to a struct.
+func scan(row scanner) (Quote, error) {
+	var (
+		quote     Quote
+		createdAt int64
+	)
+	if err := row.Scan(&quote.Number, &quote.Text, &quote.Game, &quote.AddedBy, &createdAt); err != nil {
+		return Quote{}, err
+	}
+
+	quote.CreatedAt = time.Unix(createdAt, 0)
+	return quote, nil
+}
@@ -0,0 +1,79 @@
+//go:build sqlite_fts5
+
+package quotes
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/danielbukowski/twitch-chatbot/internal/sqlitetest"
+)
+
+// quotesMigration creates tables of quotes, its search index needs the sqlite_fts5 tag.
+const quotesMigration = "20261019120800_create_quotes_tables.sql"
+
+func TestSQLiteStorage(t *testing.T) {
+	createdAt := time.Date(2026, 10, 19, 12, 0, 0, 0, time.UTC)
+
+	t.Run("does not reuse a number of a deleted quote", func(t *testing.T) {
+		// given
+		storage := NewSQLiteStorage(sqlitetest.Open(t, quotesMigration))
+		_, _ = storage.Add(context.Background(), "channel", Quote{Text: "first", AddedBy: "mod", CreatedAt: createdAt})
+		second, _ := storage.Add(context.Background(), "channel", Quote{Text: "second", AddedBy: "mod", CreatedAt: createdAt})
+		if _, err := storage.Delete(context.Background(), "channel", second); err != nil {
+			t.Fatalf("Expected no error, got `%v`", err)
+		}
+
+		// when
+		got, err := storage.Add(context.Background(), "channel", Quote{Text: "third", AddedBy: "mod", CreatedAt: createdAt})
+
+		// then
+		if err != nil {
+			t.Fatalf("Expected no error, got `%v`", err)
+		}
+		if got != 3 {
+			t.Errorf("Expected quote #3, got #%d", got)
+		}
+	})
+
+	t.Run("numbers new quotes after imported ones", func(t *testing.T) {
+		// given
+		storage := NewSQLiteStorage(sqlitetest.Open(t, quotesMigration))
+		_, err := storage.Import(context.Background(), "channel", []Quote{
+			{Number: 7, Text: "imported", AddedBy: "mod", CreatedAt: createdAt},
+			{Text: "unnumbered", AddedBy: "mod", CreatedAt: createdAt},
+		})
+		if err != nil {
+			t.Fatalf("Expected no error, got `%v`", err)
+		}
+
+		// when
+		got, err := storage.Add(context.Background(), "channel", Quote{Text: "new", AddedBy: "mod", CreatedAt: createdAt})
+
+		// then
+		if err != nil {
+			t.Fatalf("Expected no error, got `%v`", err)
+		}
+		if got != 9 {
+			t.Errorf("Expected quote #9, got #%d", got)
+		}
+	})
+
+	t.Run("keeps counters of channels apart", func(t *testing.T) {
+		// given
+		storage := NewSQLiteStorage(sqlitetest.Open(t, quotesMigration))
+		_, _ = storage.Add(context.Background(), "channel", Quote{Text: "first", AddedBy: "mod", CreatedAt: createdAt})
+
+		// when
+		got, err := storage.Add(context.Background(), "other_channel", Quote{Text: "first", AddedBy: "mod", CreatedAt: createdAt})
+
+		// then
+		if err != nil {
+			t.Fatalf("Expected no error, got `%v`", err)
+		}
+		if got != 1 {
+			t.Errorf("Expected quote #1, got #%d", got)
+		}
+	})
+}
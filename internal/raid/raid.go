@@ -0,0 +1,237 @@
+package raid
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"sync"
+	"text/template"
+	"time"
+
+	"github.com/danielbukowski/twitch-chatbot/internal/command"
+	"github.com/danielbukowski/twitch-chatbot/internal/event"
+	twitchapi "github.com/danielbukowski/twitch-chatbot/internal/twitch_api"
+	"github.com/nicklaw5/helix/v2"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.uber.org/zap"
+)
+
+var tracer = otel.Tracer("github.com/danielbukowski/twitch-chatbot/internal/raid")
+
+const (
+	channelShoutoutCooldown = 2 * time.Minute // channelShoutoutCooldown is the minimum time between native shoutouts on a channel.
+	targetShoutoutCooldown  = time.Hour       // targetShoutoutCooldown is the minimum time between native shoutouts of the same user.
+)
+
+// DefaultShoutoutTemplate is a shoutout message. The template gets a Shoutout as data.
+const DefaultShoutoutTemplate = "Go check out @{{.DisplayName}} at https://twitch.tv/{{.Login}}{{if .GameName}}, they were last seen playing {{.GameName}}{{end}}!"
+
+// Shoutout holds data of a shoutout message.
+type Shoutout struct {
+	Login       string // Login is a login of the user.
+	DisplayName string // DisplayName is a display name of the user.
+	GameName    string // GameName is a name of the last game played by the user.
+	Title       string // Title is the last title of a stream of the user.
+}
+
+// Config decides how the chatbot responds to raids.
+type Config struct {
+	ShoutoutTemplate string        // ShoutoutTemplate is a template of a shoutout message.
+	NativeShoutout   bool          // NativeShoutout enables shoutouts of Twitch next to the message on the chat.
+	RelaxDuration    time.Duration // RelaxDuration is how long moderation is relaxed after a raid.
+	RelaxedRules     []string      // RelaxedRules holds names of moderation rules not checked after a raid.
+}
+
+// DefaultConfig is a configuration suitable for most channels.
+var DefaultConfig = Config{
+	ShoutoutTemplate: DefaultShoutoutTemplate,
+	NativeShoutout:   true,
+	RelaxDuration:    2 * time.Minute,
+	RelaxedRules:     []string{"caps", "emote_spam", "symbols", "repeated_characters"},
+}
+
+// userResolver returns a user with the given login.
+type userResolver interface {
+	UserByLogin(ctx context.Context, login string) (helix.User, error)
+}
+
+// chatClient sends messages to the chat.
+type chatClient interface {
+	Say(channelName, message string)
+}
+
+// moderationRelaxer stops checking moderation rules for a while.
+type moderationRelaxer interface {
+	Relax(channelName string, duration time.Duration, ruleNames ...string)
+}
+
+// raidGuard needs more users for a spam wave for a while.
+type raidGuard interface {
+	Relax(channelName string, duration time.Duration)
+}
+
+// Service shouts out other streamers and welcomes raids.
+type Service struct {
+	config        Config
+	template      *template.Template   // Template is a parsed shoutout template.
+	helixClient   *helix.Client        // HelixClient is used to call Twitch API.
+	userResolver  userResolver         // UserResolver resolves logins of users.
+	chatClient    chatClient           // ChatClient is used to post shoutouts.
+	relaxer       moderationRelaxer    // Relaxer relaxes moderation rules after a raid.
+	guard         raidGuard            // Guard is relaxed after a raid, so raiders are not mistaken for a hate raid.
+	moderatorID   string               // ModeratorID is an ID of the user that sends native shoutouts, usually the chatbot.
+	prefix        string               // Prefix is a prefix of commands.
+	lastShoutouts map[string]time.Time // LastShoutouts holds times of the last native shoutouts of channels and of users on them.
+	mu            sync.Mutex           // Mu guards lastShoutouts.
+	now           func() time.Time     // Now returns the current time.
+	logger        *zap.Logger          // Logger is used for logging.
+}
+
+// NewService creates an instance of Service. It returns an error, when the shoutout template is invalid.
+func NewService(config Config, helixClient *helix.Client, userResolver userResolver, chatClient chatClient, relaxer moderationRelaxer, guard raidGuard, moderatorID, prefix string, logger *zap.Logger) (*Service, error) {
+	tmpl, err := template.New("shoutout").Option("missingkey=error").Parse(config.ShoutoutTemplate)
+	if err != nil {
+		return nil, errors.Join(errors.New("failed to parse the shoutout template"), err)
+	}
+
+	return &Service{
+		config:        config,
+		template:      tmpl,
+		helixClient:   helixClient,
+		userResolver:  userResolver,
+		chatClient:    chatClient,
+		relaxer:       relaxer,
+		guard:         guard,
+		moderatorID:   moderatorID,
+		prefix:        prefix,
+		lastShoutouts: make(map[string]time.Time),
+		now:           time.Now,
+		logger:        logger.Named("raid"),
+	}, nil
+}
+
+// HandleRaid relaxes moderation for raiders and shouts out the raider. It's an event handler of raids.
+func (s *Service) HandleRaid(ctx context.Context, e event.Event) {
+	raid, ok := e.(event.Raid)
+	if !ok {
+		return
+	}
+
+	s.guard.Relax(raid.ChannelName, s.config.RelaxDuration)
+	s.relaxer.Relax(raid.ChannelName, s.config.RelaxDuration, s.config.RelaxedRules...)
+
+	s.logger.Info("welcoming a raid",
+		zap.String("channel_name", raid.ChannelName),
+		zap.String("raider", raid.User.Name),
+		zap.Int("viewers", raid.Viewers),
+	)
+
+	shoutoutCtx := context.WithoutCancel(ctx)
+	go func() {
+		if err := s.Shoutout(shoutoutCtx, raid.ChannelName, raid.RoomID, raid.User.Name); err != nil {
+			s.logger.Error("failed to shout out a raider", zap.String("raider", raid.User.Name), zap.Error(err))
+		}
+	}()
+}
+
+// ShoutoutCommand shouts out a user.
+// Usage: `!so @user`.
+func (s *Service) ShoutoutCommand() command.Handler {
+	return func(ctx context.Context, args []string, _ command.ChatClient) error {
+		if len(args) < 1 {
+			return command.UsageError(fmt.Sprintf("usage: %sso @user", s.prefix))
+		}
+
+		cmdCtx := command.UnwrapContext(ctx)
+		login := command.TrimMention(args[0])
+
+		err := s.Shoutout(ctx, cmdCtx.PrivMsg.Channel, cmdCtx.PrivMsg.RoomID, login)
+		if errors.Is(err, twitchapi.ErrUserNotFound) {
+			return command.UsageError(fmt.Sprintf("user %s does not exist", login))
+		}
+		if err != nil {
+			return command.UpstreamError(err)
+		}
+
+		return nil
+	}
+}
+
+// Shoutout posts a shoutout of a user with their last game, and sends a native shoutout, when its cooldowns passed.
+// A failure of the native shoutout is only logged, because the message on the chat was already posted.
+func (s *Service) Shoutout(ctx context.Context, channelName, broadcasterID, login string) error {
+	ctx, span := tracer.Start(ctx, "shoutout")
+	defer span.End()
+
+	span.SetAttributes(attribute.String("channel.name", channelName), attribute.String("shoutout.login", login))
+
+	user, err := s.userResolver.UserByLogin(ctx, login)
+	if err != nil {
+		span.SetStatus(codes.Error, "failed to resolve a user")
+		span.RecordError(err)
+		return err
+	}
+
+	shoutout := Shoutout{Login: user.Login, DisplayName: user.DisplayName}
+
+	info, err := twitchapi.FetchChannelInformation(s.helixClient, user.ID)
+	if err != nil {
+		s.logger.Warn("failed to fetch channel information for a shoutout", zap.String("login", user.Login), zap.Error(err))
+	} else {
+		shoutout.GameName = info.GameName
+		shoutout.Title = info.Title
+	}
+
+	var b strings.Builder
+	if err = s.template.Execute(&b, shoutout); err != nil {
+		span.SetStatus(codes.Error, "failed to render a shoutout")
+		span.RecordError(err)
+		return err
+	}
+
+	s.chatClient.Say(channelName, b.String())
+
+	if s.config.NativeShoutout && user.ID != broadcasterID && s.reserveNativeShoutout(broadcasterID, user.ID) {
+		resp, err := s.helixClient.SendShoutout(&helix.SendShoutoutParams{
+			FromBroadcasterID: broadcasterID,
+			ToBroadcasterID:   user.ID,
+			ModeratorID:       s.moderatorID,
+		})
+		if err == nil {
+			err = twitchapi.ResponseError(resp.ResponseCommon)
+		}
+		if err != nil {
+			s.logger.Warn("failed to send a native shoutout", zap.String("login", user.Login), zap.Error(err))
+		}
+	}
+
+	span.SetStatus(codes.Ok, "successfully shouted out a user")
+	return nil
+}
+
+// reserveNativeShoutout reports whether cooldowns of native shoutouts passed, and if so, it starts them again.
+func (s *Service) reserveNativeShoutout(broadcasterID, userID string) bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	now := s.now()
+	channelKey := broadcasterID
+	targetKey := broadcasterID + ":" + userID
+
+	if now.Before(s.lastShoutouts[channelKey].Add(channelShoutoutCooldown)) || now.Before(s.lastShoutouts[targetKey].Add(targetShoutoutCooldown)) {
+		return false
+	}
+
+	for key, lastShoutout := range s.lastShoutouts {
+		if now.Sub(lastShoutout) > targetShoutoutCooldown {
+			delete(s.lastShoutouts, key)
+		}
+	}
+
+	s.lastShoutouts[channelKey] = now
+	s.lastShoutouts[targetKey] = now
+	return true
+}
@@ -0,0 +1,139 @@
+package raid
+
+import (
+	"context"
+	"net/http"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/danielbukowski/twitch-chatbot/internal/event"
+	"github.com/danielbukowski/twitch-chatbot/internal/helixtest"
+	twitchapi "github.com/danielbukowski/twitch-chatbot/internal/twitch_api"
+	"github.com/gempir/go-twitch-irc/v4"
+	"github.com/nicklaw5/helix/v2"
+	"go.uber.org/zap"
+)
+
+type userResolverMock struct{}
+
+func (userResolverMock) UserByLogin(_ context.Context, login string) (helix.User, error) {
+	if login != "streamer" {
+		return helix.User{}, twitchapi.ErrUserNotFound
+	}
+	return helix.User{ID: "streamer-id", Login: "streamer", DisplayName: "Streamer"}, nil
+}
+
+type chatClientMock struct {
+	mu       sync.Mutex
+	messages []string
+}
+
+func (c *chatClientMock) Say(_, message string) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.messages = append(c.messages, message)
+}
+
+type relaxerMock struct {
+	rules []string
+}
+
+func (r *relaxerMock) Relax(_ string, _ time.Duration, ruleNames ...string) {
+	r.rules = ruleNames
+}
+
+type guardMock struct {
+	relaxed bool
+}
+
+func (g *guardMock) Relax(_ string, _ time.Duration) {
+	g.relaxed = true
+}
+
+// newTwitchStandIn imitates endpoints of channel information and shoutouts, and counts native shoutouts.
+func newTwitchStandIn(t *testing.T, nativeShoutouts *int) *helix.Client {
+	t.Helper()
+
+	helixClient := helixtest.NewClient(t, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		switch r.URL.Path {
+		case "/channels":
+			_, _ = w.Write([]byte(`{"data":[{"broadcaster_id":"streamer-id","game_name":"Celeste","title":"speedruns"}]}`))
+		case "/chat/shoutouts":
+			*nativeShoutouts++
+			w.WriteHeader(http.StatusNoContent)
+		default:
+			w.WriteHeader(http.StatusNotFound)
+		}
+	}))
+
+	return helixClient
+}
+
+func TestShoutout(t *testing.T) {
+	t.Run("posts a shoutout with the last game and respects cooldowns of native shoutouts", func(t *testing.T) {
+		// given
+		nativeShoutouts := 0
+		chatClient := &chatClientMock{}
+		service, err := NewService(DefaultConfig, newTwitchStandIn(t, &nativeShoutouts), userResolverMock{}, chatClient, &relaxerMock{}, &guardMock{}, "bot-id", "!", zap.NewNop())
+		if err != nil {
+			t.Fatalf("Expected no error, got `%v`", err)
+		}
+
+		// when
+		for range 2 {
+			if err = service.Shoutout(context.Background(), "channel", "channel-id", "streamer"); err != nil {
+				t.Fatalf("Expected no error, got `%v`", err)
+			}
+		}
+
+		// then
+		expected := "Go check out @Streamer at https://twitch.tv/streamer, they were last seen playing Celeste!"
+		if len(chatClient.messages) != 2 || chatClient.messages[0] != expected {
+			t.Errorf("Expected 2 messages `%s`, got `%v`", expected, chatClient.messages)
+		}
+		if nativeShoutouts != 1 {
+			t.Errorf("Expected 1 native shoutout because of the cooldown, got %d", nativeShoutouts)
+		}
+	})
+
+	t.Run("returns an error for a user that does not exist", func(t *testing.T) {
+		// given
+		nativeShoutouts := 0
+		service, err := NewService(DefaultConfig, newTwitchStandIn(t, &nativeShoutouts), userResolverMock{}, &chatClientMock{}, &relaxerMock{}, &guardMock{}, "bot-id", "!", zap.NewNop())
+		if err != nil {
+			t.Fatalf("Expected no error, got `%v`", err)
+		}
+
+		// when
+		err = service.Shoutout(context.Background(), "channel", "channel-id", "nobody")
+
+		// then
+		if err == nil {
+			t.Errorf("Expected an error, got nil")
+		}
+	})
+}
+
+func TestHandleRaid(t *testing.T) {
+	// given
+	nativeShoutouts := 0
+	relaxer := &relaxerMock{}
+	guard := &guardMock{}
+	service, err := NewService(DefaultConfig, newTwitchStandIn(t, &nativeShoutouts), userResolverMock{}, &chatClientMock{}, relaxer, guard, "bot-id", "!", zap.NewNop())
+	if err != nil {
+		t.Fatalf("Expected no error, got `%v`", err)
+	}
+
+	// when
+	service.HandleRaid(context.Background(), event.Raid{Base: event.Base{ChannelName: "channel", User: twitch.User{Name: "streamer"}}, Viewers: 10})
+
+	// then
+	if !guard.relaxed {
+		t.Errorf("Expected the raid guard to be relaxed")
+	}
+	if len(relaxer.rules) != len(DefaultConfig.RelaxedRules) {
+		t.Errorf("Expected rules `%v` to be relaxed, got `%v`", DefaultConfig.RelaxedRules, relaxer.rules)
+	}
+}
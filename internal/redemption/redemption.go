@@ -0,0 +1,313 @@
+package redemption
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"slices"
+	"strings"
+	"sync"
+	"text/template"
+	"time"
+
+	"github.com/danielbukowski/twitch-chatbot/internal/command"
+	"github.com/danielbukowski/twitch-chatbot/internal/event"
+	"github.com/danielbukowski/twitch-chatbot/internal/moderation/actions"
+	twitchapi "github.com/danielbukowski/twitch-chatbot/internal/twitch_api"
+	"github.com/gempir/go-twitch-irc/v4"
+	"github.com/nicklaw5/helix/v2"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.uber.org/zap"
+)
+
+var tracer = otel.Tracer("github.com/danielbukowski/twitch-chatbot/internal/redemption")
+
+// deduplicationWindow is how long a redemption is remembered, so the same redemption delivered by EventSub and IRC is handled once.
+const deduplicationWindow = 30 * time.Second
+
+// handled is a redemption handled recently, which waits for its copy delivered by the other source.
+type handled struct {
+	at      time.Time     // At is when the redemption was handled.
+	fromIRC bool          // FromIRC tells, if the redemption was seen on IRC.
+	done    chan struct{} // Done is closed, when the action of the redemption has finished.
+	status  string        // Status is the outcome of the action, it's set before done is closed.
+}
+
+const (
+	statusFulfilled = "FULFILLED" // statusFulfilled marks a redemption as done.
+	statusCanceled  = "CANCELED"  // statusCanceled refunds channel points to the redeemer.
+)
+
+// Redemption is a redemption of a channel points reward.
+type Redemption struct {
+	ID            string      // ID is an ID of the redemption, it's empty for redemptions seen on IRC.
+	RewardID      string      // RewardID is an ID of the reward.
+	RewardTitle   string      // RewardTitle is a title of the reward, it's empty for redemptions seen on IRC.
+	ChannelName   string      // ChannelName is a name of the channel.
+	BroadcasterID string      // BroadcasterID is an ID of the channel.
+	User          twitch.User // User is the redeemer.
+	Input         string      // Input is a text entered by the redeemer.
+}
+
+// timeouter times out users.
+type timeouter interface {
+	Timeout(ctx context.Context, record actions.Record) error
+}
+
+// queue holds viewers waiting for their turn.
+type queue interface {
+	Add(ctx context.Context, channelName string, user twitch.User, note string) error
+}
+
+// Router triggers actions of rewards and fulfills redemptions when actions succeed, or refunds them when actions fail.
+type Router struct {
+	routes      map[string]Route              // Routes holds routes by reward IDs and lowercase titles.
+	templates   map[string]*template.Template // Templates holds parsed messages of message routes by their rewards.
+	handlers    map[string]command.Handler    // Handlers holds command handlers, which can be run by redemptions.
+	helixClient *helix.Client                 // HelixClient is used to update statuses of redemptions, it's nil without a token of the broadcaster.
+	chatClient  command.ChatClient            // ChatClient is used to post messages and passed to command handlers.
+	timeouter   timeouter                     // Timeouter times out redeemers.
+	queue       queue                         // Queue holds viewers added by redemptions.
+	seenIDs     map[string]time.Time          // SeenIDs holds IDs of recently handled redemptions.
+	handled     map[string][]*handled         // Handled holds recently handled redemptions by their reward, redeemer and input.
+	mu          sync.Mutex                    // Mu guards seenIDs and handled.
+	now         func() time.Time              // Now returns the current time.
+	logger      *zap.Logger                   // Logger is used for logging.
+}
+
+// NewRouter creates an instance of Router. It returns an error, when a route has an invalid template,
+// refers to a missing command handler, or adds to a queue that was not given.
+func NewRouter(routes []Route, handlers map[string]command.Handler, helixClient *helix.Client, chatClient command.ChatClient, timeouter timeouter, queue queue, logger *zap.Logger) (*Router, error) {
+	r := &Router{
+		routes:      make(map[string]Route, len(routes)),
+		templates:   make(map[string]*template.Template),
+		handlers:    handlers,
+		helixClient: helixClient,
+		chatClient:  chatClient,
+		timeouter:   timeouter,
+		queue:       queue,
+		seenIDs:     make(map[string]time.Time),
+		handled:     make(map[string][]*handled),
+		now:         time.Now,
+		logger:      logger.Named("redemption"),
+	}
+
+	for _, route := range routes {
+		key := strings.ToLower(route.Reward)
+
+		switch route.Action {
+		case ActionMessage:
+			tmpl, err := template.New(route.Reward).Option("missingkey=error").Parse(route.Argument)
+			if err != nil {
+				return nil, errors.Join(fmt.Errorf("failed to parse a message of %s reward", route.Reward), err)
+			}
+			r.templates[key] = tmpl
+		case ActionCommand:
+			name, _, _ := strings.Cut(route.Argument, " ")
+			if _, ok := handlers[name]; !ok {
+				return nil, fmt.Errorf("command handler %s of %s reward is not registered", name, route.Reward)
+			}
+		case ActionQueue:
+			if queue == nil {
+				return nil, fmt.Errorf("%s reward adds to a queue, but no queue is available", route.Reward)
+			}
+		}
+
+		r.routes[key] = route
+	}
+
+	return r, nil
+}
+
+// HandleRedemption routes a redemption delivered by EventSub. It's an event handler of redemptions.
+func (r *Router) HandleRedemption(ctx context.Context, e event.Event) {
+	redemption, ok := e.(event.Redemption)
+	if !ok {
+		return
+	}
+
+	r.Route(ctx, Redemption{
+		ID:            redemption.Data.ID,
+		RewardID:      redemption.Data.Reward.ID,
+		RewardTitle:   redemption.Data.Reward.Title,
+		ChannelName:   redemption.ChannelName,
+		BroadcasterID: redemption.RoomID,
+		User:          redemption.User,
+		Input:         redemption.Data.UserInput,
+	})
+}
+
+// HandleMessage routes a redemption seen on IRC. Only rewards that require a text from the user are sent to the chat,
+// and they are matched only by their IDs.
+func (r *Router) HandleMessage(ctx context.Context, privMsg *twitch.PrivateMessage) {
+	if privMsg.CustomRewardID == "" {
+		return
+	}
+
+	r.Route(ctx, Redemption{
+		RewardID:      privMsg.CustomRewardID,
+		ChannelName:   privMsg.Channel,
+		BroadcasterID: privMsg.RoomID,
+		User:          privMsg.User,
+		Input:         privMsg.Message,
+	})
+}
+
+// Route triggers an action of the redeemed reward. Redemptions of rewards without a route are left for the broadcaster.
+// A redemption delivered by both EventSub and IRC triggers the action once, and the copy from EventSub
+// fulfills or refunds the redemption, even when the action was already triggered by the copy from IRC.
+func (r *Router) Route(ctx context.Context, redemption Redemption) {
+	route, ok := r.routes[strings.ToLower(redemption.RewardID)]
+	if !ok {
+		route, ok = r.routes[strings.ToLower(redemption.RewardTitle)]
+	}
+	if !ok {
+		return
+	}
+
+	h, isCopy := r.claim(redemption)
+	if isCopy {
+		if h != nil && redemption.ID != "" {
+			<-h.done
+			r.updateStatus(redemption, h.status)
+		}
+		return
+	}
+
+	h.status = r.route(ctx, route, redemption)
+	close(h.done)
+	r.updateStatus(redemption, h.status)
+}
+
+// route executes an action of a route and returns a status the redemption should get.
+func (r *Router) route(ctx context.Context, route Route, redemption Redemption) string {
+	spanCtx, span := tracer.Start(ctx, "route")
+	defer span.End()
+
+	span.SetAttributes(
+		attribute.String("channel.name", redemption.ChannelName),
+		attribute.String("redemption.reward", route.Reward),
+		attribute.String("redemption.action", string(route.Action)),
+	)
+
+	err := r.execute(spanCtx, route, redemption)
+	if err != nil {
+		span.SetStatus(codes.Error, "failed to execute an action of a redemption")
+		span.RecordError(err)
+		r.logger.Warn("failed to execute an action of a redemption",
+			zap.String("reward", route.Reward),
+			zap.String("username", redemption.User.Name),
+			zap.Error(err),
+		)
+		return statusCanceled
+	}
+
+	span.SetStatus(codes.Ok, "successfully executed an action of a redemption")
+	return statusFulfilled
+}
+
+// execute runs an action of a route.
+func (r *Router) execute(ctx context.Context, route Route, redemption Redemption) error {
+	switch route.Action {
+	case ActionMessage:
+		var b strings.Builder
+		if err := r.templates[strings.ToLower(route.Reward)].Execute(&b, redemption); err != nil {
+			return err
+		}
+		r.chatClient.Say(redemption.ChannelName, b.String())
+		return nil
+	case ActionCommand:
+		fields := strings.Fields(route.Argument)
+		privMsg := &twitch.PrivateMessage{
+			User:    redemption.User,
+			Channel: redemption.ChannelName,
+			RoomID:  redemption.BroadcasterID,
+			Message: redemption.Input,
+		}
+		ctx = command.WithContext(ctx, command.NewContext(fields[0], privMsg, r.logger))
+
+		return r.handlers[fields[0]](ctx, append(fields[1:], strings.Fields(redemption.Input)...), r.chatClient)
+	case ActionQueue:
+		return r.queue.Add(ctx, redemption.ChannelName, redemption.User, redemption.Input)
+	case ActionTimeout:
+		return r.timeouter.Timeout(ctx, actions.Record{
+			ChannelName:   redemption.ChannelName,
+			BroadcasterID: redemption.BroadcasterID,
+			UserID:        redemption.User.ID,
+			Username:      redemption.User.Name,
+			Moderator:     redemption.User.Name,
+			Reason:        fmt.Sprintf("redeemed %s", route.Reward),
+			Trigger:       fmt.Sprintf("redemption:%s", route.Reward),
+			Duration:      route.Duration,
+		})
+	default:
+		return fmt.Errorf("unknown action %s", route.Action)
+	}
+}
+
+// claim remembers a redemption and returns it as handled. When the redemption is a copy of one handled recently,
+// the returned value is true, and the handled one is returned, unless the copy was delivered by EventSub again.
+// IRC does not share IDs of redemptions, so copies from the other source are recognized by the reward, the redeemer and the input.
+func (r *Router) claim(redemption Redemption) (*handled, bool) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	now := r.now()
+	for id, seenAt := range r.seenIDs {
+		if now.Sub(seenAt) > deduplicationWindow {
+			delete(r.seenIDs, id)
+		}
+	}
+	for key, redemptions := range r.handled {
+		redemptions = slices.DeleteFunc(redemptions, func(h *handled) bool { return now.Sub(h.at) > deduplicationWindow })
+		if len(redemptions) == 0 {
+			delete(r.handled, key)
+			continue
+		}
+		r.handled[key] = redemptions
+	}
+
+	fromIRC := redemption.ID == ""
+	if !fromIRC {
+		if _, ok := r.seenIDs[redemption.ID]; ok {
+			return nil, true
+		}
+		r.seenIDs[redemption.ID] = now
+	}
+
+	key := strings.Join([]string{redemption.RewardID, redemption.User.ID, redemption.Input}, ":")
+	redemptions := r.handled[key]
+	if i := slices.IndexFunc(redemptions, func(h *handled) bool { return h.fromIRC != fromIRC }); i != -1 {
+		h := redemptions[i]
+		r.handled[key] = slices.Delete(redemptions, i, i+1)
+		return h, true
+	}
+
+	h := &handled{at: now, fromIRC: fromIRC, done: make(chan struct{})}
+	r.handled[key] = append(redemptions, h)
+	return h, false
+}
+
+// updateStatus fulfills or refunds a redemption. Redemptions seen on IRC have no ID and are left for the broadcaster,
+// as are all redemptions, when there is no token of the broadcaster.
+// Twitch allows to update only redemptions of rewards created by the same client, so failures are only logged.
+func (r *Router) updateStatus(redemption Redemption, status string) {
+	if redemption.ID == "" || r.helixClient == nil {
+		return
+	}
+
+	resp, err := r.helixClient.UpdateChannelCustomRewardsRedemptionStatus(&helix.UpdateChannelCustomRewardsRedemptionStatusParams{
+		ID:            redemption.ID,
+		BroadcasterID: redemption.BroadcasterID,
+		RewardID:      redemption.RewardID,
+		Status:        status,
+	})
+	if err == nil {
+		err = twitchapi.ResponseError(resp.ResponseCommon)
+	}
+	if err != nil {
+		r.logger.Warn("failed to update a status of a redemption", zap.String("status", status), zap.Error(err))
+	}
+}
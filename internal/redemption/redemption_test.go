@@ -0,0 +1,308 @@
+package redemption
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/danielbukowski/twitch-chatbot/internal/command"
+	"github.com/danielbukowski/twitch-chatbot/internal/event"
+	"github.com/danielbukowski/twitch-chatbot/internal/helixtest"
+	"github.com/gempir/go-twitch-irc/v4"
+	"github.com/nicklaw5/helix/v2"
+	"go.uber.org/zap"
+)
+
+type chatClientMock struct {
+	messages []string
+}
+
+func (c *chatClientMock) Say(_, message string) {
+	c.messages = append(c.messages, message)
+}
+
+func (c *chatClientMock) Reply(_, _, message string) {
+	c.messages = append(c.messages, message)
+}
+
+func (c *chatClientMock) Join(_ ...string) {}
+
+func (c *chatClientMock) Depart(_ string) {}
+
+// newTestHelixClient starts a stand-in of Twitch API, which records statuses of redemptions.
+func newTestHelixClient(t *testing.T) (*helix.Client, func() []string) {
+	t.Helper()
+
+	var mu sync.Mutex
+	statuses := []string{}
+
+	helixClient := helixtest.NewClient(t, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodPatch || r.URL.Path != "/channel_points/custom_rewards/redemptions" {
+			w.WriteHeader(http.StatusNotFound)
+			return
+		}
+
+		var body struct {
+			Status string `json:"status"`
+		}
+		_ = json.NewDecoder(r.Body).Decode(&body)
+
+		mu.Lock()
+		statuses = append(statuses, r.URL.Query().Get("id")+":"+body.Status)
+		mu.Unlock()
+
+		w.Header().Set("Content-Type", "application/json")
+		_, _ = w.Write([]byte(`{"data":[]}`))
+	}))
+
+	return helixClient, func() []string {
+		mu.Lock()
+		defer mu.Unlock()
+		return append([]string{}, statuses...)
+	}
+}
+
+func redemptionEvent(id, rewardTitle, input string) event.Redemption {
+	return event.Redemption{
+		Base: event.Base{ChannelName: "channel", RoomID: "channel-id", User: twitch.User{ID: "user-id", Name: "viewer", DisplayName: "Viewer"}},
+		Data: helix.EventSubChannelPointsCustomRewardRedemptionEvent{
+			ID:        id,
+			UserInput: input,
+			Reward:    helix.EventSubReward{ID: "reward-" + rewardTitle, Title: rewardTitle},
+		},
+	}
+}
+
+func TestRouter(t *testing.T) {
+	t.Run("posts a message and fulfills the redemption", func(t *testing.T) {
+		// given
+		helixClient, statuses := newTestHelixClient(t)
+		chatClient := &chatClientMock{}
+		routes, _ := ParseRoutes("hydrate=message:@{{.User.DisplayName}} wants you to drink some water!")
+		router, err := NewRouter(routes, nil, helixClient, chatClient, nil, nil, zap.NewNop())
+		if err != nil {
+			t.Fatalf("Expected no error, got `%v`", err)
+		}
+
+		// when
+		router.HandleRedemption(context.Background(), redemptionEvent("redemption-1", "Hydrate", ""))
+
+		// then
+		if len(chatClient.messages) != 1 || chatClient.messages[0] != "@Viewer wants you to drink some water!" {
+			t.Fatalf("Expected a message for the redemption, got `%v`", chatClient.messages)
+		}
+		if got := statuses(); len(got) != 1 || got[0] != "redemption-1:FULFILLED" {
+			t.Fatalf("Expected the redemption to be fulfilled, got `%v`", got)
+		}
+	})
+
+	t.Run("refunds the redemption when a command handler fails", func(t *testing.T) {
+		// given
+		helixClient, statuses := newTestHelixClient(t)
+		var gotArgs []string
+		handlers := map[string]command.Handler{
+			"song": func(_ context.Context, args []string, _ command.ChatClient) error {
+				gotArgs = args
+				return command.UsageError("the song is too long")
+			},
+		}
+		routes, _ := ParseRoutes("Song request=command:song --max 5m")
+		router, err := NewRouter(routes, handlers, helixClient, &chatClientMock{}, nil, nil, zap.NewNop())
+		if err != nil {
+			t.Fatalf("Expected no error, got `%v`", err)
+		}
+
+		// when
+		router.HandleRedemption(context.Background(), redemptionEvent("redemption-2", "Song Request", "never gonna"))
+
+		// then
+		if len(gotArgs) != 4 || gotArgs[0] != "--max" || gotArgs[3] != "gonna" {
+			t.Fatalf("Expected arguments of the route followed by the input, got `%v`", gotArgs)
+		}
+		if got := statuses(); len(got) != 1 || got[0] != "redemption-2:CANCELED" {
+			t.Fatalf("Expected the redemption to be refunded, got `%v`", got)
+		}
+	})
+
+	t.Run("handles a redemption seen on both EventSub and IRC once", func(t *testing.T) {
+		// given
+		helixClient, _ := newTestHelixClient(t)
+		chatClient := &chatClientMock{}
+		routes, _ := ParseRoutes("reward-Hydrate=message:Cheers!")
+		router, err := NewRouter(routes, nil, helixClient, chatClient, nil, nil, zap.NewNop())
+		if err != nil {
+			t.Fatalf("Expected no error, got `%v`", err)
+		}
+
+		// when
+		router.HandleRedemption(context.Background(), redemptionEvent("redemption-3", "Hydrate", "cheers"))
+		router.HandleMessage(context.Background(), hydrateMessage("cheers"))
+
+		// then
+		if len(chatClient.messages) != 1 {
+			t.Fatalf("Expected a single message, got `%v`", chatClient.messages)
+		}
+	})
+
+	t.Run("leaves redemptions of rewards without a route", func(t *testing.T) {
+		// given
+		helixClient, statuses := newTestHelixClient(t)
+		chatClient := &chatClientMock{}
+		router, err := NewRouter(nil, nil, helixClient, chatClient, nil, nil, zap.NewNop())
+		if err != nil {
+			t.Fatalf("Expected no error, got `%v`", err)
+		}
+
+		// when
+		router.HandleRedemption(context.Background(), redemptionEvent("redemption-4", "Hydrate", ""))
+
+		// then
+		if len(chatClient.messages) != 0 || len(statuses()) != 0 {
+			t.Fatalf("Expected the redemption to be left, got messages `%v` and statuses `%v`", chatClient.messages, statuses())
+		}
+	})
+
+	t.Run("fulfills a redemption from EventSub, which was already handled from IRC", func(t *testing.T) {
+		// given
+		helixClient, statuses := newTestHelixClient(t)
+		chatClient := &chatClientMock{}
+		routes, _ := ParseRoutes("reward-Hydrate=message:Cheers!")
+		router, err := NewRouter(routes, nil, helixClient, chatClient, nil, nil, zap.NewNop())
+		if err != nil {
+			t.Fatalf("Expected no error, got `%v`", err)
+		}
+
+		// when
+		router.HandleMessage(context.Background(), hydrateMessage("cheers"))
+		router.HandleRedemption(context.Background(), redemptionEvent("redemption-5", "Hydrate", "cheers"))
+
+		// then
+		if len(chatClient.messages) != 1 {
+			t.Fatalf("Expected a single message, got `%v`", chatClient.messages)
+		}
+		if got := statuses(); len(got) != 1 || got[0] != "redemption-5:FULFILLED" {
+			t.Fatalf("Expected the redemption to be fulfilled, got `%v`", got)
+		}
+	})
+
+	t.Run("handles repeated redemptions of the same user", func(t *testing.T) {
+		// given
+		helixClient, statuses := newTestHelixClient(t)
+		chatClient := &chatClientMock{}
+		routes, _ := ParseRoutes("reward-Hydrate=message:Cheers!")
+		router, err := NewRouter(routes, nil, helixClient, chatClient, nil, nil, zap.NewNop())
+		if err != nil {
+			t.Fatalf("Expected no error, got `%v`", err)
+		}
+
+		// when
+		router.HandleMessage(context.Background(), hydrateMessage("cheers"))
+		router.HandleMessage(context.Background(), hydrateMessage("cheers"))
+		router.HandleRedemption(context.Background(), redemptionEvent("redemption-6", "Hydrate", "cheers"))
+		router.HandleRedemption(context.Background(), redemptionEvent("redemption-7", "Hydrate", "cheers"))
+		router.HandleRedemption(context.Background(), redemptionEvent("redemption-7", "Hydrate", "cheers"))
+
+		// then
+		if len(chatClient.messages) != 2 {
+			t.Fatalf("Expected two messages, got `%v`", chatClient.messages)
+		}
+		if got := statuses(); len(got) != 2 {
+			t.Fatalf("Expected both redemptions to be fulfilled once, got `%v`", got)
+		}
+	})
+
+	t.Run("forgets redemptions after the deduplication window", func(t *testing.T) {
+		// given
+		helixClient, _ := newTestHelixClient(t)
+		chatClient := &chatClientMock{}
+		routes, _ := ParseRoutes("reward-Hydrate=message:Cheers!")
+		router, _ := NewRouter(routes, nil, helixClient, chatClient, nil, nil, zap.NewNop())
+		now := time.Now()
+		router.now = func() time.Time { return now }
+
+		// when
+		router.HandleMessage(context.Background(), hydrateMessage("cheers"))
+		now = now.Add(deduplicationWindow + time.Second)
+		router.HandleRedemption(context.Background(), redemptionEvent("redemption-8", "Hydrate", "cheers"))
+
+		// then
+		if len(chatClient.messages) != 2 {
+			t.Fatalf("Expected two messages, got `%v`", chatClient.messages)
+		}
+	})
+}
+
+func hydrateMessage(input string) *twitch.PrivateMessage {
+	return &twitch.PrivateMessage{
+		User:           twitch.User{ID: "user-id", Name: "viewer"},
+		Channel:        "channel",
+		CustomRewardID: "reward-Hydrate",
+		Message:        input,
+	}
+}
+
+func TestNewRouter(t *testing.T) {
+	testCases := []struct {
+		name  string
+		route string
+	}{
+		{name: "missing command handler", route: "Lurk=command:lurk"},
+		{name: "queue without a queue", route: "Join=queue"},
+		{name: "invalid template", route: "Hi=message:{{.Missing"},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			// given
+			routes, err := ParseRoutes(tc.route)
+			if err != nil {
+				t.Fatalf("Expected no error, got `%v`", err)
+			}
+
+			// when
+			_, err = NewRouter(routes, nil, nil, &chatClientMock{}, nil, nil, zap.NewNop())
+
+			// then
+			if err == nil {
+				t.Fatal("Expected an error, got nil")
+			}
+		})
+	}
+}
+
+func TestParseRoutes(t *testing.T) {
+	t.Run("parses routes of every action", func(t *testing.T) {
+		// when
+		got, err := ParseRoutes("Hydrate=message:Drink: water!; Timeout me = timeout:60s;Join queue=queue;;Lurk=command:lurk")
+
+		// then
+		if err != nil {
+			t.Fatalf("Expected no error, got `%v`", err)
+		}
+		expected := []Route{
+			{Reward: "Hydrate", Action: ActionMessage, Argument: "Drink: water!"},
+			{Reward: "Timeout me", Action: ActionTimeout, Argument: "60s", Duration: time.Minute},
+			{Reward: "Join queue", Action: ActionQueue},
+			{Reward: "Lurk", Action: ActionCommand, Argument: "lurk"},
+		}
+		if len(got) != len(expected) {
+			t.Fatalf("Expected `%v`, got `%v`", expected, got)
+		}
+		for i := range expected {
+			if got[i] != expected[i] {
+				t.Errorf("Expected `%v`, got `%v`", expected[i], got[i])
+			}
+		}
+	})
+
+	t.Run("returns an error for an invalid route", func(t *testing.T) {
+		for _, text := range []string{"Hydrate", "=queue", "Hydrate=dance", "Hydrate=message", "Timeout=timeout:forever"} {
+			if _, err := ParseRoutes(text); err == nil {
+				t.Errorf("Expected an error for `%s`, got nil", text)
+			}
+		}
+	})
+}
@@ -0,0 +1,67 @@
+package redemption
+
+import (
+	"fmt"
+	"strings"
+	"time"
+)
+
+// Action is a kind of bot behavior triggered by a redemption.
+type Action string
+
+const (
+	ActionMessage Action = "message" // ActionMessage posts a message on the chat. The argument is a template of the message.
+	ActionCommand Action = "command" // ActionCommand runs a command handler registered on the router. The argument is a name of the handler with optional arguments.
+	ActionQueue   Action = "queue"   // ActionQueue adds the redeemer to the viewer queue.
+	ActionTimeout Action = "timeout" // ActionTimeout times out the redeemer. The argument is a duration of the timeout.
+)
+
+// Route binds a reward to an action.
+type Route struct {
+	Reward   string        // Reward is an ID or a title of the reward.
+	Action   Action        // Action is triggered by redemptions of the reward.
+	Argument string        // Argument is a template of a message or a name of a command handler with its arguments.
+	Duration time.Duration // Duration is a length of a timeout.
+}
+
+// ParseRoutes creates routes from a semicolon separated list, like `Hydrate=message:Drink some water!;Timeout me=timeout:60s;Join queue=queue`.
+// Rewards are matched by an ID or a title, ignoring case of titles.
+func ParseRoutes(text string) ([]Route, error) {
+	routes := []Route{}
+
+	for _, definition := range strings.Split(text, ";") {
+		definition = strings.TrimSpace(definition)
+		if definition == "" {
+			continue
+		}
+
+		reward, spec, ok := strings.Cut(definition, "=")
+		reward = strings.TrimSpace(reward)
+		if !ok || reward == "" {
+			return nil, fmt.Errorf("missing a reward in route '%s'", definition)
+		}
+
+		action, argument, _ := strings.Cut(strings.TrimSpace(spec), ":")
+		route := Route{Reward: reward, Action: Action(strings.ToLower(strings.TrimSpace(action))), Argument: strings.TrimSpace(argument)}
+
+		switch route.Action {
+		case ActionMessage, ActionCommand:
+			if route.Argument == "" {
+				return nil, fmt.Errorf("missing an argument of %s action in route '%s'", route.Action, definition)
+			}
+		case ActionQueue:
+		case ActionTimeout:
+			d, err := time.ParseDuration(route.Argument)
+			if err != nil {
+				return nil, fmt.Errorf("invalid duration of a timeout in route '%s': %w", definition, err)
+			}
+			route.Duration = d
+		default:
+			return nil, fmt.Errorf("unknown action in route '%s'", definition)
+		}
+
+		routes = append(routes, route)
+	}
+
+	return routes, nil
+}
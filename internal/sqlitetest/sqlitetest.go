@@ -0,0 +1,44 @@
+// Package sqlitetest provides an in-memory SQLite database with the schema of the chatbot for tests.
+package sqlitetest
+
+import (
+	"database/sql"
+	"os"
+	"path/filepath"
+	"runtime"
+	"strings"
+	"testing"
+
+	_ "github.com/mattn/go-sqlite3"
+)
+
+// Open creates an in-memory database and applies the up part of the migrations, which are names of files in db/migrations.
+// The database is closed, when the test ends.
+func Open(t testing.TB, migrations ...string) *sql.DB {
+	t.Helper()
+
+	db, err := sql.Open("sqlite3", "file::memory:?_foreign_keys=on")
+	if err != nil {
+		t.Fatalf("Expected no error, got `%v`", err)
+	}
+	// every connection to an in-memory database sees a different database.
+	db.SetMaxOpenConns(1)
+	t.Cleanup(func() { _ = db.Close() })
+
+	_, file, _, _ := runtime.Caller(0)
+	dir := filepath.Join(filepath.Dir(file), "..", "..", "db", "migrations")
+
+	for _, name := range migrations {
+		migration, err := os.ReadFile(filepath.Join(dir, name))
+		if err != nil {
+			t.Fatalf("Expected no error, got `%v`", err)
+		}
+
+		up, _, _ := strings.Cut(string(migration), "-- +goose Down")
+		if _, err = db.Exec(up); err != nil {
+			t.Fatalf("Expected migration %s to apply, got `%v`", name, err)
+		}
+	}
+
+	return db
+}
@@ -0,0 +1,226 @@
+package streamstatus
+
+import (
+	"cmp"
+	"context"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/danielbukowski/twitch-chatbot/internal/event"
+	twitchapi "github.com/danielbukowski/twitch-chatbot/internal/twitch_api"
+	"github.com/nicklaw5/helix/v2"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.uber.org/zap"
+)
+
+var tracer = otel.Tracer("github.com/danielbukowski/twitch-chatbot/internal/stream_status")
+
+// onlineGracePeriod is how long after a stream.online event polling can't turn the stream offline,
+// because Twitch API lists a stream with a delay after it goes live.
+const onlineGracePeriod = 5 * time.Minute
+
+// Status is a state of a stream.
+type Status struct {
+	Live      bool      // Live tells, if the stream is live.
+	StartedAt time.Time // StartedAt is when the stream started, it's zero when the stream is offline.
+	Title     string    // Title is a title of the stream, it's known only after polling Twitch API.
+	GameName  string    // GameName is a name of the streamed game, it's known only after polling Twitch API.
+}
+
+// Listener is called, when a stream goes live or offline.
+type Listener func(channelName string, status Status)
+
+// pausable is a job that should run only during streams, like a timer posting messages.
+type pausable interface {
+	Pause()
+	Resume()
+}
+
+// Service tracks, if streams of channels are live. It's updated immediately by EventSub events,
+// and Twitch API is polled as a fallback for missed events.
+type Service struct {
+	helixClient  *helix.Client        // HelixClient is used to poll streams.
+	channelNames []string             // ChannelNames holds logins of tracked channels.
+	interval     time.Duration        // Interval is how often Twitch API is polled.
+	statuses     map[string]Status    // Statuses holds the last known status of every channel.
+	onlineAt     map[string]time.Time // OnlineAt holds when channels went live according to the last stream.online events.
+	listeners    []Listener           // Listeners are called on every change of the live state.
+	mu           sync.RWMutex         // Mu guards statuses, onlineAt and listeners.
+	now          func() time.Time     // Now returns the current time.
+	logger       *zap.Logger          // Logger is used for logging.
+}
+
+// NewService creates an instance of Service, which tracks the given channels.
+func NewService(helixClient *helix.Client, channelNames []string, interval time.Duration, logger *zap.Logger) *Service {
+	names := make([]string, 0, len(channelNames))
+	for _, channelName := range channelNames {
+		names = append(names, strings.ToLower(channelName))
+	}
+
+	return &Service{
+		helixClient:  helixClient,
+		channelNames: names,
+		interval:     interval,
+		statuses:     make(map[string]Status),
+		onlineAt:     make(map[string]time.Time),
+		now:          time.Now,
+		logger:       logger.Named("stream_status"),
+	}
+}
+
+// Status returns the last known status of a stream of a channel.
+func (s *Service) Status(channelName string) Status {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	return s.statuses[strings.ToLower(channelName)]
+}
+
+// IsLive tells, if a stream of a channel is live.
+func (s *Service) IsLive(channelName string) bool {
+	return s.Status(channelName).Live
+}
+
+// OnChange registers a listener called, when a stream goes live or offline.
+func (s *Service) OnChange(listener Listener) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.listeners = append(s.listeners, listener)
+}
+
+// PauseWhileOffline resumes a job, when a stream of a channel goes live, and pauses it, when the stream goes offline.
+// The job is paused right away, unless the stream is already live.
+func (s *Service) PauseWhileOffline(channelName string, job pausable) {
+	channelName = strings.ToLower(channelName)
+
+	s.OnChange(func(changedChannel string, status Status) {
+		if changedChannel != channelName {
+			return
+		}
+		if status.Live {
+			job.Resume()
+			return
+		}
+		job.Pause()
+	})
+
+	if !s.IsLive(channelName) {
+		job.Pause()
+	}
+}
+
+// HandleEvent updates a status with a stream.online or stream.offline event. It's an event handler of stream events.
+func (s *Service) HandleEvent(_ context.Context, e event.Event) {
+	switch e := e.(type) {
+	case event.StreamOnline:
+		s.mu.Lock()
+		s.onlineAt[strings.ToLower(e.ChannelName)] = s.now()
+		s.mu.Unlock()
+
+		s.set(e.ChannelName, Status{Live: true, StartedAt: e.Data.StartedAt.Time})
+	case event.StreamOffline:
+		s.mu.Lock()
+		delete(s.onlineAt, strings.ToLower(e.ChannelName))
+		s.mu.Unlock()
+
+		s.set(e.ChannelName, Status{})
+	}
+}
+
+// Run polls Twitch API right away and then at every interval, until the context is done.
+func (s *Service) Run(ctx context.Context) error {
+	t := time.NewTicker(s.interval)
+	defer t.Stop()
+
+	for {
+		s.poll(ctx)
+
+		select {
+		case <-ctx.Done():
+			return nil
+		case <-t.C:
+		}
+	}
+}
+
+// poll fetches streams of tracked channels. Channels without a live stream are offline,
+// unless they went live recently according to a stream.online event.
+func (s *Service) poll(ctx context.Context) {
+	_, span := tracer.Start(ctx, "poll")
+	defer span.End()
+
+	span.SetAttributes(attribute.StringSlice("channel.names", s.channelNames))
+
+	resp, err := s.helixClient.GetStreams(&helix.StreamsParams{UserLogins: s.channelNames, Type: "live", First: len(s.channelNames)})
+	if err == nil {
+		err = twitchapi.ResponseError(resp.ResponseCommon)
+	}
+	if err != nil {
+		span.SetStatus(codes.Error, "failed to poll streams")
+		span.RecordError(err)
+		s.logger.Warn("failed to poll streams", zap.Error(err))
+		return
+	}
+
+	live := make(map[string]helix.Stream, len(resp.Data.Streams))
+	for _, stream := range resp.Data.Streams {
+		live[strings.ToLower(stream.UserLogin)] = stream
+	}
+
+	for _, channelName := range s.channelNames {
+		stream, ok := live[channelName]
+		if !ok {
+			if !s.isInGracePeriod(channelName) {
+				s.set(channelName, Status{})
+			}
+			continue
+		}
+		s.set(channelName, Status{Live: true, StartedAt: stream.StartedAt, Title: stream.Title, GameName: stream.GameName})
+	}
+
+	span.SetStatus(codes.Ok, "successfully polled streams")
+}
+
+// isInGracePeriod reports whether a channel went live so recently, that Twitch API may not list its stream yet.
+func (s *Service) isInGracePeriod(channelName string) bool {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	onlineAt, ok := s.onlineAt[channelName]
+	return ok && s.now().Sub(onlineAt) < onlineGracePeriod
+}
+
+// set saves a status of a channel and notifies listeners, when the live state changed.
+func (s *Service) set(channelName string, status Status) {
+	channelName = strings.ToLower(channelName)
+
+	s.mu.Lock()
+	previous, known := s.statuses[channelName]
+	if previous.Live && status.Live {
+		// events don't carry the title and the game, so details of the same stream are kept.
+		status.StartedAt = cmp.Or(status.StartedAt, previous.StartedAt)
+		status.Title = cmp.Or(status.Title, previous.Title)
+		status.GameName = cmp.Or(status.GameName, previous.GameName)
+	}
+	s.statuses[channelName] = status
+	listeners := s.listeners
+	s.mu.Unlock()
+
+	if known && previous.Live == status.Live {
+		return
+	}
+
+	if status.Live {
+		s.logger.Info("stream went live", zap.String("channel_name", channelName))
+	} else if known {
+		s.logger.Info("stream went offline", zap.String("channel_name", channelName))
+	}
+
+	for _, listener := range listeners {
+		listener(channelName, status)
+	}
+}
@@ -0,0 +1,141 @@
+package streamstatus
+
+import (
+	"context"
+	"net/http"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/danielbukowski/twitch-chatbot/internal/event"
+	"github.com/danielbukowski/twitch-chatbot/internal/helixtest"
+	"github.com/nicklaw5/helix/v2"
+	"go.uber.org/zap"
+)
+
+func newTestService(t *testing.T, streams *atomic.Value) *Service {
+	t.Helper()
+
+	helixClient := helixtest.NewClient(t, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/streams" {
+			w.WriteHeader(http.StatusNotFound)
+			return
+		}
+
+		w.Header().Set("Content-Type", "application/json")
+		_, _ = w.Write([]byte(streams.Load().(string)))
+	}))
+
+	return NewService(helixClient, []string{"Channel"}, time.Minute, zap.NewNop())
+}
+
+type jobMock struct {
+	paused bool
+}
+
+func (j *jobMock) Pause() {
+	j.paused = true
+}
+
+func (j *jobMock) Resume() {
+	j.paused = false
+}
+
+func TestService(t *testing.T) {
+	t.Run("follows stream events and notifies listeners about changes", func(t *testing.T) {
+		// given
+		streams := &atomic.Value{}
+		streams.Store(`{"data":[]}`)
+		service := newTestService(t, streams)
+		changes := []bool{}
+		service.OnChange(func(_ string, status Status) {
+			changes = append(changes, status.Live)
+		})
+		startedAt := time.Date(2026, 10, 19, 12, 0, 0, 0, time.UTC)
+
+		// when
+		service.HandleEvent(context.Background(), event.StreamOnline{
+			Base: event.Base{ChannelName: "channel"},
+			Data: helix.EventSubStreamOnlineEvent{StartedAt: helix.Time{Time: startedAt}},
+		})
+		service.HandleEvent(context.Background(), event.StreamOnline{Base: event.Base{ChannelName: "channel"}})
+		status := service.Status("CHANNEL")
+		service.HandleEvent(context.Background(), event.StreamOffline{Base: event.Base{ChannelName: "channel"}})
+
+		// then
+		if !status.Live || !status.StartedAt.Equal(startedAt) {
+			t.Fatalf("Expected a live stream started at %v, got `%+v`", startedAt, status)
+		}
+		if service.IsLive("channel") {
+			t.Fatal("Expected the stream to be offline")
+		}
+		if len(changes) != 2 || !changes[0] || changes[1] {
+			t.Fatalf("Expected to be notified about going live and offline, got `%v`", changes)
+		}
+	})
+
+	t.Run("polls Twitch API for streams", func(t *testing.T) {
+		// given
+		streams := &atomic.Value{}
+		streams.Store(`{"data":[{"user_login":"channel","type":"live","title":"Speedrun","game_name":"Celeste","started_at":"2026-10-19T12:00:00Z"}]}`)
+		service := newTestService(t, streams)
+
+		// when
+		service.poll(context.Background())
+		live := service.Status("channel")
+		streams.Store(`{"data":[]}`)
+		service.poll(context.Background())
+
+		// then
+		if !live.Live || live.Title != "Speedrun" || live.GameName != "Celeste" {
+			t.Fatalf("Expected a live stream of Celeste, got `%+v`", live)
+		}
+		if service.IsLive("channel") {
+			t.Fatal("Expected the stream to be offline after it disappeared from Twitch API")
+		}
+	})
+
+	t.Run("keeps a stream live after a stream.online event, until Twitch API lists it", func(t *testing.T) {
+		// given
+		streams := &atomic.Value{}
+		streams.Store(`{"data":[]}`)
+		service := newTestService(t, streams)
+		now := time.Date(2026, 10, 19, 12, 0, 0, 0, time.UTC)
+		service.now = func() time.Time { return now }
+
+		// when
+		service.HandleEvent(context.Background(), event.StreamOnline{Base: event.Base{ChannelName: "channel"}})
+		service.poll(context.Background())
+		liveAfterPoll := service.IsLive("channel")
+		now = now.Add(onlineGracePeriod)
+		service.poll(context.Background())
+
+		// then
+		if !liveAfterPoll {
+			t.Fatal("Expected the stream to stay live during the grace period")
+		}
+		if service.IsLive("channel") {
+			t.Fatal("Expected the stream to be offline after the grace period")
+		}
+	})
+
+	t.Run("pauses a job while the stream is offline", func(t *testing.T) {
+		// given
+		streams := &atomic.Value{}
+		streams.Store(`{"data":[]}`)
+		service := newTestService(t, streams)
+		job := &jobMock{}
+
+		// when
+		service.PauseWhileOffline("channel", job)
+		pausedAtStart := job.paused
+		service.HandleEvent(context.Background(), event.StreamOnline{Base: event.Base{ChannelName: "channel"}})
+		pausedWhileLive := job.paused
+		service.HandleEvent(context.Background(), event.StreamOffline{Base: event.Base{ChannelName: "channel"}})
+
+		// then
+		if !pausedAtStart || pausedWhileLive || !job.paused {
+			t.Fatalf("Expected the job to be paused, resumed and paused again, got %v, %v, %v", pausedAtStart, pausedWhileLive, job.paused)
+		}
+	})
+}
@@ -0,0 +1,53 @@
+package twitchapi
+
+import (
+	"sync"
+	"time"
+)
+
+type cacheEntry[V any] struct {
+	value     V
+	expiresAt time.Time
+}
+
+// ttlCache stores values for a limited time.
+type ttlCache[V any] struct {
+	entries map[string]cacheEntry[V]
+	ttl     time.Duration
+	mu      sync.Mutex
+	now     func() time.Time
+}
+
+func newTTLCache[V any](ttl time.Duration, now func() time.Time) *ttlCache[V] {
+	return &ttlCache[V]{entries: make(map[string]cacheEntry[V]), ttl: ttl, now: now}
+}
+
+// get returns a value, when it exists and has not expired yet.
+func (c *ttlCache[V]) get(key string) (V, bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	entry, ok := c.entries[key]
+	if !ok || c.now().After(entry.expiresAt) {
+		delete(c.entries, key)
+		var zero V
+		return zero, false
+	}
+
+	return entry.value, true
+}
+
+// set stores a value and removes the expired ones.
+func (c *ttlCache[V]) set(key string, value V) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	now := c.now()
+	for k, entry := range c.entries {
+		if now.After(entry.expiresAt) {
+			delete(c.entries, k)
+		}
+	}
+
+	c.entries[key] = cacheEntry[V]{value: value, expiresAt: now.Add(c.ttl)}
+}
@@ -0,0 +1,134 @@
+package twitchapi
+
+import (
+	"context"
+	"strings"
+	"time"
+
+	"github.com/nicklaw5/helix/v2"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+)
+
+// Follow describes a follow relationship between a user and a channel.
+type Follow struct {
+	IsFollower bool      // IsFollower tells, if the user follows the channel.
+	FollowedAt time.Time // FollowedAt is the time when the user followed the channel, it's zero when the user does not follow it.
+}
+
+// CachedClient wraps Twitch API calls that are made for almost every message, so their results are cached for some time.
+type CachedClient struct {
+	helixClient *helix.Client         // HelixClient is used to call Twitch API.
+	follows     *ttlCache[Follow]     // Follows caches follows by a broadcaster ID and a user ID.
+	users       *ttlCache[helix.User] // Users caches users by their IDs and logins.
+}
+
+// NewCachedClient creates an instance of CachedClient.
+// Reading follows requires a user access token of a moderator with the moderator:read:followers scope.
+func NewCachedClient(helixClient *helix.Client, followTTL, userTTL time.Duration) *CachedClient {
+	return &CachedClient{
+		helixClient: helixClient,
+		follows:     newTTLCache[Follow](followTTL, time.Now),
+		users:       newTTLCache[helix.User](userTTL, time.Now),
+	}
+}
+
+// Follow returns a follow relationship between a user and a channel. Only follows are cached,
+// so a user who follows right after being told to follow is recognized immediately.
+func (c *CachedClient) Follow(ctx context.Context, broadcasterID, userID string) (Follow, error) {
+	_, span := tracer.Start(ctx, "follow")
+	defer span.End()
+
+	key := broadcasterID + ":" + userID
+	if follow, ok := c.follows.get(key); ok {
+		span.SetAttributes(attribute.Bool("cache.hit", true))
+		span.SetStatus(codes.Ok, "found a follow relationship in the cache")
+		return follow, nil
+	}
+	span.SetAttributes(attribute.Bool("cache.hit", false))
+
+	resp, err := c.helixClient.GetChannelFollows(&helix.GetChannelFollowsParams{
+		BroadcasterID: broadcasterID,
+		UserID:        userID,
+	})
+	if err == nil {
+		err = ResponseError(resp.ResponseCommon)
+	}
+	if err != nil {
+		span.SetStatus(codes.Error, "failed to get a follow relationship")
+		span.RecordError(err)
+		return Follow{}, err
+	}
+
+	if len(resp.Data.Channels) == 0 {
+		span.SetStatus(codes.Ok, "user does not follow the channel")
+		return Follow{}, nil
+	}
+
+	follow := Follow{IsFollower: true, FollowedAt: resp.Data.Channels[0].Followed.Time}
+	c.follows.set(key, follow)
+
+	span.SetStatus(codes.Ok, "successfully got a follow relationship")
+	return follow, nil
+}
+
+// IsFollower tells, if a user follows a channel.
+func (c *CachedClient) IsFollower(ctx context.Context, broadcasterID, userID string) (bool, error) {
+	follow, err := c.Follow(ctx, broadcasterID, userID)
+	return follow.IsFollower, err
+}
+
+// UserByID returns a user with the given ID.
+func (c *CachedClient) UserByID(ctx context.Context, userID string) (helix.User, error) {
+	return c.user(ctx, "id:"+userID, &helix.UsersParams{IDs: []string{userID}})
+}
+
+// UserByLogin returns a user with the given login.
+func (c *CachedClient) UserByLogin(ctx context.Context, login string) (helix.User, error) {
+	login = strings.ToLower(login)
+	return c.user(ctx, "login:"+login, &helix.UsersParams{Logins: []string{login}})
+}
+
+func (c *CachedClient) user(ctx context.Context, key string, params *helix.UsersParams) (helix.User, error) {
+	_, span := tracer.Start(ctx, "user")
+	defer span.End()
+
+	if user, ok := c.users.get(key); ok {
+		span.SetAttributes(attribute.Bool("cache.hit", true))
+		span.SetStatus(codes.Ok, "found a user in the cache")
+		return user, nil
+	}
+	span.SetAttributes(attribute.Bool("cache.hit", false))
+
+	resp, err := c.helixClient.GetUsers(params)
+	if err == nil {
+		err = ResponseError(resp.ResponseCommon)
+	}
+	if err != nil {
+		span.SetStatus(codes.Error, "failed to get a user")
+		span.RecordError(err)
+		return helix.User{}, err
+	}
+
+	if len(resp.Data.Users) == 0 {
+		span.SetStatus(codes.Error, "user does not exist")
+		return helix.User{}, ErrUserNotFound
+	}
+
+	user := resp.Data.Users[0]
+	c.users.set("id:"+user.ID, user)
+	c.users.set("login:"+user.Login, user)
+
+	span.SetStatus(codes.Ok, "successfully got a user")
+	return user, nil
+}
+
+// AccountCreatedAt returns the time when a user created a Twitch account.
+func (c *CachedClient) AccountCreatedAt(ctx context.Context, userID string) (time.Time, error) {
+	user, err := c.UserByID(ctx, userID)
+	if err != nil {
+		return time.Time{}, err
+	}
+
+	return user.CreatedAt.Time, nil
+}
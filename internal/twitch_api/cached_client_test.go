@@ -0,0 +1,115 @@
+package twitchapi
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/danielbukowski/twitch-chatbot/internal/helixtest"
+)
+
+func TestCachedClient(t *testing.T) {
+	t.Run("asks Twitch API about a follow relationship only once within the TTL", func(t *testing.T) {
+		// given
+		var requests atomic.Int32
+		mux := http.NewServeMux()
+		mux.HandleFunc("/channels/followers", func(w http.ResponseWriter, r *http.Request) {
+			requests.Add(1)
+			w.Header().Set("Content-Type", "application/json")
+			fmt.Fprint(w, `{"data":[{"user_id":"2","user_login":"viewer","followed_at":"2024-01-02T03:04:05Z"}],"total":1}`)
+		})
+		cachedClient := NewCachedClient(helixtest.NewClient(t, mux), time.Minute, time.Minute)
+
+		// when
+		first, err := cachedClient.Follow(context.Background(), "1", "2")
+		if err != nil {
+			t.Fatalf("Expected no error, got `%v`", err)
+		}
+		second, err := cachedClient.Follow(context.Background(), "1", "2")
+		if err != nil {
+			t.Fatalf("Expected no error, got `%v`", err)
+		}
+
+		// then
+		if !first.IsFollower || first != second {
+			t.Errorf("Expected the same follow relationship, got `%v` and `%v`", first, second)
+		}
+		if got := requests.Load(); got != 1 {
+			t.Errorf("Expected 1 request, got %d", got)
+		}
+	})
+
+	t.Run("asks Twitch API again about a user who did not follow", func(t *testing.T) {
+		// given
+		var requests atomic.Int32
+		mux := http.NewServeMux()
+		mux.HandleFunc("/channels/followers", func(w http.ResponseWriter, r *http.Request) {
+			w.Header().Set("Content-Type", "application/json")
+			if requests.Add(1) == 1 {
+				fmt.Fprint(w, `{"data":[],"total":0}`)
+				return
+			}
+			fmt.Fprint(w, `{"data":[{"user_id":"2","user_login":"viewer","followed_at":"2024-01-02T03:04:05Z"}],"total":1}`)
+		})
+		cachedClient := NewCachedClient(helixtest.NewClient(t, mux), time.Minute, time.Minute)
+
+		// when
+		before, err := cachedClient.IsFollower(context.Background(), "1", "2")
+		if err != nil {
+			t.Fatalf("Expected no error, got `%v`", err)
+		}
+		after, err := cachedClient.IsFollower(context.Background(), "1", "2")
+		if err != nil {
+			t.Fatalf("Expected no error, got `%v`", err)
+		}
+
+		// then
+		if before || !after {
+			t.Errorf("Expected the user to follow only after the second request, got %t and %t", before, after)
+		}
+	})
+
+	t.Run("returns the creation date of an account", func(t *testing.T) {
+		// given
+		mux := http.NewServeMux()
+		mux.HandleFunc("/users", func(w http.ResponseWriter, r *http.Request) {
+			w.Header().Set("Content-Type", "application/json")
+			fmt.Fprint(w, `{"data":[{"id":"2","login":"viewer","created_at":"2020-05-06T07:08:09Z"}]}`)
+		})
+		cachedClient := NewCachedClient(helixtest.NewClient(t, mux), time.Minute, time.Minute)
+		expected := time.Date(2020, 5, 6, 7, 8, 9, 0, time.UTC)
+
+		// when
+		got, err := cachedClient.AccountCreatedAt(context.Background(), "2")
+
+		// then
+		if err != nil {
+			t.Fatalf("Expected no error, got `%v`", err)
+		}
+		if !got.Equal(expected) {
+			t.Errorf("Expected `%v`, got `%v`", expected, got)
+		}
+	})
+
+	t.Run("returns an error, when Twitch API responds with an error", func(t *testing.T) {
+		// given
+		mux := http.NewServeMux()
+		mux.HandleFunc("/channels/followers", func(w http.ResponseWriter, r *http.Request) {
+			w.Header().Set("Content-Type", "application/json")
+			w.WriteHeader(http.StatusUnauthorized)
+			fmt.Fprint(w, `{"error":"Unauthorized","status":401,"message":"Invalid OAuth token"}`)
+		})
+		cachedClient := NewCachedClient(helixtest.NewClient(t, mux), time.Minute, time.Minute)
+
+		// when
+		_, err := cachedClient.Follow(context.Background(), "1", "2")
+
+		// then
+		if err == nil {
+			t.Errorf("Expected an error, got nil")
+		}
+	})
+}
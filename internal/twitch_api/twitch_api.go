@@ -0,0 +1,60 @@
+package twitchapi
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+
+	"github.com/nicklaw5/helix/v2"
+	"go.opentelemetry.io/otel"
+)
+
+var tracer = otel.Tracer("github.com/danielbukowski/twitch-chatbot/internal/twitch_api")
+
+var ErrUserNotFound = errors.New("user not found")
+
+// ResponseError returns an error, when Twitch API responded with a status code that is not successful.
+// The helix client returns errors only for network failures, so every response has to be checked with this function.
+func ResponseError(resp helix.ResponseCommon) error {
+	if resp.StatusCode >= http.StatusOK && resp.StatusCode < http.StatusMultipleChoices {
+		return nil
+	}
+
+	return fmt.Errorf("twitch api responded with status %d: %s", resp.StatusCode, resp.ErrorMessage)
+}
+
+// FetchUser returns a Twitch user with the given login.
+func FetchUser(helixClient *helix.Client, login string) (helix.User, error) {
+	resp, err := helixClient.GetUsers(&helix.UsersParams{Logins: []string{login}})
+	if err != nil {
+		return helix.User{}, err
+	}
+
+	if err = ResponseError(resp.ResponseCommon); err != nil {
+		return helix.User{}, err
+	}
+
+	if len(resp.Data.Users) == 0 {
+		return helix.User{}, ErrUserNotFound
+	}
+
+	return resp.Data.Users[0], nil
+}
+
+// FetchChannelInformation returns information about a channel, like its title and the last played game.
+func FetchChannelInformation(helixClient *helix.Client, broadcasterID string) (helix.ChannelInformation, error) {
+	resp, err := helixClient.GetChannelInformation(&helix.GetChannelInformationParams{BroadcasterIDs: []string{broadcasterID}})
+	if err != nil {
+		return helix.ChannelInformation{}, err
+	}
+
+	if err = ResponseError(resp.ResponseCommon); err != nil {
+		return helix.ChannelInformation{}, err
+	}
+
+	if len(resp.Data.Channels) == 0 {
+		return helix.ChannelInformation{}, ErrUserNotFound
+	}
+
+	return resp.Data.Channels[0], nil
+}
@@ -0,0 +1,46 @@
+package twitchapi
+
+import (
+	"context"
+
+	"github.com/nicklaw5/helix/v2"
+	"go.opentelemetry.io/otel/codes"
+)
+
+// Whisperer sends private messages to users through Twitch API.
+// It requires a user access token with the user:manage:whispers scope.
+type Whisperer struct {
+	helixClient *helix.Client // HelixClient is used to call Twitch API.
+	fromUserID  string        // FromUserID is an ID of the user that sends whispers, usually the chatbot.
+}
+
+// NewWhisperer creates an instance of Whisperer.
+func NewWhisperer(helixClient *helix.Client, fromUserID string) *Whisperer {
+	return &Whisperer{helixClient: helixClient, fromUserID: fromUserID}
+}
+
+// Whisper sends a private message to a user.
+func (w *Whisperer) Whisper(ctx context.Context, toUserID, message string) error {
+	_, span := tracer.Start(ctx, "whisper")
+	defer span.End()
+
+	resp, err := w.helixClient.SendUserWhisper(&helix.SendUserWhisperParams{
+		FromUserID: w.fromUserID,
+		ToUserID:   toUserID,
+		Message:    message,
+	})
+	if err != nil {
+		span.SetStatus(codes.Error, "failed to send a whisper")
+		span.RecordError(err)
+		return err
+	}
+
+	if err = ResponseError(resp.ResponseCommon); err != nil {
+		span.SetStatus(codes.Error, "twitch api rejected a whisper")
+		span.RecordError(err)
+		return err
+	}
+
+	span.SetStatus(codes.Ok, "successfully sent a whisper")
+	return nil
+}
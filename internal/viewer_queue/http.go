@@ -0,0 +1,68 @@
+package viewerqueue
+
+import (
+	"encoding/json"
+	"net/http"
+	"strings"
+
+	"go.uber.org/zap"
+)
+
+// queueResponse is a state of a queue returned over HTTP.
+type queueResponse struct {
+	Channel  string          `json:"channel"`
+	IsOpen   bool            `json:"is_open"`
+	Priority Priority        `json:"priority"`
+	Entries  []entryResponse `json:"entries"`
+}
+
+// entryResponse is a viewer waiting in a queue returned over HTTP.
+type entryResponse struct {
+	Position     int    `json:"position"`
+	Username     string `json:"username"`
+	DisplayName  string `json:"display_name"`
+	Note         string `json:"note"`
+	IsSubscriber bool   `json:"is_subscriber"`
+	JoinedAt     int64  `json:"joined_at"`
+}
+
+// HandleQueue writes a queue of the channel from the `channel` path value as JSON, so overlays can show it.
+// Overlays are served from other origins, so any origin may read the queue.
+func (s *Service) HandleQueue(w http.ResponseWriter, r *http.Request) {
+	ctx, span := tracer.Start(r.Context(), "handleQueue")
+	defer span.End()
+
+	channelName := strings.ToLower(r.PathValue("channel"))
+
+	settings, entries, err := s.Snapshot(ctx, channelName)
+	if err != nil {
+		span.RecordError(err)
+		s.logger.Error("failed to read a queue", zap.String("channel_name", channelName), zap.Error(err))
+		http.Error(w, "failed to read the queue", http.StatusInternalServerError)
+		return
+	}
+
+	resp := queueResponse{
+		Channel:  channelName,
+		IsOpen:   settings.IsOpen,
+		Priority: settings.Priority,
+		Entries:  make([]entryResponse, 0, len(entries)),
+	}
+	for i, entry := range entries {
+		resp.Entries = append(resp.Entries, entryResponse{
+			Position:     i + 1,
+			Username:     entry.Username,
+			DisplayName:  entry.DisplayName,
+			Note:         entry.Note,
+			IsSubscriber: entry.IsSubscriber,
+			JoinedAt:     entry.JoinedAt.Unix(),
+		})
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.Header().Set("Access-Control-Allow-Origin", "*")
+	if err = json.NewEncoder(w).Encode(resp); err != nil {
+		span.RecordError(err)
+		s.logger.Warn("failed to write a queue", zap.String("channel_name", channelName), zap.Error(err))
+	}
+}
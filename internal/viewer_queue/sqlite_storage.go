@@ -0,0 +1,207 @@
+package viewerqueue
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"strings"
+	"time"
+
+	"github.com/danielbukowski/twitch-chatbot/internal/database"
+	"go.opentelemetry.io/otel/codes"
+)
+
+// SQLiteStorage stores queues of viewers and their settings per channel.
+type SQLiteStorage struct {
+	db *sql.DB
+}
+
+func NewSQLiteStorage(db *sql.DB) *SQLiteStorage {
+	return &SQLiteStorage{db: db}
+}
+
+// Settings returns settings of a queue of a channel. A queue without saved settings is closed and has no priority.
+func (s *SQLiteStorage) Settings(ctx context.Context, channelName string) (Settings, error) {
+	query := "SELECT is_open, priority FROM viewer_queue_settings WHERE channel_name = ?;"
+
+	ctx, span := tracer.Start(ctx, "settings")
+	defer span.End()
+
+	ctx, cancel := context.WithTimeout(ctx, database.RequestTimeout)
+	defer cancel()
+
+	var settings Settings
+	err := s.db.QueryRowContext(ctx, query, channelName).Scan(&settings.IsOpen, &settings.Priority)
+	if errors.Is(err, sql.ErrNoRows) {
+		span.SetStatus(codes.Ok, "queue has default settings")
+		return Settings{Priority: PriorityNone}, nil
+	}
+	if err != nil {
+		errMsg := "failed to retrieve settings of a queue"
+		span.SetStatus(codes.Error, errMsg)
+		span.RecordError(err)
+		return Settings{}, errors.Join(errors.New(errMsg), err)
+	}
+
+	span.SetStatus(codes.Ok, "successfully retrieved settings of a queue")
+	return settings, nil
+}
+
+// SaveSettings saves settings of a queue of a channel.
+func (s *SQLiteStorage) SaveSettings(ctx context.Context, channelName string, settings Settings) error {
+	query := `INSERT INTO viewer_queue_settings (channel_name, is_open, priority) VALUES (?, ?, ?)
+		ON CONFLICT (channel_name) DO UPDATE SET is_open = excluded.is_open, priority = excluded.priority;`
+
+	ctx, span := tracer.Start(ctx, "saveSettings")
+	defer span.End()
+
+	ctx, cancel := context.WithTimeout(ctx, database.RequestTimeout)
+	defer cancel()
+
+	_, err := s.db.ExecContext(ctx, query, channelName, settings.IsOpen, settings.Priority)
+	if err != nil {
+		errMsg := "failed to save settings of a queue"
+		span.SetStatus(codes.Error, errMsg)
+		span.RecordError(err)
+		return errors.Join(errors.New(errMsg), err)
+	}
+
+	span.SetStatus(codes.Ok, "successfully saved settings of a queue")
+	return nil
+}
+
+// Add adds an entry to a queue of a channel. The returned value is false, when the viewer is already in the queue.
+func (s *SQLiteStorage) Add(ctx context.Context, channelName string, entry Entry) (bool, error) {
+	query := `INSERT OR IGNORE INTO viewer_queue_entries (channel_name, user_id, username, display_name, note, is_subscriber, joined_at)
+		VALUES (?, ?, ?, ?, ?, ?, ?);`
+
+	ctx, span := tracer.Start(ctx, "add")
+	defer span.End()
+
+	ctx, cancel := context.WithTimeout(ctx, database.RequestTimeout)
+	defer cancel()
+
+	result, err := s.db.ExecContext(ctx, query, channelName, entry.UserID, entry.Username, entry.DisplayName, entry.Note, entry.IsSubscriber, entry.JoinedAt.Unix())
+	if err != nil {
+		errMsg := "failed to add an entry to a queue"
+		span.SetStatus(codes.Error, errMsg)
+		span.RecordError(err)
+		return false, errors.Join(errors.New(errMsg), err)
+	}
+
+	rows, err := result.RowsAffected()
+	if err != nil {
+		errMsg := "failed to check the added entry"
+		span.SetStatus(codes.Error, errMsg)
+		span.RecordError(err)
+		return false, errors.Join(errors.New(errMsg), err)
+	}
+
+	span.SetStatus(codes.Ok, "successfully added an entry to a queue")
+	return rows == 1, nil
+}
+
+// Remove removes viewers from a queue of a channel and returns a number of removed entries.
+func (s *SQLiteStorage) Remove(ctx context.Context, channelName string, userIDs ...string) (int64, error) {
+	if len(userIDs) == 0 {
+		return 0, nil
+	}
+
+	query := "DELETE FROM viewer_queue_entries WHERE channel_name = ? AND user_id IN (?" + strings.Repeat(", ?", len(userIDs)-1) + ");"
+
+	ctx, span := tracer.Start(ctx, "remove")
+	defer span.End()
+
+	ctx, cancel := context.WithTimeout(ctx, database.RequestTimeout)
+	defer cancel()
+
+	args := []any{channelName}
+	for _, userID := range userIDs {
+		args = append(args, userID)
+	}
+
+	result, err := s.db.ExecContext(ctx, query, args...)
+	if err != nil {
+		errMsg := "failed to remove entries from a queue"
+		span.SetStatus(codes.Error, errMsg)
+		span.RecordError(err)
+		return 0, errors.Join(errors.New(errMsg), err)
+	}
+
+	rows, err := result.RowsAffected()
+	if err != nil {
+		errMsg := "failed to count removed entries"
+		span.SetStatus(codes.Error, errMsg)
+		span.RecordError(err)
+		return 0, errors.Join(errors.New(errMsg), err)
+	}
+
+	span.SetStatus(codes.Ok, "successfully removed entries from a queue")
+	return rows, nil
+}
+
+// Entries returns entries of a queue of a channel in the order of joining.
+func (s *SQLiteStorage) Entries(ctx context.Context, channelName string) ([]Entry, error) {
+	query := `SELECT user_id, username, display_name, note, is_subscriber, joined_at FROM viewer_queue_entries
+		WHERE channel_name = ? ORDER BY joined_at, rowid;`
+
+	ctx, span := tracer.Start(ctx, "entries")
+	defer span.End()
+
+	ctx, cancel := context.WithTimeout(ctx, database.RequestTimeout)
+	defer cancel()
+
+	rows, err := s.db.QueryContext(ctx, query, channelName)
+	if err != nil {
+		errMsg := "failed to query entries of a queue"
+		span.SetStatus(codes.Error, errMsg)
+		span.RecordError(err)
+		return nil, errors.Join(errors.New(errMsg), err)
+	}
+	defer rows.Close()
+
+	entries := []Entry{}
+	for rows.Next() {
+		var entry Entry
+		var joinedAt int64
+		if err = rows.Scan(&entry.UserID, &entry.Username, &entry.DisplayName, &entry.Note, &entry.IsSubscriber, &joinedAt); err != nil {
+			errMsg := "failed to copy an entry to a struct"
+			span.SetStatus(codes.Error, errMsg)
+			span.RecordError(err)
+			return nil, errors.Join(errors.New(errMsg), err)
+		}
+		entry.JoinedAt = time.Unix(joinedAt, 0)
+		entries = append(entries, entry)
+	}
+
+	if err = rows.Err(); err != nil {
+		errMsg := "failed to iterate over entries of a queue"
+		span.SetStatus(codes.Error, errMsg)
+		span.RecordError(err)
+		return nil, errors.Join(errors.New(errMsg), err)
+	}
+
+	span.SetStatus(codes.Ok, "successfully retrieved entries of a queue")
+	return entries, nil
+}
+
+// Clear removes all entries of a queue of a channel.
+func (s *SQLiteStorage) Clear(ctx context.Context, channelName string) error {
+	query := "DELETE FROM viewer_queue_entries WHERE channel_name = ?;"
+
+	ctx, span := tracer.Start(ctx, "clear")
+	defer span.End()
+
+	ctx, cancel := context.WithTimeout(ctx, database.RequestTimeout)
+	defer cancel()
+
+	if _, err := s.db.ExecContext(ctx, query, channelName); err != nil {
+		errMsg := "failed to clear a queue"
+		span.SetStatus(codes.Error, errMsg)
+		span.RecordError(err)
+		return errors.Join(errors.New(errMsg), err)
+	}
+
+	span.SetStatus(codes.Ok, "successfully cleared a queue")
+	return nil
+}
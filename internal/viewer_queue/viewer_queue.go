@@ -0,0 +1,414 @@
+package viewerqueue
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/danielbukowski/twitch-chatbot/internal/command"
+	"github.com/danielbukowski/twitch-chatbot/internal/permission"
+	"github.com/gempir/go-twitch-irc/v4"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.uber.org/zap"
+)
+
+var tracer = otel.Tracer("github.com/danielbukowski/twitch-chatbot/internal/viewer_queue")
+
+var errNotModerator = command.PermissionDeniedError(errors.New("tried to manage the queue without being a moderator"))
+var errQueueClosed = command.UsageError("the queue is closed.")
+var errSubscribersOnly = command.UsageError("only subscribers can join the queue.")
+var errAlreadyQueued = command.UsageError("you are already in the queue.")
+
+const (
+	shownEntries = 10              // shownEntries is a number of entries shown by `!queue`.
+	maxNext      = 10              // maxNext is the largest number of viewers taken from the queue at once.
+	showCooldown = 5 * time.Second // showCooldown is how often the queue can be shown, so viewers don't flood the chat with it.
+)
+
+// Priority decides, how subscribers are treated by the queue.
+type Priority string
+
+const (
+	PriorityNone            Priority = "none"     // PriorityNone keeps everyone in the order of joining.
+	PrioritySubscribers     Priority = "subs"     // PrioritySubscribers puts subscribers ahead of other viewers.
+	PrioritySubscribersOnly Priority = "subsonly" // PrioritySubscribersOnly lets only subscribers join.
+)
+
+// ParsePriority parses a name of a priority.
+func ParsePriority(name string) (Priority, error) {
+	switch priority := Priority(strings.ToLower(name)); priority {
+	case PriorityNone, PrioritySubscribers, PrioritySubscribersOnly:
+		return priority, nil
+	default:
+		return "", fmt.Errorf("unknown priority: %s", name)
+	}
+}
+
+// Settings are settings of a queue of a channel.
+type Settings struct {
+	IsOpen   bool     // IsOpen is true, when viewers can join the queue.
+	Priority Priority // Priority decides, how subscribers are treated.
+}
+
+// Entry is a viewer waiting in a queue.
+type Entry struct {
+	UserID       string    // UserID is an ID of the viewer.
+	Username     string    // Username is a login of the viewer.
+	DisplayName  string    // DisplayName is a display name of the viewer.
+	Note         string    // Note is a text given by the viewer, like a nickname in a game.
+	IsSubscriber bool      // IsSubscriber is true, when the viewer was a subscriber at the time of joining.
+	JoinedAt     time.Time // JoinedAt is the time of joining.
+}
+
+type storage interface {
+	Settings(ctx context.Context, channelName string) (Settings, error)
+	SaveSettings(ctx context.Context, channelName string, settings Settings) error
+	Add(ctx context.Context, channelName string, entry Entry) (bool, error)
+	Remove(ctx context.Context, channelName string, userIDs ...string) (int64, error)
+	Entries(ctx context.Context, channelName string) ([]Entry, error)
+	Clear(ctx context.Context, channelName string) error
+}
+
+// levelResolver returns a permission level of the author of a message.
+type levelResolver interface {
+	LevelOf(ctx context.Context, privMsg *twitch.PrivateMessage, required permission.Level) (permission.Level, error)
+}
+
+// Service keeps queues of viewers waiting to play with streamers. Queues are stored, so they survive restarts.
+type Service struct {
+	storage       storage          // Storage saves queues and their settings.
+	levelResolver levelResolver    // LevelResolver tells, if a user may manage a queue.
+	now           func() time.Time // Now returns the current time.
+	logger        *zap.Logger      // Logger is used for logging.
+}
+
+// NewService creates an instance of Service.
+func NewService(storage storage, levelResolver levelResolver, logger *zap.Logger) *Service {
+	return &Service{
+		storage:       storage,
+		levelResolver: levelResolver,
+		now:           time.Now,
+		logger:        logger.Named("viewer_queue"),
+	}
+}
+
+// Add adds a user to a queue of a channel, so channel point redemptions can add viewers to the queue.
+func (s *Service) Add(ctx context.Context, channelName string, user twitch.User, note string) error {
+	_, err := s.join(ctx, channelName, user, note)
+	return err
+}
+
+// Join adds the user to the queue.
+// Usage: `!join [note]`.
+func (s *Service) Join() command.Handler {
+	return func(ctx context.Context, args []string, chatClient command.ChatClient) error {
+		cmdCtx := command.UnwrapContext(ctx)
+		privMsg := cmdCtx.PrivMsg
+
+		position, err := s.join(ctx, privMsg.Channel, privMsg.User, strings.Join(args, " "))
+		if err != nil {
+			return err
+		}
+
+		chatClient.Say(privMsg.Channel, fmt.Sprintf("@%s, you joined the queue at position %d.", privMsg.User.DisplayName, position))
+		return nil
+	}
+}
+
+// Leave removes the user from the queue.
+// Usage: `!leave`.
+func (s *Service) Leave() command.Handler {
+	return func(ctx context.Context, _ []string, chatClient command.ChatClient) error {
+		cmdCtx := command.UnwrapContext(ctx)
+		privMsg := cmdCtx.PrivMsg
+
+		removed, err := s.storage.Remove(ctx, privMsg.Channel, privMsg.User.ID)
+		if err != nil {
+			return command.InternalError(err)
+		}
+
+		if removed == 0 {
+			return command.UsageError("you are not in the queue.")
+		}
+
+		chatClient.Say(privMsg.Channel, fmt.Sprintf("@%s, you left the queue.", privMsg.User.DisplayName))
+		return nil
+	}
+}
+
+// Position tells the user's position in the queue.
+// Usage: `!position`.
+func (s *Service) Position() command.Handler {
+	return func(ctx context.Context, _ []string, chatClient command.ChatClient) error {
+		cmdCtx := command.UnwrapContext(ctx)
+		privMsg := cmdCtx.PrivMsg
+
+		_, entries, err := s.ordered(ctx, privMsg.Channel)
+		if err != nil {
+			return command.InternalError(err)
+		}
+
+		i := slices.IndexFunc(entries, func(entry Entry) bool { return entry.UserID == privMsg.User.ID })
+		if i == -1 {
+			chatClient.Say(privMsg.Channel, fmt.Sprintf("@%s, you are not in the queue.", privMsg.User.DisplayName))
+			return nil
+		}
+
+		chatClient.Say(privMsg.Channel, fmt.Sprintf("@%s, you are at position %d of %d.", privMsg.User.DisplayName, i+1, len(entries)))
+		return nil
+	}
+}
+
+// Queue shows the queue, moderators can also open, close and clear it or change its priority.
+// Showing the queue has a cooldown, managing it has none.
+// Usage: `!queue`, `!queue open`, `!queue close`, `!queue clear` or `!queue priority <none|subs|subsonly>`.
+func (s *Service) Queue() command.Handler {
+	show := command.Cooldown(showCooldown)(func(ctx context.Context, _ []string, chatClient command.ChatClient) error {
+		return s.show(ctx, command.UnwrapContext(ctx).PrivMsg, chatClient)
+	})
+
+	return func(ctx context.Context, args []string, chatClient command.ChatClient) error {
+		cmdCtx := command.UnwrapContext(ctx)
+		privMsg := cmdCtx.PrivMsg
+
+		if len(args) == 0 {
+			return show(ctx, args, chatClient)
+		}
+
+		level, err := s.levelResolver.LevelOf(ctx, privMsg, permission.Moderator)
+		if err != nil {
+			return command.UpstreamError(err)
+		}
+		if level < permission.Moderator {
+			return errNotModerator
+		}
+
+		return s.manage(ctx, privMsg, args, chatClient)
+	}
+}
+
+// Next takes viewers from the front of the queue.
+// Usage: `!next [count]`.
+func (s *Service) Next() command.Handler {
+	return func(ctx context.Context, args []string, chatClient command.ChatClient) error {
+		spanCtx, span := tracer.Start(ctx, "next")
+		defer span.End()
+
+		cmdCtx := command.UnwrapContext(ctx)
+		privMsg := cmdCtx.PrivMsg
+
+		count := 1
+		if len(args) > 0 {
+			n, err := strconv.Atoi(args[0])
+			if err != nil || n < 1 || n > maxNext {
+				span.SetStatus(codes.Error, "wrong count")
+				return command.UsageError(fmt.Sprintf("the count has to be a number from 1 to %d.", maxNext))
+			}
+			count = n
+		}
+
+		_, entries, err := s.ordered(spanCtx, privMsg.Channel)
+		if err != nil {
+			span.SetStatus(codes.Error, "failed to retrieve the queue")
+			return command.InternalError(err)
+		}
+
+		if len(entries) == 0 {
+			span.SetStatus(codes.Ok, "queue is empty")
+			chatClient.Say(privMsg.Channel, fmt.Sprintf("@%s, the queue is empty.", privMsg.User.DisplayName))
+			return nil
+		}
+
+		next := entries[:min(count, len(entries))]
+		userIDs := make([]string, 0, len(next))
+		mentions := make([]string, 0, len(next))
+		for _, entry := range next {
+			userIDs = append(userIDs, entry.UserID)
+			mention := "@" + entry.DisplayName
+			if entry.Note != "" {
+				mention = fmt.Sprintf("%s (%s)", mention, entry.Note)
+			}
+			mentions = append(mentions, mention)
+		}
+
+		if _, err = s.storage.Remove(spanCtx, privMsg.Channel, userIDs...); err != nil {
+			span.SetStatus(codes.Error, "failed to remove viewers from the queue")
+			return command.InternalError(err)
+		}
+
+		span.SetAttributes(attribute.Int("queue.taken", len(next)))
+		span.SetStatus(codes.Ok, "successfully took viewers from the queue")
+
+		chatClient.Say(privMsg.Channel, fmt.Sprintf("Next up: %s. %d left in the queue.", strings.Join(mentions, ", "), len(entries)-len(next)))
+		return nil
+	}
+}
+
+// Snapshot returns settings and ordered entries of a queue of a channel.
+func (s *Service) Snapshot(ctx context.Context, channelName string) (Settings, []Entry, error) {
+	return s.ordered(ctx, channelName)
+}
+
+// join adds a user to a queue of a channel and returns the user's position.
+func (s *Service) join(ctx context.Context, channelName string, user twitch.User, note string) (int, error) {
+	ctx, span := tracer.Start(ctx, "join")
+	defer span.End()
+
+	settings, err := s.storage.Settings(ctx, channelName)
+	if err != nil {
+		span.SetStatus(codes.Error, "failed to retrieve settings of the queue")
+		return 0, command.InternalError(err)
+	}
+
+	if !settings.IsOpen {
+		span.SetStatus(codes.Error, "queue is closed")
+		return 0, errQueueClosed
+	}
+
+	isSubscriber := user.Badges["subscriber"] != 0 || user.Badges["founder"] != 0
+	if settings.Priority == PrioritySubscribersOnly && !isSubscriber {
+		span.SetStatus(codes.Error, "user is not a subscriber")
+		return 0, errSubscribersOnly
+	}
+
+	added, err := s.storage.Add(ctx, channelName, Entry{
+		UserID:       user.ID,
+		Username:     strings.ToLower(user.Name),
+		DisplayName:  user.DisplayName,
+		Note:         strings.TrimSpace(note),
+		IsSubscriber: isSubscriber,
+		JoinedAt:     s.now(),
+	})
+	if err != nil {
+		span.SetStatus(codes.Error, "failed to add the user to the queue")
+		return 0, command.InternalError(err)
+	}
+
+	if !added {
+		span.SetStatus(codes.Error, "user is already in the queue")
+		return 0, errAlreadyQueued
+	}
+
+	_, entries, err := s.ordered(ctx, channelName)
+	if err != nil {
+		span.SetStatus(codes.Error, "failed to retrieve the queue")
+		return 0, command.InternalError(err)
+	}
+
+	span.SetStatus(codes.Ok, "successfully added the user to the queue")
+	return slices.IndexFunc(entries, func(entry Entry) bool { return entry.UserID == user.ID }) + 1, nil
+}
+
+// show writes the front of the queue to the chat.
+func (s *Service) show(ctx context.Context, privMsg *twitch.PrivateMessage, chatClient command.ChatClient) error {
+	settings, entries, err := s.ordered(ctx, privMsg.Channel)
+	if err != nil {
+		return command.InternalError(err)
+	}
+
+	state := "closed"
+	if settings.IsOpen {
+		state = "open"
+	}
+
+	if len(entries) == 0 {
+		chatClient.Say(privMsg.Channel, fmt.Sprintf("@%s, the queue is %s and empty.", privMsg.User.DisplayName, state))
+		return nil
+	}
+
+	places := make([]string, 0, shownEntries)
+	for i, entry := range entries[:min(shownEntries, len(entries))] {
+		places = append(places, fmt.Sprintf("%d. %s", i+1, entry.DisplayName))
+	}
+
+	chatClient.Say(privMsg.Channel, fmt.Sprintf("@%s, the queue is %s, %d waiting: %s", privMsg.User.DisplayName, state, len(entries), strings.Join(places, ", ")))
+	return nil
+}
+
+// manage changes the queue according to a subcommand of a moderator.
+func (s *Service) manage(ctx context.Context, privMsg *twitch.PrivateMessage, args []string, chatClient command.ChatClient) error {
+	ctx, span := tracer.Start(ctx, "manageQueue")
+	defer span.End()
+
+	usage := command.UsageError("usage: !queue open | !queue close | !queue clear | !queue priority <none|subs|subsonly>")
+
+	settings, err := s.storage.Settings(ctx, privMsg.Channel)
+	if err != nil {
+		span.SetStatus(codes.Error, "failed to retrieve settings of the queue")
+		return command.InternalError(err)
+	}
+
+	var reply string
+
+	switch strings.ToLower(args[0]) {
+	case "open", "close":
+		settings.IsOpen = strings.EqualFold(args[0], "open")
+		err = s.storage.SaveSettings(ctx, privMsg.Channel, settings)
+		reply = "the queue is closed."
+		if settings.IsOpen {
+			reply = "the queue is open, type !join to join."
+		}
+	case "clear":
+		err = s.storage.Clear(ctx, privMsg.Channel)
+		reply = "the queue was cleared."
+	case "priority":
+		if len(args) < 2 {
+			span.SetStatus(codes.Error, "missing priority")
+			return usage
+		}
+		priority, parseErr := ParsePriority(args[1])
+		if parseErr != nil {
+			span.SetStatus(codes.Error, "unknown priority")
+			return usage
+		}
+		settings.Priority = priority
+		err = s.storage.SaveSettings(ctx, privMsg.Channel, settings)
+		reply = fmt.Sprintf("the priority of the queue is %s now.", priority)
+	default:
+		span.SetStatus(codes.Error, "unknown subcommand")
+		return usage
+	}
+
+	if err != nil {
+		span.SetStatus(codes.Error, "failed to change the queue")
+		return command.InternalError(err)
+	}
+
+	span.SetStatus(codes.Ok, "successfully changed the queue")
+	chatClient.Say(privMsg.Channel, fmt.Sprintf("@%s, %s", privMsg.User.DisplayName, reply))
+	return nil
+}
+
+// ordered returns settings and entries of a queue in the order of their turns.
+func (s *Service) ordered(ctx context.Context, channelName string) (Settings, []Entry, error) {
+	settings, err := s.storage.Settings(ctx, channelName)
+	if err != nil {
+		return Settings{}, nil, err
+	}
+
+	entries, err := s.storage.Entries(ctx, channelName)
+	if err != nil {
+		return Settings{}, nil, err
+	}
+
+	if settings.Priority != PriorityNone {
+		slices.SortStableFunc(entries, func(a, b Entry) int {
+			switch {
+			case a.IsSubscriber == b.IsSubscriber:
+				return 0
+			case a.IsSubscriber:
+				return -1
+			default:
+				return 1
+			}
+		})
+	}
+
+	return settings, entries, nil
+}
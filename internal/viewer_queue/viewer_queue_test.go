@@ -0,0 +1,253 @@
+package viewerqueue
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"slices"
+	"testing"
+	"time"
+
+	"github.com/danielbukowski/twitch-chatbot/internal/command"
+	"github.com/danielbukowski/twitch-chatbot/internal/permission"
+	"github.com/gempir/go-twitch-irc/v4"
+	"go.uber.org/zap"
+)
+
+// storageMock keeps a single queue in memory.
+type storageMock struct {
+	settings Settings
+	entries  []Entry
+}
+
+func (s *storageMock) Settings(_ context.Context, _ string) (Settings, error) {
+	return s.settings, nil
+}
+
+func (s *storageMock) SaveSettings(_ context.Context, _ string, settings Settings) error {
+	s.settings = settings
+	return nil
+}
+
+func (s *storageMock) Add(_ context.Context, _ string, entry Entry) (bool, error) {
+	if slices.ContainsFunc(s.entries, func(e Entry) bool { return e.UserID == entry.UserID }) {
+		return false, nil
+	}
+	s.entries = append(s.entries, entry)
+	return true, nil
+}
+
+func (s *storageMock) Remove(_ context.Context, _ string, userIDs ...string) (int64, error) {
+	before := len(s.entries)
+	s.entries = slices.DeleteFunc(s.entries, func(e Entry) bool { return slices.Contains(userIDs, e.UserID) })
+	return int64(before - len(s.entries)), nil
+}
+
+func (s *storageMock) Entries(_ context.Context, _ string) ([]Entry, error) {
+	return slices.Clone(s.entries), nil
+}
+
+func (s *storageMock) Clear(_ context.Context, _ string) error {
+	s.entries = nil
+	return nil
+}
+
+type levelResolverMock permission.Level
+
+func (l levelResolverMock) LevelOf(_ context.Context, _ *twitch.PrivateMessage, _ permission.Level) (permission.Level, error) {
+	return permission.Level(l), nil
+}
+
+type chatClientMock struct {
+	messages []string
+}
+
+func (c *chatClientMock) Say(_, message string) {
+	c.messages = append(c.messages, message)
+}
+
+func (c *chatClientMock) Reply(_, _, message string) {
+	c.messages = append(c.messages, message)
+}
+
+func (c *chatClientMock) Join(_ ...string) {}
+
+func (c *chatClientMock) Depart(_ string) {}
+
+func user(userID, name string, badges map[string]int) twitch.User {
+	return twitch.User{ID: userID, Name: name, DisplayName: name, Badges: badges}
+}
+
+func commandContext(commandName string, user twitch.User) context.Context {
+	privMsg := &twitch.PrivateMessage{Channel: "channel", User: user}
+	return command.WithContext(context.Background(), command.NewContext(commandName, privMsg, zap.NewNop()))
+}
+
+func TestJoin(t *testing.T) {
+	subscriber := user("2", "subscriber", map[string]int{"subscriber": 6})
+	viewer := user("1", "viewer", nil)
+
+	testCases := []struct {
+		name        string
+		settings    Settings
+		queued      []Entry
+		user        twitch.User
+		expectedErr error
+	}{
+		{name: "joins an open queue", settings: Settings{IsOpen: true, Priority: PriorityNone}, user: viewer},
+		{name: "rejects a closed queue", settings: Settings{Priority: PriorityNone}, user: viewer, expectedErr: errQueueClosed},
+		{name: "rejects a viewer in a queue for subscribers", settings: Settings{IsOpen: true, Priority: PrioritySubscribersOnly}, user: viewer, expectedErr: errSubscribersOnly},
+		{name: "lets a subscriber in a queue for subscribers", settings: Settings{IsOpen: true, Priority: PrioritySubscribersOnly}, user: subscriber},
+		{name: "rejects a viewer already in the queue", settings: Settings{IsOpen: true, Priority: PriorityNone}, queued: []Entry{{UserID: "1"}}, user: viewer, expectedErr: errAlreadyQueued},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			// given
+			service := NewService(&storageMock{settings: tc.settings, entries: tc.queued}, levelResolverMock(permission.Everyone), zap.NewNop())
+
+			// when
+			err := service.Join()(commandContext("!join", tc.user), nil, &chatClientMock{})
+
+			// then
+			if err != tc.expectedErr {
+				t.Fatalf("Expected `%v`, got `%v`", tc.expectedErr, err)
+			}
+		})
+	}
+}
+
+func TestNext(t *testing.T) {
+	testCases := []struct {
+		name     string
+		priority Priority
+		expected string
+	}{
+		{name: "takes viewers in the order of joining", priority: PriorityNone, expected: "Next up: @first, @second (Player2). 1 left in the queue."},
+		{name: "takes subscribers first", priority: PrioritySubscribers, expected: "Next up: @second (Player2), @first. 1 left in the queue."},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			// given
+			storage := &storageMock{settings: Settings{IsOpen: true, Priority: tc.priority}}
+			service := NewService(storage, levelResolverMock(permission.Moderator), zap.NewNop())
+			now := time.Now()
+			service.now = func() time.Time { return now }
+			_ = service.Add(context.Background(), "channel", user("1", "first", nil), "")
+			_ = service.Add(context.Background(), "channel", user("2", "second", map[string]int{"founder": 0, "subscriber": 1}), "Player2")
+			_ = service.Add(context.Background(), "channel", user("3", "third", nil), "")
+			chatClient := &chatClientMock{}
+
+			// when
+			err := service.Next()(commandContext("!next", user("9", "moderator", nil)), []string{"2"}, chatClient)
+
+			// then
+			if err != nil {
+				t.Fatalf("Expected no error, got `%v`", err)
+			}
+			if len(chatClient.messages) != 1 || chatClient.messages[0] != tc.expected {
+				t.Fatalf("Expected `%s`, got `%v`", tc.expected, chatClient.messages)
+			}
+			if len(storage.entries) != 1 || storage.entries[0].UserID != "3" {
+				t.Fatalf("Expected only the third viewer to be left, got `%+v`", storage.entries)
+			}
+		})
+	}
+}
+
+func TestQueue(t *testing.T) {
+	t.Run("rejects managing the queue by a viewer", func(t *testing.T) {
+		// given
+		storage := &storageMock{settings: Settings{Priority: PriorityNone}}
+		service := NewService(storage, levelResolverMock(permission.VIP), zap.NewNop())
+
+		// when
+		err := service.Queue()(commandContext("!queue", user("1", "viewer", nil)), []string{"open"}, &chatClientMock{})
+
+		// then
+		if command.Classify(err) != command.KindPermissionDenied || storage.settings.IsOpen {
+			t.Fatalf("Expected a permission denied error, got `%v`", err)
+		}
+	})
+
+	t.Run("changes the priority of the queue", func(t *testing.T) {
+		// given
+		storage := &storageMock{settings: Settings{IsOpen: true, Priority: PriorityNone}}
+		service := NewService(storage, levelResolverMock(permission.Moderator), zap.NewNop())
+
+		// when
+		err := service.Queue()(commandContext("!queue", user("9", "moderator", nil)), []string{"priority", "SUBS"}, &chatClientMock{})
+
+		// then
+		if err != nil {
+			t.Fatalf("Expected no error, got `%v`", err)
+		}
+		if storage.settings != (Settings{IsOpen: true, Priority: PrioritySubscribers}) {
+			t.Fatalf("Expected the priority to change, got `%+v`", storage.settings)
+		}
+	})
+
+	t.Run("puts only showing the queue on cooldown", func(t *testing.T) {
+		// given
+		storage := &storageMock{settings: Settings{Priority: PriorityNone}}
+		service := NewService(storage, levelResolverMock(permission.Moderator), zap.NewNop())
+		queue := service.Queue()
+		ctx := commandContext("!queue", user("9", "moderator", nil))
+		_ = queue(ctx, nil, &chatClientMock{})
+
+		// when
+		showErr := queue(ctx, nil, &chatClientMock{})
+		openErr := queue(ctx, []string{"open"}, &chatClientMock{})
+		closeErr := queue(ctx, []string{"close"}, &chatClientMock{})
+
+		// then
+		if command.Classify(showErr) != command.KindCooldown {
+			t.Fatalf("Expected a cooldown error, got `%v`", showErr)
+		}
+		if openErr != nil || closeErr != nil {
+			t.Fatalf("Expected no errors, got `%v` and `%v`", openErr, closeErr)
+		}
+	})
+}
+
+func TestHandleQueue(t *testing.T) {
+	// given
+	joinedAt := time.Unix(1760000000, 0)
+	storage := &storageMock{
+		settings: Settings{IsOpen: true, Priority: PrioritySubscribers},
+		entries: []Entry{
+			{UserID: "1", Username: "first", DisplayName: "First", JoinedAt: joinedAt},
+			{UserID: "2", Username: "second", DisplayName: "Second", Note: "Player2", IsSubscriber: true, JoinedAt: joinedAt},
+		},
+	}
+	service := NewService(storage, levelResolverMock(permission.Everyone), zap.NewNop())
+	mux := http.NewServeMux()
+	mux.HandleFunc("GET /queue/{channel}", service.HandleQueue)
+	recorder := httptest.NewRecorder()
+
+	// when
+	mux.ServeHTTP(recorder, httptest.NewRequest(http.MethodGet, "/queue/Channel", nil))
+
+	// then
+	if recorder.Code != http.StatusOK {
+		t.Fatalf("Expected status 200, got %d", recorder.Code)
+	}
+	var got queueResponse
+	if err := json.NewDecoder(recorder.Body).Decode(&got); err != nil {
+		t.Fatalf("Expected no error, got `%v`", err)
+	}
+	expected := queueResponse{
+		Channel:  "channel",
+		IsOpen:   true,
+		Priority: PrioritySubscribers,
+		Entries: []entryResponse{
+			{Position: 1, Username: "second", DisplayName: "Second", Note: "Player2", IsSubscriber: true, JoinedAt: 1760000000},
+			{Position: 2, Username: "first", DisplayName: "First", JoinedAt: 1760000000},
+		},
+	}
+	if got.Channel != expected.Channel || got.IsOpen != expected.IsOpen || got.Priority != expected.Priority || !slices.Equal(got.Entries, expected.Entries) {
+		t.Fatalf("Expected `%+v`, got `%+v`", expected, got)
+	}
+}
@@ -0,0 +1,211 @@
+package watchtime
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/danielbukowski/twitch-chatbot/internal/command"
+	twitchapi "github.com/danielbukowski/twitch-chatbot/internal/twitch_api"
+	"github.com/nicklaw5/helix/v2"
+	"go.opentelemetry.io/otel/codes"
+)
+
+// topWatchersLimit is a number of viewers shown on the leaderboard.
+const topWatchersLimit = 5
+
+// reader reads saved watch time.
+type reader interface {
+	ByUsername(ctx context.Context, channelName, username string) (Entry, bool, error)
+	Top(ctx context.Context, channelName string, limit int) ([]Entry, error)
+}
+
+// twitchUsers resolves users and their follow relationships.
+type twitchUsers interface {
+	UserByLogin(ctx context.Context, login string) (helix.User, error)
+	Follow(ctx context.Context, broadcasterID, userID string) (twitchapi.Follow, error)
+}
+
+// Commands answers questions about follow age and watch time of viewers.
+type Commands struct {
+	reader reader           // Reader reads saved watch time.
+	users  twitchUsers      // Users resolves users and their follows.
+	now    func() time.Time // Now returns the current time.
+}
+
+// NewCommands creates an instance of Commands.
+func NewCommands(reader reader, users twitchUsers) *Commands {
+	return &Commands{reader: reader, users: users, now: time.Now}
+}
+
+// FollowAge tells how long a user has followed the channel.
+// Usage: `!followage [@user]`.
+func (c *Commands) FollowAge() command.Handler {
+	return func(ctx context.Context, args []string, chatClient command.ChatClient) error {
+		spanCtx, span := tracer.Start(ctx, "followAgeCommand")
+		defer span.End()
+
+		cmdCtx := command.UnwrapContext(ctx)
+		privMsg := cmdCtx.PrivMsg
+
+		userID, displayName := privMsg.User.ID, privMsg.User.DisplayName
+		if len(args) > 0 {
+			login := command.TrimMention(args[0])
+			user, err := c.users.UserByLogin(spanCtx, login)
+			if errors.Is(err, twitchapi.ErrUserNotFound) {
+				span.SetStatus(codes.Error, "user does not exist")
+				return command.UsageError(fmt.Sprintf("user %s does not exist", login))
+			}
+			if err != nil {
+				span.SetStatus(codes.Error, "failed to get the user")
+				span.RecordError(err)
+				return command.UpstreamError(err)
+			}
+			userID, displayName = user.ID, user.DisplayName
+		}
+
+		follow, err := c.users.Follow(spanCtx, privMsg.RoomID, userID)
+		if err != nil {
+			span.SetStatus(codes.Error, "failed to get the follow")
+			span.RecordError(err)
+			return command.UpstreamError(err)
+		}
+
+		if !follow.IsFollower {
+			span.SetStatus(codes.Ok, "user does not follow the channel")
+			chatClient.Say(privMsg.Channel, fmt.Sprintf("@%s, %s does not follow the channel.", privMsg.User.DisplayName, displayName))
+			return nil
+		}
+
+		span.SetStatus(codes.Ok, "successfully got the follow age")
+		chatClient.Say(privMsg.Channel, fmt.Sprintf("@%s, %s has followed the channel for %s.", privMsg.User.DisplayName, displayName, formatAge(follow.FollowedAt, c.now())))
+		return nil
+	}
+}
+
+// WatchTime tells how long a user has watched streams of the channel.
+// Usage: `!watchtime [@user]`.
+func (c *Commands) WatchTime() command.Handler {
+	return func(ctx context.Context, args []string, chatClient command.ChatClient) error {
+		spanCtx, span := tracer.Start(ctx, "watchTimeCommand")
+		defer span.End()
+
+		cmdCtx := command.UnwrapContext(ctx)
+		privMsg := cmdCtx.PrivMsg
+
+		username := privMsg.User.Name
+		if len(args) > 0 {
+			username = command.TrimMention(args[0])
+		}
+		username = strings.ToLower(username)
+
+		entry, found, err := c.reader.ByUsername(spanCtx, privMsg.Channel, username)
+		if err != nil {
+			span.SetStatus(codes.Error, "failed to read watch time")
+			span.RecordError(err)
+			return command.InternalError(err)
+		}
+
+		if !found {
+			span.SetStatus(codes.Ok, "user has not watched any stream")
+			chatClient.Say(privMsg.Channel, fmt.Sprintf("@%s, %s has not watched any stream yet.", privMsg.User.DisplayName, username))
+			return nil
+		}
+
+		span.SetStatus(codes.Ok, "successfully got watch time")
+		chatClient.Say(privMsg.Channel, fmt.Sprintf("@%s, %s has watched streams for %s.", privMsg.User.DisplayName, entry.Username, formatWatchTime(entry.WatchTime)))
+		return nil
+	}
+}
+
+// TopWatchers shows viewers with the longest watch time.
+// Usage: `!topwatchers`.
+func (c *Commands) TopWatchers() command.Handler {
+	return func(ctx context.Context, _ []string, chatClient command.ChatClient) error {
+		spanCtx, span := tracer.Start(ctx, "topWatchersCommand")
+		defer span.End()
+
+		cmdCtx := command.UnwrapContext(ctx)
+		privMsg := cmdCtx.PrivMsg
+
+		entries, err := c.reader.Top(spanCtx, privMsg.Channel, topWatchersLimit)
+		if err != nil {
+			span.SetStatus(codes.Error, "failed to read top watchers")
+			span.RecordError(err)
+			return command.InternalError(err)
+		}
+
+		if len(entries) == 0 {
+			span.SetStatus(codes.Ok, "nobody has watched any stream")
+			chatClient.Say(privMsg.Channel, fmt.Sprintf("@%s, nobody has watched any stream yet.", privMsg.User.DisplayName))
+			return nil
+		}
+
+		places := make([]string, 0, len(entries))
+		for i, entry := range entries {
+			places = append(places, fmt.Sprintf("%d. %s (%s)", i+1, entry.Username, formatWatchTime(entry.WatchTime)))
+		}
+
+		span.SetStatus(codes.Ok, "successfully got top watchers")
+		chatClient.Say(privMsg.Channel, fmt.Sprintf("@%s, top watchers: %s", privMsg.User.DisplayName, strings.Join(places, ", ")))
+		return nil
+	}
+}
+
+// formatWatchTime returns a readable duration, like `3d 4h 5m`.
+func formatWatchTime(d time.Duration) string {
+	d = d.Truncate(time.Minute)
+
+	days := int(d.Hours()) / 24
+	hours := int(d.Hours()) % 24
+	minutes := int(d.Minutes()) % 60
+
+	switch {
+	case days > 0:
+		return fmt.Sprintf("%dd %dh %dm", days, hours, minutes)
+	case hours > 0:
+		return fmt.Sprintf("%dh %dm", hours, minutes)
+	default:
+		return fmt.Sprintf("%dm", minutes)
+	}
+}
+
+// formatAge returns a readable calendar difference between two times, like `1 year, 2 months, 3 days`.
+func formatAge(from, to time.Time) string {
+	from, to = from.UTC(), to.UTC()
+
+	months := (to.Year()-from.Year())*12 + int(to.Month()) - int(from.Month())
+	if addMonths(from, months).After(to) {
+		months--
+	}
+	days := int(to.Sub(addMonths(from, months)).Hours() / 24)
+
+	parts := []string{}
+	for _, unit := range []struct {
+		value int
+		name  string
+	}{{months / 12, "year"}, {months % 12, "month"}, {days, "day"}} {
+		switch {
+		case unit.value == 1:
+			parts = append(parts, fmt.Sprintf("1 %s", unit.name))
+		case unit.value > 1:
+			parts = append(parts, fmt.Sprintf("%d %ss", unit.value, unit.name))
+		}
+	}
+
+	if len(parts) == 0 {
+		return "less than a day"
+	}
+
+	return strings.Join(parts, ", ")
+}
+
+// addMonths adds months to a time, keeping the day within the target month, so 31 January plus a month is 28 February.
+func addMonths(t time.Time, months int) time.Time {
+	firstDay := time.Date(t.Year(), t.Month()+time.Month(months), 1, t.Hour(), t.Minute(), t.Second(), t.Nanosecond(), time.UTC)
+	lastDay := firstDay.AddDate(0, 1, -1).Day()
+
+	return firstDay.AddDate(0, 0, min(t.Day(), lastDay)-1)
+}
@@ -0,0 +1,136 @@
+package watchtime
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"time"
+
+	"github.com/danielbukowski/twitch-chatbot/internal/database"
+	"go.opentelemetry.io/otel/codes"
+)
+
+// SQLiteStorage stores watch time of viewers aggregated per channel.
+type SQLiteStorage struct {
+	db *sql.DB
+}
+
+func NewSQLiteStorage(db *sql.DB) *SQLiteStorage {
+	return &SQLiteStorage{db: db}
+}
+
+// Add adds watch time of entries to totals of viewers on a channel.
+func (s *SQLiteStorage) Add(ctx context.Context, channelName string, entries []Entry, updatedAt time.Time) error {
+	query := `INSERT INTO watch_time (channel_name, user_id, username, seconds, updated_at) VALUES (?, ?, ?, ?, ?)
+		ON CONFLICT (channel_name, user_id) DO UPDATE SET username = excluded.username, seconds = seconds + excluded.seconds, updated_at = excluded.updated_at;`
+
+	ctx, span := tracer.Start(ctx, "add")
+	defer span.End()
+
+	ctx, cancel := context.WithTimeout(ctx, database.RequestTimeout)
+	defer cancel()
+
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		errMsg := "failed to begin a transaction"
+		span.SetStatus(codes.Error, errMsg)
+		span.RecordError(err)
+		return errors.Join(errors.New(errMsg), err)
+	}
+	//nolint:errcheck // rollback after commit returns an error that doesn't matter
+	defer tx.Rollback()
+
+	for _, entry := range entries {
+		_, err = tx.ExecContext(ctx, query, channelName, entry.UserID, entry.Username, int64(entry.WatchTime.Seconds()), updatedAt.Unix())
+		if err != nil {
+			errMsg := "failed to add watch time"
+			span.SetStatus(codes.Error, errMsg)
+			span.RecordError(err)
+			return errors.Join(errors.New(errMsg), err)
+		}
+	}
+
+	if err = tx.Commit(); err != nil {
+		errMsg := "failed to commit a transaction"
+		span.SetStatus(codes.Error, errMsg)
+		span.RecordError(err)
+		return errors.Join(errors.New(errMsg), err)
+	}
+
+	span.SetStatus(codes.Ok, "successfully added watch time")
+	return nil
+}
+
+// ByUsername returns watch time of a viewer on a channel. The second returned value is false, when the viewer was never seen.
+func (s *SQLiteStorage) ByUsername(ctx context.Context, channelName, username string) (Entry, bool, error) {
+	query := "SELECT user_id, username, seconds FROM watch_time WHERE channel_name = ? AND username = ?;"
+
+	ctx, span := tracer.Start(ctx, "byUsername")
+	defer span.End()
+
+	ctx, cancel := context.WithTimeout(ctx, database.RequestTimeout)
+	defer cancel()
+
+	var entry Entry
+	var seconds int64
+	err := s.db.QueryRowContext(ctx, query, channelName, username).Scan(&entry.UserID, &entry.Username, &seconds)
+	if errors.Is(err, sql.ErrNoRows) {
+		span.SetStatus(codes.Ok, "viewer has no watch time")
+		return Entry{}, false, nil
+	}
+	if err != nil {
+		errMsg := "failed to retrieve watch time"
+		span.SetStatus(codes.Error, errMsg)
+		span.RecordError(err)
+		return Entry{}, false, errors.Join(errors.New(errMsg), err)
+	}
+
+	entry.WatchTime = time.Duration(seconds) * time.Second
+
+	span.SetStatus(codes.Ok, "successfully retrieved watch time")
+	return entry, true, nil
+}
+
+// Top returns viewers with the longest watch time on a channel.
+func (s *SQLiteStorage) Top(ctx context.Context, channelName string, limit int) ([]Entry, error) {
+	query := "SELECT user_id, username, seconds FROM watch_time WHERE channel_name = ? ORDER BY seconds DESC, username LIMIT ?;"
+
+	ctx, span := tracer.Start(ctx, "top")
+	defer span.End()
+
+	ctx, cancel := context.WithTimeout(ctx, database.RequestTimeout)
+	defer cancel()
+
+	rows, err := s.db.QueryContext(ctx, query, channelName, limit)
+	if err != nil {
+		errMsg := "failed to query top watchers"
+		span.SetStatus(codes.Error, errMsg)
+		span.RecordError(err)
+		return nil, errors.Join(errors.New(errMsg), err)
+	}
+	defer rows.Close()
+
+	entries := []Entry{}
+	for rows.Next() {
+		var entry Entry
+		var seconds int64
+		if err = rows.Scan(&entry.UserID, &entry.Username, &seconds); err != nil {
+			errMsg := "failed to copy watch time to a struct"
+			span.SetStatus(codes.Error, errMsg)
+			span.RecordError(err)
+			return nil, errors.Join(errors.New(errMsg), err)
+		}
+		entry.WatchTime = time.Duration(seconds) * time.Second
+		entries = append(entries, entry)
+	}
+
+	if err = rows.Err(); err != nil {
+		errMsg := "failed to iterate over top watchers"
+		span.SetStatus(codes.Error, errMsg)
+		span.RecordError(err)
+		return nil, errors.Join(errors.New(errMsg), err)
+	}
+
+	span.SetStatus(codes.Ok, "successfully retrieved top watchers")
+	return entries, nil
+}
@@ -0,0 +1,182 @@
+package watchtime
+
+import (
+	"context"
+	"strings"
+	"time"
+
+	"github.com/danielbukowski/twitch-chatbot/internal/presence"
+	twitchapi "github.com/danielbukowski/twitch-chatbot/internal/twitch_api"
+	"github.com/nicklaw5/helix/v2"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.uber.org/zap"
+)
+
+var tracer = otel.Tracer("github.com/danielbukowski/twitch-chatbot/internal/watchtime")
+
+// chattersPageSize is the largest page of chatters returned by Twitch API.
+const chattersPageSize = "1000"
+
+// Entry is watch time of a viewer.
+type Entry struct {
+	UserID    string        // UserID is an ID of the viewer.
+	Username  string        // Username is a login of the viewer.
+	WatchTime time.Duration // WatchTime is how long the viewer was present on live streams.
+}
+
+type storage interface {
+	Add(ctx context.Context, channelName string, entries []Entry, updatedAt time.Time) error
+}
+
+// liveChecker tells, if a stream of a channel is live.
+type liveChecker interface {
+	IsLive(channelName string) bool
+}
+
+// activityReader returns viewers active in chat.
+type activityReader interface {
+	Active(channelName string) []presence.Viewer
+	Forget(channelName string)
+}
+
+// Tracker records how long viewers are present on live streams. A viewer is present, when the viewer
+// is on the list of chatters or is active in chat.
+// Watch time is credited at every interval and saved in aggregated form.
+type Tracker struct {
+	helixClient *helix.Client     // HelixClient is used to fetch lists of chatters.
+	storage     storage           // Storage saves watch time.
+	status      liveChecker       // Status tells, if streams are live.
+	activity    activityReader    // Activity tells, which viewers are active in chat.
+	channels    map[string]string // Channels holds IDs of tracked channels by their names.
+	moderatorID string            // ModeratorID is an ID of the user that reads chatters, usually the chatbot.
+	ignored     map[string]bool   // Ignored holds logins of users whose watch time is not tracked, like the chatbot and broadcasters.
+	interval    time.Duration     // Interval is how often watch time is credited.
+	now         func() time.Time  // Now returns the current time.
+	logger      *zap.Logger       // Logger is used for logging.
+}
+
+// NewTracker creates an instance of Tracker. Reading chatters requires a user access token of a moderator
+// with the moderator:read:chatters scope, without it only chat activity is tracked.
+func NewTracker(helixClient *helix.Client, storage storage, status liveChecker, activity activityReader, channels map[string]string, moderatorID, chatbotName string, interval time.Duration, logger *zap.Logger) *Tracker {
+	ignored := map[string]bool{strings.ToLower(chatbotName): true}
+	for channelName := range channels {
+		ignored[strings.ToLower(channelName)] = true
+	}
+
+	return &Tracker{
+		helixClient: helixClient,
+		storage:     storage,
+		status:      status,
+		activity:    activity,
+		channels:    channels,
+		moderatorID: moderatorID,
+		ignored:     ignored,
+		interval:    interval,
+		now:         time.Now,
+		logger:      logger.Named("watchtime"),
+	}
+}
+
+// Run credits watch time at every interval, until the context is done.
+func (t *Tracker) Run(ctx context.Context) error {
+	ticker := time.NewTicker(t.interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return nil
+		case <-ticker.C:
+			for channelName, broadcasterID := range t.channels {
+				t.credit(ctx, channelName, broadcasterID)
+			}
+		}
+	}
+}
+
+// credit adds the interval to watch time of every viewer present on a live stream of a channel.
+func (t *Tracker) credit(ctx context.Context, channelName, broadcasterID string) {
+	channelName = strings.ToLower(channelName)
+
+	if !t.status.IsLive(channelName) {
+		t.activity.Forget(channelName)
+		return
+	}
+
+	spanCtx, span := tracer.Start(ctx, "credit")
+	defer span.End()
+
+	now := t.now()
+
+	chatters, err := t.fetchChatters(broadcasterID)
+	if err != nil {
+		span.RecordError(err)
+		t.logger.Warn("failed to fetch chatters, only chat activity is tracked", zap.String("channel_name", channelName), zap.Error(err))
+	}
+
+	entries := t.present(channelName, chatters)
+	span.SetAttributes(attribute.String("channel.name", channelName), attribute.Int("watchtime.viewers", len(entries)))
+	if len(entries) == 0 {
+		span.SetStatus(codes.Ok, "no viewers were present")
+		return
+	}
+
+	if err = t.storage.Add(spanCtx, channelName, entries, now); err != nil {
+		span.SetStatus(codes.Error, "failed to save watch time")
+		t.logger.Error("failed to save watch time", zap.String("channel_name", channelName), zap.Error(err))
+		return
+	}
+
+	span.SetStatus(codes.Ok, "successfully credited watch time")
+}
+
+// present returns entries of chatters and viewers active in chat, each viewer once.
+func (t *Tracker) present(channelName string, chatters []helix.ChatChatter) []Entry {
+	usernames := make(map[string]string)
+	for _, chatter := range chatters {
+		usernames[chatter.UserID] = chatter.UserLogin
+	}
+	for _, viewer := range t.activity.Active(channelName) {
+		usernames[viewer.UserID] = viewer.Username
+	}
+
+	entries := []Entry{}
+	for userID, username := range usernames {
+		username = strings.ToLower(username)
+		if userID == "" || t.ignored[username] {
+			continue
+		}
+		entries = append(entries, Entry{UserID: userID, Username: username, WatchTime: t.interval})
+	}
+
+	return entries
+}
+
+// fetchChatters returns all users connected to the chat of a channel.
+func (t *Tracker) fetchChatters(broadcasterID string) ([]helix.ChatChatter, error) {
+	chatters := []helix.ChatChatter{}
+	cursor := ""
+
+	for {
+		resp, err := t.helixClient.GetChannelChatChatters(&helix.GetChatChattersParams{
+			BroadcasterID: broadcasterID,
+			ModeratorID:   t.moderatorID,
+			First:         chattersPageSize,
+			After:         cursor,
+		})
+		if err == nil {
+			err = twitchapi.ResponseError(resp.ResponseCommon)
+		}
+		if err != nil {
+			return nil, err
+		}
+
+		chatters = append(chatters, resp.Data.Chatters...)
+		cursor = resp.Data.Pagination.Cursor
+		if cursor == "" {
+			return chatters, nil
+		}
+	}
+}
@@ -0,0 +1,173 @@
+package watchtime
+
+import (
+	"context"
+	"net/http"
+	"slices"
+	"testing"
+	"time"
+
+	"github.com/danielbukowski/twitch-chatbot/internal/command"
+	"github.com/danielbukowski/twitch-chatbot/internal/helixtest"
+	"github.com/danielbukowski/twitch-chatbot/internal/presence"
+	"github.com/gempir/go-twitch-irc/v4"
+	"go.uber.org/zap"
+)
+
+type storageMock struct {
+	added map[string]time.Duration
+}
+
+func (s *storageMock) Add(_ context.Context, _ string, entries []Entry, _ time.Time) error {
+	for _, entry := range entries {
+		s.added[entry.Username] += entry.WatchTime
+	}
+	return nil
+}
+
+type liveCheckerMock struct {
+	live bool
+}
+
+func (l *liveCheckerMock) IsLive(_ string) bool {
+	return l.live
+}
+
+// newTestTracker creates a tracker with a stand-in of Twitch API, which returns chatters in two pages.
+func newTestTracker(t *testing.T, storage *storageMock, status *liveCheckerMock, activity *presence.Tracker) *Tracker {
+	t.Helper()
+
+	helixClient := helixtest.NewClient(t, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/chat/chatters" {
+			w.WriteHeader(http.StatusNotFound)
+			return
+		}
+
+		w.Header().Set("Content-Type", "application/json")
+		if r.URL.Query().Get("after") == "" {
+			_, _ = w.Write([]byte(`{"data":[{"user_id":"1","user_login":"lurker"},{"user_id":"99","user_login":"chatbot"}],"pagination":{"cursor":"next"},"total":3}`))
+			return
+		}
+		_, _ = w.Write([]byte(`{"data":[{"user_id":"2","user_login":"channel"}],"pagination":{},"total":3}`))
+	}))
+
+	return NewTracker(helixClient, storage, status, activity, map[string]string{"channel": "2"}, "99", "chatbot", time.Minute, zap.NewNop())
+}
+
+func TestTracker(t *testing.T) {
+	t.Run("credits chatters and active viewers, but not the chatbot and the broadcaster", func(t *testing.T) {
+		// given
+		storage := &storageMock{added: map[string]time.Duration{}}
+		activity := presence.NewTracker("chatbot", 10*time.Minute)
+		tracker := newTestTracker(t, storage, &liveCheckerMock{live: true}, activity)
+		activity.Observe(&twitch.PrivateMessage{Channel: "channel", User: twitch.User{ID: "3", Name: "Chatter"}})
+
+		// when
+		tracker.credit(context.Background(), "channel", "2")
+		tracker.credit(context.Background(), "channel", "2")
+
+		// then
+		if len(storage.added) != 2 || storage.added["lurker"] != 2*time.Minute || storage.added["chatter"] != 2*time.Minute {
+			t.Fatalf("Expected two minutes for the lurker and the chatter, got `%v`", storage.added)
+		}
+	})
+
+	t.Run("does not credit viewers while the stream is offline", func(t *testing.T) {
+		// given
+		storage := &storageMock{added: map[string]time.Duration{}}
+		status := &liveCheckerMock{}
+		activity := presence.NewTracker("chatbot", 10*time.Minute)
+		tracker := newTestTracker(t, storage, status, activity)
+		activity.Observe(&twitch.PrivateMessage{Channel: "channel", User: twitch.User{ID: "3", Name: "chatter"}})
+
+		// when
+		tracker.credit(context.Background(), "channel", "2")
+		status.live = true
+		tracker.credit(context.Background(), "channel", "2")
+
+		// then
+		if _, ok := storage.added["chatter"]; ok {
+			t.Fatalf("Expected viewers seen before the stream not to be credited, got `%v`", storage.added)
+		}
+	})
+}
+
+type readerMock struct {
+	top []Entry
+}
+
+func (r readerMock) ByUsername(_ context.Context, _, _ string) (Entry, bool, error) {
+	return Entry{}, false, nil
+}
+
+func (r readerMock) Top(_ context.Context, _ string, limit int) ([]Entry, error) {
+	return r.top[:min(limit, len(r.top))], nil
+}
+
+type chatClientMock struct {
+	messages []string
+}
+
+func (c *chatClientMock) Say(_, message string) {
+	c.messages = append(c.messages, message)
+}
+
+func (c *chatClientMock) Reply(_, _, message string) {
+	c.messages = append(c.messages, message)
+}
+
+func (c *chatClientMock) Join(_ ...string) {}
+
+func (c *chatClientMock) Depart(_ string) {}
+
+func TestTopWatchers(t *testing.T) {
+	// given
+	commands := NewCommands(readerMock{top: []Entry{
+		{Username: "lurker", WatchTime: 26*time.Hour + 3*time.Minute},
+		{Username: "chatter", WatchTime: 90 * time.Minute},
+	}}, nil)
+	privMsg := &twitch.PrivateMessage{Channel: "channel", User: twitch.User{DisplayName: "Viewer"}}
+	ctx := command.WithContext(context.Background(), command.NewContext("!topwatchers", privMsg, zap.NewNop()))
+	chatClient := &chatClientMock{}
+
+	// when
+	err := commands.TopWatchers()(ctx, nil, chatClient)
+
+	// then
+	if err != nil {
+		t.Fatalf("Expected no error, got `%v`", err)
+	}
+	expected := []string{"@Viewer, top watchers: 1. lurker (1d 2h 3m), 2. chatter (1h 30m)"}
+	if !slices.Equal(chatClient.messages, expected) {
+		t.Fatalf("Expected `%v`, got `%v`", expected, chatClient.messages)
+	}
+}
+
+func TestFormatAge(t *testing.T) {
+	testCases := []struct {
+		name     string
+		from, to time.Time
+		expected string
+	}{
+		{name: "less than a day", from: date(2026, 10, 19), to: date(2026, 10, 19).Add(5 * time.Hour), expected: "less than a day"},
+		{name: "years, months and days", from: date(2024, 8, 15), to: date(2026, 10, 19), expected: "2 years, 2 months, 4 days"},
+		{name: "days borrowed from the previous month", from: date(2026, 1, 31), to: date(2026, 3, 1), expected: "1 month, 1 day"},
+		{name: "a year without months", from: date(2025, 10, 19), to: date(2026, 10, 19), expected: "1 year"},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			// when
+			got := formatAge(tc.from, tc.to)
+
+			// then
+			if got != tc.expected {
+				t.Errorf("Expected `%s`, got `%s`", tc.expected, got)
+			}
+		})
+	}
+}
+
+func date(year int, month time.Month, day int) time.Time {
+	return time.Date(year, month, day, 12, 0, 0, 0, time.UTC)
+}